@@ -10,6 +10,7 @@ import (
 	"github.com/xtls/xray-core/common/signal/done"
 	core "github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/transport/internet"
 	"google.golang.org/grpc"
 )
 
@@ -21,6 +22,7 @@ type Commander struct {
 	ohm      outbound.Manager
 	tag      string
 	listen   string
+	draining *done.Instance
 }
 
 // NewCommander creates a new Commander based on the given config.
@@ -74,7 +76,12 @@ func (c *Commander) Start() error {
 	}
 
 	if len(c.listen) > 0 {
-		if l, err := net.Listen("tcp", c.listen); err != nil {
+		addr, err := net.ResolveTCPAddr("tcp", c.listen)
+		if err != nil {
+			return errors.New("invalid API server address ", c.listen).Base(err)
+		}
+		// The port is shared with the server that replaces this one on reload, until this one is drained.
+		if l, err := internet.ListenSystem(context.Background(), addr, nil); err != nil {
 			errors.LogErrorInner(context.Background(), err, "API server failed to listen on ", c.listen)
 			return err
 		} else {
@@ -101,6 +108,35 @@ func (c *Commander) Start() error {
 	})
 }
 
+// Drain implements features.Drainer. The gRPC server stops accepting, and the calls in progress get until Close to
+// finish.
+func (c *Commander) Drain() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.server == nil || c.draining != nil {
+		return nil
+	}
+	server := c.server
+	c.draining = done.New()
+	go func(d *done.Instance) {
+		server.GracefulStop()
+		d.Close()
+	}(c.draining)
+	return nil
+}
+
+// ActiveSessions implements features.SessionCounter. The calls in progress count as one session while draining.
+func (c *Commander) ActiveSessions() int {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.draining == nil || c.draining.Done() {
+		return 0
+	}
+	return 1
+}
+
 // Close implements common.Closable.
 func (c *Commander) Close() error {
 	c.Lock()
@@ -0,0 +1,35 @@
+package dispatcher
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// admitConnection checks the connection limits of the user of the session in ctx. It returns a function to be called
+// once the connection is closed. A rejection is recorded in the access log and counted in the
+// "user>>>EMAIL>>>connection>>>rejected" counter.
+func (d *DefaultDispatcher) admitConnection(ctx context.Context) (func(), error) {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || inbound.User == nil || len(inbound.User.Email) == 0 {
+		return func() {}, nil
+	}
+	release, err := policy.AdmitConnection(d.policy, inbound.User)
+	if err == nil {
+		return release, nil
+	}
+	email := inbound.User.Email
+	if c, _ := stats.GetOrRegisterCounter(d.stats, "user>>>"+email+">>>connection>>>rejected"); c != nil {
+		c.Add(1)
+	}
+	if accessMessage := log.AccessMessageFromContext(ctx); accessMessage != nil {
+		accessMessage.Status = log.AccessRejected
+		accessMessage.Reason = err
+		log.Record(accessMessage)
+	}
+	return nil, errors.New("rejected connection of user ", email).Base(err)
+}
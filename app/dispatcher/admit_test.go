@@ -0,0 +1,56 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+)
+
+func TestAdmitConnection(t *testing.T) {
+	pm, err := policy.New(context.Background(), &policy.Config{
+		Level: map[uint32]*policy.Policy{
+			0: {
+				Limit: &policy.Policy_Limit{
+					MaxConcurrentConnections: 1,
+				},
+			},
+		},
+	})
+	common.Must(err)
+	sm, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	d := &DefaultDispatcher{
+		policy: pm,
+		stats:  sm,
+		conns:  NewConnectionRegistry(),
+	}
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		Source: net.TCPDestination(net.LocalHostIP, 1234),
+		User:   &protocol.MemoryUser{Email: "love@xray.com"},
+	})
+
+	release, err := d.admitConnection(ctx)
+	common.Must(err)
+	c := d.conns.open(ctx, net.TCPDestination(net.DomainAddress("example.com"), 443))
+	c.onRelease(release)
+	in, out := newTestLinks()
+	c.track(in, out)
+
+	if _, err := d.admitConnection(ctx); err == nil {
+		t.Error("expected connection to be rejected")
+	}
+	if counter := sm.GetCounter("user>>>love@xray.com>>>connection>>>rejected"); counter == nil || counter.Value() != 1 {
+		t.Error("expected rejected connection to be counted")
+	}
+
+	common.Close(out.Writer)
+	if _, err := d.admitConnection(ctx); err != nil {
+		t.Error("expected closed connection to be counted off, but got ", err)
+	}
+}
@@ -0,0 +1,155 @@
+package command
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/routing"
+	grpc "google.golang.org/grpc"
+)
+
+// connectionServer is an implementation of ConnectionService.
+type connectionServer struct {
+	dispatcher routing.Dispatcher
+}
+
+func NewConnectionServer(d routing.Dispatcher) ConnectionServiceServer {
+	return &connectionServer{
+		dispatcher: d,
+	}
+}
+
+func (s *connectionServer) registry() (*dispatcher.ConnectionRegistry, error) {
+	d, err := s.dispatcherInstance()
+	if err != nil {
+		return nil, err
+	}
+	return d.Connections(), nil
+}
+
+func (s *connectionServer) ListConnections(ctx context.Context, request *ListConnectionsRequest) (*ListConnectionsResponse, error) {
+	registry, err := s.registry()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := registry.List()
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+
+	response := &ListConnectionsResponse{}
+	for _, info := range infos {
+		if request.Email != "" && info.Email != request.Email {
+			continue
+		}
+		if request.InboundTag != "" && info.InboundTag != request.InboundTag {
+			continue
+		}
+		conn := &Connection{
+			Id:          info.ID,
+			InboundTag:  info.InboundTag,
+			Email:       info.Email,
+			Domain:      info.Domain,
+			OutboundTag: info.OutboundTag,
+			StartTime:   info.StartTime.Unix(),
+			Uplink:      info.Uplink,
+			Downlink:    info.Downlink,
+		}
+		if info.Source.IsValid() {
+			conn.Source = info.Source.String()
+		}
+		if info.Destination.IsValid() {
+			conn.Destination = info.Destination.String()
+		}
+		response.Connections = append(response.Connections, conn)
+	}
+	return response, nil
+}
+
+func (s *connectionServer) CloseConnection(ctx context.Context, request *CloseConnectionRequest) (*CloseConnectionResponse, error) {
+	registry, err := s.registry()
+	if err != nil {
+		return nil, err
+	}
+	if !registry.Close(request.Id) {
+		return nil, errors.New("connection ", request.Id, " not found.")
+	}
+	return &CloseConnectionResponse{}, nil
+}
+
+func (s *connectionServer) CloseByUser(ctx context.Context, request *CloseByUserRequest) (*CloseByUserResponse, error) {
+	if request.Email == "" {
+		return nil, errors.New("email is empty")
+	}
+	registry, err := s.registry()
+	if err != nil {
+		return nil, err
+	}
+	return &CloseByUserResponse{
+		Count: uint32(registry.CloseByUser(request.Email)),
+	}, nil
+}
+
+func (s *connectionServer) dispatcherInstance() (*dispatcher.DefaultDispatcher, error) {
+	d, ok := s.dispatcher.(*dispatcher.DefaultDispatcher)
+	if !ok {
+		return nil, errors.New("ConnectionService only works with its own dispatcher.")
+	}
+	return d, nil
+}
+
+func (s *connectionServer) ResetUserQuota(ctx context.Context, request *ResetUserQuotaRequest) (*ResetUserQuotaResponse, error) {
+	if request.Email == "" {
+		return nil, errors.New("email is empty")
+	}
+	d, err := s.dispatcherInstance()
+	if err != nil {
+		return nil, err
+	}
+	d.ResetQuota(request.Email)
+	return &ResetUserQuotaResponse{}, nil
+}
+
+func (s *connectionServer) ExtendUserQuota(ctx context.Context, request *ExtendUserQuotaRequest) (*ExtendUserQuotaResponse, error) {
+	if request.Email == "" {
+		return nil, errors.New("email is empty")
+	}
+	d, err := s.dispatcherInstance()
+	if err != nil {
+		return nil, err
+	}
+	var expireAt time.Time
+	if request.ExpireAt != 0 {
+		expireAt = time.Unix(request.ExpireAt, 0)
+	}
+	d.ExtendQuota(request.Email, request.Quota, expireAt)
+	return &ExtendUserQuotaResponse{}, nil
+}
+
+func (s *connectionServer) mustEmbedUnimplementedConnectionServiceServer() {}
+
+type service struct {
+	dispatcher routing.Dispatcher
+}
+
+func (s *service) Register(server *grpc.Server) {
+	RegisterConnectionServiceServer(server, NewConnectionServer(s.dispatcher))
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		s := new(service)
+
+		core.RequireFeatures(ctx, func(d routing.Dispatcher) {
+			s.dispatcher = d
+		})
+
+		return s, nil
+	}))
+}
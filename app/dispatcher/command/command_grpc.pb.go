@@ -0,0 +1,277 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.2
+// source: app/dispatcher/command/command.proto
+
+package command
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ConnectionService_ListConnections_FullMethodName = "/xray.app.dispatcher.command.ConnectionService/ListConnections"
+	ConnectionService_CloseConnection_FullMethodName = "/xray.app.dispatcher.command.ConnectionService/CloseConnection"
+	ConnectionService_CloseByUser_FullMethodName     = "/xray.app.dispatcher.command.ConnectionService/CloseByUser"
+	ConnectionService_ResetUserQuota_FullMethodName  = "/xray.app.dispatcher.command.ConnectionService/ResetUserQuota"
+	ConnectionService_ExtendUserQuota_FullMethodName = "/xray.app.dispatcher.command.ConnectionService/ExtendUserQuota"
+)
+
+// ConnectionServiceClient is the client API for ConnectionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ConnectionServiceClient interface {
+	ListConnections(ctx context.Context, in *ListConnectionsRequest, opts ...grpc.CallOption) (*ListConnectionsResponse, error)
+	CloseConnection(ctx context.Context, in *CloseConnectionRequest, opts ...grpc.CallOption) (*CloseConnectionResponse, error)
+	CloseByUser(ctx context.Context, in *CloseByUserRequest, opts ...grpc.CallOption) (*CloseByUserResponse, error)
+	// Resets the traffic used by the user in the current period.
+	ResetUserQuota(ctx context.Context, in *ResetUserQuotaRequest, opts ...grpc.CallOption) (*ResetUserQuotaResponse, error)
+	// Adds traffic to the quota of the user, or extends the expiry of the user.
+	ExtendUserQuota(ctx context.Context, in *ExtendUserQuotaRequest, opts ...grpc.CallOption) (*ExtendUserQuotaResponse, error)
+}
+
+type connectionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewConnectionServiceClient(cc grpc.ClientConnInterface) ConnectionServiceClient {
+	return &connectionServiceClient{cc}
+}
+
+func (c *connectionServiceClient) ListConnections(ctx context.Context, in *ListConnectionsRequest, opts ...grpc.CallOption) (*ListConnectionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListConnectionsResponse)
+	err := c.cc.Invoke(ctx, ConnectionService_ListConnections_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionServiceClient) CloseConnection(ctx context.Context, in *CloseConnectionRequest, opts ...grpc.CallOption) (*CloseConnectionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CloseConnectionResponse)
+	err := c.cc.Invoke(ctx, ConnectionService_CloseConnection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionServiceClient) CloseByUser(ctx context.Context, in *CloseByUserRequest, opts ...grpc.CallOption) (*CloseByUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CloseByUserResponse)
+	err := c.cc.Invoke(ctx, ConnectionService_CloseByUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionServiceClient) ResetUserQuota(ctx context.Context, in *ResetUserQuotaRequest, opts ...grpc.CallOption) (*ResetUserQuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResetUserQuotaResponse)
+	err := c.cc.Invoke(ctx, ConnectionService_ResetUserQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectionServiceClient) ExtendUserQuota(ctx context.Context, in *ExtendUserQuotaRequest, opts ...grpc.CallOption) (*ExtendUserQuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExtendUserQuotaResponse)
+	err := c.cc.Invoke(ctx, ConnectionService_ExtendUserQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConnectionServiceServer is the server API for ConnectionService service.
+// All implementations must embed UnimplementedConnectionServiceServer
+// for forward compatibility.
+type ConnectionServiceServer interface {
+	ListConnections(context.Context, *ListConnectionsRequest) (*ListConnectionsResponse, error)
+	CloseConnection(context.Context, *CloseConnectionRequest) (*CloseConnectionResponse, error)
+	CloseByUser(context.Context, *CloseByUserRequest) (*CloseByUserResponse, error)
+	// Resets the traffic used by the user in the current period.
+	ResetUserQuota(context.Context, *ResetUserQuotaRequest) (*ResetUserQuotaResponse, error)
+	// Adds traffic to the quota of the user, or extends the expiry of the user.
+	ExtendUserQuota(context.Context, *ExtendUserQuotaRequest) (*ExtendUserQuotaResponse, error)
+	mustEmbedUnimplementedConnectionServiceServer()
+}
+
+// UnimplementedConnectionServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedConnectionServiceServer struct{}
+
+func (UnimplementedConnectionServiceServer) ListConnections(context.Context, *ListConnectionsRequest) (*ListConnectionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListConnections not implemented")
+}
+func (UnimplementedConnectionServiceServer) CloseConnection(context.Context, *CloseConnectionRequest) (*CloseConnectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseConnection not implemented")
+}
+func (UnimplementedConnectionServiceServer) CloseByUser(context.Context, *CloseByUserRequest) (*CloseByUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseByUser not implemented")
+}
+func (UnimplementedConnectionServiceServer) ResetUserQuota(context.Context, *ResetUserQuotaRequest) (*ResetUserQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetUserQuota not implemented")
+}
+func (UnimplementedConnectionServiceServer) ExtendUserQuota(context.Context, *ExtendUserQuotaRequest) (*ExtendUserQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExtendUserQuota not implemented")
+}
+func (UnimplementedConnectionServiceServer) mustEmbedUnimplementedConnectionServiceServer() {}
+func (UnimplementedConnectionServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeConnectionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConnectionServiceServer will
+// result in compilation errors.
+type UnsafeConnectionServiceServer interface {
+	mustEmbedUnimplementedConnectionServiceServer()
+}
+
+func RegisterConnectionServiceServer(s grpc.ServiceRegistrar, srv ConnectionServiceServer) {
+	// If the following call pancis, it indicates UnimplementedConnectionServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ConnectionService_ServiceDesc, srv)
+}
+
+func _ConnectionService_ListConnections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListConnectionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionServiceServer).ListConnections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionService_ListConnections_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionServiceServer).ListConnections(ctx, req.(*ListConnectionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionService_CloseConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseConnectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionServiceServer).CloseConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionService_CloseConnection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionServiceServer).CloseConnection(ctx, req.(*CloseConnectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionService_CloseByUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseByUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionServiceServer).CloseByUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionService_CloseByUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionServiceServer).CloseByUser(ctx, req.(*CloseByUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionService_ResetUserQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetUserQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionServiceServer).ResetUserQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionService_ResetUserQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionServiceServer).ResetUserQuota(ctx, req.(*ResetUserQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnectionService_ExtendUserQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtendUserQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectionServiceServer).ExtendUserQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConnectionService_ExtendUserQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectionServiceServer).ExtendUserQuota(ctx, req.(*ExtendUserQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ConnectionService_ServiceDesc is the grpc.ServiceDesc for ConnectionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ConnectionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "xray.app.dispatcher.command.ConnectionService",
+	HandlerType: (*ConnectionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListConnections",
+			Handler:    _ConnectionService_ListConnections_Handler,
+		},
+		{
+			MethodName: "CloseConnection",
+			Handler:    _ConnectionService_CloseConnection_Handler,
+		},
+		{
+			MethodName: "CloseByUser",
+			Handler:    _ConnectionService_CloseByUser_Handler,
+		},
+		{
+			MethodName: "ResetUserQuota",
+			Handler:    _ConnectionService_ResetUserQuota_Handler,
+		},
+		{
+			MethodName: "ExtendUserQuota",
+			Handler:    _ConnectionService_ExtendUserQuota_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "app/dispatcher/command/command.proto",
+}
@@ -0,0 +1,294 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
+)
+
+// ConnectionInfo is a snapshot of a session that is being dispatched.
+type ConnectionInfo struct {
+	ID          uint64
+	InboundTag  string
+	Email       string
+	Source      net.Destination
+	Destination net.Destination
+	Domain      string
+	OutboundTag string
+	StartTime   time.Time
+	Uplink      int64
+	Downlink    int64
+}
+
+type connection struct {
+	sync.Mutex
+	info     ConnectionInfo
+	user     *protocol.MemoryUser
+	links    []*transport.Link
+	uplink   stats.Counter
+	downlink stats.Counter
+	finish   sync.Once
+	registry *ConnectionRegistry
+	// releases are called once the connection is unregistered, to count it off the limits of the user.
+	releases []func()
+	released bool
+}
+
+// onRelease adds release to be called once the connection is unregistered, or calls it now if it already is.
+func (c *connection) onRelease(release func()) {
+	c.Lock()
+	if !c.released {
+		c.releases = append(c.releases, release)
+		c.Unlock()
+		return
+	}
+	c.Unlock()
+	release()
+}
+
+// track wraps the writers of both links returned by Dispatch, so that the traffic of this connection is counted,
+// and remembers them for Close.
+func (c *connection) track(inbound, outbound *transport.Link) {
+	inbound.Writer = &SizeStatWriter{
+		Counter: &c.uplink,
+		Writer:  inbound.Writer,
+	}
+	c.trackLink(outbound)
+	c.Lock()
+	c.links = append(c.links, inbound)
+	c.Unlock()
+}
+
+// trackLink is like track, but for the link handed over by DispatchLink, where only the outbound side is known.
+func (c *connection) trackLink(outbound *transport.Link) {
+	outbound.Writer = &connectionWriter{
+		SizeStatWriter: &SizeStatWriter{
+			Counter: &c.downlink,
+			Writer:  outbound.Writer,
+		},
+		conn: c,
+	}
+	c.Lock()
+	c.links = append(c.links, outbound)
+	c.Unlock()
+}
+
+// countUplink wraps the uplink reader of the link handed over by DispatchLink.
+// It must be called after sniffing, as the sniffer requires the original reader.
+func (c *connection) countUplink(link *transport.Link) {
+	link.Reader = newSizeStatReader(&c.uplink, link.Reader)
+}
+
+func (c *connection) setDomain(domain string) {
+	c.Lock()
+	c.info.Domain = domain
+	c.Unlock()
+}
+
+func (c *connection) setRoute(destination net.Destination, outboundTag string) {
+	c.Lock()
+	c.info.Destination = destination
+	c.info.OutboundTag = outboundTag
+	c.Unlock()
+}
+
+func (c *connection) snapshot() ConnectionInfo {
+	c.Lock()
+	info := c.info
+	c.Unlock()
+	info.Uplink = c.uplink.Value()
+	info.Downlink = c.downlink.Value()
+	return info
+}
+
+// close interrupts both directions of the connection. The proxies on either side see a closed pipe and tear down.
+func (c *connection) close() {
+	c.Lock()
+	links := c.links
+	c.Unlock()
+	for _, link := range links {
+		common.Interrupt(link.Reader)
+		common.Interrupt(link.Writer)
+	}
+}
+
+// connectionWriter is the downlink writer of a connection. The outbound always closes or interrupts it
+// when it is done with the connection, even if it hands the link over to a mux worker, so this is where
+// the connection is unregistered.
+type connectionWriter struct {
+	*SizeStatWriter
+	conn *connection
+}
+
+func (w *connectionWriter) Close() error {
+	w.conn.finish.Do(w.conn.unregister)
+	return w.SizeStatWriter.Close()
+}
+
+func (w *connectionWriter) Interrupt() {
+	w.conn.finish.Do(w.conn.unregister)
+	w.SizeStatWriter.Interrupt()
+}
+
+func (c *connection) unregister() {
+	c.registry.access.Lock()
+	delete(c.registry.conns, c.info.ID)
+	c.registry.access.Unlock()
+	c.Lock()
+	c.released = true
+	releases := c.releases
+	c.releases = nil
+	c.Unlock()
+	for _, release := range releases {
+		release()
+	}
+}
+
+type sizeStatReader struct {
+	counter feature_stats.Counter
+	reader  buf.Reader
+}
+
+// newSizeStatReader wraps reader so that the size of the data read is added to counter.
+func newSizeStatReader(counter feature_stats.Counter, reader buf.Reader) buf.Reader {
+	r := &sizeStatReader{
+		counter: counter,
+		reader:  reader,
+	}
+	if tr, ok := reader.(buf.TimeoutReader); ok {
+		return &sizeStatTimeoutReader{
+			sizeStatReader: r,
+			timeoutReader:  tr,
+		}
+	}
+	return r
+}
+
+func (r *sizeStatReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.reader.ReadMultiBuffer()
+	r.counter.Add(int64(mb.Len()))
+	return mb, err
+}
+
+func (r *sizeStatReader) Interrupt() {
+	common.Interrupt(r.reader)
+}
+
+type sizeStatTimeoutReader struct {
+	*sizeStatReader
+	timeoutReader buf.TimeoutReader
+}
+
+func (r *sizeStatTimeoutReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	mb, err := r.timeoutReader.ReadMultiBufferTimeout(timeout)
+	r.counter.Add(int64(mb.Len()))
+	return mb, err
+}
+
+// ConnectionRegistry keeps track of all sessions that are currently dispatched.
+type ConnectionRegistry struct {
+	access sync.RWMutex
+	lastID atomic.Uint64
+	conns  map[uint64]*connection
+}
+
+// NewConnectionRegistry creates an empty ConnectionRegistry.
+func NewConnectionRegistry() *ConnectionRegistry {
+	return &ConnectionRegistry{
+		conns: make(map[uint64]*connection),
+	}
+}
+
+// open registers a new connection for the session in ctx.
+func (r *ConnectionRegistry) open(ctx context.Context, destination net.Destination) *connection {
+	c := &connection{
+		registry: r,
+	}
+	c.info.ID = r.lastID.Add(1)
+	c.info.Destination = destination
+	c.info.StartTime = time.Now()
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		c.info.InboundTag = inbound.Tag
+		c.info.Source = inbound.Source
+		if inbound.User != nil {
+			c.user = inbound.User
+			c.info.Email = inbound.User.Email
+		}
+	}
+
+	r.access.Lock()
+	r.conns[c.info.ID] = c
+	r.access.Unlock()
+	return c
+}
+
+// List returns a snapshot of all active connections.
+func (r *ConnectionRegistry) List() []ConnectionInfo {
+	r.access.RLock()
+	defer r.access.RUnlock()
+
+	infos := make([]ConnectionInfo, 0, len(r.conns))
+	for _, c := range r.conns {
+		infos = append(infos, c.snapshot())
+	}
+	return infos
+}
+
+// Len returns the number of active connections.
+func (r *ConnectionRegistry) Len() int {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	return len(r.conns)
+}
+
+// CloseAll terminates all connections, and returns how many were closed.
+func (r *ConnectionRegistry) CloseAll() int {
+	return r.closeIf(func(*protocol.MemoryUser) bool {
+		return true
+	})
+}
+
+// Close terminates the connection with the given ID. It returns false if no such connection exists.
+func (r *ConnectionRegistry) Close(id uint64) bool {
+	r.access.RLock()
+	c, found := r.conns[id]
+	r.access.RUnlock()
+	if !found {
+		return false
+	}
+	c.close()
+	return true
+}
+
+// CloseByUser terminates all connections of the user with the given email, and returns how many were closed.
+func (r *ConnectionRegistry) CloseByUser(email string) int {
+	return r.closeIf(func(user *protocol.MemoryUser) bool {
+		return user != nil && user.Email == email
+	})
+}
+
+// closeIf terminates all connections whose user matches, and returns how many were closed.
+func (r *ConnectionRegistry) closeIf(match func(user *protocol.MemoryUser) bool) int {
+	var matched []*connection
+	r.access.RLock()
+	for _, c := range r.conns {
+		if match(c.user) {
+			matched = append(matched, c)
+		}
+	}
+	r.access.RUnlock()
+	for _, c := range matched {
+		c.close()
+	}
+	return len(matched)
+}
@@ -0,0 +1,86 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+func newTestLinks() (*transport.Link, *transport.Link) {
+	uplinkReader, uplinkWriter := pipe.New()
+	downlinkReader, downlinkWriter := pipe.New()
+	return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter},
+		&transport.Link{Reader: uplinkReader, Writer: downlinkWriter}
+}
+
+func TestConnectionRegistry(t *testing.T) {
+	r := NewConnectionRegistry()
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		Tag:    "in",
+		Source: net.TCPDestination(net.LocalHostIP, 1234),
+		User:   &protocol.MemoryUser{Email: "love@xray.com"},
+	})
+	dest := net.TCPDestination(net.DomainAddress("example.com"), 443)
+
+	c1 := r.open(ctx, dest)
+	in1, out1 := newTestLinks()
+	c1.track(in1, out1)
+	c2 := r.open(context.Background(), dest)
+	in2, out2 := newTestLinks()
+	c2.track(in2, out2)
+
+	common.Must(in1.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("abcd"))))
+	common.Must(out1.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("ef"))))
+	c1.setRoute(dest, "out")
+
+	infos := r.List()
+	if len(infos) != 2 {
+		t.Fatal("expected 2 connections, but got ", len(infos))
+	}
+	for _, info := range infos {
+		if info.ID != c1.info.ID {
+			continue
+		}
+		if info.Email != "love@xray.com" || info.InboundTag != "in" || info.OutboundTag != "out" {
+			t.Error("unexpected connection info: ", info)
+		}
+		if info.Uplink != 4 || info.Downlink != 2 {
+			t.Error("unexpected traffic: ", info.Uplink, " ", info.Downlink)
+		}
+	}
+
+	if n := r.CloseByUser("love@xray.com"); n != 1 {
+		t.Fatal("expected to close 1 connection, but closed ", n)
+	}
+	if _, err := in1.Reader.ReadMultiBuffer(); err == nil {
+		t.Error("expected closed connection")
+	}
+	if len(r.List()) != 1 {
+		t.Error("expected closed connection to be unregistered")
+	}
+
+	common.Must(common.Close(out2.Writer))
+	if len(r.List()) != 0 {
+		t.Error("expected finished connection to be unregistered")
+	}
+	if r.Close(c2.info.ID) {
+		t.Error("expected finished connection to be gone")
+	}
+
+	c3 := r.open(ctx, dest)
+	in3, out3 := newTestLinks()
+	c3.track(in3, out3)
+	if r.Len() != 1 {
+		t.Error("expected 1 connection, but got ", r.Len())
+	}
+	if n := r.CloseAll(); n != 1 || r.Len() != 0 {
+		t.Error("expected all connections to be closed, but closed ", n)
+	}
+}
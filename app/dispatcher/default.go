@@ -14,6 +14,8 @@ import (
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
@@ -100,6 +102,11 @@ type DefaultDispatcher struct {
 	stats  stats.Manager
 	dns    dns.Client
 	fdns   dns.FakeDNSEngine
+	conns  *ConnectionRegistry
+
+	quotaAccess  sync.RWMutex
+	quotas       map[string]*userQuota
+	quotaChecker *task.Periodic
 }
 
 func init() {
@@ -124,21 +131,40 @@ func (d *DefaultDispatcher) Init(config *Config, om outbound.Manager, router rou
 	d.policy = pm
 	d.stats = sm
 	d.dns = dns
+	d.conns = NewConnectionRegistry()
+	d.quotas = make(map[string]*userQuota)
+	d.quotaChecker = &task.Periodic{
+		Interval: quotaCheckInterval,
+		Execute:  d.enforceQuotas,
+	}
 	return nil
 }
 
+// Connections returns the registry of the sessions that are currently dispatched.
+func (d *DefaultDispatcher) Connections() *ConnectionRegistry {
+	return d.conns
+}
+
 // Type implements common.HasType.
 func (*DefaultDispatcher) Type() interface{} {
 	return routing.DispatcherType()
 }
 
 // Start implements common.Runnable.
-func (*DefaultDispatcher) Start() error {
-	return nil
+func (d *DefaultDispatcher) Start() error {
+	return d.quotaChecker.Start()
+}
+
+// ActiveSessions implements features.SessionCounter.
+func (d *DefaultDispatcher) ActiveSessions() int {
+	return d.conns.Len()
 }
 
-// Close implements common.Closable.
-func (*DefaultDispatcher) Close() error { return nil }
+// Close implements common.Closable. The sessions still in progress are terminated.
+func (d *DefaultDispatcher) Close() error {
+	d.conns.CloseAll()
+	return d.quotaChecker.Close()
+}
 
 func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *transport.Link) {
 	opt := pipe.OptionsFromContext(ctx)
@@ -162,7 +188,7 @@ func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *tran
 	}
 
 	if user != nil && len(user.Email) > 0 {
-		p := d.policy.ForLevel(user.Level)
+		p := policy.ForUser(d.policy, user)
 		if p.Stats.UserUplink {
 			name := "user>>>" + user.Email + ">>>traffic>>>uplink"
 			if c, _ := stats.GetOrRegisterCounter(d.stats, name); c != nil {
@@ -262,20 +288,38 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 		ctx = session.ContextWithContent(ctx, content)
 	}
 
+	if err := d.admitQuota(ctx); err != nil {
+		return nil, err
+	}
+	release, err := d.admitConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	sniffingRequest := content.SniffingRequest
+	conn := d.conns.open(ctx, destination)
+	conn.onRelease(release)
 	inbound, outbound := d.getLink(ctx)
+	conn.track(inbound, outbound)
+	d.countQuota(ctx, inbound, outbound)
+	d.limitRate(ctx, conn, inbound, outbound)
 	if !sniffingRequest.Enabled {
-		go d.routedDispatch(ctx, outbound, destination)
+		go d.routedDispatch(ctx, conn, outbound, destination)
 	} else {
 		go func() {
 			cReader := &cachedReader{
 				reader: outbound.Reader.(*pipe.Reader),
 			}
 			outbound.Reader = cReader
+			_, span := trace.Start(ctx, "dispatcher.sniff")
 			result, err := sniffer(ctx, cReader, sniffingRequest.MetadataOnly, destination.Network)
 			if err == nil {
 				content.Protocol = result.Protocol()
+				conn.setDomain(result.Domain())
+				span.SetAttribute("xray.sniff.protocol", result.Protocol())
+				span.SetAttribute("xray.sniff.domain", result.Domain())
 			}
+			span.End(err)
 			if err == nil && d.shouldOverride(ctx, result, sniffingRequest, destination) {
 				domain := result.Domain()
 				errors.LogInfo(ctx, "sniffed domain: ", domain)
@@ -294,7 +338,7 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 					ob.Target = destination
 				}
 			}
-			d.routedDispatch(ctx, outbound, destination)
+			d.routedDispatch(ctx, conn, outbound, destination)
 		}()
 	}
 	return inbound, nil
@@ -318,18 +362,37 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 		content = new(session.Content)
 		ctx = session.ContextWithContent(ctx, content)
 	}
+	if err := d.admitQuota(ctx); err != nil {
+		return err
+	}
+	release, err := d.admitConnection(ctx)
+	if err != nil {
+		return err
+	}
+
 	sniffingRequest := content.SniffingRequest
+	conn := d.conns.open(ctx, destination)
+	conn.onRelease(release)
+	conn.trackLink(outbound)
 	if !sniffingRequest.Enabled {
-		d.routedDispatch(ctx, outbound, destination)
+		conn.countUplink(outbound)
+		d.countLinkQuota(ctx, outbound)
+		d.limitLinkRate(ctx, conn, outbound)
+		d.routedDispatch(ctx, conn, outbound, destination)
 	} else {
 		cReader := &cachedReader{
 			reader: outbound.Reader.(*pipe.Reader),
 		}
 		outbound.Reader = cReader
+		_, span := trace.Start(ctx, "dispatcher.sniff")
 		result, err := sniffer(ctx, cReader, sniffingRequest.MetadataOnly, destination.Network)
 		if err == nil {
 			content.Protocol = result.Protocol()
+			conn.setDomain(result.Domain())
+			span.SetAttribute("xray.sniff.protocol", result.Protocol())
+			span.SetAttribute("xray.sniff.domain", result.Domain())
 		}
+		span.End(err)
 		if err == nil && d.shouldOverride(ctx, result, sniffingRequest, destination) {
 			domain := result.Domain()
 			errors.LogInfo(ctx, "sniffed domain: ", domain)
@@ -348,7 +411,10 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 				ob.Target = destination
 			}
 		}
-		d.routedDispatch(ctx, outbound, destination)
+		conn.countUplink(outbound)
+		d.countLinkQuota(ctx, outbound)
+		d.limitLinkRate(ctx, conn, outbound)
+		d.routedDispatch(ctx, conn, outbound, destination)
 	}
 
 	return nil
@@ -399,7 +465,8 @@ func sniffer(ctx context.Context, cReader *cachedReader, metadataOnly bool, netw
 	}
 	return contentResult, contentErr
 }
-func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.Link, destination net.Destination) {
+func (d *DefaultDispatcher) routedDispatch(ctx context.Context, conn *connection, link *transport.Link, destination net.Destination) {
+	_, span := trace.Start(ctx, "dispatcher.route")
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
 	if hosts, ok := d.dns.(dns.HostsLookup); ok && destination.Address.Family().IsDomain() {
@@ -428,6 +495,7 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 			handler = h
 		} else {
 			errors.LogError(ctx, "non existing tag for platform initialized detour: ", forcedOutboundTag)
+			span.End(errors.New("non existing tag for platform initialized detour: ", forcedOutboundTag))
 			common.Close(link.Writer)
 			common.Interrupt(link.Reader)
 			return
@@ -437,6 +505,7 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 			outTag := route.GetOutboundTag()
 			if h := d.ohm.GetHandler(outTag); h != nil {
 				isPickRoute = 2
+				span.SetAttribute("xray.rule.tag", route.GetRuleTag())
 				if route.GetRuleTag() == "" {
 					errors.LogInfo(ctx, "taking detour [", outTag, "] for [", destination, "]")
 				} else {
@@ -457,12 +526,14 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 
 	if handler == nil {
 		errors.LogInfo(ctx, "default outbound handler not exist")
+		span.End(errors.New("default outbound handler not exist"))
 		common.Close(link.Writer)
 		common.Interrupt(link.Reader)
 		return
 	}
 
 	ob.Tag = handler.Tag()
+	conn.setRoute(ob.Target, ob.Tag)
 	if accessMessage := log.AccessMessageFromContext(ctx); accessMessage != nil {
 		if tag := handler.Tag(); tag != "" {
 			if inTag == "" {
@@ -477,6 +548,12 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 		}
 		log.Record(accessMessage)
 	}
+	span.SetAttribute("net.destination", ob.Target)
+	span.SetAttribute("xray.outbound.tag", ob.Tag)
+	span.End(nil)
 
+	ctx, span = trace.Start(ctx, "outbound")
+	span.SetAttribute("xray.outbound.tag", ob.Tag)
 	handler.Dispatch(ctx, link)
+	span.End(nil)
 }
@@ -0,0 +1,255 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
+)
+
+// quotaCheckInterval is the interval to check the quota and expiry of the users of active connections.
+const quotaCheckInterval = 10 * time.Second
+
+var (
+	errQuotaExceeded = errors.New("traffic quota exceeded")
+	errUserExpired   = errors.New("user expired")
+)
+
+// userQuota is the state of the traffic quota of a user.
+type userQuota struct {
+	sync.Mutex
+	// counter counts the traffic of both directions in the current period.
+	counter feature_stats.Counter
+	// extra is the traffic added to the quota for the current period.
+	extra uint64
+	// expireAt overrides the expiry of the user if not zero.
+	expireAt time.Time
+}
+
+// resetTimeCounter is a counter that records when it was last reset, which starts the current quota period.
+type resetTimeCounter interface {
+	LastReset() time.Time
+	SetLastReset(time.Time)
+}
+
+// used returns the traffic used in the current period, starting a new one if period has passed.
+func (q *userQuota) used(period time.Duration) uint64 {
+	if r, ok := q.counter.(resetTimeCounter); ok && period > 0 {
+		if last := r.LastReset(); last.IsZero() {
+			// The first period starts when the counter is first used.
+			r.SetLastReset(time.Now())
+		} else if time.Since(last) >= period {
+			q.counter.Set(0)
+			q.extra = 0
+		}
+	}
+	if v := q.counter.Value(); v > 0 {
+		return uint64(v)
+	}
+	return 0
+}
+
+func (q *userQuota) check(user *protocol.MemoryUser) error {
+	q.Lock()
+	defer q.Unlock()
+
+	expireAt := user.ExpireAt
+	if !q.expireAt.IsZero() {
+		expireAt = q.expireAt
+	}
+	if !expireAt.IsZero() && !time.Now().Before(expireAt) {
+		return errUserExpired
+	}
+	if user.Quota > 0 && q.used(user.QuotaPeriod) >= user.Quota+q.extra {
+		return errQuotaExceeded
+	}
+	return nil
+}
+
+func hasQuota(user *protocol.MemoryUser) bool {
+	return user != nil && len(user.Email) > 0 && (user.Quota > 0 || !user.ExpireAt.IsZero())
+}
+
+func quotaCounterName(email string) string {
+	return "user>>>" + email + ">>>traffic>>>quota"
+}
+
+// quotaOf returns the quota state of the user with the given email, creating it if it doesn't exist.
+// The traffic is counted in the stats manager if there is one, so it can be queried and persisted. The counter is
+// protected, so that only ResetQuota and the quota period reset it, and not the resets of the stats API.
+func (d *DefaultDispatcher) quotaOf(email string) *userQuota {
+	d.quotaAccess.Lock()
+	defer d.quotaAccess.Unlock()
+
+	if q, found := d.quotas[email]; found {
+		return q
+	}
+	q := &userQuota{}
+	if c, _ := feature_stats.GetOrRegisterProtectedCounter(d.stats, quotaCounterName(email)); c != nil {
+		q.counter = c
+	} else {
+		q.counter = new(stats.Counter)
+	}
+	d.quotas[email] = q
+	return q
+}
+
+// checkQuota returns an error if the user has used up the traffic quota, or has expired.
+func (d *DefaultDispatcher) checkQuota(user *protocol.MemoryUser) error {
+	if user == nil || len(user.Email) == 0 {
+		return nil
+	}
+	if !hasQuota(user) {
+		d.quotaAccess.RLock()
+		_, found := d.quotas[user.Email]
+		d.quotaAccess.RUnlock()
+		if !found {
+			return nil
+		}
+	}
+	return d.quotaOf(user.Email).check(user)
+}
+
+// admitQuota checks the quota of the user of the session in ctx, and records the rejection in the access log.
+func (d *DefaultDispatcher) admitQuota(ctx context.Context) error {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || inbound.User == nil || len(inbound.User.Email) == 0 {
+		return nil
+	}
+	err := d.checkQuota(inbound.User)
+	if err == nil {
+		return nil
+	}
+	if accessMessage := log.AccessMessageFromContext(ctx); accessMessage != nil {
+		if err == errUserExpired {
+			accessMessage.Status = log.AccessExpired
+		} else {
+			accessMessage.Status = log.AccessExceeded
+		}
+		accessMessage.Reason = err
+		log.Record(accessMessage)
+	}
+	return errors.New("rejected user ", inbound.User.Email).Base(err)
+}
+
+// countQuota wraps the writers of both links returned by Dispatch, if the user has a traffic quota.
+func (d *DefaultDispatcher) countQuota(ctx context.Context, inbound, outbound *transport.Link) {
+	if s := session.InboundFromContext(ctx); s != nil && hasQuota(s.User) && s.User.Quota > 0 {
+		q := d.quotaOf(s.User.Email)
+		inbound.Writer = d.newQuotaWriter(ctx, s.User, q, inbound.Writer)
+		outbound.Writer = d.newQuotaWriter(ctx, s.User, q, outbound.Writer)
+	}
+}
+
+// countLinkQuota is like countQuota, but for the link handed over by DispatchLink. As the uplink writer is unknown,
+// the uplink reader is wrapped instead, and the uplink traffic over quota is only cut off by the periodic check.
+// It must be called after sniffing.
+func (d *DefaultDispatcher) countLinkQuota(ctx context.Context, link *transport.Link) {
+	if s := session.InboundFromContext(ctx); s != nil && hasQuota(s.User) && s.User.Quota > 0 {
+		q := d.quotaOf(s.User.Email)
+		link.Reader = newSizeStatReader(q.counter, link.Reader)
+		link.Writer = d.newQuotaWriter(ctx, s.User, q, link.Writer)
+	}
+}
+
+// quotaWriter counts the traffic of a user in the quota, and cuts the user off once the quota is used up.
+type quotaWriter struct {
+	*SizeStatWriter
+	ctx        context.Context
+	dispatcher *DefaultDispatcher
+	user       *protocol.MemoryUser
+}
+
+func (d *DefaultDispatcher) newQuotaWriter(ctx context.Context, user *protocol.MemoryUser, q *userQuota, writer buf.Writer) *quotaWriter {
+	return &quotaWriter{
+		SizeStatWriter: &SizeStatWriter{
+			Counter: q.counter,
+			Writer:  writer,
+		},
+		ctx:        ctx,
+		dispatcher: d,
+		user:       user,
+	}
+}
+
+func (w *quotaWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	// Only values over the base quota need the full check, as extra quota can only be added on top of it.
+	if uint64(w.Counter.Add(int64(mb.Len()))) >= w.user.Quota {
+		if err := w.dispatcher.checkQuota(w.user); err != nil {
+			buf.ReleaseMulti(mb)
+			w.dispatcher.cutOff(w.ctx, w.user.Email, err)
+			return err
+		}
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+// cutOff closes all connections of the user.
+func (d *DefaultDispatcher) cutOff(ctx context.Context, email string, err error) {
+	if n := d.conns.CloseByUser(email); n > 0 {
+		errors.LogInfoInner(ctx, err, "closed ", n, " connections of user ", email)
+	}
+}
+
+// enforceQuotas closes the connections of users that have used up their quota or expired.
+func (d *DefaultDispatcher) enforceQuotas() error {
+	results := make(map[*protocol.MemoryUser]error)
+	n := d.conns.closeIf(func(user *protocol.MemoryUser) bool {
+		if user == nil || len(user.Email) == 0 {
+			return false
+		}
+		err, found := results[user]
+		if !found {
+			err = d.checkQuota(user)
+			results[user] = err
+			if err != nil {
+				errors.LogInfoInner(context.Background(), err, "cutting off user ", user.Email)
+			}
+		}
+		return err != nil
+	})
+	if n > 0 {
+		errors.LogDebug(context.Background(), "closed ", n, " connections over quota")
+	}
+	return nil
+}
+
+// ResetQuota resets the traffic used by the user in the current period, and removes the extra quota.
+func (d *DefaultDispatcher) ResetQuota(email string) {
+	q := d.quotaOf(email)
+	q.Lock()
+	defer q.Unlock()
+
+	q.counter.Set(0)
+	q.extra = 0
+}
+
+// ExtendQuota adds extra traffic to the quota of the user for the current period, and overrides the expiry
+// of the user if expireAt is not zero. The extension is kept in memory only.
+func (d *DefaultDispatcher) ExtendQuota(email string, extra uint64, expireAt time.Time) {
+	q := d.quotaOf(email)
+	q.Lock()
+	defer q.Unlock()
+
+	q.extra += extra
+	if !expireAt.IsZero() {
+		q.expireAt = expireAt
+	}
+}
+
+// RemoveQuota drops the quota state of the user, once it's removed from all inbounds. The extra quota and expiry of
+// ExtendQuota are dropped with it, while the traffic used is kept in the counter of the stats manager.
+func (d *DefaultDispatcher) RemoveQuota(email string) {
+	d.quotaAccess.Lock()
+	defer d.quotaAccess.Unlock()
+
+	delete(d.quotas, email)
+}
@@ -0,0 +1,157 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	app_stats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+func newQuotaTestDispatcher() *DefaultDispatcher {
+	return &DefaultDispatcher{
+		stats:  stats.NoopManager{},
+		conns:  NewConnectionRegistry(),
+		quotas: make(map[string]*userQuota),
+	}
+}
+
+func TestQuota(t *testing.T) {
+	d := newQuotaTestDispatcher()
+	user := &protocol.MemoryUser{Email: "love@xray.com", Quota: 10}
+
+	if err := d.checkQuota(user); err != nil {
+		t.Fatal(err)
+	}
+	d.quotaOf(user.Email).counter.Add(10)
+	if err := d.checkQuota(user); err != errQuotaExceeded {
+		t.Error("expected quota to be exceeded, but got ", err)
+	}
+	d.ExtendQuota(user.Email, 5, time.Time{})
+	if err := d.checkQuota(user); err != nil {
+		t.Error("expected extended quota, but got ", err)
+	}
+	d.quotaOf(user.Email).counter.Add(5)
+	if err := d.checkQuota(user); err != errQuotaExceeded {
+		t.Error("expected extended quota to be exceeded, but got ", err)
+	}
+	d.ResetQuota(user.Email)
+	if err := d.checkQuota(user); err != nil {
+		t.Error("expected reset quota, but got ", err)
+	}
+	if q := d.quotaOf(user.Email); q.extra != 0 {
+		t.Error("expected extra quota to be reset, but got ", q.extra)
+	}
+
+	expired := &protocol.MemoryUser{Email: "expired@xray.com", ExpireAt: time.Now().Add(-time.Second)}
+	if err := d.checkQuota(expired); err != errUserExpired {
+		t.Error("expected user to expire, but got ", err)
+	}
+	d.ExtendQuota(expired.Email, 0, time.Now().Add(time.Hour))
+	if err := d.checkQuota(expired); err != nil {
+		t.Error("expected extended expiry, but got ", err)
+	}
+}
+
+func TestQuotaPeriod(t *testing.T) {
+	d := newQuotaTestDispatcher()
+	user := &protocol.MemoryUser{Email: "love@xray.com", Quota: 10, QuotaPeriod: time.Hour}
+
+	q := d.quotaOf(user.Email)
+	q.counter.Add(10)
+	if err := d.checkQuota(user); err != errQuotaExceeded {
+		t.Error("expected quota to be exceeded, but got ", err)
+	}
+	if r, ok := q.counter.(resetTimeCounter); ok && r.LastReset().IsZero() {
+		t.Error("expected the period to start")
+	}
+	if v := q.counter.Value(); v != 10 {
+		t.Error("expected the traffic to be kept when the period starts, but got ", v)
+	}
+	user.QuotaPeriod = time.Nanosecond
+	if err := d.checkQuota(user); err != nil {
+		t.Error("expected quota to be reset in a new period, but got ", err)
+	}
+}
+
+func TestQuotaCutOff(t *testing.T) {
+	d := newQuotaTestDispatcher()
+	user := &protocol.MemoryUser{Email: "love@xray.com", Quota: 4}
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		Source: net.TCPDestination(net.LocalHostIP, 1234),
+		User:   user,
+	})
+
+	c := d.conns.open(ctx, net.TCPDestination(net.DomainAddress("example.com"), 443))
+	in, out := newTestLinks()
+	c.track(in, out)
+	d.countQuota(ctx, in, out)
+
+	if err := in.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("abc"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("de"))); err == nil {
+		t.Error("expected write over quota to fail")
+	}
+	if len(d.conns.List()) != 0 {
+		t.Error("expected connections of user to be closed")
+	}
+	if err := d.admitQuota(ctx); err == nil {
+		t.Error("expected new connection to be rejected")
+	}
+}
+
+func TestQuotaNotResetByStats(t *testing.T) {
+	m, err := app_stats.NewManager(context.Background(), &app_stats.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newQuotaTestDispatcher()
+	d.stats = m
+	user := &protocol.MemoryUser{Email: "love@xray.com", Quota: 10}
+
+	d.quotaOf(user.Email).counter.Add(10)
+	traffic, _ := m.RegisterCounter("user>>>love@xray.com>>>traffic>>>uplink")
+	traffic.Add(10)
+
+	// The polling of panels resets the traffic of users, but not the quota.
+	s := command.NewStatsServer(m)
+	response, err := s.QueryStats(context.Background(), &command.QueryStatsRequest{Pattern: "user>>>", Reset_: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Stat) != 2 {
+		t.Error("expected the quota to be queried with the traffic, but got ", response.Stat)
+	}
+	if _, err := s.ResetStats(context.Background(), &command.ResetStatsRequest{Pattern: "user>>>"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := traffic.Value(); v != 0 {
+		t.Error("expected the traffic to be reset, but got ", v)
+	}
+	if err := d.checkQuota(user); err != errQuotaExceeded {
+		t.Error("expected quota to be exceeded, but got ", err)
+	}
+
+	d.ResetQuota(user.Email)
+	if err := d.checkQuota(user); err != nil {
+		t.Error("expected reset quota, but got ", err)
+	}
+}
+
+func TestRemoveQuota(t *testing.T) {
+	d := newQuotaTestDispatcher()
+	user := &protocol.MemoryUser{Email: "love@xray.com", Quota: 10}
+
+	d.ExtendQuota(user.Email, 5, time.Time{})
+	d.RemoveQuota(user.Email)
+	if len(d.quotas) != 0 {
+		t.Error("expected the quota of the removed user to be dropped")
+	}
+}
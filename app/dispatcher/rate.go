@@ -0,0 +1,151 @@
+package dispatcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/transport"
+	"golang.org/x/time/rate"
+)
+
+// waitRate blocks until limiter allows n bytes, or ctx is done.
+func waitRate(ctx context.Context, limiter *rate.Limiter, n int) error {
+	for n > 0 {
+		m := limiter.Burst()
+		if m <= 0 || limiter.Limit() == rate.Inf {
+			return nil
+		}
+		if m > n {
+			m = n
+		}
+		if err := limiter.WaitN(ctx, m); err != nil {
+			return err
+		}
+		n -= m
+	}
+	return nil
+}
+
+// RateLimitWriter is a writer that limits the throughput of the data written to it.
+// As data must go through it, a connection of a user with limited throughput can't splice.
+type RateLimitWriter struct {
+	Limiter *rate.Limiter
+	Writer  buf.Writer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewRateLimitWriter creates a RateLimitWriter. Writing is aborted if ctx is done, or the writer is closed.
+func NewRateLimitWriter(ctx context.Context, limiter *rate.Limiter, writer buf.Writer) *RateLimitWriter {
+	ctx, cancel := context.WithCancel(ctx)
+	return &RateLimitWriter{
+		Limiter: limiter,
+		Writer:  writer,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (w *RateLimitWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if err := waitRate(w.ctx, w.Limiter, int(mb.Len())); err != nil {
+		buf.ReleaseMulti(mb)
+		return err
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+func (w *RateLimitWriter) Close() error {
+	w.cancel()
+	return common.Close(w.Writer)
+}
+
+func (w *RateLimitWriter) Interrupt() {
+	w.cancel()
+	common.Interrupt(w.Writer)
+}
+
+type rateLimitReader struct {
+	limiter *rate.Limiter
+	reader  buf.Reader
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func (r *rateLimitReader) wait(mb buf.MultiBuffer, err error) (buf.MultiBuffer, error) {
+	if werr := waitRate(r.ctx, r.limiter, int(mb.Len())); werr != nil {
+		buf.ReleaseMulti(mb)
+		return nil, werr
+	}
+	return mb, err
+}
+
+func (r *rateLimitReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	return r.wait(r.reader.ReadMultiBuffer())
+}
+
+func (r *rateLimitReader) Interrupt() {
+	r.cancel()
+	common.Interrupt(r.reader)
+}
+
+type rateLimitTimeoutReader struct {
+	*rateLimitReader
+	timeoutReader buf.TimeoutReader
+}
+
+func (r *rateLimitTimeoutReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	return r.wait(r.timeoutReader.ReadMultiBufferTimeout(timeout))
+}
+
+// rateLimiters returns the rate limiters of the user of the inbound in ctx, which conn holds until it is unregistered.
+func (d *DefaultDispatcher) rateLimiters(ctx context.Context, conn *connection) (*rate.Limiter, *rate.Limiter) {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil {
+		return nil, nil
+	}
+	uplink, downlink, release := policy.UserRateLimiters(d.policy, inbound.User)
+	conn.onRelease(release)
+	return uplink, downlink
+}
+
+// limitRate wraps the writers of both links returned by Dispatch, if the throughput of the user is limited.
+func (d *DefaultDispatcher) limitRate(ctx context.Context, conn *connection, inbound, outbound *transport.Link) {
+	uplink, downlink := d.rateLimiters(ctx, conn)
+	if uplink != nil {
+		inbound.Writer = NewRateLimitWriter(ctx, uplink, inbound.Writer)
+	}
+	if downlink != nil {
+		outbound.Writer = NewRateLimitWriter(ctx, downlink, outbound.Writer)
+	}
+}
+
+// limitLinkRate is like limitRate, but for the link handed over by DispatchLink. As the uplink writer is unknown,
+// the uplink reader is wrapped instead, so it must be called after sniffing.
+func (d *DefaultDispatcher) limitLinkRate(ctx context.Context, conn *connection, link *transport.Link) {
+	uplink, downlink := d.rateLimiters(ctx, conn)
+	if uplink != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		r := &rateLimitReader{
+			limiter: uplink,
+			reader:  link.Reader,
+			ctx:     ctx,
+			cancel:  cancel,
+		}
+		if tr, ok := link.Reader.(buf.TimeoutReader); ok {
+			link.Reader = &rateLimitTimeoutReader{
+				rateLimitReader: r,
+				timeoutReader:   tr,
+			}
+		} else {
+			link.Reader = r
+		}
+	}
+	if downlink != nil {
+		link.Writer = NewRateLimitWriter(ctx, downlink, link.Writer)
+	}
+}
@@ -0,0 +1,49 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/transport/pipe"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitWriter(t *testing.T) {
+	reader, writer := pipe.New()
+	limiter := rate.NewLimiter(16*1024, 16*1024)
+	w := NewRateLimitWriter(context.Background(), limiter, writer)
+
+	go func() {
+		for {
+			mb, err := reader.ReadMultiBuffer()
+			buf.ReleaseMulti(mb)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	common.Must(w.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 32*1024))))
+	if d := time.Since(start); d < 800*time.Millisecond {
+		t.Error("expected write to be limited, but took ", d)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- w.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 64*1024)))
+	}()
+	time.Sleep(100 * time.Millisecond)
+	w.Interrupt()
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("expected interrupted write to fail")
+		}
+	case <-time.After(time.Second):
+		t.Error("expected interrupted write to return")
+	}
+}
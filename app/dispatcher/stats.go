@@ -23,3 +23,21 @@ func (w *SizeStatWriter) Close() error {
 func (w *SizeStatWriter) Interrupt() {
 	common.Interrupt(w.Writer)
 }
+
+// AddToSizeStatWriters adds n to the counters of all SizeStatWriters that writer wraps, for the data that bypasses
+// writer, e.g. when it is spliced.
+func AddToSizeStatWriters(writer buf.Writer, n int64) {
+	for {
+		switch w := writer.(type) {
+		case *SizeStatWriter:
+			w.Counter.Add(n)
+			writer = w.Writer
+		case *connectionWriter:
+			writer = w.SizeStatWriter
+		case *quotaWriter:
+			writer = w.SizeStatWriter
+		default:
+			return
+		}
+	}
+}
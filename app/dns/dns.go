@@ -13,6 +13,7 @@ import (
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/strmatcher"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/features/dns"
 )
 
@@ -157,6 +158,11 @@ func (s *DNS) IsOwnLink(ctx context.Context) bool {
 
 // LookupIP implements dns.Client.
 func (s *DNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, error) {
+	return s.LookupIPWithContext(context.Background(), domain, option)
+}
+
+// LookupIPWithContext implements dns.TracedLookup. The queries are still sent in the context of the DNS app.
+func (s *DNS) LookupIPWithContext(traceCtx context.Context, domain string, option dns.IPOption) ([]net.IP, error) {
 	if domain == "" {
 		return nil, errors.New("empty domain name")
 	}
@@ -193,7 +199,12 @@ func (s *DNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, error) {
 			errors.LogDebug(s.ctx, "skip DNS resolution for domain ", domain, " at server ", client.Name())
 			continue
 		}
+		_, span := trace.StartChild(traceCtx, "dns.query")
+		span.SetAttribute("dns.domain", domain)
+		span.SetAttribute("dns.server", client.Name())
 		ips, err := client.QueryIP(ctx, domain, option, s.disableCache)
+		span.SetAttribute("dns.answers", len(ips))
+		span.End(err)
 		if len(ips) > 0 {
 			return ips, nil
 		}
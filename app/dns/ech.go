@@ -0,0 +1,113 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/session"
+	dns_feature "github.com/xtls/xray-core/features/dns"
+)
+
+// exchanger is implemented by the name servers that can send queries of any type.
+type exchanger interface {
+	// exchange sends the packed query msg and returns the packed response.
+	exchange(ctx context.Context, msg []byte) ([]byte, error)
+}
+
+// exchangeStream sends msg over a DNS stream connection, like TCP or QUIC, and reads the response. done is called
+// once msg is sent.
+func exchangeStream(conn io.ReadWriter, msg []byte, done func() error) ([]byte, error) {
+	b := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(b, uint16(len(msg)))
+	copy(b[2:], msg)
+	if _, err := conn.Write(b); err != nil {
+		return nil, errors.New("failed to send query").Base(err)
+	}
+	if done != nil {
+		if err := done(); err != nil {
+			return nil, err
+		}
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, errors.New("failed to read response length").Base(err)
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, errors.New("failed to read response").Base(err)
+	}
+	return resp, nil
+}
+
+// LookupECHConfigList implements dns.ECHConfigLookup. Only DoH, DoQ and DNS over TCP servers are asked, since the
+// other ones can't send HTTPS queries.
+func (s *DNS) LookupECHConfigList(domain string) ([]byte, uint32, error) {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return nil, 0, errors.New("empty domain name")
+	}
+
+	query := new(dns.Msg)
+	query.SetQuestion(Fqdn(domain), dns.TypeHTTPS)
+	msg, err := query.Pack()
+	if err != nil {
+		return nil, 0, errors.New("failed to pack HTTPS query").Base(err)
+	}
+
+	errs := []error{}
+	ctx := session.ContextWithInbound(s.ctx, &session.Inbound{Tag: s.tag})
+	ctx = session.ContextWithContent(ctx, &session.Content{
+		Protocol:       "dns",
+		SkipDNSResolve: true,
+	})
+	for _, client := range s.sortClients(domain) {
+		e, ok := client.server.(exchanger)
+		if !ok {
+			continue
+		}
+		queryCtx, cancel := context.WithTimeout(ctx, 4*time.Second)
+		resp, err := e.exchange(queryCtx, msg)
+		cancel()
+		if err == nil {
+			var config []byte
+			var ttl uint32
+			config, ttl, err = parseECHConfigList(resp)
+			if err == nil || err == dns_feature.ErrEmptyResponse {
+				errors.LogInfo(s.ctx, "found ", len(config), " bytes of ECH configs for ", domain, " at server ", client.Name())
+				return config, ttl, err
+			}
+		}
+		errors.LogInfoInner(s.ctx, err, "failed to lookup ECH configs for domain ", domain, " at server ", client.Name())
+		errs = append(errs, err)
+	}
+	return nil, 0, errors.New("no ECH configs for domain ", domain).Base(errors.Combine(errs...))
+}
+
+// parseECHConfigList returns the ECH configs in the HTTPS records of resp, and their TTL.
+func parseECHConfigList(resp []byte) ([]byte, uint32, error) {
+	m := new(dns.Msg)
+	if err := m.Unpack(resp); err != nil {
+		return nil, 0, errors.New("failed to parse HTTPS response").Base(err)
+	}
+	if m.Rcode != dns.RcodeSuccess {
+		return nil, 0, dns_feature.RCodeError(m.Rcode)
+	}
+	for _, rr := range m.Answer {
+		https, ok := rr.(*dns.HTTPS)
+		if !ok {
+			continue
+		}
+		for _, v := range https.Value {
+			if ech, ok := v.(*dns.SVCBECHConfig); ok {
+				return ech.ECH, https.Hdr.Ttl, nil
+			}
+		}
+	}
+	return nil, 0, dns_feature.ErrEmptyResponse
+}
@@ -0,0 +1,42 @@
+package dns
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/xtls/xray-core/common"
+	dns_feature "github.com/xtls/xray-core/features/dns"
+)
+
+func Test_parseECHConfigList(t *testing.T) {
+	echConfig := []byte{0, 4, 0xfe, 0x0d, 0, 0}
+
+	ans := new(dns.Msg)
+	ans.SetQuestion("example.com.", dns.TypeHTTPS)
+	ans.Response = true
+	ans.Answer = append(ans.Answer, &dns.HTTPS{SVCB: dns.SVCB{
+		Hdr:      dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET, Ttl: 300},
+		Priority: 1,
+		Target:   ".",
+		Value: []dns.SVCBKeyValue{
+			&dns.SVCBAlpn{Alpn: []string{"h2"}},
+			&dns.SVCBECHConfig{ECH: echConfig},
+		},
+	}})
+	resp, err := ans.Pack()
+	common.Must(err)
+
+	config, ttl, err := parseECHConfigList(resp)
+	common.Must(err)
+	if !bytes.Equal(config, echConfig) || ttl != 300 {
+		t.Error("config: ", config, " ttl: ", ttl)
+	}
+
+	ans.Answer = nil
+	resp, err = ans.Pack()
+	common.Must(err)
+	if _, _, err := parseECHConfigList(resp); err != dns_feature.ErrEmptyResponse {
+		t.Error("expected empty response, but got ", err)
+	}
+}
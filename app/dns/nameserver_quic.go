@@ -244,6 +244,19 @@ func (s *QUICNameServer) sendQuery(ctx context.Context, domain string, clientIP
 	}
 }
 
+// exchange implements exchanger.
+func (s *QUICNameServer) exchange(ctx context.Context, msg []byte) ([]byte, error) {
+	stream, err := s.openStream(ctx)
+	if err != nil {
+		return nil, errors.New("failed to open quic connection").Base(err)
+	}
+	defer stream.CancelRead(0)
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+	return exchangeStream(stream, msg, stream.Close)
+}
+
 func (s *QUICNameServer) findIPsForDomain(domain string, option dns_feature.IPOption) ([]net.IP, error) {
 	s.RLock()
 	record, found := s.ips[domain]
@@ -273,6 +273,19 @@ func (s *TCPNameServer) sendQuery(ctx context.Context, domain string, clientIP n
 	}
 }
 
+// exchange implements exchanger.
+func (s *TCPNameServer) exchange(ctx context.Context, msg []byte) ([]byte, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, errors.New("failed to dial namesever").Base(err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return exchangeStream(conn, msg, nil)
+}
+
 func (s *TCPNameServer) findIPsForDomain(domain string, option dns_feature.IPOption) ([]net.IP, error) {
 	s.RLock()
 	record, found := s.ips[domain]
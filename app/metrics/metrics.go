@@ -2,10 +2,14 @@ package metrics
 
 import (
 	"context"
+	goerrors "errors"
 	"expvar"
+	gonet "net"
 	"net/http"
 	_ "net/http/pprof"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/xtls/xray-core/app/observatory"
 	"github.com/xtls/xray-core/app/stats"
@@ -17,6 +21,7 @@ import (
 	"github.com/xtls/xray-core/features/extension"
 	"github.com/xtls/xray-core/features/outbound"
 	feature_stats "github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport/internet"
 )
 
 type MetricsHandler struct {
@@ -26,63 +31,86 @@ type MetricsHandler struct {
 	tag          string
 	listen       string
 	tcpListener  net.Listener
+	ctx          context.Context
 }
 
+// The expvar variables are global, so they are published once, and report the metrics of the latest handler.
+var (
+	publishOnce   sync.Once
+	activeHandler atomic.Pointer[MetricsHandler]
+)
+
 // NewMetricsHandler creates a new MetricsHandler based on the given config.
 func NewMetricsHandler(ctx context.Context, config *Config) (*MetricsHandler, error) {
 	c := &MetricsHandler{
 		tag:    config.Tag,
 		listen: config.Listen,
+		ctx:    ctx,
 	}
 	common.Must(core.RequireFeatures(ctx, func(om outbound.Manager, sm feature_stats.Manager) {
 		c.statsManager = sm
 		c.ohm = om
 	}))
-	expvar.Publish("stats", expvar.Func(func() interface{} {
-		manager, ok := c.statsManager.(*stats.Manager)
-		if !ok {
-			return nil
-		}
-		resp := map[string]map[string]map[string]int64{
-			"inbound":  {},
-			"outbound": {},
-			"user":     {},
-		}
-		manager.VisitCounters(func(name string, counter feature_stats.Counter) bool {
-			nameSplit := strings.Split(name, ">>>")
-			typeName, tagOrUser, direction := nameSplit[0], nameSplit[1], nameSplit[3]
-			if item, found := resp[typeName][tagOrUser]; found {
-				item[direction] = counter.Value()
-			} else {
-				resp[typeName][tagOrUser] = map[string]int64{
-					direction: counter.Value(),
-				}
-			}
+	activeHandler.Store(c)
+	publishOnce.Do(func() {
+		expvar.Publish("stats", expvar.Func(func() interface{} {
+			return activeHandler.Load().statsVar()
+		}))
+		expvar.Publish("observatory", expvar.Func(func() interface{} {
+			return activeHandler.Load().observatoryVar()
+		}))
+	})
+	return c, nil
+}
+
+func (c *MetricsHandler) statsVar() interface{} {
+	manager, ok := c.statsManager.(*stats.Manager)
+	if !ok {
+		return nil
+	}
+	resp := map[string]map[string]map[string]int64{
+		"inbound":  {},
+		"outbound": {},
+		"user":     {},
+	}
+	manager.VisitCounters(func(name string, counter feature_stats.Counter) bool {
+		nameSplit := strings.Split(name, ">>>")
+		if len(nameSplit) != 4 || resp[nameSplit[0]] == nil {
+			// Skip the counters of other features, which aren't named like the traffic of a handler or user.
 			return true
-		})
-		return resp
-	}))
-	expvar.Publish("observatory", expvar.Func(func() interface{} {
-		if c.observatory == nil {
-			common.Must(core.RequireFeatures(ctx, func(observatory extension.Observatory) error {
-				c.observatory = observatory
-				return nil
-			}))
-			if c.observatory == nil {
-				return nil
-			}
 		}
-		resp := map[string]*observatory.OutboundStatus{}
-		if o, err := c.observatory.GetObservation(context.Background()); err != nil {
-			return err
+		typeName, tagOrUser, direction := nameSplit[0], nameSplit[1], nameSplit[3]
+		if item, found := resp[typeName][tagOrUser]; found {
+			item[direction] = counter.Value()
 		} else {
-			for _, x := range o.(*observatory.ObservationResult).GetStatus() {
-				resp[x.OutboundTag] = x
+			resp[typeName][tagOrUser] = map[string]int64{
+				direction: counter.Value(),
 			}
 		}
-		return resp
-	}))
-	return c, nil
+		return true
+	})
+	return resp
+}
+
+func (c *MetricsHandler) observatoryVar() interface{} {
+	if c.observatory == nil {
+		common.Must(core.RequireFeatures(c.ctx, func(observatory extension.Observatory) error {
+			c.observatory = observatory
+			return nil
+		}))
+		if c.observatory == nil {
+			return nil
+		}
+	}
+	resp := map[string]*observatory.OutboundStatus{}
+	if o, err := c.observatory.GetObservation(context.Background()); err != nil {
+		return err
+	} else {
+		for _, x := range o.(*observatory.ObservationResult).GetStatus() {
+			resp[x.OutboundTag] = x
+		}
+	}
+	return resp
 }
 
 func (p *MetricsHandler) Type() interface{} {
@@ -93,7 +121,12 @@ func (p *MetricsHandler) Start() error {
 
 	// direct listen a port if listen is set
 	if p.listen != "" {
-		TCPlistener, err := net.Listen("tcp", p.listen)
+		addr, err := net.ResolveTCPAddr("tcp", p.listen)
+		if err != nil {
+			return err
+		}
+		// The port is shared with the server that replaces this one on reload, until this one is drained.
+		TCPlistener, err := internet.ListenSystem(context.Background(), addr, nil)
 		if err != nil {
 			return err
 		}
@@ -101,7 +134,7 @@ func (p *MetricsHandler) Start() error {
 		errors.LogInfo(context.Background(), "Metrics server listening on ", p.listen)
 
 		go func() {
-			if err := http.Serve(TCPlistener, http.DefaultServeMux); err != nil {
+			if err := http.Serve(TCPlistener, http.DefaultServeMux); err != nil && !goerrors.Is(err, gonet.ErrClosed) {
 				errors.LogErrorInner(context.Background(), err, "failed to start metrics server")
 			}
 		}()
@@ -128,7 +161,17 @@ func (p *MetricsHandler) Start() error {
 	})
 }
 
+// Drain implements features.Drainer.
+func (p *MetricsHandler) Drain() error {
+	return p.Close()
+}
+
 func (p *MetricsHandler) Close() error {
+	if p.tcpListener != nil {
+		err := p.tcpListener.Close()
+		p.tcpListener = nil
+		return err
+	}
 	return nil
 }
 
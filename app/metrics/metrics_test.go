@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/app/stats"
+)
+
+func TestStatsVar(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"inbound>>>api>>>traffic>>>uplink",
+		"user>>>love@xray.com>>>traffic>>>downlink",
+		"user>>>love@xray.com>>>rejected",
+		"kcp>>>fec>>>shards>>>recovered",
+	} {
+		c, err := m.RegisterCounter(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Add(1)
+	}
+
+	c := &MetricsHandler{statsManager: m}
+	expected := map[string]map[string]map[string]int64{
+		"inbound":  {"api": {"uplink": 1}},
+		"outbound": {},
+		"user":     {"love@xray.com": {"downlink": 1}},
+	}
+	if r := cmp.Diff(c.statsVar(), expected); r != "" {
+		t.Error(r)
+	}
+}
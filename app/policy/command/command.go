@@ -0,0 +1,105 @@
+package command
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	feature_policy "github.com/xtls/xray-core/features/policy"
+	grpc "google.golang.org/grpc"
+)
+
+// policyServer is an implementation of PolicyService.
+type policyServer struct {
+	policy feature_policy.Manager
+}
+
+func NewPolicyServer(m feature_policy.Manager) PolicyServiceServer {
+	return &policyServer{
+		policy: m,
+	}
+}
+
+func (s *policyServer) instance() (*policy.Instance, error) {
+	m, ok := s.policy.(*policy.Instance)
+	if !ok {
+		return nil, errors.New("PolicyService only works with its own policy.Manager.")
+	}
+	return m, nil
+}
+
+func (s *policyServer) SetLevelRate(ctx context.Context, request *SetLevelRateRequest) (*SetLevelRateResponse, error) {
+	m, err := s.instance()
+	if err != nil {
+		return nil, err
+	}
+	m.SetLevelRate(request.Level, feature_policy.Rate{
+		Uplink:   request.Uplink,
+		Downlink: request.Downlink,
+	})
+	return &SetLevelRateResponse{}, nil
+}
+
+func (s *policyServer) SetUserRate(ctx context.Context, request *SetUserRateRequest) (*SetUserRateResponse, error) {
+	if request.Email == "" {
+		return nil, errors.New("email is empty")
+	}
+	m, err := s.instance()
+	if err != nil {
+		return nil, err
+	}
+	m.SetUserRate(request.Email, feature_policy.Rate{
+		Uplink:   request.Uplink,
+		Downlink: request.Downlink,
+	})
+	return &SetUserRateResponse{}, nil
+}
+
+func (s *policyServer) RemoveUserRate(ctx context.Context, request *RemoveUserRateRequest) (*RemoveUserRateResponse, error) {
+	if request.Email == "" {
+		return nil, errors.New("email is empty")
+	}
+	m, err := s.instance()
+	if err != nil {
+		return nil, err
+	}
+	m.RemoveUserRate(request.Email)
+	return &RemoveUserRateResponse{}, nil
+}
+
+func (s *policyServer) GetUserRate(ctx context.Context, request *GetUserRateRequest) (*GetUserRateResponse, error) {
+	m, err := s.instance()
+	if err != nil {
+		return nil, err
+	}
+	r, override := m.UserRate(request.Email, request.Level)
+	return &GetUserRateResponse{
+		Uplink:   r.Uplink,
+		Downlink: r.Downlink,
+		Override: override,
+	}, nil
+}
+
+func (s *policyServer) mustEmbedUnimplementedPolicyServiceServer() {}
+
+type service struct {
+	policy feature_policy.Manager
+}
+
+func (s *service) Register(server *grpc.Server) {
+	RegisterPolicyServiceServer(server, NewPolicyServer(s.policy))
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		s := new(service)
+
+		core.RequireFeatures(ctx, func(m feature_policy.Manager) {
+			s.policy = m
+		})
+
+		return s, nil
+	}))
+}
@@ -0,0 +1,235 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.2
+// source: app/policy/command/command.proto
+
+package command
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PolicyService_SetLevelRate_FullMethodName   = "/xray.app.policy.command.PolicyService/SetLevelRate"
+	PolicyService_SetUserRate_FullMethodName    = "/xray.app.policy.command.PolicyService/SetUserRate"
+	PolicyService_RemoveUserRate_FullMethodName = "/xray.app.policy.command.PolicyService/RemoveUserRate"
+	PolicyService_GetUserRate_FullMethodName    = "/xray.app.policy.command.PolicyService/GetUserRate"
+)
+
+// PolicyServiceClient is the client API for PolicyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PolicyServiceClient interface {
+	SetLevelRate(ctx context.Context, in *SetLevelRateRequest, opts ...grpc.CallOption) (*SetLevelRateResponse, error)
+	SetUserRate(ctx context.Context, in *SetUserRateRequest, opts ...grpc.CallOption) (*SetUserRateResponse, error)
+	RemoveUserRate(ctx context.Context, in *RemoveUserRateRequest, opts ...grpc.CallOption) (*RemoveUserRateResponse, error)
+	GetUserRate(ctx context.Context, in *GetUserRateRequest, opts ...grpc.CallOption) (*GetUserRateResponse, error)
+}
+
+type policyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPolicyServiceClient(cc grpc.ClientConnInterface) PolicyServiceClient {
+	return &policyServiceClient{cc}
+}
+
+func (c *policyServiceClient) SetLevelRate(ctx context.Context, in *SetLevelRateRequest, opts ...grpc.CallOption) (*SetLevelRateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLevelRateResponse)
+	err := c.cc.Invoke(ctx, PolicyService_SetLevelRate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyServiceClient) SetUserRate(ctx context.Context, in *SetUserRateRequest, opts ...grpc.CallOption) (*SetUserRateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetUserRateResponse)
+	err := c.cc.Invoke(ctx, PolicyService_SetUserRate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyServiceClient) RemoveUserRate(ctx context.Context, in *RemoveUserRateRequest, opts ...grpc.CallOption) (*RemoveUserRateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveUserRateResponse)
+	err := c.cc.Invoke(ctx, PolicyService_RemoveUserRate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyServiceClient) GetUserRate(ctx context.Context, in *GetUserRateRequest, opts ...grpc.CallOption) (*GetUserRateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserRateResponse)
+	err := c.cc.Invoke(ctx, PolicyService_GetUserRate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PolicyServiceServer is the server API for PolicyService service.
+// All implementations must embed UnimplementedPolicyServiceServer
+// for forward compatibility.
+type PolicyServiceServer interface {
+	SetLevelRate(context.Context, *SetLevelRateRequest) (*SetLevelRateResponse, error)
+	SetUserRate(context.Context, *SetUserRateRequest) (*SetUserRateResponse, error)
+	RemoveUserRate(context.Context, *RemoveUserRateRequest) (*RemoveUserRateResponse, error)
+	GetUserRate(context.Context, *GetUserRateRequest) (*GetUserRateResponse, error)
+	mustEmbedUnimplementedPolicyServiceServer()
+}
+
+// UnimplementedPolicyServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPolicyServiceServer struct{}
+
+func (UnimplementedPolicyServiceServer) SetLevelRate(context.Context, *SetLevelRateRequest) (*SetLevelRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLevelRate not implemented")
+}
+func (UnimplementedPolicyServiceServer) SetUserRate(context.Context, *SetUserRateRequest) (*SetUserRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserRate not implemented")
+}
+func (UnimplementedPolicyServiceServer) RemoveUserRate(context.Context, *RemoveUserRateRequest) (*RemoveUserRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveUserRate not implemented")
+}
+func (UnimplementedPolicyServiceServer) GetUserRate(context.Context, *GetUserRateRequest) (*GetUserRateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserRate not implemented")
+}
+func (UnimplementedPolicyServiceServer) mustEmbedUnimplementedPolicyServiceServer() {}
+func (UnimplementedPolicyServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafePolicyServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PolicyServiceServer will
+// result in compilation errors.
+type UnsafePolicyServiceServer interface {
+	mustEmbedUnimplementedPolicyServiceServer()
+}
+
+func RegisterPolicyServiceServer(s grpc.ServiceRegistrar, srv PolicyServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPolicyServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PolicyService_ServiceDesc, srv)
+}
+
+func _PolicyService_SetLevelRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLevelRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyServiceServer).SetLevelRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyService_SetLevelRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyServiceServer).SetLevelRate(ctx, req.(*SetLevelRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyService_SetUserRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyServiceServer).SetUserRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyService_SetUserRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyServiceServer).SetUserRate(ctx, req.(*SetUserRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyService_RemoveUserRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveUserRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyServiceServer).RemoveUserRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyService_RemoveUserRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyServiceServer).RemoveUserRate(ctx, req.(*RemoveUserRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PolicyService_GetUserRate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyServiceServer).GetUserRate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PolicyService_GetUserRate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyServiceServer).GetUserRate(ctx, req.(*GetUserRateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PolicyService_ServiceDesc is the grpc.ServiceDesc for PolicyService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PolicyService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "xray.app.policy.command.PolicyService",
+	HandlerType: (*PolicyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetLevelRate",
+			Handler:    _PolicyService_SetLevelRate_Handler,
+		},
+		{
+			MethodName: "SetUserRate",
+			Handler:    _PolicyService_SetUserRate_Handler,
+		},
+		{
+			MethodName: "RemoveUserRate",
+			Handler:    _PolicyService_RemoveUserRate_Handler,
+		},
+		{
+			MethodName: "GetUserRate",
+			Handler:    _PolicyService_GetUserRate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "app/policy/command/command.proto",
+}
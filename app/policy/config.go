@@ -3,6 +3,7 @@ package policy
 import (
 	"time"
 
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/features/policy"
 )
 
@@ -58,6 +59,21 @@ func (p *Policy) overrideWith(another *Policy) {
 			Connection: another.Buffer.Connection,
 		}
 	}
+	if another.Limit != nil {
+		p.Limit = &Policy_Limit{
+			MaxOnlineIps:             another.Limit.MaxOnlineIps,
+			MaxConnections:           another.Limit.MaxConnections,
+			GracePeriod:              another.Limit.GracePeriod,
+			MaxConcurrentConnections: another.Limit.MaxConcurrentConnections,
+			NewConnectionsPerSecond:  another.Limit.NewConnectionsPerSecond,
+		}
+	}
+	if another.Rate != nil {
+		p.Rate = &Policy_Rate{
+			Uplink:   another.Rate.Uplink,
+			Downlink: another.Rate.Downlink,
+		}
+	}
 }
 
 // ToCorePolicy converts this Policy to policy.Session.
@@ -78,9 +94,55 @@ func (p *Policy) ToCorePolicy() policy.Session {
 	if p.Buffer != nil {
 		cp.Buffer.PerConnection = p.Buffer.Connection
 	}
+	if p.Limit != nil {
+		cp.Limit = p.Limit.ToCoreLimit()
+	}
+	if p.Rate != nil {
+		cp.Rate = p.Rate.ToCoreRate()
+	}
 	return cp
 }
 
+// overrideWithUser sets the timeouts and buffer that the user overrides in p.
+func overrideWithUser(p *policy.Session, up *protocol.UserPolicy) {
+	if t := up.Timeout; t != nil {
+		if t.Handshake != nil {
+			p.Timeouts.Handshake = time.Second * time.Duration(t.Handshake.Value)
+		}
+		if t.ConnectionIdle != nil {
+			p.Timeouts.ConnectionIdle = time.Second * time.Duration(t.ConnectionIdle.Value)
+		}
+		if t.UplinkOnly != nil {
+			p.Timeouts.UplinkOnly = time.Second * time.Duration(t.UplinkOnly.Value)
+		}
+		if t.DownlinkOnly != nil {
+			p.Timeouts.DownlinkOnly = time.Second * time.Duration(t.DownlinkOnly.Value)
+		}
+	}
+	if up.Buffer != nil {
+		p.Buffer.PerConnection = up.Buffer.Connection
+	}
+}
+
+// ToCoreLimit converts this Policy_Limit to policy.Limit.
+func (l *Policy_Limit) ToCoreLimit() policy.Limit {
+	return policy.Limit{
+		MaxOnlineIPs:             l.MaxOnlineIps,
+		MaxConnections:           l.MaxConnections,
+		GracePeriod:              l.GracePeriod.Duration(),
+		MaxConcurrentConnections: l.MaxConcurrentConnections,
+		NewConnectionsPerSecond:  l.NewConnectionsPerSecond,
+	}
+}
+
+// ToCoreRate converts this Policy_Rate to policy.Rate.
+func (r *Policy_Rate) ToCoreRate() policy.Rate {
+	return policy.Rate{
+		Uplink:   r.Uplink,
+		Downlink: r.Downlink,
+	}
+}
+
 // ToCorePolicy converts this SystemPolicy to policy.System.
 func (p *SystemPolicy) ToCorePolicy() policy.System {
 	return policy.System{
@@ -0,0 +1,171 @@
+package policy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/policy"
+	"golang.org/x/time/rate"
+)
+
+type onlineIP struct {
+	connections int
+	lastSeen    time.Time
+}
+
+type onlineUser struct {
+	connections int
+	ips         map[string]*onlineIP
+}
+
+// expire removes the IPs that have been offline for longer than gracePeriod.
+func (u *onlineUser) expire(now time.Time, gracePeriod time.Duration) {
+	for k, ip := range u.ips {
+		if ip.connections == 0 && now.Sub(ip.lastSeen) >= gracePeriod {
+			delete(u.ips, k)
+		}
+	}
+}
+
+// userTracker counts the connections and online IPs of users, on all inbounds.
+type userTracker struct {
+	access sync.Mutex
+	users  map[string]*onlineUser
+}
+
+func newUserTracker() *userTracker {
+	return &userTracker{
+		users: make(map[string]*onlineUser),
+	}
+}
+
+func (t *userTracker) track(email string, limit policy.Limit, ip net.Address) (func(), error) {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	now := time.Now()
+	u, found := t.users[email]
+	if !found {
+		u = &onlineUser{
+			ips: make(map[string]*onlineIP),
+		}
+		t.users[email] = u
+	}
+	u.expire(now, limit.GracePeriod)
+
+	if limit.MaxConnections > 0 && u.connections >= int(limit.MaxConnections) {
+		return nil, errors.New("too many connections of user ", email, ", limit: ", limit.MaxConnections)
+	}
+	key := ip.String()
+	o, found := u.ips[key]
+	if !found {
+		if limit.MaxOnlineIPs > 0 && len(u.ips) >= int(limit.MaxOnlineIPs) {
+			return nil, errors.New("too many online IPs of user ", email, ", limit: ", limit.MaxOnlineIPs)
+		}
+		o = &onlineIP{}
+		u.ips[key] = o
+	}
+	u.connections++
+	o.connections++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.access.Lock()
+			defer t.access.Unlock()
+
+			u.connections--
+			o.connections--
+			o.lastSeen = time.Now()
+			u.expire(o.lastSeen, limit.GracePeriod)
+			if len(u.ips) == 0 && t.users[email] == u {
+				delete(t.users, email)
+			}
+		})
+	}, nil
+}
+
+// idleTimeout is how long the state of a user without connections is kept.
+const idleTimeout = time.Minute
+
+type userConnections struct {
+	active   uint32
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// connectionLimiter counts the connections dispatched for users, and the rate they are opened at.
+type connectionLimiter struct {
+	access      sync.Mutex
+	users       map[string]*userConnections
+	lastCleanup time.Time
+}
+
+func newConnectionLimiter() *connectionLimiter {
+	return &connectionLimiter{
+		users:       make(map[string]*userConnections),
+		lastCleanup: time.Now(),
+	}
+}
+
+// cleanup drops the users idle for longer than idleTimeout, at most once in idleTimeout. By then their rate limiters
+// are full again, so nothing is lost.
+func (l *connectionLimiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < idleTimeout {
+		return
+	}
+	for email, u := range l.users {
+		if u.active == 0 && now.Sub(u.lastSeen) >= idleTimeout {
+			delete(l.users, email)
+		}
+	}
+	l.lastCleanup = now
+}
+
+func (l *connectionLimiter) admit(email string, limit policy.Limit) (func(), error) {
+	if limit.MaxConcurrentConnections == 0 && limit.NewConnectionsPerSecond == 0 {
+		return func() {}, nil
+	}
+
+	l.access.Lock()
+	defer l.access.Unlock()
+
+	now := time.Now()
+	l.cleanup(now)
+	u, found := l.users[email]
+	if !found {
+		u = &userConnections{}
+		l.users[email] = u
+	}
+	u.lastSeen = now
+
+	if limit.MaxConcurrentConnections > 0 && u.active >= limit.MaxConcurrentConnections {
+		return nil, errors.New("too many concurrent connections of user ", email, ", limit: ", limit.MaxConcurrentConnections)
+	}
+	if limit.NewConnectionsPerSecond > 0 {
+		r := rate.Limit(limit.NewConnectionsPerSecond)
+		if u.limiter == nil {
+			u.limiter = rate.NewLimiter(r, int(limit.NewConnectionsPerSecond))
+		} else if u.limiter.Limit() != r {
+			u.limiter.SetLimitAt(now, r)
+			u.limiter.SetBurstAt(now, int(limit.NewConnectionsPerSecond))
+		}
+		if !u.limiter.AllowN(now, 1) {
+			return nil, errors.New("too many new connections of user ", email, ", limit: ", limit.NewConnectionsPerSecond, "/s")
+		}
+	}
+	u.active++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.access.Lock()
+			defer l.access.Unlock()
+
+			u.active--
+			u.lastSeen = time.Now()
+		})
+	}, nil
+}
@@ -2,22 +2,35 @@ package policy
 
 import (
 	"context"
+	"sync"
 
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/features/policy"
 )
 
 // Instance is an instance of Policy manager.
 type Instance struct {
-	levels map[uint32]*Policy
-	system *SystemPolicy
+	access  sync.RWMutex
+	levels  map[uint32]*Policy
+	system  *SystemPolicy
+	tracker *userTracker
+	rates   *userRates
+	conns   *connectionLimiter
+	// limits overrides the Limit of the users' levels, by email.
+	limits map[string]policy.Limit
 }
 
 // New creates new Policy manager instance.
 func New(ctx context.Context, config *Config) (*Instance, error) {
 	m := &Instance{
-		levels: make(map[uint32]*Policy),
-		system: config.System,
+		levels:  make(map[uint32]*Policy),
+		system:  config.System,
+		tracker: newUserTracker(),
+		rates:   newUserRates(),
+		conns:   newConnectionLimiter(),
+		limits:  make(map[string]policy.Limit),
 	}
 	if len(config.Level) > 0 {
 		for lv, p := range config.Level {
@@ -26,6 +39,12 @@ func New(ctx context.Context, config *Config) (*Instance, error) {
 			m.levels[lv] = pp
 		}
 	}
+	for email, r := range config.UserRate {
+		m.rates.overrides[email] = r.ToCoreRate()
+	}
+	for email, l := range config.UserLimit {
+		m.limits[email] = l.ToCoreLimit()
+	}
 
 	return m, nil
 }
@@ -37,12 +56,30 @@ func (*Instance) Type() interface{} {
 
 // ForLevel implements policy.Manager.
 func (m *Instance) ForLevel(level uint32) policy.Session {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
 	if p, ok := m.levels[level]; ok {
 		return p.ToCorePolicy()
 	}
 	return policy.SessionDefault()
 }
 
+// ForUser implements policy.UserPolicyManager. Each setting of the user's level is overridden by one source only:
+// the timeouts and buffer by the policy of the user, the limit by UserLimit of the config, and the rate by UserRate
+// of the config or SetUserRate. The limit and rate apply by email, so they are left out for users without one.
+func (m *Instance) ForUser(user *protocol.MemoryUser) policy.Session {
+	p := m.ForLevel(user.Level)
+	if user.Policy != nil {
+		overrideWithUser(&p, user.Policy)
+	}
+	if len(user.Email) > 0 {
+		p.Limit = m.userLimit(user.Email, user.Level)
+		p.Rate, _ = m.UserRate(user.Email, user.Level)
+	}
+	return p
+}
+
 // ForSystem implements policy.Manager.
 func (m *Instance) ForSystem() policy.System {
 	if m.system == nil {
@@ -51,6 +88,28 @@ func (m *Instance) ForSystem() policy.System {
 	return m.system.ToCorePolicy()
 }
 
+// userLimit returns the Limit of the user, or the one of the user's level if it's not overridden.
+func (m *Instance) userLimit(email string, level uint32) policy.Limit {
+	if l, found := m.limits[email]; found {
+		return l
+	}
+	return m.ForLevel(level).Limit
+}
+
+// TrackUser implements policy.UserTracker.
+func (m *Instance) TrackUser(email string, level uint32, ip net.Address) (func(), error) {
+	limit := m.userLimit(email, level)
+	if limit.MaxOnlineIPs == 0 && limit.MaxConnections == 0 {
+		return func() {}, nil
+	}
+	return m.tracker.track(email, limit, ip)
+}
+
+// AdmitConnection implements policy.ConnectionAdmitter.
+func (m *Instance) AdmitConnection(email string, level uint32) (func(), error) {
+	return m.conns.admit(email, m.userLimit(email, level))
+}
+
 // Start implements common.Runnable.Start().
 func (m *Instance) Start() error {
 	return nil
@@ -7,6 +7,8 @@ import (
 
 	. "github.com/xtls/xray-core/app/policy"
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/features/policy"
 )
 
@@ -43,3 +45,292 @@ func TestPolicy(t *testing.T) {
 		}
 	}
 }
+
+func TestUserPolicy(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			1: {
+				Timeout: &Policy_Timeout{
+					Handshake:      &Second{Value: 2},
+					ConnectionIdle: &Second{Value: 10},
+				},
+				Buffer: &Policy_Buffer{Connection: 1024},
+			},
+		},
+	})
+	common.Must(err)
+
+	user := &protocol.MemoryUser{
+		Email: "love@xray.com",
+		Level: 1,
+		Policy: &protocol.UserPolicy{
+			Timeout: &protocol.UserPolicy_Timeout{
+				ConnectionIdle: &protocol.UserPolicy_Second{Value: 3600},
+				UplinkOnly:     &protocol.UserPolicy_Second{Value: 0},
+			},
+		},
+	}
+	p := policy.ForUser(manager, user)
+	if p.Timeouts.ConnectionIdle != time.Hour || p.Timeouts.UplinkOnly != 0 {
+		t.Error("expected timeouts of user, but got ", p.Timeouts)
+	}
+	if p.Timeouts.Handshake != 2*time.Second || p.Buffer.PerConnection != 1024 {
+		t.Error("expected policy of level for settings left out, but got ", p)
+	}
+
+	user.Policy = nil
+	if p := policy.ForUser(manager, user); p.Timeouts.ConnectionIdle != 10*time.Second {
+		t.Error("expected policy of level, but got ", p.Timeouts.ConnectionIdle)
+	}
+}
+
+func TestUserPolicyPrecedence(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			1: {
+				Timeout: &Policy_Timeout{ConnectionIdle: &Second{Value: 10}},
+				Limit:   &Policy_Limit{MaxConnections: 4},
+				Rate:    &Policy_Rate{Uplink: 1024},
+			},
+		},
+		UserLimit: map[string]*Policy_Limit{
+			"love@xray.com": {MaxConnections: 8},
+		},
+		UserRate: map[string]*Policy_Rate{
+			"love@xray.com": {Downlink: 2048},
+		},
+	})
+	common.Must(err)
+
+	user := &protocol.MemoryUser{
+		Email: "love@xray.com",
+		Level: 1,
+		Policy: &protocol.UserPolicy{
+			Timeout: &protocol.UserPolicy_Timeout{
+				ConnectionIdle: &protocol.UserPolicy_Second{Value: 3600},
+			},
+		},
+	}
+	p := policy.ForUser(manager, user)
+	if p.Timeouts.ConnectionIdle != time.Hour {
+		t.Error("expected timeout of user policy, but got ", p.Timeouts.ConnectionIdle)
+	}
+	if p.Limit.MaxConnections != 8 {
+		t.Error("expected limit of user, but got ", p.Limit)
+	}
+	if p.Rate != (policy.Rate{Downlink: 2048}) {
+		t.Error("expected rate of user to replace the one of level, but got ", p.Rate)
+	}
+
+	manager.SetUserRate(user.Email, policy.Rate{Uplink: 512})
+	if p := policy.ForUser(manager, user); p.Rate != (policy.Rate{Uplink: 512}) {
+		t.Error("expected rate set at runtime, but got ", p.Rate)
+	}
+
+	other := &protocol.MemoryUser{Email: "other@xray.com", Level: 1}
+	if p := policy.ForUser(manager, other); p.Limit.MaxConnections != 4 || p.Rate != (policy.Rate{Uplink: 1024}) {
+		t.Error("expected limit and rate of level, but got ", p.Limit, p.Rate)
+	}
+}
+
+func TestUserLimit(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			0: {
+				Limit: &Policy_Limit{
+					MaxOnlineIps:   2,
+					MaxConnections: 3,
+				},
+			},
+		},
+	})
+	common.Must(err)
+
+	ip1 := net.ParseAddress("10.0.0.1")
+	ip2 := net.ParseAddress("10.0.0.2")
+	ip3 := net.ParseAddress("10.0.0.3")
+
+	release1, err := manager.TrackUser("a", 0, ip1)
+	common.Must(err)
+	release2, err := manager.TrackUser("a", 0, ip2)
+	common.Must(err)
+	if _, err := manager.TrackUser("a", 0, ip3); err == nil {
+		t.Error("expected online IP limit to be exceeded")
+	}
+	if _, err := manager.TrackUser("b", 0, ip3); err != nil {
+		t.Error("unexpected error for another user: ", err)
+	}
+	if _, err := manager.TrackUser("a", 1, ip3); err != nil {
+		t.Error("unexpected error for unlimited level: ", err)
+	}
+
+	release3, err := manager.TrackUser("a", 0, ip1)
+	common.Must(err)
+	if _, err := manager.TrackUser("a", 0, ip1); err == nil {
+		t.Error("expected connection limit to be exceeded")
+	}
+
+	release2()
+	release2()
+	release3()
+	if _, err := manager.TrackUser("a", 0, ip3); err != nil {
+		t.Error("expected released IP to be offline, but got ", err)
+	}
+	release1()
+}
+
+func TestUserLimitGracePeriod(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			0: {
+				Limit: &Policy_Limit{
+					MaxOnlineIps: 1,
+					GracePeriod:  &Second{Value: 60},
+				},
+			},
+		},
+	})
+	common.Must(err)
+
+	release, err := manager.TrackUser("a", 0, net.ParseAddress("10.0.0.1"))
+	common.Must(err)
+	release()
+	if _, err := manager.TrackUser("a", 0, net.ParseAddress("10.0.0.2")); err == nil {
+		t.Error("expected IP to be online in grace period")
+	}
+	if _, err := manager.TrackUser("a", 0, net.ParseAddress("10.0.0.1")); err != nil {
+		t.Error("unexpected error for the same IP: ", err)
+	}
+}
+
+func TestAdmitConnection(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			0: {
+				Limit: &Policy_Limit{
+					MaxConcurrentConnections: 2,
+				},
+			},
+		},
+		UserLimit: map[string]*Policy_Limit{
+			"b": {
+				NewConnectionsPerSecond: 2,
+			},
+		},
+	})
+	common.Must(err)
+
+	release1, err := manager.AdmitConnection("a", 0)
+	common.Must(err)
+	release2, err := manager.AdmitConnection("a", 0)
+	common.Must(err)
+	if _, err := manager.AdmitConnection("a", 0); err == nil {
+		t.Error("expected concurrent connection limit to be exceeded")
+	}
+	release2()
+	release2()
+	release3, err := manager.AdmitConnection("a", 0)
+	if err != nil {
+		t.Error("expected released connection not to be counted, but got ", err)
+	}
+	if _, err := manager.AdmitConnection("a", 0); err == nil {
+		t.Error("expected released connection to be counted off only once")
+	}
+	release1()
+	release3()
+
+	for i := 0; i < 2; i++ {
+		if _, err := manager.AdmitConnection("b", 0); err != nil {
+			t.Error("unexpected error in burst: ", err)
+		}
+	}
+	if _, err := manager.AdmitConnection("b", 0); err == nil {
+		t.Error("expected new connection rate to be exceeded")
+	}
+	time.Sleep(600 * time.Millisecond)
+	if _, err := manager.AdmitConnection("b", 0); err != nil {
+		t.Error("expected new connection to be admitted after a while, but got ", err)
+	}
+}
+
+func TestConnectionLimits(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			0: {
+				Limit: &Policy_Limit{
+					MaxConnections:           1,
+					MaxConcurrentConnections: 2,
+				},
+			},
+		},
+	})
+	common.Must(err)
+
+	// A Mux connection is tracked once by the inbound, and each of its streams is admitted by the dispatcher.
+	release, err := manager.TrackUser("a", 0, net.ParseAddress("10.0.0.1"))
+	common.Must(err)
+	for i := 0; i < 2; i++ {
+		if _, err := manager.AdmitConnection("a", 0); err != nil {
+			t.Error("expected stream of a Mux connection to be admitted, but got ", err)
+		}
+	}
+	if _, err := manager.AdmitConnection("a", 0); err == nil {
+		t.Error("expected streams of a Mux connection to be limited by MaxConcurrentConnections")
+	}
+	if _, err := manager.TrackUser("a", 0, net.ParseAddress("10.0.0.1")); err == nil {
+		t.Error("expected client connections to be limited by MaxConnections")
+	}
+	release()
+}
+
+func TestUserRate(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			0: {
+				Rate: &Policy_Rate{
+					Uplink: 1024 * 1024,
+				},
+			},
+		},
+		UserRate: map[string]*Policy_Rate{
+			"b": {
+				Downlink: 2 * 1024 * 1024,
+			},
+		},
+	})
+	common.Must(err)
+
+	uplink, downlink, _ := manager.UserRateLimiters("a", 0)
+	if uplink == nil || downlink != nil {
+		t.Fatal("expected only uplink to be limited")
+	}
+	if uplink.Limit() != 1024*1024 {
+		t.Error("unexpected uplink rate: ", uplink.Limit())
+	}
+	if another, _, _ := manager.UserRateLimiters("a", 0); another != uplink {
+		t.Error("expected limiter to be shared by connections of the same user")
+	}
+
+	if uplink, downlink, _ := manager.UserRateLimiters("b", 0); uplink != nil || downlink == nil {
+		t.Error("expected rate of user to override the one of level")
+	}
+	if uplink, downlink, _ := manager.UserRateLimiters("c", 1); uplink != nil || downlink != nil {
+		t.Error("expected no limit for level 1")
+	}
+
+	manager.SetLevelRate(0, policy.Rate{Uplink: 2048 * 1024})
+	if uplink.Limit() != 2048*1024 {
+		t.Error("expected level rate to apply to existing limiter, but got ", uplink.Limit())
+	}
+	manager.SetUserRate("a", policy.Rate{Uplink: 512 * 1024})
+	if uplink.Limit() != 512*1024 {
+		t.Error("expected user rate to apply to existing limiter, but got ", uplink.Limit())
+	}
+	if r, override := manager.UserRate("a", 0); !override || r.Uplink != 512*1024 {
+		t.Error("unexpected user rate: ", r, override)
+	}
+	manager.RemoveUserRate("a")
+	if uplink.Limit() != 2048*1024 {
+		t.Error("expected level rate to apply again, but got ", uplink.Limit())
+	}
+}
@@ -0,0 +1,214 @@
+package policy
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/features/policy"
+	"golang.org/x/time/rate"
+)
+
+// newLimiter creates a limiter of bytesPerSec, with a burst of one second. A limiter of 0 is unlimited.
+func newLimiter(bytesPerSec uint64) *rate.Limiter {
+	l := rate.NewLimiter(rate.Inf, 0)
+	setLimit(l, bytesPerSec)
+	return l
+}
+
+func setLimit(l *rate.Limiter, bytesPerSec uint64) {
+	if bytesPerSec == 0 {
+		l.SetLimit(rate.Inf)
+		return
+	}
+	burst := int64(bytesPerSec)
+	if burst < buf.Size {
+		burst = buf.Size
+	}
+	if burst > math.MaxInt32 {
+		burst = math.MaxInt32
+	}
+	l.SetLimit(rate.Limit(bytesPerSec))
+	l.SetBurst(int(burst))
+}
+
+type userLimiters struct {
+	level    uint32
+	uplink   *rate.Limiter
+	downlink *rate.Limiter
+	// connections is the number of live connections using the limiters.
+	connections int
+	lastSeen    time.Time
+}
+
+// idle returns whether the limiters have no connections since idleTimeout before now, and are full.
+func (l *userLimiters) idle(now time.Time) bool {
+	full := func(r *rate.Limiter) bool {
+		return r.Limit() == rate.Inf || r.TokensAt(now) >= float64(r.Burst())
+	}
+	return l.connections == 0 && now.Sub(l.lastSeen) >= idleTimeout && full(l.uplink) && full(l.downlink)
+}
+
+func (l *userLimiters) set(r policy.Rate) {
+	setLimit(l.uplink, r.Uplink)
+	setLimit(l.downlink, r.Downlink)
+}
+
+// userRates holds the rate limiters shared by the connections of each user.
+type userRates struct {
+	access    sync.Mutex
+	overrides map[string]policy.Rate
+	limiters  map[string]*userLimiters
+	// lastCleanup is when the idle limiters were last dropped.
+	lastCleanup time.Time
+}
+
+func newUserRates() *userRates {
+	return &userRates{
+		overrides:   make(map[string]policy.Rate),
+		limiters:    make(map[string]*userLimiters),
+		lastCleanup: time.Now(),
+	}
+}
+
+// cleanup drops the limiters of the users gone idle, at most once in idleTimeout, so that the ones of removed users
+// don't pile up. The limiters of a user with live connections are never dropped, so that the connections keep sharing
+// them and following changes of the rate. The caller must hold the lock.
+func (r *userRates) cleanup(now time.Time) {
+	if now.Sub(r.lastCleanup) < idleTimeout {
+		return
+	}
+	for email, l := range r.limiters {
+		if l.idle(now) {
+			delete(r.limiters, email)
+		}
+	}
+	r.lastCleanup = now
+}
+
+// UserRateLimiters implements policy.RateLimiter.
+func (m *Instance) UserRateLimiters(email string, level uint32) (*rate.Limiter, *rate.Limiter, func()) {
+	levelRate := m.ForLevel(level).Rate
+
+	m.rates.access.Lock()
+	defer m.rates.access.Unlock()
+
+	now := time.Now()
+	m.rates.cleanup(now)
+	r, found := m.rates.overrides[email]
+	if !found {
+		r = levelRate
+	}
+	l, found := m.rates.limiters[email]
+	if r.Uplink == 0 && r.Downlink == 0 {
+		if found {
+			l.set(r)
+		}
+		return nil, nil, func() {}
+	}
+	if !found {
+		l = &userLimiters{
+			uplink:   newLimiter(r.Uplink),
+			downlink: newLimiter(r.Downlink),
+		}
+		m.rates.limiters[email] = l
+	} else {
+		l.set(r)
+	}
+	l.level = level
+	l.lastSeen = now
+	l.connections++
+
+	var uplink, downlink *rate.Limiter
+	if r.Uplink > 0 {
+		uplink = l.uplink
+	}
+	if r.Downlink > 0 {
+		downlink = l.downlink
+	}
+	var once sync.Once
+	return uplink, downlink, func() {
+		once.Do(func() {
+			m.rates.access.Lock()
+			defer m.rates.access.Unlock()
+
+			l.connections--
+			l.lastSeen = time.Now()
+		})
+	}
+}
+
+// UserRate returns the rate limit of the user at level, and whether it is overridden for the user.
+func (m *Instance) UserRate(email string, level uint32) (policy.Rate, bool) {
+	levelRate := m.ForLevel(level).Rate
+
+	m.rates.access.Lock()
+	defer m.rates.access.Unlock()
+
+	if r, found := m.rates.overrides[email]; found {
+		return r, true
+	}
+	return levelRate, false
+}
+
+// SetLevelRate changes the rate limit of level. It applies to the existing connections of the users at level,
+// except the ones of users with their own rate limit. A connection started without limit stays unlimited.
+func (m *Instance) SetLevelRate(level uint32, r policy.Rate) {
+	m.access.Lock()
+	p, found := m.levels[level]
+	if !found {
+		p = defaultPolicy()
+		m.levels[level] = p
+	}
+	p.Rate = &Policy_Rate{
+		Uplink:   r.Uplink,
+		Downlink: r.Downlink,
+	}
+	m.access.Unlock()
+
+	m.rates.access.Lock()
+	defer m.rates.access.Unlock()
+
+	for email, l := range m.rates.limiters {
+		if _, found := m.rates.overrides[email]; !found && l.level == level {
+			l.set(r)
+		}
+	}
+}
+
+// SetUserRate sets the rate limit of the user, overriding the one of the user's level.
+// It applies to the existing connections of the user that are limited.
+func (m *Instance) SetUserRate(email string, r policy.Rate) {
+	m.rates.access.Lock()
+	defer m.rates.access.Unlock()
+
+	m.rates.overrides[email] = r
+	if l, found := m.rates.limiters[email]; found {
+		l.set(r)
+	}
+}
+
+// RemoveUserRate removes the rate limit of the user, so that the one of the user's level applies again.
+func (m *Instance) RemoveUserRate(email string) {
+	m.rates.access.Lock()
+	delete(m.rates.overrides, email)
+	l, found := m.rates.limiters[email]
+	var level uint32
+	if found {
+		level = l.level
+	}
+	m.rates.access.Unlock()
+
+	if !found {
+		return
+	}
+	levelRate := m.ForLevel(level).Rate
+
+	m.rates.access.Lock()
+	defer m.rates.access.Unlock()
+
+	if _, found := m.rates.overrides[email]; !found {
+		l.set(levelRate)
+	}
+}
@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/features/policy"
+)
+
+func TestUserRateCleanup(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			0: {
+				Rate: &Policy_Rate{
+					Uplink: 1024 * 1024,
+				},
+			},
+		},
+	})
+	common.Must(err)
+
+	uplink, _, release := manager.UserRateLimiters("a", 0)
+	later := time.Now().Add(2 * idleTimeout)
+	manager.rates.access.Lock()
+	manager.rates.cleanup(later)
+	manager.rates.access.Unlock()
+	if another, _, anotherRelease := manager.UserRateLimiters("a", 0); another != uplink {
+		t.Error("expected limiter of a user with live connections to be kept")
+	} else {
+		anotherRelease()
+	}
+	manager.SetUserRate("a", policy.Rate{Uplink: 512 * 1024})
+	if uplink.Limit() != 512*1024 {
+		t.Error("expected user rate to apply to the limiter of a live connection, but got ", uplink.Limit())
+	}
+
+	release()
+	release()
+	manager.rates.access.Lock()
+	l := manager.rates.limiters["a"]
+	if l.connections != 0 {
+		t.Error("expected connections to be counted off once, but got ", l.connections)
+	}
+	l.lastSeen = time.Now().Add(-idleTimeout)
+	manager.rates.cleanup(later.Add(idleTimeout))
+	_, found := manager.rates.limiters["a"]
+	manager.rates.access.Unlock()
+	if found {
+		t.Error("expected limiter of a user without connections to be dropped")
+	}
+}
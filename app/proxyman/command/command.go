@@ -2,13 +2,18 @@ package command
 
 import (
 	"context"
+	"sort"
+	"time"
 
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/proxy"
 	grpc "google.golang.org/grpc"
 )
@@ -63,10 +68,39 @@ func (op *RemoveUserOperation) ApplyInbound(ctx context.Context, handler inbound
 	return um.RemoveUser(ctx, op.Email)
 }
 
+// ApplyInbound implements InboundOperation.
+func (op *UpdateUserOperation) ApplyInbound(ctx context.Context, handler inbound.Handler) error {
+	p, err := getInbound(handler)
+	if err != nil {
+		return err
+	}
+	um, ok := p.(proxy.UserManager)
+	if !ok {
+		return errors.New("proxy is not a UserManager")
+	}
+	mUser, err := op.User.ToMemoryUser()
+	if err != nil {
+		return errors.New("failed to parse user").Base(err)
+	}
+	return um.UpdateUser(ctx, mUser, time.Duration(op.Overlap)*time.Second)
+}
+
+// ApplyInbound implements InboundOperation.
+func (op *SetSourceFilterOperation) ApplyInbound(ctx context.Context, handler inbound.Handler) error {
+	f, ok := handler.(interface {
+		SetSourceFilter(*proxyman.SourceFilterConfig) error
+	})
+	if !ok {
+		return errors.New("handler doesn't support source filter")
+	}
+	return f.SetSourceFilter(op.Filter)
+}
+
 type handlerServer struct {
-	s   *core.Instance
-	ihm inbound.Manager
-	ohm outbound.Manager
+	s          *core.Instance
+	ihm        inbound.Manager
+	ohm        outbound.Manager
+	dispatcher routing.Dispatcher
 }
 
 func (s *handlerServer) AddInbound(ctx context.Context, request *AddInboundRequest) (*AddInboundResponse, error) {
@@ -96,13 +130,46 @@ func (s *handlerServer) AlterInbound(ctx context.Context, request *AlterInboundR
 		return nil, errors.New("failed to get handler: ", request.Tag).Base(err)
 	}
 
-	return &AlterInboundResponse{}, operation.ApplyInbound(ctx, handler)
+	if err := operation.ApplyInbound(ctx, handler); err != nil {
+		return nil, err
+	}
+	if op, ok := operation.(*RemoveUserOperation); ok {
+		s.forgetUsers(ctx, []string{op.Email})
+	}
+	return &AlterInboundResponse{}, nil
 }
 
-func (s *handlerServer) GetInboundUsers(ctx context.Context, request *GetInboundUserRequest) (*GetInboundUserResponse, error) {
-	handler, err := s.ihm.GetHandler(ctx, request.Tag)
+// forgetUsers drops the quotas the dispatcher keeps for the users, which are no longer in any inbound.
+func (s *handlerServer) forgetUsers(ctx context.Context, emails []string) {
+	d, ok := s.dispatcher.(*dispatcher.DefaultDispatcher)
+	if !ok {
+		return
+	}
+	for _, email := range emails {
+		if !s.hasUser(ctx, email) {
+			d.RemoveQuota(email)
+		}
+	}
+}
+
+// hasUser returns whether an inbound has the user with the email.
+func (s *handlerServer) hasUser(ctx context.Context, email string) bool {
+	for _, handler := range s.ihm.ListHandlers(ctx) {
+		p, err := getInbound(handler)
+		if err != nil {
+			continue
+		}
+		if um, ok := p.(proxy.UserManager); ok && um.GetUser(ctx, email) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *handlerServer) getUserManager(ctx context.Context, tag string) (proxy.UserManager, error) {
+	handler, err := s.ihm.GetHandler(ctx, tag)
 	if err != nil {
-		return nil, errors.New("failed to get handler: ", request.Tag).Base(err)
+		return nil, errors.New("failed to get handler: ", tag).Base(err)
 	}
 	p, err := getInbound(handler)
 	if err != nil {
@@ -112,31 +179,106 @@ func (s *handlerServer) GetInboundUsers(ctx context.Context, request *GetInbound
 	if !ok {
 		return nil, errors.New("proxy is not a UserManager")
 	}
+	return um, nil
+}
+
+func sortUsers(users []*protocol.MemoryUser) {
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Email < users[j].Email
+	})
+}
+
+// toProtoUsers converts the users, sorted by email.
+func toProtoUsers(users []*protocol.MemoryUser) []*protocol.User {
+	sortUsers(users)
+	result := make([]*protocol.User, 0, len(users))
+	for _, u := range users {
+		result = append(result, protocol.ToProtoUser(u))
+	}
+	return result
+}
+
+func (s *handlerServer) GetInboundUsers(ctx context.Context, request *GetInboundUserRequest) (*GetInboundUserResponse, error) {
+	um, err := s.getUserManager(ctx, request.Tag)
+	if err != nil {
+		return nil, err
+	}
 	if len(request.Email) > 0 {
-		return &GetInboundUserResponse{Users: []*protocol.User{protocol.ToProtoUser(um.GetUser(ctx, request.Email))}}, nil
+		return &GetInboundUserResponse{Users: []*protocol.User{protocol.ToProtoUser(um.GetUser(ctx, request.Email))}, Total: 1}, nil
 	}
-	var result = make([]*protocol.User, 0, 100)
 	users := um.GetUsers(ctx)
-	for _, u := range users {
-		result = append(result, protocol.ToProtoUser(u))
+	total := len(users)
+	sortUsers(users)
+	if request.Offset > 0 || request.Limit > 0 {
+		start := min(int(request.Offset), total)
+		end := total
+		if request.Limit > 0 {
+			end = min(start+int(request.Limit), total)
+		}
+		users = users[start:end]
 	}
-	return &GetInboundUserResponse{Users: result}, nil
+	return &GetInboundUserResponse{Users: toProtoUsers(users), Total: int64(total)}, nil
 }
 
 func (s *handlerServer) GetInboundUsersCount(ctx context.Context, request *GetInboundUserRequest) (*GetInboundUsersCountResponse, error) {
-	handler, err := s.ihm.GetHandler(ctx, request.Tag)
+	um, err := s.getUserManager(ctx, request.Tag)
 	if err != nil {
-		return nil, errors.New("failed to get handler: ", request.Tag).Base(err)
+		return nil, err
 	}
-	p, err := getInbound(handler)
+	return &GetInboundUsersCountResponse{Count: um.GetUsersCount(ctx)}, nil
+}
+
+func (s *handlerServer) SyncInboundUsers(ctx context.Context, request *SyncInboundUsersRequest) (*SyncInboundUsersResponse, error) {
+	um, err := s.getUserManager(ctx, request.Tag)
 	if err != nil {
 		return nil, err
 	}
-	um, ok := p.(proxy.UserManager)
-	if !ok {
-		return nil, errors.New("proxy is not a UserManager")
+	users := make([]*protocol.MemoryUser, 0, len(request.Users))
+	for _, user := range request.Users {
+		mUser, err := user.ToMemoryUser()
+		if err != nil {
+			return nil, errors.New("failed to parse user ", user.Email).Base(err)
+		}
+		users = append(users, mUser)
 	}
-	return &GetInboundUsersCountResponse{Count: um.GetUsersCount(ctx)}, nil
+	kept := make(map[string]bool, len(users))
+	for _, user := range users {
+		kept[user.Email] = true
+	}
+	var dropped []string
+	for _, user := range um.GetUsers(ctx) {
+		if !kept[user.Email] {
+			dropped = append(dropped, user.Email)
+		}
+	}
+	added, removed, err := um.SyncUsers(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	s.forgetUsers(ctx, dropped)
+	return &SyncInboundUsersResponse{Added: uint32(added), Removed: uint32(removed)}, nil
+}
+
+func (s *handlerServer) ListAllUsers(ctx context.Context, request *ListAllUsersRequest) (*ListAllUsersResponse, error) {
+	response := &ListAllUsersResponse{}
+	for _, handler := range s.ihm.ListHandlers(ctx) {
+		p, err := getInbound(handler)
+		if err != nil {
+			continue
+		}
+		um, ok := p.(proxy.UserManager)
+		if !ok {
+			continue
+		}
+		response.Inbounds = append(response.Inbounds, &InboundUsers{
+			Tag:   handler.Tag(),
+			Users: toProtoUsers(um.GetUsers(ctx)),
+		})
+	}
+	sort.Slice(response.Inbounds, func(i, j int) bool {
+		return response.Inbounds[i].Tag < response.Inbounds[j].Tag
+	})
+	return response, nil
 }
 
 func (s *handlerServer) AddOutbound(ctx context.Context, request *AddOutboundRequest) (*AddOutboundResponse, error) {
@@ -174,9 +316,10 @@ func (s *service) Register(server *grpc.Server) {
 	hs := &handlerServer{
 		s: s.v,
 	}
-	common.Must(s.v.RequireFeatures(func(im inbound.Manager, om outbound.Manager) {
+	common.Must(s.v.RequireFeatures(func(im inbound.Manager, om outbound.Manager, d routing.Dispatcher) {
 		hs.ihm = im
 		hs.ohm = om
+		hs.dispatcher = d
 	}, false))
 	RegisterHandlerServiceServer(server, hs)
 
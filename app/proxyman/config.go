@@ -21,3 +21,17 @@ func (c *ReceiverConfig) GetEffectiveSniffingSettings() *SniffingConfig {
 
 	return nil
 }
+
+func (c *ConnectionPoolConfig) GetMaxIdleTimeValue() uint32 {
+	if c.MaxIdleTime == 0 {
+		return 60
+	}
+	return c.MaxIdleTime
+}
+
+func (c *ConnectionPoolConfig) GetHealthCheckIntervalValue() uint32 {
+	if c.HealthCheckInterval == 0 {
+		return 10
+	}
+	return c.HealthCheckInterval
+}
@@ -46,6 +46,7 @@ type AlwaysOnInboundHandler struct {
 	workers []worker
 	mux     *mux.Server
 	tag     string
+	filter  *sourceFilter
 }
 
 func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *proxyman.ReceiverConfig, proxyConfig interface{}) (*AlwaysOnInboundHandler, error) {
@@ -58,10 +59,15 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 		return nil, errors.New("not an inbound proxy.")
 	}
 
+	filter, err := newSourceFilter(receiverConfig.SourceFilter)
+	if err != nil {
+		return nil, errors.New("failed to build source filter").Base(err)
+	}
 	h := &AlwaysOnInboundHandler{
-		proxy: p,
-		mux:   mux.NewServer(ctx),
-		tag:   tag,
+		proxy:  p,
+		mux:    mux.NewServer(ctx),
+		tag:    tag,
+		filter: filter,
 	}
 
 	uplinkCounter, downlinkCounter := getStatCounter(core.MustFromContext(ctx), tag)
@@ -122,6 +128,7 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 						sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
 						uplinkCounter:   uplinkCounter,
 						downlinkCounter: downlinkCounter,
+						filter:          filter,
 						ctx:             ctx,
 					}
 					h.workers = append(h.workers, worker)
@@ -138,6 +145,7 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 						uplinkCounter:   uplinkCounter,
 						downlinkCounter: downlinkCounter,
 						stream:          mss,
+						filter:          filter,
 						ctx:             ctx,
 					}
 					h.workers = append(h.workers, worker)
@@ -159,6 +167,19 @@ func (h *AlwaysOnInboundHandler) Start() error {
 	return nil
 }
 
+// Drain implements features.Drainer. The workers stop accepting, and the mux connections refuse new sub-connections.
+func (h *AlwaysOnInboundHandler) Drain() error {
+	var errs []error
+	for _, worker := range h.workers {
+		errs = append(errs, worker.Drain())
+	}
+	h.mux.Drain()
+	if err := errors.Combine(errs...); err != nil {
+		return errors.New("failed to drain all workers").Base(err)
+	}
+	return nil
+}
+
 // Close implements common.Closable.
 func (h *AlwaysOnInboundHandler) Close() error {
 	var errs []error
@@ -187,3 +208,8 @@ func (h *AlwaysOnInboundHandler) Tag() string {
 func (h *AlwaysOnInboundHandler) GetInbound() proxy.Inbound {
 	return h.proxy
 }
+
+// SetSourceFilter replaces the source filter of the handler. A nil config accepts all connections.
+func (h *AlwaysOnInboundHandler) SetSourceFilter(config *proxyman.SourceFilterConfig) error {
+	return h.filter.update(config)
+}
@@ -29,18 +29,24 @@ type DynamicInboundHandler struct {
 	lastRefresh    time.Time
 	mux            *mux.Server
 	task           *task.Periodic
+	filter         *sourceFilter
 
 	ctx context.Context
 }
 
 func NewDynamicInboundHandler(ctx context.Context, tag string, receiverConfig *proxyman.ReceiverConfig, proxyConfig interface{}) (*DynamicInboundHandler, error) {
 	v := core.MustFromContext(ctx)
+	filter, err := newSourceFilter(receiverConfig.SourceFilter)
+	if err != nil {
+		return nil, errors.New("failed to build source filter").Base(err)
+	}
 	h := &DynamicInboundHandler{
 		tag:            tag,
 		proxyConfig:    proxyConfig,
 		receiverConfig: receiverConfig,
 		portsInUse:     make(map[net.Port]struct{}),
 		mux:            mux.NewServer(ctx),
+		filter:         filter,
 		v:              v,
 		ctx:            ctx,
 	}
@@ -141,6 +147,7 @@ func (h *DynamicInboundHandler) refresh() error {
 				sniffingConfig:  h.receiverConfig.GetEffectiveSniffingSettings(),
 				uplinkCounter:   uplinkCounter,
 				downlinkCounter: downlinkCounter,
+				filter:          h.filter,
 				ctx:             h.ctx,
 			}
 			if err := worker.Start(); err != nil {
@@ -161,6 +168,7 @@ func (h *DynamicInboundHandler) refresh() error {
 				uplinkCounter:   uplinkCounter,
 				downlinkCounter: downlinkCounter,
 				stream:          h.streamSettings,
+				filter:          h.filter,
 				ctx:             h.ctx,
 			}
 			if err := worker.Start(); err != nil {
@@ -186,6 +194,26 @@ func (h *DynamicInboundHandler) Start() error {
 	return h.task.Start()
 }
 
+// Drain implements features.Drainer. No more workers are allocated, the current ones stop accepting, and the mux
+// connections refuse new sub-connections.
+func (h *DynamicInboundHandler) Drain() error {
+	if err := h.task.Close(); err != nil {
+		return err
+	}
+	h.workerMutex.RLock()
+	defer h.workerMutex.RUnlock()
+
+	var errs []error
+	for _, worker := range h.worker {
+		errs = append(errs, worker.Drain())
+	}
+	h.mux.Drain()
+	if err := errors.Combine(errs...); err != nil {
+		return errors.New("failed to drain all workers").Base(err)
+	}
+	return nil
+}
+
 func (h *DynamicInboundHandler) Close() error {
 	return h.task.Close()
 }
@@ -205,3 +233,8 @@ func (h *DynamicInboundHandler) GetRandomInboundProxy() (interface{}, net.Port,
 func (h *DynamicInboundHandler) Tag() string {
 	return h.tag
 }
+
+// SetSourceFilter replaces the source filter of the handler. A nil config accepts all connections.
+func (h *DynamicInboundHandler) SetSourceFilter(config *proxyman.SourceFilterConfig) error {
+	return h.filter.update(config)
+}
@@ -0,0 +1,186 @@
+package inbound
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// sourceIdleTimeout is how long the accept rate limiter of a source IP is kept after its last connection.
+	sourceIdleTimeout = time.Minute
+	// maxSources bounds the rate limiters against floods of spoofed UDP sources. Once reached, new sources are
+	// rejected until the idle ones are dropped.
+	maxSources = 1 << 16
+)
+
+type sourceRules struct {
+	allow *router.MultiGeoIPMatcher
+	deny  *router.MultiGeoIPMatcher
+	rate  rate.Limit
+	burst int
+}
+
+type sourceLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// sourceFilter checks the source IPs of the connections of an inbound, right after they are accepted. It's shared by
+// all workers of the inbound, and its rules can be replaced at runtime.
+type sourceFilter struct {
+	rules atomic.Pointer[sourceRules]
+
+	access      sync.Mutex
+	limiters    map[string]*sourceLimiter
+	lastCleanup time.Time
+}
+
+func newSourceFilter(config *proxyman.SourceFilterConfig) (*sourceFilter, error) {
+	f := &sourceFilter{
+		limiters:    make(map[string]*sourceLimiter),
+		lastCleanup: time.Now(),
+	}
+	if err := f.update(config); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// update replaces the rules of f. A nil config accepts all connections.
+func (f *sourceFilter) update(config *proxyman.SourceFilterConfig) error {
+	var rules *sourceRules
+	if config != nil && (len(config.Allow) > 0 || len(config.Deny) > 0 || config.AcceptRate > 0) {
+		rules = &sourceRules{
+			rate:  rate.Inf,
+			burst: int(config.AcceptBurst),
+		}
+		var err error
+		if len(config.Allow) > 0 {
+			if rules.allow, err = router.NewMultiGeoIPMatcher(config.Allow, true); err != nil {
+				return errors.New("failed to build allowed source IPs").Base(err)
+			}
+		}
+		if len(config.Deny) > 0 {
+			if rules.deny, err = router.NewMultiGeoIPMatcher(config.Deny, true); err != nil {
+				return errors.New("failed to build denied source IPs").Base(err)
+			}
+		}
+		if config.AcceptRate > 0 {
+			rules.rate = rate.Limit(config.AcceptRate)
+			if rules.burst == 0 {
+				rules.burst = int(config.AcceptRate)
+			}
+		}
+	}
+
+	f.access.Lock()
+	defer f.access.Unlock()
+	f.rules.Store(rules)
+	// The limiters of the previous rate start over.
+	f.limiters = make(map[string]*sourceLimiter)
+	return nil
+}
+
+// match checks ip against the allowed and denied IPs.
+func (f *sourceFilter) match(ip net.IP) error {
+	return f.rules.Load().match(ip)
+}
+
+func (r *sourceRules) match(ip net.IP) error {
+	if r == nil {
+		return nil
+	}
+	// GeoIP matchers tell IPv4 from IPv6 by length.
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if r.allow != nil && !r.allow.MatchIP(ip) {
+		return errors.New("source IP ", ip, " is not allowed")
+	}
+	if r.deny != nil && r.deny.MatchIP(ip) {
+		return errors.New("source IP ", ip, " is denied")
+	}
+	return nil
+}
+
+// admit checks ip like match, and counts a new connection from it against the accept rate.
+func (f *sourceFilter) admit(ip net.IP) error {
+	rules := f.rules.Load()
+	if err := rules.match(ip); err != nil {
+		return err
+	}
+	if rules == nil || rules.rate == rate.Inf {
+		return nil
+	}
+
+	f.access.Lock()
+	defer f.access.Unlock()
+
+	// The rules may have been replaced meanwhile, and the limiters with them.
+	if rules = f.rules.Load(); rules == nil || rules.rate == rate.Inf {
+		return nil
+	}
+	now := time.Now()
+	if now.Sub(f.lastCleanup) >= sourceIdleTimeout || len(f.limiters) >= maxSources {
+		for k, l := range f.limiters {
+			if now.Sub(l.lastSeen) >= sourceIdleTimeout {
+				delete(f.limiters, k)
+			}
+		}
+		f.lastCleanup = now
+	}
+	key := ip.String()
+	l, found := f.limiters[key]
+	if !found {
+		if len(f.limiters) >= maxSources {
+			return errors.New("too many source IPs")
+		}
+		l = &sourceLimiter{
+			limiter: rate.NewLimiter(rules.rate, rules.burst),
+		}
+		f.limiters[key] = l
+	}
+	l.lastSeen = now
+	if !l.limiter.AllowN(now, 1) {
+		return errors.New("source IP ", ip, " exceeds accept rate")
+	}
+	return nil
+}
+
+// sourceIP returns the IP of source, or nil if it has none, like unix sockets.
+func sourceIP(source net.Addr) net.IP {
+	switch addr := source.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	case nil:
+		return nil
+	}
+	host, _, err := net.SplitHostPort(source.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// allow implements internet.ConnectionFilter. Sources without IP are always allowed.
+func (f *sourceFilter) allow(ctx context.Context, source net.Addr) bool {
+	ip := sourceIP(source)
+	if ip == nil {
+		return true
+	}
+	if err := f.admit(ip); err != nil {
+		errors.LogDebugInner(ctx, err, "rejected connection")
+		return false
+	}
+	return true
+}
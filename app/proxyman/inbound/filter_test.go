@@ -0,0 +1,46 @@
+package inbound
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestSourceFilter(t *testing.T) {
+	f, err := newSourceFilter(&proxyman.SourceFilterConfig{
+		Allow: []*router.GeoIP{{Cidr: []*router.CIDR{{Ip: []byte{10, 0, 0, 0}, Prefix: 8}}}},
+		Deny:  []*router.GeoIP{{Cidr: []*router.CIDR{{Ip: []byte{10, 0, 0, 2}, Prefix: 32}}}},
+	})
+	common.Must(err)
+
+	if err := f.admit(net.ParseIP("10.0.0.1")); err != nil {
+		t.Error("unexpected error for allowed IP: ", err)
+	}
+	if err := f.admit(net.ParseIP("10.0.0.2")); err == nil {
+		t.Error("expected denied IP to be rejected")
+	}
+	if err := f.admit(net.ParseIP("192.168.0.1")); err == nil {
+		t.Error("expected IP not allowed to be rejected")
+	}
+
+	common.Must(f.update(&proxyman.SourceFilterConfig{AcceptRate: 1, AcceptBurst: 2}))
+	for i := 0; i < 2; i++ {
+		if err := f.admit(net.ParseIP("192.168.0.1")); err != nil {
+			t.Error("unexpected error in burst: ", err)
+		}
+	}
+	if err := f.admit(net.ParseIP("192.168.0.1")); err == nil {
+		t.Error("expected accept rate to be exceeded")
+	}
+	if err := f.admit(net.ParseIP("192.168.0.2")); err != nil {
+		t.Error("unexpected error for another IP: ", err)
+	}
+
+	common.Must(f.update(nil))
+	if err := f.admit(net.ParseIP("192.168.0.1")); err != nil {
+		t.Error("expected filter to be removed, but got ", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features"
 	"github.com/xtls/xray-core/features/inbound"
 )
 
@@ -69,6 +70,19 @@ func (m *Manager) GetHandler(ctx context.Context, tag string) (inbound.Handler,
 	return handler, nil
 }
 
+// ListHandlers implements inbound.Manager.
+func (m *Manager) ListHandlers(ctx context.Context) []inbound.Handler {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	handlers := make([]inbound.Handler, 0, len(m.untaggedHandler)+len(m.taggedHandlers))
+	handlers = append(handlers, m.untaggedHandler...)
+	for _, handler := range m.taggedHandlers {
+		handlers = append(handlers, handler)
+	}
+	return handlers
+}
+
 // RemoveHandler implements inbound.Manager.
 func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 	if tag == "" {
@@ -110,6 +124,29 @@ func (m *Manager) Start() error {
 	return nil
 }
 
+// Drain implements features.Drainer. All handlers stop accepting connections.
+func (m *Manager) Drain() error {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	var errs []error
+	drain := func(handler inbound.Handler) {
+		if d, ok := handler.(features.Drainer); ok {
+			errs = append(errs, d.Drain())
+		}
+	}
+	for _, handler := range m.taggedHandlers {
+		drain(handler)
+	}
+	for _, handler := range m.untaggedHandler {
+		drain(handler)
+	}
+	if err := errors.Combine(errs...); err != nil {
+		return errors.New("failed to drain all handlers").Base(err)
+	}
+	return nil
+}
+
 // Close implements common.Closable.
 func (m *Manager) Close() error {
 	m.access.Lock()
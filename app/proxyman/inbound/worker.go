@@ -16,6 +16,7 @@ import (
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/signal/done"
 	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy"
@@ -28,6 +29,8 @@ import (
 
 type worker interface {
 	Start() error
+	// Drain stops accepting connections. The ones accepted are served until Close.
+	Drain() error
 	Close() error
 	Port() net.Port
 	Proxy() proxy.Inbound
@@ -44,8 +47,11 @@ type tcpWorker struct {
 	sniffingConfig  *proxyman.SniffingConfig
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	filter          *sourceFilter
 
-	hub internet.Listener
+	hub        internet.Listener
+	connFilter *internet.ConnectionFilter
+	drained    bool
 
 	ctx context.Context
 }
@@ -58,6 +64,10 @@ func getTProxyType(s *internet.MemoryStreamConfig) internet.SocketConfig_TProxyM
 }
 
 func (w *tcpWorker) callback(conn stat.Connection) {
+	if w.connFilter != nil && !w.connFilter.Installed() && !w.filter.allow(w.ctx, conn.RemoteAddr()) {
+		conn.Close()
+		return
+	}
 	ctx, cancel := context.WithCancel(w.ctx)
 	sid := session.NewID()
 	ctx = c.ContextWithID(ctx, sid)
@@ -106,9 +116,14 @@ func (w *tcpWorker) callback(conn stat.Connection) {
 	}
 	ctx = session.ContextWithContent(ctx, content)
 
-	if err := w.proxy.Process(ctx, net.Network_TCP, conn, w.dispatcher); err != nil {
+	ctx, span := trace.Start(ctx, "inbound")
+	span.SetAttribute("xray.inbound.tag", w.tag)
+	span.SetAttribute("net.peer", conn.RemoteAddr())
+	err := w.proxy.Process(ctx, net.Network_TCP, conn, w.dispatcher)
+	if err != nil {
 		errors.LogInfoInner(ctx, err, "connection ends")
 	}
+	span.End(err)
 	cancel()
 	conn.Close()
 }
@@ -119,6 +134,18 @@ func (w *tcpWorker) Proxy() proxy.Inbound {
 
 func (w *tcpWorker) Start() error {
 	ctx := context.Background()
+	if w.filter != nil {
+		// The system listener drops the rejected connections before any handshake, if it can. Otherwise callback does.
+		w.connFilter = &internet.ConnectionFilter{
+			Allow: func(source net.Addr) bool {
+				return w.filter.allow(w.ctx, source)
+			},
+		}
+		ctx = internet.ContextWithConnectionFilter(ctx, w.connFilter)
+	}
+	if authenticator, ok := w.proxy.(internet.Authenticator); ok {
+		ctx = internet.ContextWithAuthenticator(ctx, authenticator)
+	}
 	hub, err := internet.ListenTCP(ctx, w.address, w.port, w.stream, func(conn stat.Connection) {
 		go w.callback(conn)
 	})
@@ -129,11 +156,25 @@ func (w *tcpWorker) Start() error {
 	return nil
 }
 
+func (w *tcpWorker) Drain() error {
+	if w.hub == nil || w.drained {
+		return nil
+	}
+	w.drained = true
+	if d, ok := w.hub.(internet.DrainableListener); ok {
+		return d.Drain()
+	}
+	// The connections accepted by the other listeners outlive them.
+	return common.Close(w.hub)
+}
+
 func (w *tcpWorker) Close() error {
 	var errs []interface{}
 	if w.hub != nil {
-		if err := common.Close(w.hub); err != nil {
-			errs = append(errs, err)
+		if _, drainable := w.hub.(internet.DrainableListener); drainable || !w.drained {
+			if err := common.Close(w.hub); err != nil {
+				errs = append(errs, err)
+			}
 		}
 		if err := common.Close(w.proxy); err != nil {
 			errs = append(errs, err)
@@ -246,20 +287,27 @@ type udpWorker struct {
 	sniffingConfig  *proxyman.SniffingConfig
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	filter          *sourceFilter
 
 	checker    *task.Periodic
 	activeConn map[connID]*udpConn
+	drained    bool
 
 	ctx  context.Context
 	cone bool
 }
 
-func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
+func (w *udpWorker) getConnection(id connID) (*udpConn, bool, error) {
 	w.Lock()
 	defer w.Unlock()
 
 	if conn, found := w.activeConn[id]; found && !conn.done.Done() {
-		return conn, true
+		return conn, true, nil
+	}
+	if w.filter != nil {
+		if err := w.filter.admit(id.src.Address.IP()); err != nil {
+			return nil, false, err
+		}
 	}
 
 	pReader, pWriter := pipe.New(pipe.DiscardOverflow(), pipe.WithSizeLimit(16*1024))
@@ -284,7 +332,7 @@ func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
 	w.activeConn[id] = conn
 
 	conn.updateActivity()
-	return conn, false
+	return conn, false, nil
 }
 
 func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest net.Destination) {
@@ -297,7 +345,19 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 		}
 		b.UDP = &originalDest
 	}
-	conn, existing := w.getConnection(id)
+	if w.filter != nil {
+		// Checked on every packet, so that the sources denied at runtime are cut off.
+		if err := w.filter.match(source.Address.IP()); err != nil {
+			b.Release()
+			return
+		}
+	}
+	conn, existing, err := w.getConnection(id)
+	if err != nil {
+		errors.LogDebugInner(w.ctx, err, "rejected UDP session")
+		b.Release()
+		return
+	}
 
 	// payload will be discarded in pipe is full.
 	conn.writer.WriteMultiBuffer(buf.MultiBuffer{b})
@@ -328,9 +388,14 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 				content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
 			}
 			ctx = session.ContextWithContent(ctx, content)
-			if err := w.proxy.Process(ctx, net.Network_UDP, conn, w.dispatcher); err != nil {
+			ctx, span := trace.Start(ctx, "inbound")
+			span.SetAttribute("xray.inbound.tag", w.tag)
+			span.SetAttribute("net.peer", source)
+			err := w.proxy.Process(ctx, net.Network_UDP, conn, w.dispatcher)
+			if err != nil {
 				errors.LogInfoInner(ctx, err, "connection ends")
 			}
+			span.End(err)
 			conn.Close()
 			// conn not removed by checker TODO may be lock worker here is better
 			if !conn.inactive {
@@ -400,13 +465,26 @@ func (w *udpWorker) Start() error {
 	return nil
 }
 
+// Drain closes the UDP socket. As there is no way to tell new sessions from the ones in progress before reading
+// their packets, those get cut off too, unless their packets are sent to another socket on the same port.
+func (w *udpWorker) Drain() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.hub == nil || w.drained {
+		return nil
+	}
+	w.drained = true
+	return w.hub.Close()
+}
+
 func (w *udpWorker) Close() error {
 	w.Lock()
 	defer w.Unlock()
 
 	var errs []interface{}
 
-	if w.hub != nil {
+	if w.hub != nil && !w.drained {
 		if err := w.hub.Close(); err != nil {
 			errs = append(errs, err)
 		}
@@ -446,7 +524,8 @@ type dsWorker struct {
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
 
-	hub internet.Listener
+	hub     internet.Listener
+	drained bool
 
 	ctx context.Context
 }
@@ -509,11 +588,25 @@ func (w *dsWorker) Start() error {
 	return nil
 }
 
+func (w *dsWorker) Drain() error {
+	if w.hub == nil || w.drained {
+		return nil
+	}
+	w.drained = true
+	if d, ok := w.hub.(internet.DrainableListener); ok {
+		return d.Drain()
+	}
+	// The connections accepted by the other listeners outlive them.
+	return common.Close(w.hub)
+}
+
 func (w *dsWorker) Close() error {
 	var errs []interface{}
 	if w.hub != nil {
-		if err := common.Close(w.hub); err != nil {
-			errs = append(errs, err)
+		if _, drainable := w.hub.(internet.DrainableListener); drainable || !w.drained {
+			if err := common.Close(w.hub); err != nil {
+				errs = append(errs, err)
+			}
 		}
 		if err := common.Close(w.proxy); err != nil {
 			errs = append(errs, err)
@@ -64,6 +64,7 @@ type Handler struct {
 	mux             *mux.ClientManager
 	xudp            *mux.ClientManager
 	udp443          string
+	pool            *connPool
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
 }
@@ -160,6 +161,20 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 		}
 	}
 
+	// The connections of a pool are of RAW, whose read deadlines are for its health checks, and can't be sent
+	// through an address of each request, or the pipe of a dialer proxy. Each of them carries a single request of a
+	// protocol dialing its server, rather than the requests of Mux.
+	if s := h.senderSettings; s.GetConnectionPool().GetSize() > 0 && h.streamSettings.ProtocolName == "tcp" &&
+		s.Via == nil && h.streamSettings.SocketSettings.GetDialerProxy() == "" {
+		if _, ok := proxyHandler.(proxy.PoolableOutbound); !ok {
+			return nil, errors.New("connection pool is only for VLESS, VMess, Trojan and Shadowsocks")
+		}
+		if s.MultiplexSettings.GetEnabled() {
+			return nil, errors.New("connection pool is conflicted with Mux")
+		}
+		h.pool = newConnPool(ctx, config.Tag, s.ConnectionPool, h.streamSettings)
+	}
+
 	h.proxy = proxyHandler
 	return h, nil
 }
@@ -260,6 +275,10 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (stat.Connecti
 
 				if config := tls.ConfigFromStreamSettings(h.streamSettings); config != nil {
 					tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
+					if err := config.ApplyECH(ctx, tlsConfig); err != nil {
+						conn.Close()
+						return nil, err
+					}
 					conn = tls.Client(conn, tlsConfig)
 				}
 
@@ -293,7 +312,16 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (stat.Connecti
 		return conn, err
 	}
 
-	conn, err := internet.Dial(ctx, dest, h.streamSettings)
+	var conn stat.Connection
+	var err error
+	if h.pool != nil && dest.Network == net.Network_TCP && session.MitmServerNameFromContext(ctx) == "" {
+		if conn = h.pool.Get(dest); conn != nil {
+			errors.LogDebug(ctx, "using a pooled connection to ", dest)
+		}
+	}
+	if conn == nil {
+		conn, err = internet.Dial(ctx, dest, h.streamSettings)
+	}
 	conn = h.getStatCouterConnection(conn)
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
@@ -319,12 +347,18 @@ func (h *Handler) GetOutbound() proxy.Outbound {
 
 // Start implements common.Runnable.
 func (h *Handler) Start() error {
+	if h.pool != nil {
+		return h.pool.Start()
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (h *Handler) Close() error {
 	common.Close(h.mux)
+	if h.pool != nil {
+		h.pool.Close()
+	}
 	return nil
 }
 
@@ -3,6 +3,7 @@ package outbound_test
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -12,13 +13,17 @@ import (
 	"github.com/xtls/xray-core/app/proxyman"
 	. "github.com/xtls/xray-core/app/proxyman/outbound"
 	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/session"
 	core "github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/proxy/trojan"
 	"github.com/xtls/xray-core/transport/internet/stat"
+	_ "github.com/xtls/xray-core/transport/internet/tcp"
 )
 
 func TestInterfaces(t *testing.T) {
@@ -174,3 +179,116 @@ func TestTagsCache(t *testing.T) {
 	stop_get = true
 	wg_get.Wait()
 }
+
+func TestOutboundConnectionPool(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	acceptN := func(n int) []net.Conn {
+		var conns []net.Conn
+		for i := 0; i < n; i++ {
+			select {
+			case conn := <-accepted:
+				conns = append(conns, conn)
+			case <-time.After(5 * time.Second):
+				t.Fatal("accepted ", len(conns), " connections, want ", n)
+			}
+		}
+		return conns
+	}
+
+	v, _ := core.New(&core.Config{})
+	v.AddFeature((outbound.Manager)(new(Manager)))
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	trojanConfig := serial.ToTypedMessage(&trojan.ClientConfig{
+		Server: []*protocol.ServerEndpoint{{
+			Address: net.NewIPOrDomain(net.LocalHostIP),
+			Port:    443,
+			User: []*protocol.User{{
+				Account: serial.ToTypedMessage(&trojan.Account{Password: "password"}),
+			}},
+		}},
+	})
+
+	// The connections of Freedom are to the destinations of its requests, and those of Mux are not of a request.
+	if _, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag: "tag",
+		SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+			ConnectionPool: &proxyman.ConnectionPoolConfig{Size: 2},
+		}),
+		ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+	}); err == nil {
+		t.Error("expected an error for a pool of Freedom")
+	}
+	if _, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag: "tag",
+		SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+			ConnectionPool:    &proxyman.ConnectionPoolConfig{Size: 2},
+			MultiplexSettings: &proxyman.MultiplexingConfig{Enabled: true},
+		}),
+		ProxySettings: trojanConfig,
+	}); err == nil {
+		t.Error("expected an error for a pool with Mux")
+	}
+
+	h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag: "tag",
+		SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+			ConnectionPool: &proxyman.ConnectionPoolConfig{Size: 2},
+		}),
+		ProxySettings: trojanConfig,
+	})
+	common.Must(err)
+	common.Must(h.Start())
+	defer h.Close()
+
+	dest := net.DestinationFromAddr(listener.Addr())
+	dial := func() stat.Connection {
+		conn, err := h.(*Handler).Dial(session.ContextWithOutbounds(ctx, []*session.Outbound{{}}), dest)
+		common.Must(err)
+		return conn
+	}
+
+	// The first dial warms the pool up.
+	dial().Close()
+	pooled := acceptN(3)[1:]
+
+	// Taken from the pool, which is filled again.
+	conn := dial()
+	common.Must2(conn.Write([]byte("test")))
+	var b [4]byte
+	var idle []net.Conn
+	for _, c := range pooled {
+		c.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		if n, _ := c.Read(b[:]); string(b[:n]) != "test" {
+			idle = append(idle, c)
+		}
+	}
+	if len(idle) != 1 {
+		t.Error("connection is not from the pool")
+	}
+	conn.Close()
+	idle = append(idle, acceptN(1)...)
+
+	// The idle connections closed by the server are not taken.
+	for _, c := range idle {
+		c.Close()
+	}
+	time.Sleep(100 * time.Millisecond)
+	conn = dial()
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := conn.Read(b[:]); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Error("connection is closed: ", err)
+	}
+}
@@ -0,0 +1,204 @@
+package outbound
+
+import (
+	"context"
+	goerrors "errors"
+	gonet "net"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// healthCheckTimeout is how long an idle connection is read, to see whether it's closed.
+const healthCheckTimeout = time.Millisecond
+
+type idleConn struct {
+	stat.Connection
+	since time.Time
+}
+
+type destPool struct {
+	conns    []*idleConn
+	dialing  int
+	lastUsed time.Time
+}
+
+// connPool keeps idle connections of the transport to the destinations of an outbound, which are taken once each,
+// and dialed again in the background. A destination is pooled once it's dialed, until it isn't for the max idle time.
+type connPool struct {
+	ctx            context.Context
+	tag            string
+	streamSettings *internet.MemoryStreamConfig
+	size           int
+	maxIdleTime    time.Duration
+	checker        *task.Periodic
+
+	access sync.Mutex
+	dests  map[net.Destination]*destPool
+	closed bool
+}
+
+func newConnPool(ctx context.Context, tag string, config *proxyman.ConnectionPoolConfig, streamSettings *internet.MemoryStreamConfig) *connPool {
+	p := &connPool{
+		ctx:            ctx,
+		tag:            tag,
+		streamSettings: streamSettings,
+		size:           int(config.Size),
+		maxIdleTime:    time.Duration(config.GetMaxIdleTimeValue()) * time.Second,
+		dests:          make(map[net.Destination]*destPool),
+	}
+	p.checker = &task.Periodic{
+		Interval: time.Duration(config.GetHealthCheckIntervalValue()) * time.Second,
+		Execute:  p.check,
+	}
+	return p
+}
+
+// Get returns an idle connection to dest, or nil if there is none.
+func (p *connPool) Get(dest net.Destination) stat.Connection {
+	defer p.fill(dest)
+	for {
+		p.access.Lock()
+		if p.closed {
+			p.access.Unlock()
+			return nil
+		}
+		d := p.dests[dest]
+		if d == nil {
+			d = new(destPool)
+			p.dests[dest] = d
+		}
+		d.lastUsed = time.Now()
+		if len(d.conns) == 0 {
+			p.access.Unlock()
+			return nil
+		}
+		conn := d.conns[len(d.conns)-1]
+		d.conns = d.conns[:len(d.conns)-1]
+		p.access.Unlock()
+
+		if time.Since(conn.since) < p.maxIdleTime && isAlive(conn) {
+			return conn.Connection
+		}
+		conn.Close()
+	}
+}
+
+// fill dials the connections dest lacks in the background.
+func (p *connPool) fill(dest net.Destination) {
+	p.access.Lock()
+	defer p.access.Unlock()
+	d := p.dests[dest]
+	if p.closed || d == nil {
+		return
+	}
+	for n := p.size - len(d.conns) - d.dialing; n > 0; n-- {
+		d.dialing++
+		go p.dial(dest, d)
+	}
+}
+
+func (p *connPool) dial(dest net.Destination, d *destPool) {
+	ctx := session.ContextWithOutbounds(p.ctx, []*session.Outbound{{
+		Target: dest,
+		Tag:    p.tag,
+	}})
+	conn, err := internet.Dial(ctx, dest, p.streamSettings)
+
+	p.access.Lock()
+	defer p.access.Unlock()
+	d.dialing--
+	if err != nil {
+		errors.LogInfoInner(ctx, err, "failed to dial a pooled connection to ", dest)
+		return
+	}
+	if p.closed || p.dests[dest] != d {
+		conn.Close()
+		return
+	}
+	d.conns = append(d.conns, &idleConn{
+		Connection: conn,
+		since:      time.Now(),
+	})
+}
+
+// check removes the destinations not dialed for the max idle time, and closes the idle connections that are too old
+// or closed by the server, and then fills the pools.
+func (p *connPool) check() error {
+	checked := make(map[net.Destination][]*idleConn)
+	p.access.Lock()
+	for dest, d := range p.dests {
+		if time.Since(d.lastUsed) > p.maxIdleTime {
+			for _, conn := range d.conns {
+				conn.Close()
+			}
+			delete(p.dests, dest)
+			continue
+		}
+		checked[dest] = d.conns
+		d.conns = nil
+	}
+	p.access.Unlock()
+
+	for dest, conns := range checked {
+		alive := conns[:0]
+		for _, conn := range conns {
+			if time.Since(conn.since) < p.maxIdleTime && isAlive(conn) {
+				alive = append(alive, conn)
+			} else {
+				conn.Close()
+			}
+		}
+		p.access.Lock()
+		if d := p.dests[dest]; d != nil && !p.closed {
+			// Older than the connections dialed while checking, so that they are taken later.
+			d.conns = append(alive, d.conns...)
+		} else {
+			for _, conn := range alive {
+				conn.Close()
+			}
+		}
+		p.access.Unlock()
+		p.fill(dest)
+	}
+	return nil
+}
+
+// isAlive returns whether conn is neither closed nor sent anything, as the server of a proxy doesn't speak first.
+func isAlive(conn net.Conn) bool {
+	if err := conn.SetReadDeadline(time.Now().Add(healthCheckTimeout)); err != nil {
+		return false
+	}
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	conn.SetReadDeadline(time.Time{})
+	var netErr gonet.Error
+	return goerrors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Start implements common.Runnable.
+func (p *connPool) Start() error {
+	return p.checker.Start()
+}
+
+// Close implements common.Closable.
+func (p *connPool) Close() error {
+	p.checker.Close()
+	p.access.Lock()
+	defer p.access.Unlock()
+	p.closed = true
+	for _, d := range p.dests {
+		for _, conn := range d.conns {
+			conn.Close()
+		}
+	}
+	p.dests = nil
+	return nil
+}
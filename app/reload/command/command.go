@@ -0,0 +1,37 @@
+package command
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/core"
+	grpc "google.golang.org/grpc"
+)
+
+type reloadServer struct {
+	v *core.Instance
+}
+
+// ReloadConfig implements ReloadService.
+func (s *reloadServer) ReloadConfig(ctx context.Context, request *ReloadConfigRequest) (*ReloadConfigResponse, error) {
+	if err := s.v.Reload(); err != nil {
+		return nil, err
+	}
+	return &ReloadConfigResponse{}, nil
+}
+
+func (s *reloadServer) mustEmbedUnimplementedReloadServiceServer() {}
+
+type service struct {
+	v *core.Instance
+}
+
+func (s *service) Register(server *grpc.Server) {
+	RegisterReloadServiceServer(server, &reloadServer{v: s.v})
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		return &service{v: core.MustFromContext(ctx)}, nil
+	}))
+}
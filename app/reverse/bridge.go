@@ -2,6 +2,7 @@ package reverse
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/xtls/xray-core/common/errors"
@@ -20,8 +21,11 @@ type Bridge struct {
 	dispatcher  routing.Dispatcher
 	tag         string
 	domain      string
-	workers     []*BridgeWorker
 	monitorTask *task.Periodic
+
+	access   sync.Mutex
+	workers  []*BridgeWorker
+	draining bool
 }
 
 // NewBridge creates a new Bridge instance.
@@ -60,6 +64,12 @@ func (b *Bridge) cleanup() {
 }
 
 func (b *Bridge) monitor() error {
+	b.access.Lock()
+	defer b.access.Unlock()
+
+	if b.draining {
+		return nil
+	}
 	b.cleanup()
 
 	var numConnections uint32
@@ -88,6 +98,19 @@ func (b *Bridge) Start() error {
 	return b.monitorTask.Start()
 }
 
+// Drain stops b from connecting to the portal, and makes its workers refuse new connections from it. The
+// connections in progress go on.
+func (b *Bridge) Drain() error {
+	b.access.Lock()
+	defer b.access.Unlock()
+
+	b.draining = true
+	for _, w := range b.workers {
+		w.worker.Drain()
+	}
+	return b.monitorTask.Close()
+}
+
 func (b *Bridge) Close() error {
 	return b.monitorTask.Close()
 }
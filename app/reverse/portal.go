@@ -62,6 +62,12 @@ func (p *Portal) Close() error {
 	return p.ohm.RemoveHandler(context.Background(), p.tag)
 }
 
+// Drain switches the bridges connected to p to DRAIN, so that they connect again, to the server replacing this one if
+// any.
+func (p *Portal) Drain() error {
+	return p.picker.Drain()
+}
+
 func (p *Portal) HandleConnection(ctx context.Context, link *transport.Link) error {
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
@@ -187,6 +193,18 @@ func (p *StaticMuxPicker) PickAvailable() (*mux.ClientWorker, error) {
 	return nil, errors.New("no mux client worker available")
 }
 
+// Drain switches the bridges of all workers to DRAIN.
+func (p *StaticMuxPicker) Drain() error {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	var errs []error
+	for _, w := range p.workers {
+		errs = append(errs, w.Drain())
+	}
+	return errors.Combine(errs...)
+}
+
 func (p *StaticMuxPicker) AddWorker(worker *PortalWorker) {
 	p.access.Lock()
 	defer p.access.Unlock()
@@ -195,6 +213,7 @@ func (p *StaticMuxPicker) AddWorker(worker *PortalWorker) {
 }
 
 type PortalWorker struct {
+	access   sync.Mutex
 	client   *mux.ClientWorker
 	control  *task.Periodic
 	writer   buf.Writer
@@ -233,6 +252,9 @@ func NewPortalWorker(client *mux.ClientWorker) (*PortalWorker, error) {
 }
 
 func (w *PortalWorker) heartbeat() error {
+	w.access.Lock()
+	defer w.access.Unlock()
+
 	if w.client.Closed() {
 		return errors.New("client worker stopped")
 	}
@@ -241,20 +263,41 @@ func (w *PortalWorker) heartbeat() error {
 		return errors.New("already disposed")
 	}
 
+	if w.client.TotalConnections() > 256 {
+		return w.drain()
+	}
+
 	msg := &Control{}
 	msg.FillInRandom()
+	return w.write(msg)
+}
 
-	if w.client.TotalConnections() > 256 {
-		w.draining = true
-		msg.State = Control_DRAIN
-
-		defer func() {
-			common.Close(w.writer)
-			common.Interrupt(w.reader)
-			w.writer = nil
-		}()
+// Drain switches the bridge of w to DRAIN, so that it connects again for new connections. The connections in
+// progress go on.
+func (w *PortalWorker) Drain() error {
+	w.access.Lock()
+	defer w.access.Unlock()
+
+	if w.draining || w.writer == nil || w.client.Closed() {
+		return nil
 	}
+	return w.drain()
+}
+
+func (w *PortalWorker) drain() error {
+	w.draining = true
+	defer func() {
+		common.Close(w.writer)
+		common.Interrupt(w.reader)
+		w.writer = nil
+	}()
+
+	msg := &Control{State: Control_DRAIN}
+	msg.FillInRandom()
+	return w.write(msg)
+}
 
+func (w *PortalWorker) write(msg *Control) error {
 	b, err := proto.Marshal(msg)
 	common.Must(err)
 	mb := buf.MergeBytes(nil, b)
@@ -80,6 +80,20 @@ func (r *Reverse) Start() error {
 	return nil
 }
 
+// Drain implements features.Drainer.
+func (r *Reverse) Drain() error {
+	var errs []error
+	for _, b := range r.bridges {
+		errs = append(errs, b.Drain())
+	}
+
+	for _, p := range r.portals {
+		errs = append(errs, p.Drain())
+	}
+
+	return errors.Combine(errs...)
+}
+
 func (r *Reverse) Close() error {
 	var errs []error
 	for _, b := range r.bridges {
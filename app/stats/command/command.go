@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"runtime"
+	"sort"
 	"time"
 
 	"github.com/xtls/xray-core/app/stats"
@@ -34,14 +35,15 @@ func (s *statsServer) GetStats(ctx context.Context, request *GetStatsRequest) (*
 	}
 	var value int64
 	if request.Reset_ {
-		value = c.Set(0)
+		value = resetCounter(c)
 	} else {
 		value = c.Value()
 	}
 	return &GetStatsResponse{
 		Stat: &Stat{
-			Name:  request.Name,
-			Value: value,
+			Name:      request.Name,
+			Value:     value,
+			LastReset: lastReset(c),
 		},
 	}, nil
 }
@@ -95,13 +97,45 @@ func (s *statsServer) QueryStats(ctx context.Context, request *QueryStatsRequest
 		if matcher.Match(name) {
 			var value int64
 			if request.Reset_ {
-				value = c.Set(0)
+				value = resetCounter(c)
 			} else {
 				value = c.Value()
 			}
 			response.Stat = append(response.Stat, &Stat{
-				Name:  name,
-				Value: value,
+				Name:      name,
+				Value:     value,
+				LastReset: lastReset(c),
+			})
+		}
+		return true
+	})
+
+	return response, nil
+}
+
+func (s *statsServer) ResetStats(ctx context.Context, request *ResetStatsRequest) (*ResetStatsResponse, error) {
+	matcherType := strmatcher.Substr
+	if request.Regexp {
+		matcherType = strmatcher.Regex
+	}
+	matcher, err := matcherType.New(request.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ResetStatsResponse{}
+
+	manager, ok := s.stats.(*stats.Manager)
+	if !ok {
+		return nil, errors.New("ResetStats only works its own stats.Manager.")
+	}
+
+	manager.VisitCounters(func(name string, c feature_stats.Counter) bool {
+		if matcher.Match(name) {
+			response.Stat = append(response.Stat, &Stat{
+				Name:      name,
+				Value:     resetCounter(c),
+				LastReset: lastReset(c),
 			})
 		}
 		return true
@@ -110,6 +144,25 @@ func (s *statsServer) QueryStats(ctx context.Context, request *QueryStatsRequest
 	return response, nil
 }
 
+// resetCounter sets the counter to 0 and returns its previous value, unless it is protected, such as the traffic of a
+// quota, whose value is returned as is.
+func resetCounter(c feature_stats.Counter) int64 {
+	if sc, ok := c.(*stats.Counter); ok && sc.Protected() {
+		return sc.Value()
+	}
+	return c.Set(0)
+}
+
+// lastReset returns the time in Unix seconds the counter was last reset, or 0 if it is unknown.
+func lastReset(c feature_stats.Counter) int64 {
+	if sc, ok := c.(*stats.Counter); ok {
+		if t := sc.LastReset(); !t.IsZero() {
+			return t.Unix()
+		}
+	}
+	return 0
+}
+
 func (s *statsServer) GetSysStats(ctx context.Context, request *SysStatsRequest) (*SysStatsResponse, error) {
 	var rtm runtime.MemStats
 	runtime.ReadMemStats(&rtm)
@@ -132,6 +185,99 @@ func (s *statsServer) GetSysStats(ctx context.Context, request *SysStatsRequest)
 	return response, nil
 }
 
+func (s *statsServer) SubscribeStats(request *SubscribeStatsRequest, stream StatsService_SubscribeStatsServer) error {
+	manager, ok := s.stats.(*stats.Manager)
+	if !ok {
+		return errors.New("SubscribeStats only works its own stats.Manager.")
+	}
+
+	matchers := make([]strmatcher.Matcher, 0, len(request.Patterns))
+	for _, pattern := range request.Patterns {
+		matcher, err := strmatcher.Substr.New(pattern)
+		if err != nil {
+			return err
+		}
+		matchers = append(matchers, matcher)
+	}
+	match := func(name string) bool {
+		if len(matchers) == 0 {
+			return true
+		}
+		for _, matcher := range matchers {
+			if matcher.Match(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	interval := time.Duration(request.Interval) * time.Second
+	if interval < stats.SamplingInterval {
+		interval = stats.SamplingInterval
+	}
+
+	subscriber, err := manager.SubscribeCounters()
+	if err != nil {
+		return err
+	}
+	defer manager.UnsubscribeCounters(subscriber)
+
+	var last *stats.CounterSnapshot
+	for {
+		select {
+		case value, ok := <-subscriber:
+			if !ok {
+				return errors.New("Upstream closed the subscriber channel.")
+			}
+			snapshot, ok := value.(*stats.CounterSnapshot)
+			if !ok {
+				return errors.New("Upstream sent malformed statistics.")
+			}
+			if last == nil {
+				last = snapshot
+				continue
+			}
+			// Tolerate jitter of the sampler, so that an update is not delayed for a whole sampling interval.
+			elapsed := snapshot.Time.Sub(last.Time)
+			if elapsed < interval-stats.SamplingInterval/2 {
+				continue
+			}
+
+			response := &SubscribeStatsResponse{
+				Time:     snapshot.Time.UnixMilli(),
+				Duration: elapsed.Milliseconds(),
+			}
+			names := make([]string, 0, len(snapshot.Counters))
+			for name := range snapshot.Counters {
+				if match(name) {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				value := snapshot.Counters[name]
+				delta := value - last.Counters[name]
+				if delta < 0 {
+					// The counter has been reset since the last update.
+					delta = value
+				}
+				response.Stat = append(response.Stat, &StatRate{
+					Name:  name,
+					Value: value,
+					Delta: delta,
+					Rate:  float64(delta) / elapsed.Seconds(),
+				})
+			}
+			if err := stream.Send(response); err != nil {
+				return err
+			}
+			last = snapshot
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
 func (s *statsServer) mustEmbedUnimplementedStatsServiceServer() {}
 
 type service struct {
@@ -22,8 +22,10 @@ const (
 	StatsService_GetStats_FullMethodName             = "/xray.app.stats.command.StatsService/GetStats"
 	StatsService_GetStatsOnline_FullMethodName       = "/xray.app.stats.command.StatsService/GetStatsOnline"
 	StatsService_QueryStats_FullMethodName           = "/xray.app.stats.command.StatsService/QueryStats"
+	StatsService_ResetStats_FullMethodName           = "/xray.app.stats.command.StatsService/ResetStats"
 	StatsService_GetSysStats_FullMethodName          = "/xray.app.stats.command.StatsService/GetSysStats"
 	StatsService_GetStatsOnlineIpList_FullMethodName = "/xray.app.stats.command.StatsService/GetStatsOnlineIpList"
+	StatsService_SubscribeStats_FullMethodName       = "/xray.app.stats.command.StatsService/SubscribeStats"
 )
 
 // StatsServiceClient is the client API for StatsService service.
@@ -33,8 +35,10 @@ type StatsServiceClient interface {
 	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
 	GetStatsOnline(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
 	QueryStats(ctx context.Context, in *QueryStatsRequest, opts ...grpc.CallOption) (*QueryStatsResponse, error)
+	ResetStats(ctx context.Context, in *ResetStatsRequest, opts ...grpc.CallOption) (*ResetStatsResponse, error)
 	GetSysStats(ctx context.Context, in *SysStatsRequest, opts ...grpc.CallOption) (*SysStatsResponse, error)
 	GetStatsOnlineIpList(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsOnlineIpListResponse, error)
+	SubscribeStats(ctx context.Context, in *SubscribeStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeStatsResponse], error)
 }
 
 type statsServiceClient struct {
@@ -75,6 +79,16 @@ func (c *statsServiceClient) QueryStats(ctx context.Context, in *QueryStatsReque
 	return out, nil
 }
 
+func (c *statsServiceClient) ResetStats(ctx context.Context, in *ResetStatsRequest, opts ...grpc.CallOption) (*ResetStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResetStatsResponse)
+	err := c.cc.Invoke(ctx, StatsService_ResetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *statsServiceClient) GetSysStats(ctx context.Context, in *SysStatsRequest, opts ...grpc.CallOption) (*SysStatsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(SysStatsResponse)
@@ -95,6 +109,25 @@ func (c *statsServiceClient) GetStatsOnlineIpList(ctx context.Context, in *GetSt
 	return out, nil
 }
 
+func (c *statsServiceClient) SubscribeStats(ctx context.Context, in *SubscribeStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeStatsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StatsService_ServiceDesc.Streams[0], StatsService_SubscribeStats_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeStatsRequest, SubscribeStatsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeStatsClient = grpc.ServerStreamingClient[SubscribeStatsResponse]
+
 // StatsServiceServer is the server API for StatsService service.
 // All implementations must embed UnimplementedStatsServiceServer
 // for forward compatibility.
@@ -102,8 +135,10 @@ type StatsServiceServer interface {
 	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
 	GetStatsOnline(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
 	QueryStats(context.Context, *QueryStatsRequest) (*QueryStatsResponse, error)
+	ResetStats(context.Context, *ResetStatsRequest) (*ResetStatsResponse, error)
 	GetSysStats(context.Context, *SysStatsRequest) (*SysStatsResponse, error)
 	GetStatsOnlineIpList(context.Context, *GetStatsRequest) (*GetStatsOnlineIpListResponse, error)
+	SubscribeStats(*SubscribeStatsRequest, grpc.ServerStreamingServer[SubscribeStatsResponse]) error
 	mustEmbedUnimplementedStatsServiceServer()
 }
 
@@ -123,12 +158,18 @@ func (UnimplementedStatsServiceServer) GetStatsOnline(context.Context, *GetStats
 func (UnimplementedStatsServiceServer) QueryStats(context.Context, *QueryStatsRequest) (*QueryStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method QueryStats not implemented")
 }
+func (UnimplementedStatsServiceServer) ResetStats(context.Context, *ResetStatsRequest) (*ResetStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetStats not implemented")
+}
 func (UnimplementedStatsServiceServer) GetSysStats(context.Context, *SysStatsRequest) (*SysStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetSysStats not implemented")
 }
 func (UnimplementedStatsServiceServer) GetStatsOnlineIpList(context.Context, *GetStatsRequest) (*GetStatsOnlineIpListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStatsOnlineIpList not implemented")
 }
+func (UnimplementedStatsServiceServer) SubscribeStats(*SubscribeStatsRequest, grpc.ServerStreamingServer[SubscribeStatsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeStats not implemented")
+}
 func (UnimplementedStatsServiceServer) mustEmbedUnimplementedStatsServiceServer() {}
 func (UnimplementedStatsServiceServer) testEmbeddedByValue()                      {}
 
@@ -204,6 +245,24 @@ func _StatsService_QueryStats_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StatsService_ResetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).ResetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_ResetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).ResetStats(ctx, req.(*ResetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StatsService_GetSysStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SysStatsRequest)
 	if err := dec(in); err != nil {
@@ -240,6 +299,17 @@ func _StatsService_GetStatsOnlineIpList_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StatsService_SubscribeStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StatsServiceServer).SubscribeStats(m, &grpc.GenericServerStream[SubscribeStatsRequest, SubscribeStatsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeStatsServer = grpc.ServerStreamingServer[SubscribeStatsResponse]
+
 // StatsService_ServiceDesc is the grpc.ServiceDesc for StatsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -259,6 +329,10 @@ var StatsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "QueryStats",
 			Handler:    _StatsService_QueryStats_Handler,
 		},
+		{
+			MethodName: "ResetStats",
+			Handler:    _StatsService_ResetStats_Handler,
+		},
 		{
 			MethodName: "GetSysStats",
 			Handler:    _StatsService_GetSysStats_Handler,
@@ -268,6 +342,12 @@ var StatsService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _StatsService_GetStatsOnlineIpList_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeStats",
+			Handler:       _StatsService_SubscribeStats_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "app/stats/command/command.proto",
 }
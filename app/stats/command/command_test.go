@@ -3,12 +3,14 @@ package command_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/xtls/xray-core/app/stats"
 	. "github.com/xtls/xray-core/app/stats/command"
 	"github.com/xtls/xray-core/common"
+	"google.golang.org/grpc"
 )
 
 func TestGetStats(t *testing.T) {
@@ -53,7 +55,7 @@ func TestGetStats(t *testing.T) {
 			}
 		} else {
 			common.Must(err)
-			if r := cmp.Diff(resp.Stat, &Stat{Name: tc.name, Value: tc.value}, cmpopts.IgnoreUnexported(Stat{})); r != "" {
+			if r := cmp.Diff(resp.Stat, &Stat{Name: tc.name, Value: tc.value}, cmpopts.IgnoreUnexported(Stat{}), cmpopts.IgnoreFields(Stat{}, "LastReset")); r != "" {
 				t.Error(r)
 			}
 		}
@@ -85,7 +87,98 @@ func TestQueryStats(t *testing.T) {
 		{Name: "test_counter_2", Value: 2},
 		{Name: "test_counter_3", Value: 3},
 	}, cmpopts.SortSlices(func(s1, s2 *Stat) bool { return s1.Name < s2.Name }),
-		cmpopts.IgnoreUnexported(Stat{})); r != "" {
+		cmpopts.IgnoreUnexported(Stat{}), cmpopts.IgnoreFields(Stat{}, "LastReset")); r != "" {
 		t.Error(r)
 	}
 }
+
+func TestResetStats(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	sc1, err := m.RegisterCounter("user>>>a>>>traffic>>>uplink")
+	common.Must(err)
+	sc1.Add(1)
+
+	sc2, err := m.RegisterCounter("user>>>b>>>traffic>>>uplink")
+	common.Must(err)
+	sc2.Add(2)
+
+	s := NewStatsServer(m)
+	resp, err := s.ResetStats(context.Background(), &ResetStatsRequest{
+		Pattern: "^user>>>a>>>",
+		Regexp:  true,
+	})
+	common.Must(err)
+	if len(resp.Stat) != 1 || resp.Stat[0].Name != "user>>>a>>>traffic>>>uplink" || resp.Stat[0].Value != 1 {
+		t.Fatal("unexpected reset stats: ", resp.Stat)
+	}
+	if resp.Stat[0].LastReset == 0 {
+		t.Error("expected last reset time")
+	}
+	if sc1.Value() != 0 || sc2.Value() != 2 {
+		t.Error("unexpected counter values: ", sc1.Value(), " ", sc2.Value())
+	}
+}
+
+type subscribeStatsStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *SubscribeStatsResponse
+}
+
+func (s *subscribeStatsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *subscribeStatsStream) Send(r *SubscribeStatsResponse) error {
+	s.responses <- r
+	return nil
+}
+
+func TestSubscribeStats(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	common.Must(m.Start())
+	defer m.Close()
+
+	sc1, err := m.RegisterCounter("user>>>a>>>traffic>>>uplink")
+	common.Must(err)
+	_, err = m.RegisterCounter("inbound>>>b>>>traffic>>>uplink")
+	common.Must(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &subscribeStatsStream{
+		ctx:       ctx,
+		responses: make(chan *SubscribeStatsResponse, 1),
+	}
+	s := NewStatsServer(m)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.SubscribeStats(&SubscribeStatsRequest{Patterns: []string{"user>>>"}}, stream)
+	}()
+
+	go func() {
+		for ctx.Err() == nil {
+			sc1.Add(100)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case resp := <-stream.responses:
+		if len(resp.Stat) != 1 || resp.Stat[0].Name != "user>>>a>>>traffic>>>uplink" {
+			t.Fatal("unexpected stats: ", resp.Stat)
+		}
+		if resp.Stat[0].Delta <= 0 || resp.Stat[0].Rate <= 0 {
+			t.Error("unexpected delta or rate: ", resp.Stat[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for stats")
+	}
+
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Error("unexpected error: ", err)
+	}
+}
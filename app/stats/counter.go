@@ -1,10 +1,16 @@
 package stats
 
-import "sync/atomic"
+import (
+	"sync/atomic"
+	"time"
+)
 
 // Counter is an implementation of stats.Counter.
 type Counter struct {
-	value int64
+	value     int64
+	lastReset int64
+	// protected is set for the counters that are the state of a feature, which the API doesn't reset.
+	protected atomic.Bool
 }
 
 // Value implements stats.Counter.
@@ -14,6 +20,7 @@ func (c *Counter) Value() int64 {
 
 // Set implements stats.Counter.
 func (c *Counter) Set(newValue int64) int64 {
+	atomic.StoreInt64(&c.lastReset, time.Now().Unix())
 	return atomic.SwapInt64(&c.value, newValue)
 }
 
@@ -21,3 +28,21 @@ func (c *Counter) Set(newValue int64) int64 {
 func (c *Counter) Add(delta int64) int64 {
 	return atomic.AddInt64(&c.value, delta)
 }
+
+// LastReset returns the time the counter was last set, or the zero time if it never was.
+func (c *Counter) LastReset() time.Time {
+	if t := atomic.LoadInt64(&c.lastReset); t != 0 {
+		return time.Unix(t, 0)
+	}
+	return time.Time{}
+}
+
+// SetLastReset records t as the time the counter was last set, without changing its value.
+func (c *Counter) SetLastReset(t time.Time) {
+	atomic.StoreInt64(&c.lastReset, t.Unix())
+}
+
+// Protected returns whether the counter is kept from being reset through the API.
+func (c *Counter) Protected() bool {
+	return c.protected.Load()
+}
@@ -0,0 +1,144 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features"
+)
+
+type persistedCounter struct {
+	Value     int64 `json:"value"`
+	LastReset int64 `json:"lastReset,omitempty"`
+	Protected bool  `json:"protected,omitempty"`
+}
+
+type persistedCounters struct {
+	Counters map[string]persistedCounter `json:"counters"`
+}
+
+// loadCounters restores the counters saved in the persist file. Counters which have been registered
+// already are added to.
+func (m *Manager) loadCounters() error {
+	data, err := os.ReadFile(m.persistFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.New("failed to read counters from ", m.persistFile).Base(err)
+	}
+	var saved persistedCounters
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return errors.New("failed to parse counters in ", m.persistFile).Base(err)
+	}
+
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	for name, pc := range saved.Counters {
+		c, found := m.counters[name]
+		if !found {
+			c = new(Counter)
+			m.counters[name] = c
+		}
+		c.Add(pc.Value)
+		atomic.StoreInt64(&c.lastReset, pc.LastReset)
+		if pc.Protected {
+			c.protected.Store(true)
+		}
+		m.saved[name] = pc.Value
+	}
+	errors.LogInfo(context.Background(), "restored ", len(saved.Counters), " counters from ", m.persistFile)
+	return nil
+}
+
+// saveCounters writes all counters to the persist file. The file is replaced atomically, so that it is
+// never left half written.
+func (m *Manager) saveCounters() error {
+	saved := persistedCounters{
+		Counters: make(map[string]persistedCounter),
+	}
+	m.access.Lock()
+	for name, c := range m.counters {
+		saved.Counters[name] = persistedCounter{
+			Value:     c.Value(),
+			LastReset: atomic.LoadInt64(&c.lastReset),
+			Protected: c.Protected(),
+		}
+		m.saved[name] = saved.Counters[name].Value
+	}
+	m.access.Unlock()
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(m.persistFile), filepath.Base(m.persistFile)+".*.tmp")
+	if err != nil {
+		return errors.New("failed to save counters").Base(err)
+	}
+	if _, err := f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), m.persistFile)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return errors.New("failed to save counters to ", m.persistFile).Base(err)
+	}
+	return nil
+}
+
+// HandOver implements features.HandOverer. successor, which has loaded the same persist file, is made to own it. m
+// stops saving the file, and adds the traffic it counted since the last save to successor instead, now and once more
+// when it is closed, after its sessions are drained.
+func (m *Manager) HandOver(f features.Feature) {
+	successor, ok := f.(*Manager)
+	if !ok || m.persister == nil || m.persistFile != successor.persistFile {
+		return
+	}
+	m.persister.Close()
+
+	m.access.Lock()
+	m.successor = successor
+	m.access.Unlock()
+	m.passCounters()
+}
+
+// passCounters adds the changes of the counters since they were last saved or passed to the successor of m.
+func (m *Manager) passCounters() {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	s := m.successor
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	for name, c := range m.counters {
+		value := c.Value()
+		delta := value - m.saved[name]
+		lastReset := atomic.LoadInt64(&c.lastReset)
+		m.saved[name] = value
+
+		sc, found := s.counters[name]
+		if !found {
+			sc = new(Counter)
+			s.counters[name] = sc
+		}
+		sc.Add(delta)
+		if c.Protected() {
+			sc.protected.Store(true)
+		}
+		if lastReset > atomic.LoadInt64(&sc.lastReset) {
+			atomic.StoreInt64(&sc.lastReset, lastReset)
+		}
+	}
+}
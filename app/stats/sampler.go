@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// SamplingInterval is the interval between two snapshots of the counter sampler.
+const SamplingInterval = time.Second
+
+// samplerChannel is the name of the channel the counter sampler publishes to.
+const samplerChannel = "stats>>>sampler"
+
+// CounterSnapshot is the value of all counters at a point in time, published by the counter sampler.
+type CounterSnapshot struct {
+	Time     time.Time
+	Counters map[string]int64
+}
+
+// SubscribeCounters subscribes to the snapshots of all counters. While there is at least one subscriber,
+// a CounterSnapshot is published every SamplingInterval.
+func (m *Manager) SubscribeCounters() (chan interface{}, error) {
+	m.samplerAccess.Lock()
+	defer m.samplerAccess.Unlock()
+
+	c, err := stats.GetOrRegisterChannel(m, samplerChannel)
+	if err != nil {
+		return nil, err
+	}
+	subscriber, err := stats.SubscribeRunnableChannel(c)
+	if err != nil {
+		return nil, err
+	}
+	if m.sampler == nil {
+		m.sampler = &task.Periodic{
+			Interval: SamplingInterval,
+			Execute: func() error {
+				m.publishSnapshot(c)
+				return nil
+			},
+		}
+		if err := m.sampler.Start(); err != nil {
+			return nil, err
+		}
+	}
+	return subscriber, nil
+}
+
+// UnsubscribeCounters unsubscribes from the counter sampler, and stops it if there is no more subscriber.
+func (m *Manager) UnsubscribeCounters(subscriber chan interface{}) error {
+	m.samplerAccess.Lock()
+	defer m.samplerAccess.Unlock()
+
+	c := m.GetChannel(samplerChannel)
+	if c == nil {
+		return nil
+	}
+	if err := stats.UnsubscribeClosableChannel(c, subscriber); err != nil {
+		return err
+	}
+	if len(c.Subscribers()) == 0 && m.sampler != nil {
+		m.sampler.Close()
+		m.sampler = nil
+	}
+	return nil
+}
+
+func (m *Manager) publishSnapshot(c stats.Channel) {
+	snapshot := &CounterSnapshot{
+		Time:     time.Now(),
+		Counters: make(map[string]int64),
+	}
+	m.VisitCounters(func(name string, counter stats.Counter) bool {
+		snapshot.Counters[name] = counter.Value()
+		return true
+	})
+	// A snapshot that can't be delivered before the next one is taken is discarded.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(SamplingInterval, cancel)
+	c.Publish(ctx, snapshot)
+}
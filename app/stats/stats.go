@@ -3,9 +3,11 @@ package stats
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/features/stats"
 )
 
@@ -16,6 +18,16 @@ type Manager struct {
 	onlineMap map[string]*OnlineMap
 	channels  map[string]*Channel
 	running   bool
+
+	samplerAccess sync.Mutex
+	sampler       *task.Periodic
+
+	persistFile string
+	persister   *task.Periodic
+	// saved holds the values of the counters in the persist file, by name.
+	saved map[string]int64
+	// successor, if not nil, owns the persist file, and gets the traffic counted by m instead.
+	successor *Manager
 }
 
 // NewManager creates an instance of Statistics Manager.
@@ -24,6 +36,24 @@ func NewManager(ctx context.Context, config *Config) (*Manager, error) {
 		counters:  make(map[string]*Counter),
 		onlineMap: make(map[string]*OnlineMap),
 		channels:  make(map[string]*Channel),
+		saved:     make(map[string]int64),
+	}
+
+	if config.PersistFile != "" {
+		m.persistFile = config.PersistFile
+		interval := time.Duration(config.PersistInterval) * time.Second
+		if interval == 0 {
+			interval = time.Minute
+		}
+		m.persister = &task.Periodic{
+			Interval: interval,
+			Execute: func() error {
+				if err := m.saveCounters(); err != nil {
+					errors.LogWarningInner(context.Background(), err, "failed to persist counters")
+				}
+				return nil
+			},
+		}
 	}
 
 	return m, nil
@@ -60,6 +90,16 @@ func (m *Manager) UnregisterCounter(name string) error {
 	return nil
 }
 
+// ProtectCounter implements stats.ProtectedCounterManager.
+func (m *Manager) ProtectCounter(name string) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	if c, found := m.counters[name]; found {
+		c.protected.Store(true)
+	}
+}
+
 // GetCounter implements stats.Manager.
 func (m *Manager) GetCounter(name string) stats.Counter {
 	m.access.RLock()
@@ -163,6 +203,15 @@ func (m *Manager) GetChannel(name string) stats.Channel {
 
 // Start implements common.Runnable.
 func (m *Manager) Start() error {
+	if m.persister != nil {
+		if err := m.loadCounters(); err != nil {
+			return err
+		}
+		if err := m.persister.Start(); err != nil {
+			return err
+		}
+	}
+
 	m.access.Lock()
 	defer m.access.Unlock()
 	m.running = true
@@ -180,6 +229,22 @@ func (m *Manager) Start() error {
 
 // Close implement common.Closable.
 func (m *Manager) Close() error {
+	m.samplerAccess.Lock()
+	if m.sampler != nil {
+		m.sampler.Close()
+		m.sampler = nil
+	}
+	m.samplerAccess.Unlock()
+
+	if m.persister != nil {
+		m.persister.Close()
+		if m.successor != nil {
+			m.passCounters()
+		} else if err := m.saveCounters(); err != nil {
+			errors.LogWarningInner(context.Background(), err, "failed to persist counters")
+		}
+	}
+
 	m.access.Lock()
 	defer m.access.Unlock()
 	m.running = false
@@ -2,6 +2,7 @@ package stats_test
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -84,3 +85,76 @@ func TestStatsChannelRunnable(t *testing.T) {
 		t.Fatalf("unexpected running channel: test.channel.%d", 3)
 	}
 }
+
+func TestPersistCounters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stats.json")
+
+	m, err := NewManager(context.Background(), &Config{PersistFile: file})
+	common.Must(err)
+	common.Must(m.Start())
+	c, err := m.RegisterCounter("user>>>a>>>traffic>>>uplink")
+	common.Must(err)
+	c.Set(0)
+	c.Add(100)
+	common.Must(m.Close())
+
+	m, err = NewManager(context.Background(), &Config{PersistFile: file})
+	common.Must(err)
+	// Counters registered before Start are added to.
+	c, err = m.RegisterCounter("user>>>a>>>traffic>>>uplink")
+	common.Must(err)
+	c.Add(1)
+	common.Must(m.Start())
+	defer m.Close()
+
+	if v := c.Value(); v != 101 {
+		t.Error("expected restored counter value 101, but got ", v)
+	}
+	if m.GetCounter("user>>>a>>>traffic>>>uplink").(*Counter).LastReset().IsZero() {
+		t.Error("expected restored last reset time")
+	}
+}
+
+func TestHandOverCounters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stats.json")
+	const name = "user>>>a>>>traffic>>>uplink"
+
+	old, err := NewManager(context.Background(), &Config{PersistFile: file, PersistInterval: 3600})
+	common.Must(err)
+	common.Must(old.Start())
+	c, err := old.RegisterCounter(name)
+	common.Must(err)
+	c.Add(100)
+	common.Must(old.Close())
+
+	old, err = NewManager(context.Background(), &Config{PersistFile: file, PersistInterval: 3600})
+	common.Must(err)
+	common.Must(old.Start())
+	c = old.GetCounter(name).(*Counter)
+	// Counted after the last save, before the successor loads the file.
+	c.Add(10)
+
+	m, err := NewManager(context.Background(), &Config{PersistFile: file, PersistInterval: 3600})
+	common.Must(err)
+	common.Must(m.Start())
+	old.HandOver(m)
+	if v := m.GetCounter(name).Value(); v != 110 {
+		t.Error("expected counter value 110 after hand over, but got ", v)
+	}
+
+	// Counted by the sessions of the old manager while it is drained.
+	c.Add(5)
+	common.Must(old.Close())
+	if v := m.GetCounter(name).Value(); v != 115 {
+		t.Error("expected counter value 115 after the old manager is closed, but got ", v)
+	}
+
+	common.Must(m.Close())
+	m, err = NewManager(context.Background(), &Config{PersistFile: file})
+	common.Must(err)
+	common.Must(m.Start())
+	defer m.Close()
+	if v := m.GetCounter(name).Value(); v != 115 {
+		t.Error("expected saved counter value 115, but got ", v)
+	}
+}
@@ -0,0 +1,253 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/signal/done"
+	"github.com/xtls/xray-core/common/trace"
+)
+
+const (
+	maxBatchSize  = 512
+	flushInterval = 5 * time.Second
+)
+
+// Tracer exports the spans recorded through common/trace in OTLP JSON, to an OTLP/HTTP endpoint or a file.
+type Tracer struct {
+	config *Config
+	spans  chan *trace.Span
+	done   *done.Instance
+	wg     sync.WaitGroup
+	client *http.Client
+	file   *os.File
+}
+
+// New creates a new Tracer based on the given config.
+func New(ctx context.Context, config *Config) (*Tracer, error) {
+	if config.Endpoint == "" && config.File == "" {
+		return nil, errors.New("tracing must have an endpoint or a file")
+	}
+	t := &Tracer{
+		config: config,
+		spans:  make(chan *trace.Span, maxBatchSize*4),
+		done:   done.New(),
+	}
+	if config.Endpoint != "" {
+		t.client = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+	}
+	return t, nil
+}
+
+// Type implements common.HasType.
+func (*Tracer) Type() interface{} {
+	return (*Tracer)(nil)
+}
+
+// Start implements common.Runnable.
+func (t *Tracer) Start() error {
+	if t.config.File != "" {
+		f, err := os.OpenFile(t.config.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return errors.New("failed to open trace file ", t.config.File).Base(err)
+		}
+		t.file = f
+	}
+	sampleRatio := float64(t.config.SampleRatio)
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+	t.wg.Add(1)
+	go t.run()
+	trace.RegisterExporter(t, sampleRatio)
+	return nil
+}
+
+// Close implements common.Closable.
+func (t *Tracer) Close() error {
+	trace.UnregisterExporter(t)
+	t.done.Close()
+	t.wg.Wait()
+	if t.file != nil {
+		return t.file.Close()
+	}
+	return nil
+}
+
+// Export implements trace.Exporter. Spans are dropped if the backend can't keep up.
+func (t *Tracer) Export(s *trace.Span) {
+	select {
+	case t.spans <- s:
+	default:
+	}
+}
+
+func (t *Tracer) run() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*trace.Span, 0, maxBatchSize)
+	for {
+		select {
+		case s := <-t.spans:
+			batch = append(batch, s)
+			if len(batch) < maxBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-t.done.Wait():
+		drain:
+			for {
+				select {
+				case s := <-t.spans:
+					batch = append(batch, s)
+				default:
+					break drain
+				}
+			}
+			t.flush(batch)
+			return
+		}
+		t.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (t *Tracer) flush(batch []*trace.Span) {
+	if len(batch) == 0 {
+		return
+	}
+	data, err := json.Marshal(t.encode(batch))
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to encode spans")
+		return
+	}
+	if t.file != nil {
+		if _, err := t.file.Write(append(data, '\n')); err != nil {
+			errors.LogWarningInner(context.Background(), err, "failed to write spans to ", t.config.File)
+		}
+	}
+	if t.client != nil {
+		resp, err := t.client.Post(t.config.Endpoint, "application/json", bytes.NewReader(data))
+		if err != nil {
+			errors.LogWarningInner(context.Background(), err, "failed to export spans to ", t.config.Endpoint)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			errors.LogWarning(context.Background(), "failed to export spans to ", t.config.Endpoint, ": ", resp.Status)
+		}
+	}
+}
+
+// The following types are the JSON encoding of OTLP ExportTraceServiceRequest.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeError  = 2
+)
+
+func (t *Tracer) encode(batch []*trace.Span) *otlpRequest {
+	serviceName := t.config.ServiceName
+	if serviceName == "" {
+		serviceName = "xray"
+	}
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		span := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+		}
+		if s.ParentID.IsValid() {
+			span.ParentSpanID = s.ParentID.String()
+		}
+		for _, a := range s.Attributes {
+			span.Attributes = append(span.Attributes, otlpAttribute{Key: a.Key, Value: otlpValue{StringValue: a.Value}})
+		}
+		if s.Error != "" {
+			span.Status = otlpStatus{Code: otlpStatusCodeError, Message: s.Error}
+		}
+		spans = append(spans, span)
+	}
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: serviceName}}},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/xtls/xray-core"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		return New(ctx, cfg.(*Config))
+	}))
+}
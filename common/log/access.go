@@ -18,6 +18,10 @@ type AccessStatus string
 const (
 	AccessAccepted = AccessStatus("accepted")
 	AccessRejected = AccessStatus("rejected")
+	// AccessExceeded is the status of a request rejected because the traffic quota of the user is used up.
+	AccessExceeded = AccessStatus("exceeded")
+	// AccessExpired is the status of a request rejected because the user has expired.
+	AccessExpired = AccessStatus("expired")
 )
 
 type AccessMessage struct {
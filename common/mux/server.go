@@ -3,6 +3,8 @@ package mux
 import (
 	"context"
 	"io"
+	"sync"
+	"sync/atomic"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
@@ -19,11 +21,17 @@ import (
 
 type Server struct {
 	dispatcher routing.Dispatcher
+
+	access   sync.Mutex
+	workers  map[*ServerWorker]struct{}
+	draining bool
 }
 
 // NewServer creates a new mux.Server.
 func NewServer(ctx context.Context) *Server {
-	s := &Server{}
+	s := &Server{
+		workers: make(map[*ServerWorker]struct{}),
+	}
 	core.RequireFeatures(ctx, func(d routing.Dispatcher) {
 		s.dispatcher = d
 	})
@@ -45,13 +53,10 @@ func (s *Server) Dispatch(ctx context.Context, dest net.Destination) (*transport
 	uplinkReader, uplinkWriter := pipe.New(opts...)
 	downlinkReader, downlinkWriter := pipe.New(opts...)
 
-	_, err := NewServerWorker(ctx, s.dispatcher, &transport.Link{
+	s.newWorker(ctx, &transport.Link{
 		Reader: uplinkReader,
 		Writer: downlinkWriter,
 	})
-	if err != nil {
-		return nil, err
-	}
 
 	return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter}, nil
 }
@@ -61,8 +66,49 @@ func (s *Server) DispatchLink(ctx context.Context, dest net.Destination, link *t
 	if dest.Address != muxCoolAddress {
 		return s.dispatcher.DispatchLink(ctx, dest, link)
 	}
-	_, err := NewServerWorker(ctx, s.dispatcher, link)
-	return err
+	s.newWorker(ctx, link)
+	return nil
+}
+
+func (s *Server) newWorker(ctx context.Context, link *transport.Link) {
+	worker := &ServerWorker{
+		dispatcher:     s.dispatcher,
+		link:           link,
+		sessionManager: NewSessionManager(),
+		server:         s,
+	}
+
+	s.access.Lock()
+	s.workers[worker] = struct{}{}
+	draining := s.draining
+	s.access.Unlock()
+
+	if draining {
+		worker.Drain()
+	}
+	go worker.run(ctx)
+}
+
+func (s *Server) removeWorker(worker *ServerWorker) {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	delete(s.workers, worker)
+}
+
+// Drain makes the workers of s, including the ones to come, refuse new sub-connections. See ServerWorker.Drain.
+func (s *Server) Drain() {
+	s.access.Lock()
+	s.draining = true
+	workers := make([]*ServerWorker, 0, len(s.workers))
+	for w := range s.workers {
+		workers = append(workers, w)
+	}
+	s.access.Unlock()
+
+	for _, w := range workers {
+		w.Drain()
+	}
 }
 
 // Start implements common.Runnable.
@@ -79,6 +125,8 @@ type ServerWorker struct {
 	dispatcher     routing.Dispatcher
 	link           *transport.Link
 	sessionManager *SessionManager
+	server         *Server
+	draining       atomic.Bool
 }
 
 func NewServerWorker(ctx context.Context, d routing.Dispatcher, link *transport.Link) (*ServerWorker, error) {
@@ -91,7 +139,7 @@ func NewServerWorker(ctx context.Context, d routing.Dispatcher, link *transport.
 	return worker, nil
 }
 
-func handle(ctx context.Context, s *Session, output buf.Writer) {
+func handle(ctx context.Context, s *Session, output buf.Writer, w *ServerWorker) {
 	writer := NewResponseWriter(s.ID, output, s.transferType)
 	if err := buf.Copy(s.input, writer); err != nil {
 		errors.LogInfoInner(ctx, err, "session ", s.ID, " ends.")
@@ -100,6 +148,23 @@ func handle(ctx context.Context, s *Session, output buf.Writer) {
 
 	writer.Close()
 	s.Close(false)
+	w.closeIfIdle()
+}
+
+// Drain makes w refuse new sub-connections, and close the mux connection once the ones in progress finish.
+func (w *ServerWorker) Drain() {
+	w.draining.Store(true)
+	w.closeIfIdle()
+}
+
+func (w *ServerWorker) closeIfIdle() {
+	if !w.draining.Load() || w.sessionManager.Size() > 0 {
+		return
+	}
+	if w.sessionManager.CloseIfNoSession() {
+		common.Interrupt(w.link.Reader)
+		common.Close(w.link.Writer)
+	}
 }
 
 func (w *ServerWorker) ActiveConnections() uint32 {
@@ -118,6 +183,17 @@ func (w *ServerWorker) handleStatusKeepAlive(meta *FrameMetadata, reader *buf.Bu
 }
 
 func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata, reader *buf.BufferedReader) error {
+	if w.draining.Load() {
+		errors.LogInfo(ctx, "refused request for ", meta.Target, " while draining")
+		closingWriter := NewResponseWriter(meta.SessionID, w.link.Writer, protocol.TransferTypeStream)
+		closingWriter.hasError = true
+		closingWriter.Close()
+		if meta.Option.Has(OptionData) {
+			return buf.Copy(NewStreamReader(reader), buf.Discard)
+		}
+		return nil
+	}
+
 	// deep-clone outbounds because it is going to be mutated concurrently
 	// (Target and OriginalTarget)
 	ctx = session.ContextCloneOutbounds(ctx)
@@ -201,7 +277,7 @@ func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata,
 			transferType: protocol.TransferTypePacket,
 			XUDP:         x,
 		}
-		go handle(ctx, x.Mux, w.link.Writer)
+		go handle(ctx, x.Mux, w.link.Writer, w)
 		x.Status = Active
 		if !w.sessionManager.Add(x.Mux) {
 			x.Mux.Close(false)
@@ -227,7 +303,7 @@ func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata,
 		s.transferType = protocol.TransferTypePacket
 	}
 	w.sessionManager.Add(s)
-	go handle(ctx, s, w.link.Writer)
+	go handle(ctx, s, w.link.Writer, w)
 	if !meta.Option.Has(OptionData) {
 		return nil
 	}
@@ -308,6 +384,9 @@ func (w *ServerWorker) run(ctx context.Context) {
 	reader := &buf.BufferedReader{Reader: input}
 
 	defer w.sessionManager.Close()
+	if w.server != nil {
+		defer w.server.removeWorker(w)
+	}
 
 	for {
 		select {
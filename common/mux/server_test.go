@@ -3,6 +3,7 @@ package mux_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
@@ -122,3 +123,54 @@ func TestRegressionOutboundLeak(t *testing.T) {
 		t.Error("outbound target got leaked: ", outbounds[0].Target.String())
 	}
 }
+
+func TestServerWorkerDrain(t *testing.T) {
+	websiteUplink, websiteDownlink := newLinkPair()
+	dispatcher := TestDispatcher{
+		OnDispatch: func(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+			return websiteDownlink, nil
+		},
+	}
+
+	muxServerUplink, muxServerDownlink := newLinkPair()
+	worker, err := mux.NewServerWorker(context.Background(), &dispatcher, muxServerUplink)
+	common.Must(err)
+	client, err := mux.NewClientWorker(*muxServerDownlink, mux.ClientStrategy{})
+	common.Must(err)
+
+	clientCtx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("www.example.com"), 80),
+	}})
+	activeUplink, activeDownlink := newLinkPair()
+	if !client.Dispatch(clientCtx, activeUplink) {
+		t.Fatal("failed to dispatch")
+	}
+	common.Must(activeDownlink.Writer.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("hello"))}))
+	if _, err := websiteUplink.Reader.ReadMultiBuffer(); err != nil {
+		t.Fatal(err)
+	}
+
+	worker.Drain()
+
+	refusedUplink, refusedDownlink := newLinkPair()
+	if !client.Dispatch(clientCtx, refusedUplink) {
+		t.Fatal("failed to dispatch")
+	}
+	common.Must(refusedDownlink.Writer.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("hello"))}))
+	if _, err := refusedDownlink.Reader.ReadMultiBuffer(); err == nil {
+		t.Error("expected new session to be refused while draining")
+	}
+
+	common.Must(websiteUplink.Writer.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("world"))}))
+	if mb, err := activeDownlink.Reader.ReadMultiBuffer(); err != nil || mb.String() != "world" {
+		t.Error("expected session in progress to go on while draining: ", err)
+	}
+
+	common.Must(common.Close(websiteUplink.Writer))
+	for i := 0; i < 50 && !worker.Closed(); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !worker.Closed() {
+		t.Error("expected drained worker to be closed after its last session")
+	}
+}
@@ -1,6 +1,8 @@
 package protocol
 
 import (
+	"time"
+
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/serial"
 )
@@ -28,22 +30,36 @@ func (u *User) ToMemoryUser() (*MemoryUser, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &MemoryUser{
-		Account: account,
-		Email:   u.Email,
-		Level:   u.Level,
-	}, nil
+	mu := &MemoryUser{
+		Account:     account,
+		Email:       u.Email,
+		Level:       u.Level,
+		Quota:       u.Quota,
+		QuotaPeriod: time.Duration(u.QuotaPeriod) * time.Second,
+		Policy:      u.Policy,
+	}
+	if u.ExpireAt != 0 {
+		mu.ExpireAt = time.Unix(u.ExpireAt, 0)
+	}
+	return mu, nil
 }
 
 func ToProtoUser(mu *MemoryUser) *User {
 	if mu == nil {
 		return nil
 	}
-	return &User{
-		Account: serial.ToTypedMessage(mu.Account.ToProto()),
-		Email:   mu.Email,
-		Level:   mu.Level,
+	u := &User{
+		Account:     serial.ToTypedMessage(mu.Account.ToProto()),
+		Email:       mu.Email,
+		Level:       mu.Level,
+		Quota:       mu.Quota,
+		QuotaPeriod: uint64(mu.QuotaPeriod / time.Second),
+		Policy:      mu.Policy,
+	}
+	if !mu.ExpireAt.IsZero() {
+		u.ExpireAt = mu.ExpireAt.Unix()
 	}
+	return u
 }
 
 // MemoryUser is a parsed form of User, to reduce number of parsing of Account proto.
@@ -52,4 +68,12 @@ type MemoryUser struct {
 	Account Account
 	Email   string
 	Level   uint32
+	// Quota is the traffic quota in bytes. 0 for unlimited.
+	Quota uint64
+	// QuotaPeriod is the period after which the used traffic is reset. 0 for never.
+	QuotaPeriod time.Duration
+	// ExpireAt is the time when the user expires. The zero time for never.
+	ExpireAt time.Time
+	// Policy overrides the policy of the user level. nil for none.
+	Policy *UserPolicy
 }
@@ -0,0 +1,214 @@
+// Package trace records spans of the work done for a session, so that it can be exported to a tracing backend.
+package trace // import "github.com/xtls/xray-core/common/trace"
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	mrand "math/rand"
+	"sync"
+	"time"
+
+	c "github.com/xtls/xray-core/common/ctx"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/serial"
+)
+
+// SessionIDAttribute is the attribute that holds the session ID, the same as the one in logs.
+const SessionIDAttribute = "xray.session.id"
+
+// TraceID identifies a trace, that is all spans of a session.
+type TraceID [16]byte
+
+// String returns the hex form of the ID.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span in a trace.
+type SpanID [8]byte
+
+// String returns the hex form of the ID.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid returns true if the ID is not all zero.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// Attribute is a key-value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Span is a timed operation in a trace. All methods of Span can be called on a nil Span, which is what
+// Start returns if tracing is disabled or the trace is not sampled.
+type Span struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	ParentID   SpanID
+	Name       string
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes []Attribute
+	// Error is the error the operation ended with, if any.
+	Error string
+
+	access sync.Mutex
+	ended  bool
+}
+
+// SetAttribute adds an attribute to the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.access.Lock()
+	s.Attributes = append(s.Attributes, Attribute{Key: key, Value: serial.ToString(value)})
+	s.access.Unlock()
+}
+
+// End ends the span, and hands it over to the exporter. Calls after the first one have no effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.access.Lock()
+	if s.ended {
+		s.access.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.access.Unlock()
+	exporter.Export(s)
+}
+
+// Exporter sends ended spans to a tracing backend. Export must not block.
+type Exporter interface {
+	Export(*Span)
+}
+
+type syncExporter struct {
+	sync.RWMutex
+	Exporter
+	sampleRatio float64
+}
+
+func (e *syncExporter) Export(s *Span) {
+	e.RLock()
+	defer e.RUnlock()
+
+	if e.Exporter != nil {
+		e.Exporter.Export(s)
+	}
+}
+
+// sample returns whether tracing is enabled, and whether a new trace is sampled.
+func (e *syncExporter) sample() (bool, bool) {
+	e.RLock()
+	defer e.RUnlock()
+
+	if e.Exporter == nil {
+		return false, false
+	}
+	return true, e.sampleRatio >= 1 || mrand.Float64() < e.sampleRatio
+}
+
+var exporter syncExporter
+
+// RegisterExporter registers the exporter for ended spans. A new trace is recorded with the probability of sampleRatio.
+// Tracing is disabled if exporter is nil.
+func RegisterExporter(e Exporter, sampleRatio float64) {
+	exporter.Lock()
+	defer exporter.Unlock()
+
+	exporter.Exporter = e
+	exporter.sampleRatio = sampleRatio
+}
+
+// UnregisterExporter disables tracing, if e is still the registered exporter. An exporter closed after another one
+// replaced it, as on reload, leaves tracing to the new one.
+func UnregisterExporter(e Exporter) {
+	exporter.Lock()
+	defer exporter.Unlock()
+
+	if exporter.Exporter == e {
+		exporter.Exporter = nil
+		exporter.sampleRatio = 0
+	}
+}
+
+type spanKey int
+
+const (
+	currentSpanKey spanKey = iota
+	notSampledKey
+)
+
+// FromContext returns the span in ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Span {
+	if s, ok := ctx.Value(currentSpanKey).(*Span); ok {
+		return s
+	}
+	return nil
+}
+
+// ContextWithSpan returns a new context carrying the span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, currentSpanKey, s)
+}
+
+// Start starts a span named name. If ctx carries a span, the new span is its child, otherwise a new trace is
+// sampled, unless ctx was returned by a Start that didn't sample. The returned context carries the new span, or the
+// decision not to sample, so that the work done for the session downstream doesn't start traces of its own.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		if ctx.Value(notSampledKey) != nil {
+			return ctx, nil
+		}
+		enabled, sampled := exporter.sample()
+		if !enabled {
+			return ctx, nil
+		}
+		if !sampled {
+			return context.WithValue(ctx, notSampledKey, true), nil
+		}
+	}
+
+	s := &Span{
+		Name:      name,
+		StartTime: time.Now(),
+	}
+	rand.Read(s.SpanID[:])
+	if parent != nil {
+		s.TraceID = parent.TraceID
+		s.ParentID = parent.SpanID
+	} else {
+		rand.Read(s.TraceID[:])
+		if id := c.IDFromContext(ctx); id != 0 {
+			s.SetAttribute(SessionIDAttribute, uint32(id))
+			errors.LogDebug(ctx, "trace ", s.TraceID)
+		}
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+// StartChild is like Start, but only starts a span if ctx carries one, for the work that is traced as part of a
+// session but shouldn't start a trace on its own.
+func StartChild(ctx context.Context, name string) (context.Context, *Span) {
+	if FromContext(ctx) == nil {
+		return ctx, nil
+	}
+	return Start(ctx, name)
+}
@@ -0,0 +1,109 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	c "github.com/xtls/xray-core/common/ctx"
+	. "github.com/xtls/xray-core/common/trace"
+)
+
+type testExporter struct {
+	spans []*Span
+}
+
+func (e *testExporter) Export(s *Span) {
+	e.spans = append(e.spans, s)
+}
+
+func TestSpan(t *testing.T) {
+	ctx := c.ContextWithID(context.Background(), 42)
+
+	if _, span := Start(ctx, "disabled"); span != nil {
+		t.Fatal("expected no span when tracing is disabled")
+	}
+
+	exporter := &testExporter{}
+	RegisterExporter(exporter, 1)
+	defer RegisterExporter(nil, 0)
+
+	ctx, root := Start(ctx, "root")
+	_, child := Start(ctx, "child")
+	child.SetAttribute("key", 1)
+	child.End(errors.New("failed"))
+	child.End(nil)
+	root.End(nil)
+
+	if len(exporter.spans) != 2 {
+		t.Fatal("expected 2 spans, but got ", len(exporter.spans))
+	}
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID || root.ParentID.IsValid() {
+		t.Error("unexpected span relationship")
+	}
+	if child.Error != "failed" {
+		t.Error("unexpected error: ", child.Error)
+	}
+	if len(child.Attributes) != 1 || child.Attributes[0] != (Attribute{Key: "key", Value: "1"}) {
+		t.Error("unexpected attributes: ", child.Attributes)
+	}
+	if len(root.Attributes) != 1 || root.Attributes[0] != (Attribute{Key: SessionIDAttribute, Value: "42"}) {
+		t.Error("expected session ID attribute on root span, but got ", root.Attributes)
+	}
+}
+
+func TestNotSampled(t *testing.T) {
+	exporter := &testExporter{}
+	RegisterExporter(exporter, 0)
+	defer RegisterExporter(nil, 0)
+
+	ctx, span := Start(context.Background(), "inbound")
+	if span != nil {
+		t.Fatal("expected no span with a sample ratio of 0")
+	}
+
+	// Whatever the downstream work rolls, it stays in the decision of the inbound.
+	RegisterExporter(exporter, 1)
+	ctx, span = Start(ctx, "dispatcher.route")
+	if span != nil {
+		t.Error("expected no span downstream of an inbound not sampled")
+	}
+	if _, span := Start(ctx, "outbound"); span != nil {
+		t.Error("expected no span downstream of an inbound not sampled")
+	}
+	if len(exporter.spans) != 0 {
+		t.Error("expected no spans, but got ", len(exporter.spans))
+	}
+}
+
+func TestUnregisterExporter(t *testing.T) {
+	old, current := &testExporter{}, &testExporter{}
+	RegisterExporter(old, 1)
+	RegisterExporter(current, 1)
+	defer RegisterExporter(nil, 0)
+
+	UnregisterExporter(old)
+	if _, span := Start(context.Background(), "root"); span == nil {
+		t.Error("expected the replaced exporter to leave tracing enabled")
+	}
+	UnregisterExporter(current)
+	if _, span := Start(context.Background(), "root"); span != nil {
+		t.Error("expected tracing to be disabled")
+	}
+}
+
+func TestStartChild(t *testing.T) {
+	exporter := &testExporter{}
+	RegisterExporter(exporter, 1)
+	defer RegisterExporter(nil, 0)
+
+	if _, span := StartChild(context.Background(), "orphan"); span != nil {
+		t.Fatal("expected no span without a parent")
+	}
+
+	ctx, root := Start(context.Background(), "root")
+	_, child := StartChild(ctx, "child")
+	if child == nil || child.TraceID != root.TraceID || child.ParentID != root.SpanID {
+		t.Error("expected a child of the root span")
+	}
+}
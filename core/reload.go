@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/features"
+)
+
+// drainLogInterval is the interval to report the sessions left while draining.
+const drainLogInterval = 5 * time.Second
+
+// SetReloader sets the function that reloads the config of the process running s, for Reload.
+func (s *Instance) SetReloader(reload func() error) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	s.reloader = reload
+}
+
+// Reload replaces s with a new Instance built from the same config files. The new Instance takes over the listening
+// ports, and s is drained.
+func (s *Instance) Reload() error {
+	s.statusLock.Lock()
+	reload := s.reloader
+	s.statusLock.Unlock()
+
+	if reload == nil {
+		return errors.New("reload is not supported by the process")
+	}
+	return reload()
+}
+
+// HandOver passes the state of the features of s to the ones of successor, which replaces s on reload.
+func (s *Instance) HandOver(successor *Instance) {
+	s.statusLock.Lock()
+	all := append([]features.Feature(nil), s.features...)
+	s.statusLock.Unlock()
+
+	for _, f := range all {
+		if h, ok := f.(features.HandOverer); ok {
+			if sf := successor.GetFeature(f.Type()); sf != nil {
+				h.HandOver(sf)
+			}
+		}
+	}
+}
+
+// Drain stops the features of s from taking new work, and waits until the sessions in progress finish, or ctx is
+// done. s is still to be closed afterwards, which terminates the sessions left.
+func (s *Instance) Drain(ctx context.Context) error {
+	s.statusLock.Lock()
+	all := append([]features.Feature(nil), s.features...)
+	s.statusLock.Unlock()
+
+	var errs []error
+	for _, f := range all {
+		if d, ok := f.(features.Drainer); ok {
+			errs = append(errs, d.Drain())
+		}
+	}
+
+	activeSessions := func() int {
+		n := 0
+		for _, f := range all {
+			if c, ok := f.(features.SessionCounter); ok {
+				n += c.ActiveSessions()
+			}
+		}
+		return n
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	lastLog := time.Now()
+	for {
+		n := activeSessions()
+		if n == 0 {
+			errors.LogInfo(s.ctx, "all sessions are finished")
+			break
+		}
+		if time.Since(lastLog) >= drainLogInterval {
+			errors.LogInfo(s.ctx, "draining, ", n, " sessions left")
+			lastLog = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			errors.LogWarning(s.ctx, "drain timed out, ", n, " sessions left")
+			return errors.Combine(errs...)
+		case <-ticker.C:
+		}
+	}
+	return errors.Combine(errs...)
+}
@@ -20,7 +20,8 @@ import (
 	"github.com/xtls/xray-core/transport/internet"
 )
 
-// Server is an instance of Xray. At any time, there must be at most one Server instance running.
+// Server is an instance of Xray. At any time, there must be at most one Server instance running, except for the one
+// being drained on reload.
 type Server interface {
 	common.Runnable
 }
@@ -86,6 +87,7 @@ type Instance struct {
 	pendingOptionalResolutions []resolution
 	running                    bool
 	resolveLock                sync.Mutex
+	reloader                   func() error
 
 	ctx context.Context
 }
@@ -1,6 +1,8 @@
 package dns
 
 import (
+	"context"
+
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/serial"
@@ -24,6 +26,27 @@ type Client interface {
 	LookupIP(domain string, option IPOption) ([]net.IP, error)
 }
 
+// ECHConfigLookup is implemented by a Client that can look up the ECH configs of a domain.
+type ECHConfigLookup interface {
+	// LookupECHConfigList returns the ECHConfigList in the HTTPS record of domain, and its TTL in seconds. It returns
+	// ErrEmptyResponse if the record is found without ECH configs.
+	LookupECHConfigList(domain string) ([]byte, uint32, error)
+}
+
+// TracedLookup is implemented by a Client whose lookups can be traced as part of a session.
+type TracedLookup interface {
+	// LookupIPWithContext is LookupIP, recording the queries as children of the span in ctx.
+	LookupIPWithContext(ctx context.Context, domain string, option IPOption) ([]net.IP, error)
+}
+
+// LookupIPWithContext looks up domain by c, which traces it as part of the session in ctx if it can.
+func LookupIPWithContext(ctx context.Context, c Client, domain string, option IPOption) ([]net.IP, error) {
+	if t, ok := c.(TracedLookup); ok {
+		return t.LookupIPWithContext(ctx, domain, option)
+	}
+	return c.LookupIP(domain, option)
+}
+
 type HostsLookup interface {
 	LookupHosts(domain string) *net.Address
 }
@@ -10,3 +10,23 @@ type Feature interface {
 	common.HasType
 	common.Runnable
 }
+
+// Drainer is implemented by features that can stop taking new work before they are closed, so that the work in
+// progress gets time to finish.
+type Drainer interface {
+	// Drain stops taking new work, without waiting for the work in progress.
+	Drain() error
+}
+
+// HandOverer is implemented by features that pass their state to the feature of the same type replacing them on
+// reload.
+type HandOverer interface {
+	// HandOver passes the state to successor, which has been started.
+	HandOver(successor Feature)
+}
+
+// SessionCounter is implemented by features that keep track of the sessions in progress.
+type SessionCounter interface {
+	// ActiveSessions returns the number of sessions in progress.
+	ActiveSessions() int
+}
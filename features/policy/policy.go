@@ -5,8 +5,11 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/features"
+	"golang.org/x/time/rate"
 )
 
 // Timeout contains limits for connection timeout.
@@ -37,6 +40,30 @@ type Buffer struct {
 	PerConnection int32
 }
 
+// Limit contains limits of a user, counted on all inbounds.
+type Limit struct {
+	// Maximum number of IPs a user can be online from at the same time. 0 for unlimited.
+	MaxOnlineIPs uint32
+	// Maximum number of concurrent client connections of a user, checked by the inbounds, where a Mux connection
+	// counts once. 0 for unlimited.
+	MaxConnections uint32
+	// Time an IP is still counted as online after the last connection from it is closed.
+	GracePeriod time.Duration
+	// Maximum number of concurrent connections dispatched for a user, counting every multiplexed one. It applies on
+	// top of MaxConnections, and is the one that limits the streams of Mux. 0 for unlimited.
+	MaxConcurrentConnections uint32
+	// Maximum number of new connections dispatched for a user per second. 0 for unlimited.
+	NewConnectionsPerSecond uint32
+}
+
+// Rate contains throughput limits of a user, shared by all connections of the user.
+type Rate struct {
+	// Uplink throughput in bytes per second. 0 for unlimited.
+	Uplink uint64
+	// Downlink throughput in bytes per second. 0 for unlimited.
+	Downlink uint64
+}
+
 // SystemStats contains stat policy settings on system level.
 type SystemStats struct {
 	// Whether or not to enable stat counter for uplink traffic in inbound handlers.
@@ -60,6 +87,8 @@ type Session struct {
 	Timeouts Timeout // Timeout settings
 	Stats    Stats
 	Buffer   Buffer
+	Limit    Limit
+	Rate     Rate
 }
 
 // Manager is a feature that provides Policy for the given user by its id or level.
@@ -75,6 +104,70 @@ type Manager interface {
 	ForSystem() System
 }
 
+// UserPolicyManager is implemented by a Manager that supports the policy overrides of users.
+type UserPolicyManager interface {
+	// ForUser returns the Session policy for the given user, which is the one of the user level with the overrides
+	// of the user on top.
+	ForUser(user *protocol.MemoryUser) Session
+}
+
+// ForUser returns the Session policy for user in m. The overrides of user are ignored if m doesn't support them.
+func ForUser(m Manager, user *protocol.MemoryUser) Session {
+	if user == nil {
+		return m.ForLevel(0)
+	}
+	if manager, ok := m.(UserPolicyManager); ok {
+		return manager.ForUser(user)
+	}
+	return m.ForLevel(user.Level)
+}
+
+// UserTracker is implemented by a Manager that enforces Limit on users.
+type UserTracker interface {
+	// TrackUser registers a connection of the user from the given IP. It returns a function to be called once the
+	// connection is closed, or an error if the connection exceeds the Limit of the user's level.
+	TrackUser(email string, level uint32, ip net.Address) (func(), error)
+}
+
+// TrackUser registers a connection of the user in m, if m enforces Limit. Users without email are not tracked.
+func TrackUser(m Manager, user *protocol.MemoryUser, source net.Destination) (func(), error) {
+	if tracker, ok := m.(UserTracker); ok && user != nil && len(user.Email) > 0 && source.IsValid() {
+		return tracker.TrackUser(user.Email, user.Level, source.Address)
+	}
+	return func() {}, nil
+}
+
+// ConnectionAdmitter is implemented by a Manager that limits the connections dispatched for users.
+type ConnectionAdmitter interface {
+	// AdmitConnection registers a new connection of the user. It returns a function to be called once the connection
+	// is closed, or an error if the connection exceeds the Limit of the user.
+	AdmitConnection(email string, level uint32) (func(), error)
+}
+
+// AdmitConnection registers a new connection of user in m, if m limits connections. Users without email are not limited.
+func AdmitConnection(m Manager, user *protocol.MemoryUser) (func(), error) {
+	if admitter, ok := m.(ConnectionAdmitter); ok && user != nil && len(user.Email) > 0 {
+		return admitter.AdmitConnection(user.Email, user.Level)
+	}
+	return func() {}, nil
+}
+
+// RateLimiter is implemented by a Manager that limits the throughput of users.
+type RateLimiter interface {
+	// UserRateLimiters returns the uplink and downlink limiters of the user, or nil for an unlimited direction.
+	// The limiters are shared by all connections of the user. It returns a function to be called once the connection
+	// using them is closed.
+	UserRateLimiters(email string, level uint32) (uplink *rate.Limiter, downlink *rate.Limiter, release func())
+}
+
+// UserRateLimiters returns the rate limiters of user in m, if m limits throughput. Users without email are not limited.
+func UserRateLimiters(m Manager, user *protocol.MemoryUser) (*rate.Limiter, *rate.Limiter, func()) {
+	if limiter, ok := m.(RateLimiter); ok && user != nil && len(user.Email) > 0 {
+		return limiter.UserRateLimiters(user.Email, user.Level)
+	}
+	return nil, nil, func() {}
+}
+
 // ManagerType returns the type of Manager interface. Can be used to implement common.HasType.
 //
 // xray:api:stable
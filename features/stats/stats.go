@@ -100,6 +100,28 @@ type Manager interface {
 	GetChannel(string) Channel
 }
 
+// ProtectedCounterManager is a Manager that can keep counters from being reset through its API, for the counters that
+// are the state of a feature, such as the traffic used in a quota.
+type ProtectedCounterManager interface {
+	Manager
+
+	// ProtectCounter keeps the registered counter of the identifier from being reset through the API.
+	ProtectCounter(string)
+}
+
+// GetOrRegisterProtectedCounter is like GetOrRegisterCounter, but keeps the counter from being reset through the API of
+// the manager, if it supports that.
+func GetOrRegisterProtectedCounter(m Manager, name string) (Counter, error) {
+	counter, err := GetOrRegisterCounter(m, name)
+	if err != nil {
+		return nil, err
+	}
+	if pm, ok := m.(ProtectedCounterManager); ok {
+		pm.ProtectCounter(name)
+	}
+	return counter, nil
+}
+
 // GetOrRegisterCounter tries to get the StatCounter first. If not exist, it then tries to create a new counter.
 func GetOrRegisterCounter(m Manager, name string) (Counter, error) {
 	counter := m.GetCounter(name)
@@ -4,9 +4,12 @@ import (
 	"strings"
 
 	"github.com/xtls/xray-core/app/commander"
+	connectionservice "github.com/xtls/xray-core/app/dispatcher/command"
 	loggerservice "github.com/xtls/xray-core/app/log/command"
 	observatoryservice "github.com/xtls/xray-core/app/observatory/command"
+	policyservice "github.com/xtls/xray-core/app/policy/command"
 	handlerservice "github.com/xtls/xray-core/app/proxyman/command"
+	reloadservice "github.com/xtls/xray-core/app/reload/command"
 	routerservice "github.com/xtls/xray-core/app/router/command"
 	statsservice "github.com/xtls/xray-core/app/stats/command"
 	"github.com/xtls/xray-core/common/errors"
@@ -39,6 +42,12 @@ func (c *APIConfig) Build() (*commander.Config, error) {
 			services = append(services, serial.ToTypedMessage(&observatoryservice.Config{}))
 		case "routingservice":
 			services = append(services, serial.ToTypedMessage(&routerservice.Config{}))
+		case "connectionservice":
+			services = append(services, serial.ToTypedMessage(&connectionservice.Config{}))
+		case "policyservice":
+			services = append(services, serial.ToTypedMessage(&policyservice.Config{}))
+		case "reloadservice":
+			services = append(services, serial.ToTypedMessage(&reloadservice.Config{}))
 		}
 	}
 
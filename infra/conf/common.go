@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/platform"
 	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/infra/conf/cfgcommon/duration"
+	"github.com/xtls/xray-core/proxy/externalauth"
 )
 
 type StringList []string
@@ -267,6 +270,45 @@ func (v *User) Build() *protocol.User {
 	}
 }
 
+// UserQuotaConfig is the traffic quota, expiry and policy overrides of an inbound user, shared by all protocols.
+type UserQuotaConfig struct {
+	Quota       uint64            `json:"quota"`
+	QuotaPeriod duration.Duration `json:"quotaPeriod"`
+	ExpireAt    int64             `json:"expireAt"`
+	Policy      *UserPolicyConfig `json:"policy"`
+}
+
+// Apply sets the quota, expiry and policy overrides of user.
+func (c *UserQuotaConfig) Apply(user *protocol.User) {
+	user.Quota = c.Quota
+	user.QuotaPeriod = uint64(time.Duration(c.QuotaPeriod) / time.Second)
+	user.ExpireAt = c.ExpireAt
+	if c.Policy != nil {
+		user.Policy = c.Policy.Build()
+	}
+}
+
+// ExternalAuthConfig is the backend an inbound asks about the users it doesn't know.
+type ExternalAuthConfig struct {
+	URL         string `json:"url"`
+	TTL         uint32 `json:"ttl"`
+	NegativeTTL uint32 `json:"negativeTtl"`
+	Timeout     uint32 `json:"timeout"`
+}
+
+// Build implements Buildable.
+func (c *ExternalAuthConfig) Build() (*externalauth.Config, error) {
+	if c.URL == "" {
+		return nil, errors.New("external auth: missing url")
+	}
+	return &externalauth.Config{
+		Url:         c.URL,
+		Ttl:         c.TTL,
+		NegativeTtl: c.NegativeTTL,
+		Timeout:     c.Timeout,
+	}, nil
+}
+
 // Int32Range deserializes from "1-2" or 1, so can deserialize from both int and number.
 // Negative integers can be passed as sentinel values, but do not parse as ranges.
 // Value will be exchanged if From > To, use .Left and .Right to get original value if need.
@@ -0,0 +1,93 @@
+package conf
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/proxy/hysteria2"
+	"google.golang.org/protobuf/proto"
+)
+
+// Hysteria2ServerTarget is configuration of a single Hysteria2 server
+type Hysteria2ServerTarget struct {
+	Address  *Address `json:"address"`
+	Port     uint16   `json:"port"`
+	Password string   `json:"password"`
+	Email    string   `json:"email"`
+	Level    byte     `json:"level"`
+}
+
+// Hysteria2ClientConfig is configuration of Hysteria2 servers
+type Hysteria2ClientConfig struct {
+	Servers []*Hysteria2ServerTarget `json:"servers"`
+}
+
+// Build implements Buildable
+func (c *Hysteria2ClientConfig) Build() (proto.Message, error) {
+	if len(c.Servers) == 0 {
+		return nil, errors.New("0 Hysteria2 server configured.")
+	}
+
+	config := &hysteria2.ClientConfig{
+		Server: make([]*protocol.ServerEndpoint, len(c.Servers)),
+	}
+	for idx, rec := range c.Servers {
+		if rec.Address == nil {
+			return nil, errors.New("Hysteria2 server address is not set.")
+		}
+		if rec.Port == 0 {
+			return nil, errors.New("Invalid Hysteria2 port.")
+		}
+		if rec.Password == "" {
+			return nil, errors.New("Hysteria2 password is not specified.")
+		}
+		config.Server[idx] = &protocol.ServerEndpoint{
+			Address: rec.Address.Build(),
+			Port:    uint32(rec.Port),
+			User: []*protocol.User{
+				{
+					Level: uint32(rec.Level),
+					Email: rec.Email,
+					Account: serial.ToTypedMessage(&hysteria2.Account{
+						Password: rec.Password,
+					}),
+				},
+			},
+		}
+	}
+	return config, nil
+}
+
+// Hysteria2UserConfig is user configuration
+type Hysteria2UserConfig struct {
+	Password string `json:"password"`
+	Level    byte   `json:"level"`
+	Email    string `json:"email"`
+	UserQuotaConfig
+}
+
+// Hysteria2ServerConfig is Inbound configuration
+type Hysteria2ServerConfig struct {
+	Clients []*Hysteria2UserConfig `json:"clients"`
+}
+
+// Build implements Buildable
+func (c *Hysteria2ServerConfig) Build() (proto.Message, error) {
+	config := &hysteria2.ServerConfig{
+		Users: make([]*protocol.User, len(c.Clients)),
+	}
+	for idx, rawUser := range c.Clients {
+		if rawUser.Password == "" {
+			return nil, errors.New("Hysteria2 password is not specified.")
+		}
+		config.Users[idx] = &protocol.User{
+			Level: uint32(rawUser.Level),
+			Email: rawUser.Email,
+			Account: serial.ToTypedMessage(&hysteria2.Account{
+				Password: rawUser.Password,
+			}),
+		}
+		rawUser.UserQuotaConfig.Apply(config.Users[idx])
+	}
+	return config, nil
+}
@@ -0,0 +1,111 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	. "github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/hysteria2"
+	transport "github.com/xtls/xray-core/transport/internet/hysteria2"
+)
+
+func TestHysteria2Outbound(t *testing.T) {
+	creator := func() Buildable {
+		return new(Hysteria2ClientConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"servers": [{
+					"address": "example.com",
+					"port": 443,
+					"password": "password",
+					"email": "love@example.com"
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &hysteria2.ClientConfig{
+				Server: []*protocol.ServerEndpoint{
+					{
+						Address: &net.IPOrDomain{
+							Address: &net.IPOrDomain_Domain{
+								Domain: "example.com",
+							},
+						},
+						Port: 443,
+						User: []*protocol.User{
+							{
+								Email: "love@example.com",
+								Account: serial.ToTypedMessage(&hysteria2.Account{
+									Password: "password",
+								}),
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestHysteria2Inbound(t *testing.T) {
+	creator := func() Buildable {
+		return new(Hysteria2ServerConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"clients": [{
+					"password": "password",
+					"level": 1,
+					"email": "love@example.com"
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &hysteria2.ServerConfig{
+				Users: []*protocol.User{
+					{
+						Level: 1,
+						Email: "love@example.com",
+						Account: serial.ToTypedMessage(&hysteria2.Account{
+							Password: "password",
+						}),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestHysteria2Transport(t *testing.T) {
+	creator := func() Buildable {
+		return new(Hysteria2Config)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"obfs": {
+					"type": "salamander",
+					"password": "obfuscation"
+				},
+				"up": 100,
+				"down": "1.5 gbps",
+				"congestion": "brutal",
+				"idleTimeout": 60
+			}`,
+			Parser: loadJSON(creator),
+			Output: &transport.Config{
+				ObfsPassword: "obfuscation",
+				Up:           100 * 1000 * 1000 / 8,
+				Down:         1500 * 1000 * 1000 / 8,
+				Congestion:   transport.Congestion_BRUTAL,
+				IdleTimeout:  60,
+			},
+		},
+	})
+}
@@ -0,0 +1,88 @@
+package conf
+
+import (
+	"encoding/json"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/proxy/masque"
+	"google.golang.org/protobuf/proto"
+)
+
+type MASQUEAccount struct {
+	Username string `json:"user"`
+	Password string `json:"pass"`
+}
+
+func (v *MASQUEAccount) Build() *masque.Account {
+	return &masque.Account{
+		Username: v.Username,
+		Password: v.Password,
+	}
+}
+
+type MASQUEServerConfig struct {
+	Accounts  []*MASQUEAccount `json:"accounts"`
+	UserLevel uint32           `json:"userLevel"`
+}
+
+// Build implements Buildable.
+func (c *MASQUEServerConfig) Build() (proto.Message, error) {
+	config := &masque.ServerConfig{
+		UserLevel: c.UserLevel,
+	}
+	if len(c.Accounts) > 0 {
+		config.Accounts = make(map[string]string)
+		for _, account := range c.Accounts {
+			config.Accounts[account.Username] = account.Password
+		}
+	}
+	return config, nil
+}
+
+type MASQUERemoteConfig struct {
+	Address *Address          `json:"address"`
+	Port    uint16            `json:"port"`
+	Users   []json.RawMessage `json:"users"`
+}
+
+type MASQUEClientConfig struct {
+	Servers []*MASQUERemoteConfig `json:"servers"`
+}
+
+// Build implements Buildable.
+func (c *MASQUEClientConfig) Build() (proto.Message, error) {
+	if len(c.Servers) == 0 {
+		return nil, errors.New("0 MASQUE server configured.")
+	}
+	config := &masque.ClientConfig{
+		Server: make([]*protocol.ServerEndpoint, len(c.Servers)),
+	}
+	for idx, serverConfig := range c.Servers {
+		if serverConfig.Address == nil {
+			return nil, errors.New("MASQUE server address is not set.")
+		}
+		if serverConfig.Port == 0 {
+			return nil, errors.New("Invalid MASQUE port.")
+		}
+		server := &protocol.ServerEndpoint{
+			Address: serverConfig.Address.Build(),
+			Port:    uint32(serverConfig.Port),
+		}
+		for _, rawUser := range serverConfig.Users {
+			user := new(protocol.User)
+			if err := json.Unmarshal(rawUser, user); err != nil {
+				return nil, errors.New("failed to parse MASQUE user").Base(err).AtError()
+			}
+			account := new(MASQUEAccount)
+			if err := json.Unmarshal(rawUser, account); err != nil {
+				return nil, errors.New("failed to parse MASQUE account").Base(err).AtError()
+			}
+			user.Account = serial.ToTypedMessage(account.Build())
+			server.User = append(server.User, user)
+		}
+		config.Server[idx] = server
+	}
+	return config, nil
+}
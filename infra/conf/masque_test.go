@@ -0,0 +1,99 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	. "github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/masque"
+	transport "github.com/xtls/xray-core/transport/internet/masque"
+)
+
+func TestMASQUEOutbound(t *testing.T) {
+	creator := func() Buildable {
+		return new(MASQUEClientConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"servers": [{
+					"address": "example.com",
+					"port": 443,
+					"users": [{
+						"user": "my-username",
+						"pass": "my-password",
+						"level": 1
+					}]
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &masque.ClientConfig{
+				Server: []*protocol.ServerEndpoint{
+					{
+						Address: &net.IPOrDomain{
+							Address: &net.IPOrDomain_Domain{
+								Domain: "example.com",
+							},
+						},
+						Port: 443,
+						User: []*protocol.User{
+							{
+								Level: 1,
+								Account: serial.ToTypedMessage(&masque.Account{
+									Username: "my-username",
+									Password: "my-password",
+								}),
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestMASQUEInbound(t *testing.T) {
+	creator := func() Buildable {
+		return new(MASQUEServerConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"accounts": [{
+					"user": "my-username",
+					"pass": "my-password"
+				}],
+				"userLevel": 1
+			}`,
+			Parser: loadJSON(creator),
+			Output: &masque.ServerConfig{
+				Accounts: map[string]string{
+					"my-username": "my-password",
+				},
+				UserLevel: 1,
+			},
+		},
+	})
+}
+
+func TestMASQUETransport(t *testing.T) {
+	creator := func() Buildable {
+		return new(MASQUEConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"idleTimeout": 60
+			}`,
+			Parser: loadJSON(creator),
+			Output: &transport.Config{
+				IdleTimeout: 60,
+			},
+		},
+	})
+}
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/common/protocol"
 )
 
 type Policy struct {
@@ -13,6 +14,14 @@ type Policy struct {
 	StatsUserDownlink bool    `json:"statsUserDownlink"`
 	StatsUserOnline   bool    `json:"statsUserOnline"`
 	BufferSize        *int32  `json:"bufferSize"`
+	MaxOnlineIPs      uint32  `json:"maxOnlineIPs"`
+	MaxConnections    uint32  `json:"maxConnections"`
+	OnlineGracePeriod uint32  `json:"onlineGracePeriod"`
+	UplinkRate        uint64  `json:"uplinkRate"`
+	DownlinkRate      uint64  `json:"downlinkRate"`
+
+	MaxConcurrentConnections uint32 `json:"maxConcurrentConnections"`
+	NewConnectionsPerSecond  uint32 `json:"newConnectionsPerSecond"`
 }
 
 func (t *Policy) Build() (*policy.Policy, error) {
@@ -49,9 +58,65 @@ func (t *Policy) Build() (*policy.Policy, error) {
 		}
 	}
 
+	if t.MaxOnlineIPs > 0 || t.MaxConnections > 0 || t.MaxConcurrentConnections > 0 || t.NewConnectionsPerSecond > 0 {
+		p.Limit = &policy.Policy_Limit{
+			MaxOnlineIps:             t.MaxOnlineIPs,
+			MaxConnections:           t.MaxConnections,
+			GracePeriod:              &policy.Second{Value: t.OnlineGracePeriod},
+			MaxConcurrentConnections: t.MaxConcurrentConnections,
+			NewConnectionsPerSecond:  t.NewConnectionsPerSecond,
+		}
+	}
+
+	if t.UplinkRate > 0 || t.DownlinkRate > 0 {
+		p.Rate = &policy.Policy_Rate{
+			Uplink:   t.UplinkRate,
+			Downlink: t.DownlinkRate,
+		}
+	}
+
 	return p, nil
 }
 
+// UserPolicyConfig overrides the timeouts and buffer of the level of a user. The settings left out are taken from
+// the level.
+type UserPolicyConfig struct {
+	Handshake      *uint32 `json:"handshake"`
+	ConnectionIdle *uint32 `json:"connIdle"`
+	UplinkOnly     *uint32 `json:"uplinkOnly"`
+	DownlinkOnly   *uint32 `json:"downlinkOnly"`
+	BufferSize     *int32  `json:"bufferSize"`
+}
+
+func (c *UserPolicyConfig) Build() *protocol.UserPolicy {
+	p := new(protocol.UserPolicy)
+	if c.Handshake != nil || c.ConnectionIdle != nil || c.UplinkOnly != nil || c.DownlinkOnly != nil {
+		p.Timeout = new(protocol.UserPolicy_Timeout)
+		if c.Handshake != nil {
+			p.Timeout.Handshake = &protocol.UserPolicy_Second{Value: *c.Handshake}
+		}
+		if c.ConnectionIdle != nil {
+			p.Timeout.ConnectionIdle = &protocol.UserPolicy_Second{Value: *c.ConnectionIdle}
+		}
+		if c.UplinkOnly != nil {
+			p.Timeout.UplinkOnly = &protocol.UserPolicy_Second{Value: *c.UplinkOnly}
+		}
+		if c.DownlinkOnly != nil {
+			p.Timeout.DownlinkOnly = &protocol.UserPolicy_Second{Value: *c.DownlinkOnly}
+		}
+	}
+	if c.BufferSize != nil {
+		bs := int32(-1)
+		if *c.BufferSize >= 0 {
+			bs = (*c.BufferSize) * 1024
+		}
+		p.Buffer = &protocol.UserPolicy_Buffer{
+			Connection: bs,
+		}
+	}
+	return p
+}
+
 type SystemPolicy struct {
 	StatsInboundUplink    bool `json:"statsInboundUplink"`
 	StatsInboundDownlink  bool `json:"statsInboundDownlink"`
@@ -70,9 +135,26 @@ func (p *SystemPolicy) Build() (*policy.SystemPolicy, error) {
 	}, nil
 }
 
+// UserRate is the rate limit of a user, in bytes per second.
+type UserRate struct {
+	UplinkRate   uint64 `json:"uplinkRate"`
+	DownlinkRate uint64 `json:"downlinkRate"`
+}
+
+// UserLimit is the limit of the connections of a user.
+type UserLimit struct {
+	MaxOnlineIPs             uint32 `json:"maxOnlineIPs"`
+	MaxConnections           uint32 `json:"maxConnections"`
+	OnlineGracePeriod        uint32 `json:"onlineGracePeriod"`
+	MaxConcurrentConnections uint32 `json:"maxConcurrentConnections"`
+	NewConnectionsPerSecond  uint32 `json:"newConnectionsPerSecond"`
+}
+
 type PolicyConfig struct {
-	Levels map[uint32]*Policy `json:"levels"`
-	System *SystemPolicy      `json:"system"`
+	Levels     map[uint32]*Policy    `json:"levels"`
+	System     *SystemPolicy         `json:"system"`
+	UserRates  map[string]*UserRate  `json:"userRates"`
+	UserLimits map[string]*UserLimit `json:"userLimits"`
 }
 
 func (c *PolicyConfig) Build() (*policy.Config, error) {
@@ -90,6 +172,35 @@ func (c *PolicyConfig) Build() (*policy.Config, error) {
 		Level: levels,
 	}
 
+	for email, r := range c.UserRates {
+		if r == nil {
+			continue
+		}
+		if config.UserRate == nil {
+			config.UserRate = make(map[string]*policy.Policy_Rate)
+		}
+		config.UserRate[email] = &policy.Policy_Rate{
+			Uplink:   r.UplinkRate,
+			Downlink: r.DownlinkRate,
+		}
+	}
+
+	for email, l := range c.UserLimits {
+		if l == nil {
+			continue
+		}
+		if config.UserLimit == nil {
+			config.UserLimit = make(map[string]*policy.Policy_Limit)
+		}
+		config.UserLimit[email] = &policy.Policy_Limit{
+			MaxOnlineIps:             l.MaxOnlineIPs,
+			MaxConnections:           l.MaxConnections,
+			GracePeriod:              &policy.Second{Value: l.OnlineGracePeriod},
+			MaxConcurrentConnections: l.MaxConcurrentConnections,
+			NewConnectionsPerSecond:  l.NewConnectionsPerSecond,
+		}
+	}
+
 	if c.System != nil {
 		sc, err := c.System.Build()
 		if err != nil {
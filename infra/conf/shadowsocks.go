@@ -37,16 +37,18 @@ type ShadowsocksUserConfig struct {
 	Email    string   `json:"email"`
 	Address  *Address `json:"address"`
 	Port     uint16   `json:"port"`
+	UserQuotaConfig
 }
 
 type ShadowsocksServerConfig struct {
-	Cipher      string                   `json:"method"`
-	Password    string                   `json:"password"`
-	Level       byte                     `json:"level"`
-	Email       string                   `json:"email"`
-	Users       []*ShadowsocksUserConfig `json:"clients"`
-	NetworkList *NetworkList             `json:"network"`
-	IVCheck     bool                     `json:"ivCheck"`
+	Cipher       string                   `json:"method"`
+	Password     string                   `json:"password"`
+	Level        byte                     `json:"level"`
+	Email        string                   `json:"email"`
+	Users        []*ShadowsocksUserConfig `json:"clients"`
+	NetworkList  *NetworkList             `json:"network"`
+	IVCheck      bool                     `json:"ivCheck"`
+	ExternalAuth *ExternalAuthConfig      `json:"externalAuth"`
 }
 
 func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
@@ -71,11 +73,13 @@ func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 				account.CipherType > shadowsocks.CipherType_XCHACHA20_POLY1305 {
 				return nil, errors.New("unsupported cipher method: ", user.Cipher)
 			}
-			config.Users = append(config.Users, &protocol.User{
+			u := &protocol.User{
 				Email:   user.Email,
 				Level:   uint32(user.Level),
 				Account: serial.ToTypedMessage(account),
-			})
+			}
+			user.UserQuotaConfig.Apply(u)
+			config.Users = append(config.Users, u)
 		}
 	} else {
 		account := &shadowsocks.Account{
@@ -100,7 +104,7 @@ func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 }
 
 func buildShadowsocks2022(v *ShadowsocksServerConfig) (proto.Message, error) {
-	if len(v.Users) == 0 {
+	if len(v.Users) == 0 && v.ExternalAuth == nil {
 		config := new(shadowsocks_2022.ServerConfig)
 		config.Method = v.Cipher
 		config.Key = v.Password
@@ -116,11 +120,18 @@ func buildShadowsocks2022(v *ShadowsocksServerConfig) (proto.Message, error) {
 		return nil, errors.New("shadowsocks 2022 (multi-user): only blake3-aes-*-gcm methods are supported")
 	}
 
-	if v.Users[0].Address == nil {
+	if len(v.Users) == 0 || v.Users[0].Address == nil {
 		config := new(shadowsocks_2022.MultiUserServerConfig)
 		config.Method = v.Cipher
 		config.Key = v.Password
 		config.Network = v.NetworkList.Build()
+		if v.ExternalAuth != nil {
+			externalAuth, err := v.ExternalAuth.Build()
+			if err != nil {
+				return nil, errors.New(`shadowsocks 2022 (multi-user): invalid "externalAuth"`).Base(err)
+			}
+			config.ExternalAuth = externalAuth
+		}
 
 		for _, user := range v.Users {
 			if user.Cipher != "" {
@@ -129,11 +140,13 @@ func buildShadowsocks2022(v *ShadowsocksServerConfig) (proto.Message, error) {
 			account := &shadowsocks_2022.Account{
 				Key: user.Password,
 			}
-			config.Users = append(config.Users, &protocol.User{
+			u := &protocol.User{
 				Email:   user.Email,
 				Level:   uint32(user.Level),
 				Account: serial.ToTypedMessage(account),
-			})
+			}
+			user.UserQuotaConfig.Apply(u)
+			config.Users = append(config.Users, u)
 		}
 		return config, nil
 	}
@@ -0,0 +1,29 @@
+package conf
+
+import (
+	"github.com/xtls/xray-core/app/tracing"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+type TracingConfig struct {
+	Endpoint    string  `json:"endpoint"`
+	File        string  `json:"file"`
+	ServiceName string  `json:"serviceName"`
+	SampleRatio float32 `json:"sampleRatio"`
+}
+
+func (c *TracingConfig) Build() (*tracing.Config, error) {
+	if c.Endpoint == "" && c.File == "" {
+		return nil, errors.New("Tracing must have an endpoint or a file.")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return nil, errors.New("Tracing sampleRatio must be between 0 and 1.")
+	}
+
+	return &tracing.Config{
+		Endpoint:    c.Endpoint,
+		File:        c.File,
+		ServiceName: c.ServiceName,
+		SampleRatio: c.SampleRatio,
+	}, nil
+}
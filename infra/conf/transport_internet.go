@@ -1,11 +1,13 @@
 package conf
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"math"
 	"net/url"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -16,12 +18,17 @@ import (
 	"github.com/xtls/xray-core/common/platform/filesystem"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/fallback"
 	"github.com/xtls/xray-core/transport/internet/httpupgrade"
+	"github.com/xtls/xray-core/transport/internet/hysteria2"
 	"github.com/xtls/xray-core/transport/internet/kcp"
+	"github.com/xtls/xray-core/transport/internet/masque"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/splithttp"
 	"github.com/xtls/xray-core/transport/internet/tcp"
 	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/tuic"
+	"github.com/xtls/xray-core/transport/internet/udp"
 	"github.com/xtls/xray-core/transport/internet/websocket"
 	"google.golang.org/protobuf/proto"
 )
@@ -53,6 +60,27 @@ type KCPConfig struct {
 	WriteBufferSize *uint32         `json:"writeBufferSize"`
 	HeaderConfig    json.RawMessage `json:"header"`
 	Seed            *string         `json:"seed"`
+	FEC             *KCPFECConfig   `json:"fec"`
+	Hop             *UDPHopConfig   `json:"hop"`
+}
+
+type KCPFECConfig struct {
+	DataShards   uint32 `json:"dataShards"`
+	ParityShards uint32 `json:"parityShards"`
+}
+
+// Build builds the FEC of mKCP.
+func (c *KCPFECConfig) Build() (*kcp.FEC, error) {
+	if c.DataShards == 0 || c.ParityShards == 0 {
+		return nil, errors.New("mKCP FEC needs both data and parity shards").AtError()
+	}
+	if c.DataShards+c.ParityShards > 255 {
+		return nil, errors.New("too many mKCP FEC shards: ", c.DataShards, "+", c.ParityShards).AtError()
+	}
+	return &kcp.FEC{
+		DataShards:   c.DataShards,
+		ParityShards: c.ParityShards,
+	}, nil
 }
 
 // Build implements Buildable.
@@ -114,9 +142,195 @@ func (c *KCPConfig) Build() (proto.Message, error) {
 		config.Seed = &kcp.EncryptionSeed{Seed: *c.Seed}
 	}
 
+	if c.FEC != nil {
+		fec, err := c.FEC.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Fec = fec
+	}
+
+	if c.Hop != nil {
+		hop, err := c.Hop.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Hop = hop
+	}
+
+	return config, nil
+}
+
+// Bandwidth is a rate in bytes per second. In JSON it's a number of Mbps, or a string of a number of bits per second
+// with a unit, like "100 mbps".
+type Bandwidth uint64
+
+// UnmarshalJSON implements encoding/json.Unmarshaler.UnmarshalJSON
+func (b *Bandwidth) UnmarshalJSON(data []byte) error {
+	var mbps uint64
+	if err := json.Unmarshal(data, &mbps); err == nil {
+		*b = Bandwidth(mbps * 1000 * 1000 / 8)
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return errors.New("invalid bandwidth: ", string(data))
+	}
+	str = strings.ToLower(strings.TrimSpace(str))
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i <= 0 {
+		return errors.New("invalid bandwidth: ", str)
+	}
+	value, err := strconv.ParseFloat(str[:i], 64)
+	if err != nil {
+		return errors.New("invalid bandwidth: ", str).Base(err)
+	}
+	var bps float64
+	switch strings.TrimSpace(str[i:]) {
+	case "b", "bps":
+		bps = 1
+	case "k", "kb", "kbps":
+		bps = 1000
+	case "m", "mb", "mbps":
+		bps = 1000 * 1000
+	case "g", "gb", "gbps":
+		bps = 1000 * 1000 * 1000
+	case "t", "tb", "tbps":
+		bps = 1000 * 1000 * 1000 * 1000
+	default:
+		return errors.New("unknown unit of bandwidth: ", str)
+	}
+	*b = Bandwidth(value * bps / 8)
+	return nil
+}
+
+type Hysteria2ObfsConfig struct {
+	Type     string `json:"type"`
+	Password string `json:"password"`
+}
+
+type Hysteria2Config struct {
+	Obfs                  *Hysteria2ObfsConfig `json:"obfs"`
+	Up                    Bandwidth            `json:"up"`
+	Down                  Bandwidth            `json:"down"`
+	Congestion            string               `json:"congestion"`
+	IgnoreClientBandwidth bool                 `json:"ignoreClientBandwidth"`
+	Masquerade            *HTTPFallbackConfig  `json:"masquerade"`
+	Hop                   *UDPHopConfig        `json:"hop"`
+	IdleTimeout           uint32               `json:"idleTimeout"`
+}
+
+// Build implements Buildable.
+func (c *Hysteria2Config) Build() (proto.Message, error) {
+	config := &hysteria2.Config{
+		Up:                    uint64(c.Up),
+		Down:                  uint64(c.Down),
+		IgnoreClientBandwidth: c.IgnoreClientBandwidth,
+		IdleTimeout:           c.IdleTimeout,
+	}
+	if c.Obfs != nil {
+		switch strings.ToLower(c.Obfs.Type) {
+		case "", "salamander":
+			if len(c.Obfs.Password) < 4 {
+				return nil, errors.New("Hysteria2 salamander password is shorter than 4").AtError()
+			}
+			config.ObfsPassword = c.Obfs.Password
+		default:
+			return nil, errors.New("unknown Hysteria2 obfs type: ", c.Obfs.Type).AtError()
+		}
+	}
+	switch strings.ToLower(c.Congestion) {
+	case "", "auto":
+		config.Congestion = hysteria2.Congestion_AUTO
+	case "bbr":
+		config.Congestion = hysteria2.Congestion_BBR
+	case "brutal":
+		config.Congestion = hysteria2.Congestion_BRUTAL
+	default:
+		return nil, errors.New("unknown Hysteria2 congestion control: ", c.Congestion).AtError()
+	}
+	if c.Masquerade != nil {
+		masquerade, err := c.Masquerade.Build()
+		if err != nil {
+			return nil, errors.New(`invalid "masquerade" of Hysteria2`).Base(err)
+		}
+		config.Masquerade = masquerade
+	}
+	if c.Hop != nil {
+		hop, err := c.Hop.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Hop = hop
+	}
+	return config, nil
+}
+
+type TUICConfig struct {
+	Congestion       string `json:"congestion"`
+	ZeroRTTHandshake bool   `json:"zeroRttHandshake"`
+	IdleTimeout      uint32 `json:"idleTimeout"`
+}
+
+// Build implements Buildable.
+func (c *TUICConfig) Build() (proto.Message, error) {
+	config := &tuic.Config{
+		ZeroRttHandshake: c.ZeroRTTHandshake,
+		IdleTimeout:      c.IdleTimeout,
+	}
+	switch strings.ToLower(c.Congestion) {
+	case "", "cubic":
+		config.Congestion = tuic.Congestion_CUBIC
+	case "bbr":
+		config.Congestion = tuic.Congestion_BBR
+	default:
+		return nil, errors.New("unknown TUIC congestion control: ", c.Congestion).AtError()
+	}
+	return config, nil
+}
+
+type MASQUEConfig struct {
+	Masquerade  *HTTPFallbackConfig `json:"masquerade"`
+	IdleTimeout uint32              `json:"idleTimeout"`
+}
+
+// Build implements Buildable.
+func (c *MASQUEConfig) Build() (proto.Message, error) {
+	config := &masque.Config{
+		IdleTimeout: c.IdleTimeout,
+	}
+	if c.Masquerade != nil {
+		masquerade, err := c.Masquerade.Build()
+		if err != nil {
+			return nil, errors.New(`invalid "masquerade" of MASQUE`).Base(err)
+		}
+		config.Masquerade = masquerade
+	}
 	return config, nil
 }
 
+type UDPHopConfig struct {
+	Ports    *PortList `json:"ports"`
+	Interval uint32    `json:"interval"`
+}
+
+// Build builds the hop of a QUIC or mKCP transport. A server listens on every port of the hop, so it may have
+// at most udp.MaxHopPorts ports.
+func (c *UDPHopConfig) Build() (*udp.Hop, error) {
+	hop := &udp.Hop{
+		Interval: c.Interval,
+	}
+	if c.Ports != nil {
+		hop.Ports = c.Ports.Build()
+	}
+	if n := len(hop.AllPorts()); n > udp.MaxHopPorts {
+		return nil, errors.New("too many hop ports: ", n, " > ", udp.MaxHopPorts).AtError()
+	}
+	return hop, nil
+}
+
 type TCPConfig struct {
 	HeaderConfig        json.RawMessage `json:"header"`
 	AcceptProxyProtocol bool            `json:"acceptProxyProtocol"`
@@ -142,12 +356,67 @@ func (c *TCPConfig) Build() (proto.Message, error) {
 	return config, nil
 }
 
+type HTTPFallbackConfig struct {
+	Dest json.RawMessage `json:"dest"`
+	Dir  string          `json:"dir"`
+	Xver uint32          `json:"xver"`
+}
+
+// Build implements Buildable.
+func (c *HTTPFallbackConfig) Build() (*fallback.Config, error) {
+	config := &fallback.Config{
+		Dir:  c.Dir,
+		Xver: c.Xver,
+	}
+	var i uint16
+	if err := json.Unmarshal(c.Dest, &i); err == nil {
+		config.Dest = strconv.Itoa(int(i))
+	} else if len(c.Dest) > 0 {
+		if err := json.Unmarshal(c.Dest, &config.Dest); err != nil {
+			return nil, errors.New(`invalid "dest" of fallback`).Base(err)
+		}
+	}
+
+	switch {
+	case config.Dest == "":
+		if config.Dir == "" {
+			return nil, errors.New(`either "dest" or "dir" is needed for fallback`)
+		}
+	case filepath.IsAbs(config.Dest) || config.Dest[0] == '@':
+		config.Type = "unix"
+		if strings.HasPrefix(config.Dest, "@@") && (runtime.GOOS == "linux" || runtime.GOOS == "android") {
+			fullAddr := make([]byte, len(syscall.RawSockaddrUnix{}.Path)) // may need padding to work with haproxy
+			copy(fullAddr, config.Dest[1:])
+			config.Dest = string(fullAddr)
+		}
+	default:
+		if dest, ok := strings.CutPrefix(config.Dest, "https://"); ok {
+			config.Dest = dest
+			config.Tls = true
+		} else {
+			config.Dest = strings.TrimPrefix(config.Dest, "http://")
+		}
+		if _, err := strconv.Atoi(config.Dest); err == nil {
+			config.Dest = "127.0.0.1:" + config.Dest
+		}
+		if _, _, err := net.SplitHostPort(config.Dest); err != nil {
+			return nil, errors.New(`invalid "dest" of fallback: `, config.Dest)
+		}
+		config.Type = "tcp"
+	}
+	if config.Xver > 2 {
+		return nil, errors.New(`invalid PROXY protocol version of fallback, "xver" only accepts 0, 1, 2`)
+	}
+	return config, nil
+}
+
 type WebSocketConfig struct {
-	Host                string            `json:"host"`
-	Path                string            `json:"path"`
-	Headers             map[string]string `json:"headers"`
-	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
-	HeartbeatPeriod     uint32            `json:"heartbeatPeriod"`
+	Host                string              `json:"host"`
+	Path                string              `json:"path"`
+	Headers             map[string]string   `json:"headers"`
+	AcceptProxyProtocol bool                `json:"acceptProxyProtocol"`
+	HeartbeatPeriod     uint32              `json:"heartbeatPeriod"`
+	Fallback            *HTTPFallbackConfig `json:"fallback"`
 }
 
 // Build implements Buildable.
@@ -181,6 +450,12 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 		Ed:                  ed,
 		HeartbeatPeriod:     c.HeartbeatPeriod,
 	}
+	if c.Fallback != nil {
+		var err error
+		if config.Fallback, err = c.Fallback.Build(); err != nil {
+			return nil, err
+		}
+	}
 	return config, nil
 }
 
@@ -221,20 +496,22 @@ func (c *HttpUpgradeConfig) Build() (proto.Message, error) {
 }
 
 type SplitHTTPConfig struct {
-	Host                 string            `json:"host"`
-	Path                 string            `json:"path"`
-	Mode                 string            `json:"mode"`
-	Headers              map[string]string `json:"headers"`
-	XPaddingBytes        Int32Range        `json:"xPaddingBytes"`
-	NoGRPCHeader         bool              `json:"noGRPCHeader"`
-	NoSSEHeader          bool              `json:"noSSEHeader"`
-	ScMaxEachPostBytes   Int32Range        `json:"scMaxEachPostBytes"`
-	ScMinPostsIntervalMs Int32Range        `json:"scMinPostsIntervalMs"`
-	ScMaxBufferedPosts   int64             `json:"scMaxBufferedPosts"`
-	ScStreamUpServerSecs Int32Range        `json:"scStreamUpServerSecs"`
-	Xmux                 XmuxConfig        `json:"xmux"`
-	DownloadSettings     *StreamConfig     `json:"downloadSettings"`
-	Extra                json.RawMessage   `json:"extra"`
+	Host                 string              `json:"host"`
+	Path                 string              `json:"path"`
+	Mode                 string              `json:"mode"`
+	Headers              map[string]string   `json:"headers"`
+	XPaddingBytes        Int32Range          `json:"xPaddingBytes"`
+	NoGRPCHeader         bool                `json:"noGRPCHeader"`
+	NoSSEHeader          bool                `json:"noSSEHeader"`
+	ScMaxEachPostBytes   Int32Range          `json:"scMaxEachPostBytes"`
+	ScMinPostsIntervalMs Int32Range          `json:"scMinPostsIntervalMs"`
+	ScMaxBufferedPosts   int64               `json:"scMaxBufferedPosts"`
+	ScStreamUpServerSecs Int32Range          `json:"scStreamUpServerSecs"`
+	Xmux                 XmuxConfig          `json:"xmux"`
+	DownloadSettings     *StreamConfig       `json:"downloadSettings"`
+	Fallback             *HTTPFallbackConfig `json:"fallback"`
+	Hop                  *UDPHopConfig       `json:"hop"`
+	Extra                json.RawMessage     `json:"extra"`
 }
 
 type XmuxConfig struct {
@@ -263,6 +540,12 @@ func (c *SplitHTTPConfig) Build() (proto.Message, error) {
 		extra.Host = c.Host
 		extra.Path = c.Path
 		extra.Mode = c.Mode
+		if extra.Fallback == nil {
+			extra.Fallback = c.Fallback
+		}
+		if extra.Hop == nil {
+			extra.Hop = c.Hop
+		}
 		c = &extra
 	}
 
@@ -329,6 +612,21 @@ func (c *SplitHTTPConfig) Build() (proto.Message, error) {
 		}
 	}
 
+	if c.Fallback != nil {
+		var err error
+		if config.Fallback, err = c.Fallback.Build(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Hop != nil {
+		hop, err := c.Hop.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Hop = hop
+	}
+
 	return config, nil
 }
 
@@ -394,6 +692,39 @@ func (c *TLSCertConfig) Build() (*tls.Certificate, error) {
 	return certificate, nil
 }
 
+type ACMEConfig struct {
+	Domains                  StringList `json:"domains"`
+	Email                    string     `json:"email"`
+	DirectoryURL             string     `json:"directoryUrl"`
+	StorageDir               string     `json:"storageDir"`
+	HTTPChallengeListen      string     `json:"httpChallengeListen"`
+	DirectoryCertificateFile string     `json:"directoryCertificateFile"`
+	RenewBeforeDays          uint32     `json:"renewBeforeDays"`
+}
+
+// Build implements Buildable.
+func (c *ACMEConfig) Build() (*tls.ACME, error) {
+	if len(c.Domains) == 0 {
+		return nil, errors.New(`no "domains" for ACME`)
+	}
+	config := &tls.ACME{
+		Domains:             []string(c.Domains),
+		Email:               c.Email,
+		DirectoryUrl:        c.DirectoryURL,
+		StorageDir:          c.StorageDir,
+		HttpChallengeListen: c.HTTPChallengeListen,
+		RenewBefore:         c.RenewBeforeDays * 24 * 3600,
+	}
+	if c.DirectoryCertificateFile != "" {
+		certificate, err := filesystem.ReadFile(c.DirectoryCertificateFile)
+		if err != nil {
+			return nil, errors.New(`failed to read "directoryCertificateFile"`).Base(err)
+		}
+		config.DirectoryCertificate = certificate
+	}
+	return config, nil
+}
+
 type TLSConfig struct {
 	Insecure                             bool             `json:"allowInsecure"`
 	Certs                                []*TLSCertConfig `json:"certificates"`
@@ -412,6 +743,11 @@ type TLSConfig struct {
 	MasterKeyLog                         string           `json:"masterKeyLog"`
 	ServerNameToVerify                   string           `json:"serverNameToVerify"`
 	VerifyPeerCertInNames                []string         `json:"verifyPeerCertInNames"`
+	ECHConfigList                        string           `json:"echConfigList"`
+	ECHForceQuery                        string           `json:"echForceQuery"`
+	ECHServerKeys                        string           `json:"echServerKeys"`
+	ECHServerKeysFile                    string           `json:"echServerKeysFile"`
+	ACME                                 *ACMEConfig      `json:"acme"`
 }
 
 // Build implements Buildable.
@@ -483,6 +819,71 @@ func (c *TLSConfig) Build() (proto.Message, error) {
 	}
 	config.VerifyPeerCertInNames = c.VerifyPeerCertInNames
 
+	if c.ECHConfigList != "" {
+		if domain, ok := strings.CutPrefix(c.ECHConfigList, "dns"); ok && (domain == "" || strings.HasPrefix(domain, "://")) {
+			config.EchConfigDomain = strings.TrimPrefix(domain, "://")
+			if config.EchConfigDomain == "" {
+				config.EchConfigDomain = c.ServerName
+			}
+			if config.EchConfigDomain == "" {
+				return nil, errors.New(`"serverName" is needed to look up "echConfigList"`)
+			}
+		} else {
+			configList, err := base64.StdEncoding.DecodeString(c.ECHConfigList)
+			if err != nil {
+				return nil, errors.New(`invalid "echConfigList"`).Base(err)
+			}
+			if _, err := tls.ParseECHConfigList(configList); err != nil {
+				return nil, err
+			}
+			config.EchConfigList = configList
+		}
+	}
+	switch strings.ToLower(c.ECHForceQuery) {
+	case "", "none":
+		config.EchForceQuery = tls.ECHForceQuery_None
+	case "half":
+		config.EchForceQuery = tls.ECHForceQuery_Half
+	case "full":
+		config.EchForceQuery = tls.ECHForceQuery_Full
+	default:
+		return nil, errors.New(`unknown "echForceQuery": `, c.ECHForceQuery)
+	}
+	if (len(config.EchConfigList) > 0 || config.EchConfigDomain != "") && tls.GetFingerprint(config.Fingerprint) != nil {
+		errors.LogWarning(context.Background(), `"fingerprint" `, config.Fingerprint, ` is not used when ECH is, as the handshake is done by crypto/tls then`)
+	}
+	if c.ECHServerKeys != "" {
+		keys, err := base64.StdEncoding.DecodeString(c.ECHServerKeys)
+		if err != nil {
+			return nil, errors.New(`invalid "echServerKeys"`).Base(err)
+		}
+		if _, err := tls.ParseECHKeys(keys); err != nil {
+			return nil, err
+		}
+		config.EchServerKeys = keys
+	}
+	if c.ECHServerKeysFile != "" {
+		keys, err := filesystem.ReadFile(c.ECHServerKeysFile)
+		if err != nil {
+			return nil, errors.New(`failed to read "echServerKeysFile"`).Base(err)
+		}
+		if _, err := tls.ParseECHKeys(keys); err != nil {
+			return nil, err
+		}
+		config.EchServerKeysPath = c.ECHServerKeysFile
+	}
+	if (c.ECHServerKeys != "" || c.ECHServerKeysFile != "") && c.MinVersion != "" && c.MinVersion != "1.3" {
+		return nil, errors.New(`ECH needs "minVersion" of TLS 1.3`)
+	}
+
+	if c.ACME != nil {
+		acme, err := c.ACME.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Acme = acme
+	}
+
 	return config, nil
 }
 
@@ -668,6 +1069,12 @@ func (p TransportProtocol) Build() (string, error) {
 		return "splithttp", nil
 	case "kcp", "mkcp":
 		return "mkcp", nil
+	case "hysteria2", "hy2":
+		return "hysteria2", nil
+	case "tuic":
+		return "tuic", nil
+	case "masque":
+		return "masque", nil
 	case "grpc":
 		errors.PrintDeprecatedFeatureWarning("gRPC transport (with unnecessary costs, etc.)", "XHTTP stream-up H2")
 		return "grpc", nil
@@ -813,6 +1220,9 @@ type StreamConfig struct {
 	XHTTPSettings       *SplitHTTPConfig   `json:"xhttpSettings"`
 	SplitHTTPSettings   *SplitHTTPConfig   `json:"splithttpSettings"`
 	KCPSettings         *KCPConfig         `json:"kcpSettings"`
+	Hysteria2Settings   *Hysteria2Config   `json:"hysteria2Settings"`
+	TUICSettings        *TUICConfig        `json:"tuicSettings"`
+	MASQUESettings      *MASQUEConfig      `json:"masqueSettings"`
 	GRPCSettings        *GRPCConfig        `json:"grpcSettings"`
 	WSSettings          *WebSocketConfig   `json:"wsSettings"`
 	HTTPUPGRADESettings *HttpUpgradeConfig `json:"httpupgradeSettings"`
@@ -904,6 +1314,36 @@ func (c *StreamConfig) Build() (*internet.StreamConfig, error) {
 			Settings:     serial.ToTypedMessage(ts),
 		})
 	}
+	if c.Hysteria2Settings != nil {
+		hs, err := c.Hysteria2Settings.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build Hysteria2 config.").Base(err)
+		}
+		config.TransportSettings = append(config.TransportSettings, &internet.TransportConfig{
+			ProtocolName: "hysteria2",
+			Settings:     serial.ToTypedMessage(hs),
+		})
+	}
+	if c.TUICSettings != nil {
+		ts, err := c.TUICSettings.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build TUIC config.").Base(err)
+		}
+		config.TransportSettings = append(config.TransportSettings, &internet.TransportConfig{
+			ProtocolName: "tuic",
+			Settings:     serial.ToTypedMessage(ts),
+		})
+	}
+	if c.MASQUESettings != nil {
+		ms, err := c.MASQUESettings.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build MASQUE config.").Base(err)
+		}
+		config.TransportSettings = append(config.TransportSettings, &internet.TransportConfig{
+			ProtocolName: "masque",
+			Settings:     serial.ToTypedMessage(ms),
+		})
+	}
 	if c.GRPCSettings != nil {
 		gs, err := c.GRPCSettings.Build()
 		if err != nil {
@@ -934,6 +1374,15 @@ func (c *StreamConfig) Build() (*internet.StreamConfig, error) {
 			Settings:     serial.ToTypedMessage(hs),
 		})
 	}
+	if config.ProtocolName == "hysteria2" && config.SecurityType != serial.GetMessageType(&tls.Config{}) {
+		return nil, errors.New("Hysteria2 requires TLS.")
+	}
+	if config.ProtocolName == "tuic" && config.SecurityType != serial.GetMessageType(&tls.Config{}) {
+		return nil, errors.New("TUIC requires TLS.")
+	}
+	if config.ProtocolName == "masque" && config.SecurityType != serial.GetMessageType(&tls.Config{}) {
+		return nil, errors.New("MASQUE requires TLS.")
+	}
 	if c.SocketSettings != nil {
 		ss, err := c.SocketSettings.Build()
 		if err != nil {
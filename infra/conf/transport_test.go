@@ -149,3 +149,21 @@ func TestSocketConfig(t *testing.T) {
 		t.Fatalf("unexpected parsed TFO value, which should be -1")
 	}
 }
+
+func TestUDPHopConfig(t *testing.T) {
+	build := func(s string) error {
+		config := new(UDPHopConfig)
+		if err := json.Unmarshal([]byte(s), config); err != nil {
+			return err
+		}
+		_, err := config.Build()
+		return err
+	}
+
+	if err := build(`{"ports": "20000-21023", "interval": 30}`); err != nil {
+		t.Error("unexpected error: ", err)
+	}
+	if err := build(`{"ports": "20000-21000,30000-30023"}`); err == nil {
+		t.Error("expected an error for too many hop ports")
+	}
+}
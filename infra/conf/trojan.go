@@ -89,12 +89,14 @@ type TrojanUserConfig struct {
 	Level    byte   `json:"level"`
 	Email    string `json:"email"`
 	Flow     string `json:"flow"`
+	UserQuotaConfig
 }
 
 // TrojanServerConfig is Inbound configuration
 type TrojanServerConfig struct {
-	Clients   []*TrojanUserConfig      `json:"clients"`
-	Fallbacks []*TrojanInboundFallback `json:"fallbacks"`
+	Clients      []*TrojanUserConfig      `json:"clients"`
+	Fallbacks    []*TrojanInboundFallback `json:"fallbacks"`
+	ExternalAuth *ExternalAuthConfig      `json:"externalAuth"`
 }
 
 // Build implements Buildable
@@ -115,6 +117,15 @@ func (c *TrojanServerConfig) Build() (proto.Message, error) {
 				Password: rawUser.Password,
 			}),
 		}
+		rawUser.UserQuotaConfig.Apply(config.Users[idx])
+	}
+
+	if c.ExternalAuth != nil {
+		externalAuth, err := c.ExternalAuth.Build()
+		if err != nil {
+			return nil, errors.New(`Trojan settings: invalid "externalAuth"`).Base(err)
+		}
+		config.ExternalAuth = externalAuth
 	}
 
 	for _, fb := range c.Fallbacks {
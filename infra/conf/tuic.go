@@ -0,0 +1,120 @@
+package conf
+
+import (
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/proxy/tuic"
+	"google.golang.org/protobuf/proto"
+)
+
+// TUICServerTarget is configuration of a single TUIC server
+type TUICServerTarget struct {
+	Address  *Address `json:"address"`
+	Port     uint16   `json:"port"`
+	UUID     string   `json:"uuid"`
+	Password string   `json:"password"`
+	Email    string   `json:"email"`
+	Level    byte     `json:"level"`
+}
+
+// TUICClientConfig is configuration of TUIC servers
+type TUICClientConfig struct {
+	Servers      []*TUICServerTarget `json:"servers"`
+	UDPRelayMode string              `json:"udpRelayMode"`
+	Heartbeat    uint32              `json:"heartbeat"`
+}
+
+func buildTUICAccount(id, password string) (*tuic.Account, error) {
+	u, err := uuid.ParseString(id)
+	if err != nil {
+		return nil, errors.New("invalid TUIC UUID: ", id).Base(err)
+	}
+	return &tuic.Account{
+		Uuid:     u.String(),
+		Password: password,
+	}, nil
+}
+
+// Build implements Buildable
+func (c *TUICClientConfig) Build() (proto.Message, error) {
+	if len(c.Servers) == 0 {
+		return nil, errors.New("0 TUIC server configured.")
+	}
+
+	config := &tuic.ClientConfig{
+		Server:    make([]*protocol.ServerEndpoint, len(c.Servers)),
+		Heartbeat: c.Heartbeat,
+	}
+	switch strings.ToLower(c.UDPRelayMode) {
+	case "", "native":
+		config.UdpRelayMode = tuic.UDPRelayMode_NATIVE
+	case "quic":
+		config.UdpRelayMode = tuic.UDPRelayMode_QUIC
+	default:
+		return nil, errors.New("unknown TUIC UDP relay mode: ", c.UDPRelayMode)
+	}
+	for idx, rec := range c.Servers {
+		if rec.Address == nil {
+			return nil, errors.New("TUIC server address is not set.")
+		}
+		if rec.Port == 0 {
+			return nil, errors.New("Invalid TUIC port.")
+		}
+		account, err := buildTUICAccount(rec.UUID, rec.Password)
+		if err != nil {
+			return nil, err
+		}
+		config.Server[idx] = &protocol.ServerEndpoint{
+			Address: rec.Address.Build(),
+			Port:    uint32(rec.Port),
+			User: []*protocol.User{
+				{
+					Level:   uint32(rec.Level),
+					Email:   rec.Email,
+					Account: serial.ToTypedMessage(account),
+				},
+			},
+		}
+	}
+	return config, nil
+}
+
+// TUICUserConfig is user configuration
+type TUICUserConfig struct {
+	UUID     string `json:"uuid"`
+	Password string `json:"password"`
+	Level    byte   `json:"level"`
+	Email    string `json:"email"`
+	UserQuotaConfig
+}
+
+// TUICServerConfig is Inbound configuration
+type TUICServerConfig struct {
+	Clients     []*TUICUserConfig `json:"clients"`
+	AuthTimeout uint32            `json:"authTimeout"`
+}
+
+// Build implements Buildable
+func (c *TUICServerConfig) Build() (proto.Message, error) {
+	config := &tuic.ServerConfig{
+		Users:       make([]*protocol.User, len(c.Clients)),
+		AuthTimeout: c.AuthTimeout,
+	}
+	for idx, rawUser := range c.Clients {
+		account, err := buildTUICAccount(rawUser.UUID, rawUser.Password)
+		if err != nil {
+			return nil, err
+		}
+		config.Users[idx] = &protocol.User{
+			Level:   uint32(rawUser.Level),
+			Email:   rawUser.Email,
+			Account: serial.ToTypedMessage(account),
+		}
+		rawUser.UserQuotaConfig.Apply(config.Users[idx])
+	}
+	return config, nil
+}
@@ -0,0 +1,112 @@
+package conf_test
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	. "github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/tuic"
+	transport "github.com/xtls/xray-core/transport/internet/tuic"
+)
+
+func TestTUICOutbound(t *testing.T) {
+	creator := func() Buildable {
+		return new(TUICClientConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"servers": [{
+					"address": "example.com",
+					"port": 443,
+					"uuid": "27848739-7e62-4138-9fd3-098a63964b6b",
+					"password": "password"
+				}],
+				"udpRelayMode": "quic",
+				"heartbeat": 5
+			}`,
+			Parser: loadJSON(creator),
+			Output: &tuic.ClientConfig{
+				Server: []*protocol.ServerEndpoint{
+					{
+						Address: &net.IPOrDomain{
+							Address: &net.IPOrDomain_Domain{
+								Domain: "example.com",
+							},
+						},
+						Port: 443,
+						User: []*protocol.User{
+							{
+								Account: serial.ToTypedMessage(&tuic.Account{
+									Uuid:     "27848739-7e62-4138-9fd3-098a63964b6b",
+									Password: "password",
+								}),
+							},
+						},
+					},
+				},
+				UdpRelayMode: tuic.UDPRelayMode_QUIC,
+				Heartbeat:    5,
+			},
+		},
+	})
+}
+
+func TestTUICInbound(t *testing.T) {
+	creator := func() Buildable {
+		return new(TUICServerConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"clients": [{
+					"uuid": "27848739-7e62-4138-9fd3-098a63964b6b",
+					"password": "password",
+					"level": 1,
+					"email": "love@example.com"
+				}],
+				"authTimeout": 5
+			}`,
+			Parser: loadJSON(creator),
+			Output: &tuic.ServerConfig{
+				Users: []*protocol.User{
+					{
+						Level: 1,
+						Email: "love@example.com",
+						Account: serial.ToTypedMessage(&tuic.Account{
+							Uuid:     "27848739-7e62-4138-9fd3-098a63964b6b",
+							Password: "password",
+						}),
+					},
+				},
+				AuthTimeout: 5,
+			},
+		},
+	})
+}
+
+func TestTUICTransport(t *testing.T) {
+	creator := func() Buildable {
+		return new(TUICConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"congestion": "bbr",
+				"zeroRttHandshake": true,
+				"idleTimeout": 60
+			}`,
+			Parser: loadJSON(creator),
+			Output: &transport.Config{
+				Congestion:       transport.Congestion_BBR,
+				ZeroRttHandshake: true,
+				IdleTimeout:      60,
+			},
+		},
+	})
+}
@@ -29,9 +29,10 @@ type VLessInboundFallback struct {
 }
 
 type VLessInboundConfig struct {
-	Clients    []json.RawMessage       `json:"clients"`
-	Decryption string                  `json:"decryption"`
-	Fallbacks  []*VLessInboundFallback `json:"fallbacks"`
+	Clients      []json.RawMessage       `json:"clients"`
+	Decryption   string                  `json:"decryption"`
+	Fallbacks    []*VLessInboundFallback `json:"fallbacks"`
+	ExternalAuth *ExternalAuthConfig     `json:"externalAuth"`
 }
 
 // Build implements Buildable
@@ -47,6 +48,11 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 		if err := json.Unmarshal(rawUser, account); err != nil {
 			return nil, errors.New(`VLESS clients: invalid user`).Base(err)
 		}
+		quota := new(UserQuotaConfig)
+		if err := json.Unmarshal(rawUser, quota); err != nil {
+			return nil, errors.New(`VLESS clients: invalid user`).Base(err)
+		}
+		quota.Apply(user)
 
 		u, err := uuid.ParseString(account.Id)
 		if err != nil {
@@ -73,6 +79,14 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 	}
 	config.Decryption = c.Decryption
 
+	if c.ExternalAuth != nil {
+		externalAuth, err := c.ExternalAuth.Build()
+		if err != nil {
+			return nil, errors.New(`VLESS settings: invalid "externalAuth"`).Base(err)
+		}
+		config.ExternalAuth = externalAuth
+	}
+
 	for _, fb := range c.Fallbacks {
 		var i uint16
 		var s string
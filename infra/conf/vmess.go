@@ -96,6 +96,11 @@ func (c *VMessInboundConfig) Build() (proto.Message, error) {
 		if err := json.Unmarshal(rawData, account); err != nil {
 			return nil, errors.New("invalid VMess user").Base(err)
 		}
+		quota := new(UserQuotaConfig)
+		if err := json.Unmarshal(rawData, quota); err != nil {
+			return nil, errors.New("invalid VMess user").Base(err)
+		}
+		quota.Apply(user)
 
 		u, err := uuid.ParseString(account.ID)
 		if err != nil {
@@ -29,6 +29,9 @@ var (
 		"vless":         func() interface{} { return new(VLessInboundConfig) },
 		"vmess":         func() interface{} { return new(VMessInboundConfig) },
 		"trojan":        func() interface{} { return new(TrojanServerConfig) },
+		"hysteria2":     func() interface{} { return new(Hysteria2ServerConfig) },
+		"tuic":          func() interface{} { return new(TUICServerConfig) },
+		"masque":        func() interface{} { return new(MASQUEServerConfig) },
 		"wireguard":     func() interface{} { return &WireGuardConfig{IsClient: false} },
 	}, "protocol", "settings")
 
@@ -42,6 +45,9 @@ var (
 		"vless":       func() interface{} { return new(VLessOutboundConfig) },
 		"vmess":       func() interface{} { return new(VMessOutboundConfig) },
 		"trojan":      func() interface{} { return new(TrojanClientConfig) },
+		"hysteria2":   func() interface{} { return new(Hysteria2ClientConfig) },
+		"tuic":        func() interface{} { return new(TUICClientConfig) },
+		"masque":      func() interface{} { return new(MASQUEClientConfig) },
 		"dns":         func() interface{} { return new(DNSOutboundConfig) },
 		"wireguard":   func() interface{} { return &WireGuardConfig{IsClient: true} },
 	}, "protocol", "settings")
@@ -95,6 +101,37 @@ func (c *SniffingConfig) Build() (*proxyman.SniffingConfig, error) {
 	}, nil
 }
 
+// SourceFilterConfig filters the connections of an inbound by their source IPs, before any handshake.
+type SourceFilterConfig struct {
+	Allow       *StringList `json:"allow"`
+	Deny        *StringList `json:"deny"`
+	AcceptRate  uint32      `json:"acceptRate"`
+	AcceptBurst uint32      `json:"acceptBurst"`
+}
+
+// Build implements Buildable.
+func (c *SourceFilterConfig) Build() (*proxyman.SourceFilterConfig, error) {
+	config := &proxyman.SourceFilterConfig{
+		AcceptRate:  c.AcceptRate,
+		AcceptBurst: c.AcceptBurst,
+	}
+	if c.Allow != nil {
+		allow, err := ToCidrList(*c.Allow)
+		if err != nil {
+			return nil, errors.New("invalid allowed IPs").Base(err)
+		}
+		config.Allow = allow
+	}
+	if c.Deny != nil {
+		deny, err := ToCidrList(*c.Deny)
+		if err != nil {
+			return nil, errors.New("invalid denied IPs").Base(err)
+		}
+		config.Deny = deny
+	}
+	return config, nil
+}
+
 type MuxConfig struct {
 	Enabled         bool   `json:"enabled"`
 	Concurrency     int16  `json:"concurrency"`
@@ -119,6 +156,21 @@ func (m *MuxConfig) Build() (*proxyman.MultiplexingConfig, error) {
 	}, nil
 }
 
+type ConnectionPoolConfig struct {
+	Size                uint32 `json:"size"`
+	MaxIdleTime         uint32 `json:"maxIdleTime"`
+	HealthCheckInterval uint32 `json:"healthCheckInterval"`
+}
+
+// Build implements Buildable.
+func (c *ConnectionPoolConfig) Build() (*proxyman.ConnectionPoolConfig, error) {
+	return &proxyman.ConnectionPoolConfig{
+		Size:                c.Size,
+		MaxIdleTime:         c.MaxIdleTime,
+		HealthCheckInterval: c.HealthCheckInterval,
+	}, nil
+}
+
 type InboundDetourAllocationConfig struct {
 	Strategy    string  `json:"strategy"`
 	Concurrency *uint32 `json:"concurrency"`
@@ -162,6 +214,7 @@ type InboundDetourConfig struct {
 	Allocation     *InboundDetourAllocationConfig `json:"allocate"`
 	StreamSetting  *StreamConfig                  `json:"streamSettings"`
 	SniffingConfig *SniffingConfig                `json:"sniffing"`
+	SourceFilter   *SourceFilterConfig            `json:"sourceFilter"`
 }
 
 // Build implements Buildable.
@@ -234,6 +287,13 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 		}
 		receiverSettings.SniffingSettings = s
 	}
+	if c.SourceFilter != nil {
+		f, err := c.SourceFilter.Build()
+		if err != nil {
+			return nil, errors.New("failed to build source filter").Base(err)
+		}
+		receiverSettings.SourceFilter = f
+	}
 
 	settings := []byte("{}")
 	if c.Settings != nil {
@@ -259,13 +319,14 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 }
 
 type OutboundDetourConfig struct {
-	Protocol      string           `json:"protocol"`
-	SendThrough   *string          `json:"sendThrough"`
-	Tag           string           `json:"tag"`
-	Settings      *json.RawMessage `json:"settings"`
-	StreamSetting *StreamConfig    `json:"streamSettings"`
-	ProxySettings *ProxyConfig     `json:"proxySettings"`
-	MuxSettings   *MuxConfig       `json:"mux"`
+	Protocol       string                `json:"protocol"`
+	SendThrough    *string               `json:"sendThrough"`
+	Tag            string                `json:"tag"`
+	Settings       *json.RawMessage      `json:"settings"`
+	StreamSetting  *StreamConfig         `json:"streamSettings"`
+	ProxySettings  *ProxyConfig          `json:"proxySettings"`
+	MuxSettings    *MuxConfig            `json:"mux"`
+	ConnectionPool *ConnectionPoolConfig `json:"connectionPool"`
 }
 
 func (c *OutboundDetourConfig) checkChainProxyConfig() error {
@@ -336,6 +397,33 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 		senderSettings.MultiplexSettings = ms
 	}
 
+	if c.ConnectionPool != nil && c.ConnectionPool.Size > 0 {
+		switch strings.ToLower(c.Protocol) {
+		case "vless", "vmess", "trojan", "shadowsocks":
+		default:
+			return nil, errors.New("connectionPool is only for vless, vmess, trojan and shadowsocks")
+		}
+		if c.MuxSettings != nil && c.MuxSettings.Enabled {
+			return nil, errors.New("connectionPool is conflicted with mux")
+		}
+		if ss := senderSettings.StreamSettings; ss != nil {
+			if ss.ProtocolName != "" && ss.ProtocolName != "tcp" {
+				return nil, errors.New("connectionPool is only for RAW transport")
+			}
+			if ss.SocketSettings.GetDialerProxy() != "" {
+				return nil, errors.New("connectionPool is conflicted with sockopt.dialerProxy")
+			}
+		}
+		if c.SendThrough != nil {
+			return nil, errors.New("connectionPool is conflicted with sendThrough")
+		}
+		cp, err := c.ConnectionPool.Build()
+		if err != nil {
+			return nil, errors.New("failed to build connection pool config.").Base(err)
+		}
+		senderSettings.ConnectionPool = cp
+	}
+
 	settings := []byte("{}")
 	if c.Settings != nil {
 		settings = ([]byte)(*c.Settings)
@@ -356,11 +444,17 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 	}, nil
 }
 
-type StatsConfig struct{}
+type StatsConfig struct {
+	PersistFile     string `json:"persistFile"`
+	PersistInterval uint32 `json:"persistInterval"`
+}
 
 // Build implements Buildable.
 func (c *StatsConfig) Build() (*stats.Config, error) {
-	return &stats.Config{}, nil
+	return &stats.Config{
+		PersistFile:     c.PersistFile,
+		PersistInterval: c.PersistInterval,
+	}, nil
 }
 
 type Config struct {
@@ -381,6 +475,7 @@ type Config struct {
 	FakeDNS          *FakeDNSConfig          `json:"fakeDns"`
 	Observatory      *ObservatoryConfig      `json:"observatory"`
 	BurstObservatory *BurstObservatoryConfig `json:"burstObservatory"`
+	Tracing          *TracingConfig          `json:"tracing"`
 }
 
 func (c *Config) findInboundTag(tag string) int {
@@ -449,6 +544,10 @@ func (c *Config) Override(o *Config, fn string) {
 		c.BurstObservatory = o.BurstObservatory
 	}
 
+	if o.Tracing != nil {
+		c.Tracing = o.Tracing
+	}
+
 	// update the Inbound in slice if the only one in override config has same tag
 	if len(o.InboundConfigs) > 0 {
 		for i := range o.InboundConfigs {
@@ -522,6 +621,13 @@ func (c *Config) Build() (*core.Config, error) {
 		}
 		config.App = append(config.App, serial.ToTypedMessage(statsConf))
 	}
+	if c.Tracing != nil {
+		tracingConf, err := c.Tracing.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.App = append(config.App, serial.ToTypedMessage(tracingConf))
+	}
 
 	var logConfMsg *serial.TypedMessage
 	if c.LogConfig != nil {
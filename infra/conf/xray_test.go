@@ -210,6 +210,47 @@ func TestMuxConfig_Build(t *testing.T) {
 	}
 }
 
+func TestOutboundDetourConfig_ConnectionPool(t *testing.T) {
+	const vless = `"protocol": "vless", "settings": {"vnext": [{"address": "example.com", "port": 443, "users": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "encryption": "none"}]}]}`
+	tests := []struct {
+		name   string
+		fields string
+		want   *proxyman.ConnectionPoolConfig
+		err    bool
+	}{
+		{"raw", `{` + vless + `, "connectionPool": {"size": 2, "maxIdleTime": 30}}`, &proxyman.ConnectionPoolConfig{
+			Size:        2,
+			MaxIdleTime: 30,
+		}, false},
+		{"websocket", `{` + vless + `, "streamSettings": {"network": "ws"}, "connectionPool": {"size": 2}}`, nil, true},
+		{"sendThrough", `{` + vless + `, "sendThrough": "127.0.0.1", "connectionPool": {"size": 2}}`, nil, true},
+		{"freedom", `{"protocol": "freedom", "connectionPool": {"size": 2}}`, nil, true},
+		{"mux", `{` + vless + `, "mux": {"enabled": true}, "connectionPool": {"size": 2}}`, nil, true},
+		{"mux disabled", `{` + vless + `, "mux": {"enabled": false}, "connectionPool": {"size": 2}}`, &proxyman.ConnectionPoolConfig{
+			Size: 2,
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &OutboundDetourConfig{}
+			common.Must(json.Unmarshal([]byte(tt.fields), c))
+			config, err := c.Build()
+			if tt.err {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			common.Must(err)
+			senderSettings, err := config.SenderSettings.GetInstance()
+			common.Must(err)
+			if got := senderSettings.(*proxyman.SenderConfig).ConnectionPool; !proto.Equal(got, tt.want) {
+				t.Errorf("ConnectionPool = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestConfig_Override(t *testing.T) {
 	tests := []struct {
 		name string
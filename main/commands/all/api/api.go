@@ -12,6 +12,7 @@ var CmdAPI = &base.Command{
 `,
 	Commands: []*base.Command{
 		cmdRestartLogger,
+		cmdReloadConfig,
 		cmdGetStats,
 		cmdQueryStats,
 		cmdSysStats,
@@ -26,7 +27,10 @@ var CmdAPI = &base.Command{
 		cmdAddRules,
 		cmdRemoveRules,
 		cmdSourceIpBlock,
+		cmdSourceFilter,
 		cmdOnlineStats,
 		cmdOnlineStatsIpList,
+		cmdListConnections,
+		cmdCloseConnections,
 	},
 }
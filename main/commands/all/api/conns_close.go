@@ -0,0 +1,66 @@
+package api
+
+import (
+	connectionService "github.com/xtls/xray-core/app/dispatcher/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdCloseConnections = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api connsclose [--server=127.0.0.1:8080] <-id id | -email email>",
+	Short:       "Close active connections",
+	Long: `
+Close a connection by its ID, or all connections of a user.
+
+> Ensure that "ConnectionService" is enabled under "config.api.services" in the server configuration.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-id
+		The ID of the connection, as listed by "{{.Exec}} api conns".
+
+	-email
+		Close all connections of this user.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -id 42
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -email "xray@love.com"
+`,
+	Run: executeCloseConnections,
+}
+
+func executeCloseConnections(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	id := cmd.Flag.Uint64("id", 0, "")
+	email := cmd.Flag.String("email", "", "")
+	cmd.Flag.Parse(args)
+
+	if (*id == 0) == (*email == "") {
+		base.Fatalf("exactly one of -id and -email must be specified")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := connectionService.NewConnectionServiceClient(conn)
+	if *id != 0 {
+		resp, err := client.CloseConnection(ctx, &connectionService.CloseConnectionRequest{Id: *id})
+		if err != nil {
+			base.Fatalf("failed to close connection: %s", err)
+		}
+		showJSONResponse(resp)
+		return
+	}
+	resp, err := client.CloseByUser(ctx, &connectionService.CloseByUserRequest{Email: *email})
+	if err != nil {
+		base.Fatalf("failed to close connections: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	connectionService "github.com/xtls/xray-core/app/dispatcher/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdListConnections = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api conns [--server=127.0.0.1:8080] [-email ''] [-inbound '']",
+	Short:       "List active connections",
+	Long: `
+List the connections that are currently flowing through Xray, with their
+inbound, user, source, destination, sniffed domain, outbound, start time
+and traffic.
+
+> Ensure that "ConnectionService" is enabled under "config.api.services" in the server configuration.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-email
+		Only list the connections of this user.
+
+	-inbound
+		Only list the connections of this inbound tag.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -email "xray@love.com"
+`,
+	Run: executeListConnections,
+}
+
+func executeListConnections(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	email := cmd.Flag.String("email", "", "")
+	inbound := cmd.Flag.String("inbound", "", "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := connectionService.NewConnectionServiceClient(conn)
+	r := &connectionService.ListConnectionsRequest{
+		Email:      *email,
+		InboundTag: *inbound,
+	}
+	resp, err := client.ListConnections(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to list connections: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -7,7 +7,7 @@ import (
 
 var cmdInboundUser = &base.Command{
 	CustomFlags: true,
-	UsageLine:   "{{.Exec}} api inbounduser [--server=127.0.0.1:8080] -tag=tag [-email=email]",
+	UsageLine:   "{{.Exec}} api inbounduser [--server=127.0.0.1:8080] -tag=tag [-email=email] [-offset=n -limit=n]",
 	Short:       "Retrieve inbound user(s)",
 	Long: `
 Get User info from an inbound.
@@ -26,10 +26,14 @@ Arguments:
 	-email
 		The user's email address. If not provided, all users will be retrieved.
 
+	-offset, -limit
+		Retrieve a page of the users sorted by email. Default all users
+
 Example:
 
 	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name"
 	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name" -email="xray@love.com"
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name" -offset=100 -limit=100
 `,
 	Run: executeInboundUser,
 }
@@ -38,8 +42,11 @@ func executeInboundUser(cmd *base.Command, args []string) {
 	setSharedFlags(cmd)
 	var tag string
 	var email string
+	var offset, limit uint
 	cmd.Flag.StringVar(&tag, "tag", "", "")
 	cmd.Flag.StringVar(&email, "email", "", "")
+	cmd.Flag.UintVar(&offset, "offset", 0, "")
+	cmd.Flag.UintVar(&limit, "limit", 0, "")
 	cmd.Flag.Parse(args)
 
 	conn, ctx, close := dialAPIServer()
@@ -47,8 +54,10 @@ func executeInboundUser(cmd *base.Command, args []string) {
 
 	client := handlerService.NewHandlerServiceClient(conn)
 	r := &handlerService.GetInboundUserRequest{
-		Tag:   tag,
-		Email: email,
+		Tag:    tag,
+		Email:  email,
+		Offset: uint32(offset),
+		Limit:  uint32(limit),
 	}
 	resp, err := client.GetInboundUsers(ctx, r)
 	if err != nil {
@@ -0,0 +1,47 @@
+package api
+
+import (
+	reloadService "github.com/xtls/xray-core/app/reload/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdReloadConfig = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api reload [--server=127.0.0.1:8080]",
+	Short:       "Reload the config",
+	Long: `
+Reload the config files of Xray, like sending SIGHUP to it. The listening
+ports are handed over to a new instance, and the sessions of the old one
+are drained.
+
+> Ensure that "ReloadService" is enabled under "config.api.services" in the server configuration.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+`,
+	Run: executeReloadConfig,
+}
+
+func executeReloadConfig(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := reloadService.NewReloadServiceClient(conn)
+	resp, err := client.ReloadConfig(ctx, &reloadService.ReloadConfigRequest{})
+	if err != nil {
+		base.Fatalf("failed to reload config: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"strings"
+
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	cserial "github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdSourceFilter = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api sif [--server=127.0.0.1:8080] -tag=tag [-allow=ips] [-deny=ips] [-rate=n] [-burst=n]",
+	Short:       "Set source IP filter of an inbound",
+	Long: `
+Replace the source IP filter of an inbound. Connections from the rejected
+source IPs are dropped right after they are accepted, before any handshake.
+Without any filter flag, the filter is removed.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Inbound tag
+
+	-allow
+		Comma separated IPs, CIDRs or geoip references. If set, only
+		connections from them are accepted.
+
+	-deny
+		Comma separated IPs, CIDRs or geoip references to reject.
+
+	-rate
+		Connections accepted from a source IP per second. Default 0 (unlimited)
+
+	-burst
+		Connections accepted from a source IP at once. Default to rate
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag=vless -deny=1.2.3.4,geoip:private -rate=10
+`,
+	Run: executeSourceFilter,
+}
+
+func executeSourceFilter(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var (
+		tag   string
+		allow string
+		deny  string
+		rate  uint
+		burst uint
+	)
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.StringVar(&allow, "allow", "", "")
+	cmd.Flag.StringVar(&deny, "deny", "", "")
+	cmd.Flag.UintVar(&rate, "rate", 0, "")
+	cmd.Flag.UintVar(&burst, "burst", 0, "")
+	cmd.Flag.Parse(args)
+
+	if tag == "" {
+		base.Fatalf("inbound tag must be specified")
+	}
+	c := &conf.SourceFilterConfig{
+		AcceptRate:  uint32(rate),
+		AcceptBurst: uint32(burst),
+	}
+	if allow != "" {
+		l := conf.StringList(strings.Split(allow, ","))
+		c.Allow = &l
+	}
+	if deny != "" {
+		l := conf.StringList(strings.Split(deny, ","))
+		c.Deny = &l
+	}
+	filter, err := c.Build()
+	if err != nil {
+		base.Fatalf("failed to build source filter: %s", err)
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	resp, err := client.AlterInbound(ctx, &handlerService.AlterInboundRequest{
+		Tag:       tag,
+		Operation: cserial.ToTypedMessage(&handlerService.SetSourceFilterOperation{Filter: filter}),
+	})
+	if err != nil {
+		base.Fatalf("failed to set source filter: %s", err)
+	}
+	showJSONResponse(resp)
+}
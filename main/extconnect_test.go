@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+// TestExtendedConnect checks that with the packages of Xray, the HTTP/2 servers of net/http are initialized after
+// extended CONNECT is enabled, which WebSocket over HTTP/2 needs.
+func TestExtendedConnect(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodConnect, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(":protocol", "websocket")
+	transport := &http2.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+	}
+	defer transport.CloseIdleConnections()
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if strings.Contains(os.Getenv("GODEBUG"), "http2xconnect") {
+		t.Error("expected GODEBUG to be restored for the processes started by Xray")
+	}
+}
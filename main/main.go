@@ -6,6 +6,8 @@ import (
 
 	"github.com/xtls/xray-core/main/commands/base"
 	_ "github.com/xtls/xray-core/main/distro/all"
+	// Enables extended CONNECT of HTTP/2 for WebSocket, before the HTTP packages are initialized.
+	_ "github.com/xtls/xray-core/transport/internet/websocket/extconnect"
 )
 
 func main() {
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/trace"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+)
+
+// TestReload reloads a server twice, and checks that the listeners, the traffic counted and tracing survive.
+func TestReload(t *testing.T) {
+	echo := tcp.Server{
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := echo.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+
+	dir := t.TempDir()
+	port := tcp.PickPort()
+	config := fmt.Sprintf(`{
+		"log": {"loglevel": "none"},
+		"stats": {"persistFile": %q},
+		"policy": {"system": {"statsInboundUplink": true}},
+		"tracing": {"file": %q, "sampleRatio": 1},
+		"inbounds": [{
+			"tag": "in",
+			"listen": "127.0.0.1",
+			"port": %d,
+			"protocol": "dokodemo-door",
+			"settings": {"address": "127.0.0.1", "port": %d, "network": "tcp"}
+		}],
+		"outbounds": [{"protocol": "freedom"}]
+	}`, filepath.Join(dir, "stats.json"), filepath.Join(dir, "trace.json"), port, dest.Port)
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	savedFiles, savedDrain := configFiles, *drain
+	configFiles, *drain = cmdarg.Arg{configFile}, 5*time.Second
+	defer func() {
+		configFiles, *drain = savedFiles, savedDrain
+	}()
+
+	server, err := startXray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &reloader{server: server}
+	server.SetReloader(r.reload)
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.close()
+
+	payload := []byte("hello")
+	roundTrip := func() {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		response := make([]byte, len(payload))
+		if _, err := io.ReadFull(conn, response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uplink := func() int64 {
+		r.access.Lock()
+		defer r.access.Unlock()
+		c := r.server.GetFeature(stats.ManagerType()).(stats.Manager).GetCounter("inbound>>>in>>>traffic>>>uplink")
+		if c == nil {
+			return 0
+		}
+		return c.Value()
+	}
+
+	roundTrip()
+	for i := 1; i <= 2; i++ {
+		if err := r.server.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		r.draining.Wait()
+
+		roundTrip()
+		// The connections are counted when they are closed.
+		deadline := time.Now().Add(5 * time.Second)
+		for uplink() != int64((i+1)*len(payload)) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if v := uplink(); v != int64((i+1)*len(payload)) {
+			t.Errorf("reload %d: expected the traffic of the old servers to be handed over, but got %d", i, v)
+		}
+		if _, span := trace.Start(context.Background(), "test"); span == nil {
+			t.Errorf("reload %d: expected tracing to survive the close of the old server", i)
+		}
+	}
+}
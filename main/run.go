@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -40,6 +42,20 @@ The -test flag tells Xray to test config files only,
 without launching the server.
 
 The -dump flag tells Xray to print the merged config.
+
+Xray reloads the config files on SIGHUP. The new server takes over 
+the listening ports, and the old one is given the time set by the 
+-drain=duration flag for its sessions to finish. Default "30s".
+
+On SIGTERM or interrupt, the -grace=duration flag sets the time for 
+the sessions in progress to finish, while the listeners no longer 
+accept. A second signal closes the server at once. Default "0s", 
+to close at once.
+
+For WebSocket over HTTP/2, extended CONNECT is enabled in all HTTP/2 
+servers of the process, as by GODEBUG=http2xconnect=1 at start. 
+GODEBUG=http2xconnect=0 disables it. The processes started by Xray 
+get GODEBUG as it was.
 	`,
 }
 
@@ -54,6 +70,8 @@ var (
 	dump        = cmdRun.Flag.Bool("dump", false, "Dump merged config only, without launching Xray server.")
 	test        = cmdRun.Flag.Bool("test", false, "Test config file only, without launching Xray server.")
 	format      = cmdRun.Flag.String("format", "auto", "Format of input file.")
+	drain       = cmdRun.Flag.Duration("drain", 30*time.Second, "Time for the sessions of the old server to finish on reload.")
+	grace       = cmdRun.Flag.Duration("grace", 0, "Time for the sessions to finish on shutdown.")
 
 	/* We have to do this here because Golang's Test will also need to parse flag, before
 	 * main func in this file is run.
@@ -87,11 +105,13 @@ func executeRun(cmd *base.Command, args []string) {
 		os.Exit(0)
 	}
 
+	r := &reloader{server: server}
+	server.SetReloader(r.reload)
 	if err := server.Start(); err != nil {
 		fmt.Println("Failed to start:", err)
 		os.Exit(-1)
 	}
-	defer server.Close()
+	defer r.close()
 
 	/*
 		conf.FileCache = nil
@@ -105,9 +125,93 @@ func executeRun(cmd *base.Command, args []string) {
 
 	{
 		osSignals := make(chan os.Signal, 1)
-		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
-		<-osSignals
+		signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		for sig := range osSignals {
+			if sig != syscall.SIGHUP {
+				break
+			}
+			if err := r.reload(); err != nil {
+				log.Println("Failed to reload:", err)
+			}
+		}
+		if *grace > 0 {
+			r.shutdown(osSignals)
+		}
+	}
+}
+
+// reloader replaces the running server with a new one built from the config files.
+type reloader struct {
+	access sync.Mutex
+	server *core.Instance
+	// draining counts the old servers not closed yet.
+	draining sync.WaitGroup
+}
+
+func (r *reloader) reload() error {
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	if files := getConfigFilePath(false); len(files) == 1 && files[0] == "stdin:" {
+		return errors.New("config from STDIN can't be reloaded")
+	}
+	server, err := startXray()
+	if err != nil {
+		return err
 	}
+	server.SetReloader(r.reload)
+	// The listeners use SO_REUSEPORT, so the new server can listen before the old one stops.
+	if err := server.Start(); err != nil {
+		server.Close()
+		return errors.New("failed to start new server").Base(err)
+	}
+
+	old := r.server
+	old.HandOver(server)
+	r.server = server
+	log.Println("Config reloaded, draining the old server for", *drain)
+	r.draining.Add(1)
+	go func() {
+		defer r.draining.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), *drain)
+		defer cancel()
+		if err := old.Drain(ctx); err != nil {
+			log.Println("Failed to drain the old server:", err)
+		}
+		old.Close()
+	}()
+	return nil
+}
+
+// shutdown drains the server for the grace period, or until another signal than SIGHUP arrives.
+func (r *reloader) shutdown(osSignals <-chan os.Signal) {
+	r.access.Lock()
+	server := r.server
+	r.access.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *grace)
+	defer cancel()
+	go func() {
+		for sig := range osSignals {
+			if sig != syscall.SIGHUP {
+				log.Println("Closing the server at once")
+				cancel()
+				return
+			}
+		}
+	}()
+
+	log.Println("Shutting down, waiting up to", *grace, "for the sessions to finish")
+	if err := server.Drain(ctx); err != nil {
+		log.Println("Failed to drain the server:", err)
+	}
+}
+
+func (r *reloader) close() {
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	r.server.Close()
 }
 
 func dumpConfig() int {
@@ -148,7 +252,8 @@ func getRegepxByFormat() string {
 	}
 }
 
-func readConfDir(dirPath string) {
+func readConfDir(dirPath string) cmdarg.Arg {
+	var files cmdarg.Arg
 	confs, err := os.ReadDir(dirPath)
 	if err != nil {
 		log.Fatalln(err)
@@ -159,26 +264,29 @@ func readConfDir(dirPath string) {
 			log.Fatalln(err)
 		}
 		if matched {
-			configFiles.Set(path.Join(dirPath, f.Name()))
+			files.Set(path.Join(dirPath, f.Name()))
 		}
 	}
+	return files
 }
 
 func getConfigFilePath(verbose bool) cmdarg.Arg {
+	// The files in confdir are read again on every call, for reload.
+	files := append(cmdarg.Arg(nil), configFiles...)
 	if dirExists(configDir) {
 		if verbose {
 			log.Println("Using confdir from arg:", configDir)
 		}
-		readConfDir(configDir)
+		files = append(files, readConfDir(configDir)...)
 	} else if envConfDir := platform.GetConfDirPath(); dirExists(envConfDir) {
 		if verbose {
 			log.Println("Using confdir from env:", envConfDir)
 		}
-		readConfDir(envConfDir)
+		files = append(files, readConfDir(envConfDir)...)
 	}
 
-	if len(configFiles) > 0 {
-		return configFiles
+	if len(files) > 0 {
+		return files
 	}
 
 	if workingDir, err := os.Getwd(); err == nil {
@@ -212,7 +320,7 @@ func getConfigFormat() string {
 	return f
 }
 
-func startXray() (core.Server, error) {
+func startXray() (*core.Instance, error) {
 	configFiles := getConfigFilePath(true)
 
 	// config, err := core.LoadConfig(getConfigFormat(), configFiles[0], configFiles)
@@ -0,0 +1,259 @@
+// Package externalauth asks an external backend about the users an inbound doesn't know.
+//
+// VMess is not supported, since its AEAD header can only be matched with the ID of the user, which is the very thing
+// that isn't known.
+package externalauth // import "github.com/xtls/xray-core/proxy/externalauth"
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	defaultTTL         = 5 * time.Minute
+	defaultNegativeTTL = time.Minute
+	defaultTimeout     = 3 * time.Second
+
+	cleanupInterval = time.Minute
+	// maxCacheSize bounds the cache against clients trying random credentials. Rejections aren't cached beyond it,
+	// and authorized users replace other entries, rejections first.
+	maxCacheSize = 1 << 16
+	// evictionSamples is how many entries are looked at to choose the one to replace in a full cache.
+	evictionSamples = 16
+	// maxResponseSize bounds the answer of the HTTP backend.
+	maxResponseSize = 64 << 10
+)
+
+type backend interface {
+	authorize(ctx context.Context, request *AuthorizeRequest) (*AuthorizeResponse, error)
+	close() error
+}
+
+type httpBackend struct {
+	url    string
+	client *http.Client
+}
+
+func (b *httpBackend) authorize(ctx context.Context, request *AuthorizeRequest) (*AuthorizeResponse, error) {
+	body, err := protojson.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.New("unexpected status ", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	response := new(AuthorizeResponse)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, response); err != nil {
+		return nil, errors.New("failed to parse response").Base(err)
+	}
+	return response, nil
+}
+
+func (b *httpBackend) close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
+
+type grpcBackend struct {
+	conn   *grpc.ClientConn
+	client AuthServiceClient
+}
+
+func (b *grpcBackend) authorize(ctx context.Context, request *AuthorizeRequest) (*AuthorizeResponse, error) {
+	return b.client.Authorize(ctx, request)
+}
+
+func (b *grpcBackend) close() error {
+	return b.conn.Close()
+}
+
+type entry struct {
+	// user is nil if the credential is rejected.
+	user   *protocol.MemoryUser
+	expire time.Time
+}
+
+// Authenticator asks the backend about the credentials unknown to an inbound, and caches the answers.
+type Authenticator struct {
+	name        string
+	backend     backend
+	ttl         time.Duration
+	negativeTTL time.Duration
+	timeout     time.Duration
+
+	access      sync.Mutex
+	cache       map[string]*entry
+	lastCleanup time.Time
+	group       singleflight.Group
+	closeOnce   sync.Once
+}
+
+// New creates an Authenticator for the inbound protocol of the given name.
+func New(config *Config, name string) (*Authenticator, error) {
+	a := &Authenticator{
+		name:        name,
+		ttl:         defaultTTL,
+		negativeTTL: defaultNegativeTTL,
+		timeout:     defaultTimeout,
+		cache:       make(map[string]*entry),
+		lastCleanup: time.Now(),
+	}
+	if config.Ttl > 0 {
+		a.ttl = time.Duration(config.Ttl) * time.Second
+	}
+	if config.NegativeTtl > 0 {
+		a.negativeTTL = time.Duration(config.NegativeTtl) * time.Second
+	}
+	if config.Timeout > 0 {
+		a.timeout = time.Duration(config.Timeout) * time.Millisecond
+	}
+
+	switch {
+	case strings.HasPrefix(config.Url, "http://"), strings.HasPrefix(config.Url, "https://"):
+		a.backend = &httpBackend{
+			url:    config.Url,
+			client: &http.Client{Timeout: a.timeout},
+		}
+	case strings.HasPrefix(config.Url, "grpc://"):
+		conn, err := grpc.NewClient(strings.TrimPrefix(config.Url, "grpc://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, errors.New("failed to create gRPC client for ", config.Url).Base(err)
+		}
+		a.backend = &grpcBackend{
+			conn:   conn,
+			client: NewAuthServiceClient(conn),
+		}
+	default:
+		return nil, errors.New("unsupported external auth URL: ", config.Url)
+	}
+	return a, nil
+}
+
+// Close closes the connection to the backend. It may be called more than once, as the inbounds of several networks
+// share the proxy.
+func (a *Authenticator) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		err = a.backend.close()
+	})
+	return err
+}
+
+// TTL returns how long an authorized user is cached.
+func (a *Authenticator) TTL() time.Duration {
+	return a.ttl
+}
+
+// Get returns the user with the credential, asking the backend if the answer isn't cached. newUser makes the user
+// from an authorized answer. Get returns nil if the credential is rejected, or the backend fails.
+func (a *Authenticator) Get(credential string, newUser func(*AuthorizeResponse) (*protocol.MemoryUser, error)) *protocol.MemoryUser {
+	a.access.Lock()
+	e, found := a.cache[credential]
+	a.access.Unlock()
+	if found && time.Now().Before(e.expire) {
+		return e.user
+	}
+
+	u, _, _ := a.group.Do(credential, func() (interface{}, error) {
+		return a.authorize(credential, newUser), nil
+	})
+	return u.(*protocol.MemoryUser)
+}
+
+func (a *Authenticator) authorize(credential string, newUser func(*AuthorizeResponse) (*protocol.MemoryUser, error)) *protocol.MemoryUser {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	response, err := a.backend.authorize(ctx, &AuthorizeRequest{
+		Protocol:   a.name,
+		Credential: credential,
+	})
+	if err != nil {
+		errors.LogWarningInner(ctx, err, "failed to ask external auth backend about ", a.name, " user")
+		return nil
+	}
+
+	var user *protocol.MemoryUser
+	ttl := a.negativeTTL
+	if response.Authorized {
+		if response.Email == "" {
+			errors.LogWarning(ctx, "external auth backend authorized ", a.name, " user without email")
+			return nil
+		}
+		user, err = newUser(response)
+		if err != nil {
+			errors.LogWarningInner(ctx, err, "invalid ", a.name, " user ", response.Email, " from external auth backend")
+			return nil
+		}
+		ttl = a.ttl
+	}
+
+	now := time.Now()
+	a.access.Lock()
+	defer a.access.Unlock()
+
+	if now.Sub(a.lastCleanup) >= cleanupInterval {
+		for c, e := range a.cache {
+			if now.After(e.expire) {
+				delete(a.cache, c)
+			}
+		}
+		a.lastCleanup = now
+	}
+	if _, found := a.cache[credential]; !found && len(a.cache) >= maxCacheSize {
+		if user == nil {
+			return nil
+		}
+		a.evict()
+	}
+	a.cache[credential] = &entry{user: user, expire: now.Add(ttl)}
+	return user
+}
+
+// evict removes an entry of the full cache, a rejection if one is found among some samples, or else the one of them
+// that expires first. The caller must hold the lock.
+func (a *Authenticator) evict() {
+	var victim string
+	var victimExpire time.Time
+	n := 0
+	for c, e := range a.cache {
+		if e.user == nil {
+			victim = c
+			break
+		}
+		if n == 0 || e.expire.Before(victimExpire) {
+			victim, victimExpire = c, e.expire
+		}
+		if n++; n >= evictionSamples {
+			break
+		}
+	}
+	delete(a.cache, victim)
+}
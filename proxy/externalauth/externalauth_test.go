@@ -0,0 +1,92 @@
+package externalauth_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol"
+	. "github.com/xtls/xray-core/proxy/externalauth"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func newUser(r *AuthorizeResponse) (*protocol.MemoryUser, error) {
+	return &protocol.MemoryUser{Email: r.Email, Level: r.Level}, nil
+}
+
+func TestAuthenticatorHTTP(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		request := new(AuthorizeRequest)
+		common.Must(protojson.Unmarshal(body, request))
+		response := &AuthorizeResponse{}
+		if request.Protocol == "vless" && request.Credential == "good" {
+			response = &AuthorizeResponse{Authorized: true, Email: "love@xray.com", Level: 1}
+		}
+		data, _ := protojson.Marshal(response)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	a, err := New(&Config{Url: server.URL, Ttl: 1, NegativeTtl: 1}, "vless")
+	common.Must(err)
+
+	user := a.Get("good", newUser)
+	if user == nil || user.Email != "love@xray.com" || user.Level != 1 {
+		t.Fatal("unexpected user: ", user)
+	}
+	if a.Get("good", newUser) != user {
+		t.Error("expected cached user")
+	}
+	if a.Get("bad", newUser) != nil || a.Get("bad", newUser) != nil {
+		t.Error("expected rejection")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Error("expected 2 calls to the backend, but got ", n)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if a.Get("good", newUser) == user {
+		t.Error("expected user to be asked for again after TTL")
+	}
+	if n := calls.Load(); n != 3 {
+		t.Error("expected 3 calls to the backend, but got ", n)
+	}
+}
+
+func TestAuthenticatorBackendFailure(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	a, err := New(&Config{Url: server.URL}, "trojan")
+	common.Must(err)
+
+	if a.Get("any", newUser) != nil || a.Get("any", newUser) != nil {
+		t.Error("expected no user")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Error("expected failures not to be cached, but got ", n, " calls")
+	}
+}
+
+func TestAuthenticatorClose(t *testing.T) {
+	a, err := New(&Config{Url: "grpc://127.0.0.1:1"}, "vless")
+	common.Must(err)
+
+	common.Must(a.Close())
+	// The inbounds of several networks share the proxy, so it may be closed again.
+	common.Must(a.Close())
+	if a.Get("any", newUser) != nil {
+		t.Error("expected no user after close")
+	}
+}
@@ -129,7 +129,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 
 	p := c.policyManager.ForLevel(0)
 	if user != nil {
-		p = c.policyManager.ForLevel(user.Level)
+		p = policy.ForUser(c.policyManager, user)
 	}
 
 	var newCtx context.Context
@@ -0,0 +1,209 @@
+package hysteria2
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/retry"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/hysteria2"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// Client is an outbound connection handler for Hysteria2 protocol.
+type Client struct {
+	serverPicker  protocol.ServerPicker
+	policyManager policy.Manager
+}
+
+// NewClient creates a new Hysteria2 client.
+func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
+	serverList := protocol.NewServerList()
+	for _, rec := range config.Server {
+		s, err := protocol.NewServerSpecFromPB(rec)
+		if err != nil {
+			return nil, errors.New("failed to parse server spec").Base(err)
+		}
+		serverList.AddServer(s)
+	}
+	if serverList.Size() == 0 {
+		return nil, errors.New("0 server")
+	}
+
+	v := core.MustFromContext(ctx)
+	return &Client{
+		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
+		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+	}, nil
+}
+
+// Process implements OutboundHandler.Process().
+func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
+	outbounds := session.OutboundsFromContext(ctx)
+	ob := outbounds[len(outbounds)-1]
+	if !ob.Target.IsValid() {
+		return errors.New("target not specified")
+	}
+	ob.Name = "hysteria2"
+	ob.CanSpliceCopy = 3
+	destination := ob.Target
+
+	var server *protocol.ServerSpec
+	var user *protocol.MemoryUser
+	var conn stat.Connection
+	err := retry.ExponentialBackoff(5, 100).On(func() error {
+		server = c.serverPicker.PickServer()
+		user = server.PickUser()
+		account, ok := user.Account.(*MemoryAccount)
+		if !ok {
+			return errors.New("user account is not valid")
+		}
+		rawConn, err := dialer.Dial(hysteria2.ContextWithAuth(ctx, account.Password), server.Destination())
+		if err != nil {
+			return err
+		}
+		conn = rawConn
+		return nil
+	})
+	if err != nil {
+		return errors.New("failed to find an available destination").AtWarning().Base(err)
+	}
+	errors.LogInfo(ctx, "tunneling request to ", destination, " via ", server.Destination().NetAddr())
+	defer conn.Close()
+
+	iConn := conn
+	if statConn, ok := iConn.(*stat.CounterConnection); ok {
+		iConn = statConn.Connection
+	}
+	hyConn, ok := iConn.(*hysteria2.ClientConn)
+	if !ok {
+		return errors.New("Hysteria2 outbound requires the Hysteria2 transport")
+	}
+
+	sessionPolicy := policy.ForUser(c.policyManager, user)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+
+	if destination.Network == net.Network_UDP {
+		return c.processUDP(ctx, sessionPolicy, timer, hyConn, destination, link)
+	}
+
+	postRequest := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		if err := WriteTCPRequest(conn, destination.NetAddr()); err != nil {
+			return errors.New("failed to write request").Base(err).AtWarning()
+		}
+		if err := buf.Copy(link.Reader, buf.NewWriter(conn), buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transfer request payload").Base(err).AtInfo()
+		}
+		return nil
+	}
+
+	getResponse := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		ok, message, err := ReadTCPResponse(conn)
+		if err != nil {
+			return errors.New("failed to read response").Base(err)
+		}
+		if !ok {
+			return errors.New("server failed to connect to ", destination, ": ", message)
+		}
+		return buf.Copy(buf.NewReader(conn), link.Writer, buf.UpdateActivity(timer))
+	}
+
+	responseDoneAndCloseWriter := task.OnSuccess(getResponse, task.Close(link.Writer))
+	if err := task.Run(ctx, postRequest, responseDoneAndCloseWriter); err != nil {
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
+
+func (c *Client) processUDP(ctx context.Context, sessionPolicy policy.Session, timer *signal.ActivityTimer, conn *hysteria2.ClientConn, destination net.Destination, link *transport.Link) error {
+	udpSession, err := conn.ListenUDP()
+	if err != nil {
+		return err
+	}
+	defer udpSession.Close()
+
+	var packetID uint16
+	postRequest := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		for {
+			mb, err := link.Reader.ReadMultiBuffer()
+			if err != nil {
+				return nil
+			}
+			timer.Update()
+			for _, b := range mb {
+				target := destination
+				if b.UDP != nil {
+					target = *b.UDP
+				}
+				packetID++
+				message := &UDPMessage{
+					SessionID: udpSession.ID,
+					PacketID:  packetID,
+					FragCount: 1,
+					Address:   target.NetAddr(),
+					Payload:   b.Bytes(),
+				}
+				if err := sendUDPMessage(udpSession.Send, message); err != nil {
+					errors.LogInfoInner(ctx, err, "failed to send UDP packet to ", target)
+				}
+				b.Release()
+			}
+		}
+	}
+
+	getResponse := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		var defragger Defragger
+		for {
+			datagram, err := udpSession.Receive()
+			if err != nil {
+				return nil
+			}
+			message, err := ParseUDPMessage(datagram)
+			if err != nil {
+				errors.LogDebugInner(ctx, err, "invalid UDP message")
+				continue
+			}
+			if message = defragger.Feed(message); message == nil {
+				continue
+			}
+			source, err := net.ParseDestination("udp:" + message.Address)
+			if err != nil {
+				errors.LogDebugInner(ctx, err, "invalid address ", message.Address)
+				continue
+			}
+			b := buf.FromBytes(message.Payload)
+			b.UDP = &source
+			if err := link.Writer.WriteMultiBuffer(buf.MultiBuffer{b}); err != nil {
+				return err
+			}
+			timer.Update()
+		}
+	}
+
+	responseDoneAndCloseWriter := task.OnSuccess(getResponse, task.Close(link.Writer))
+	if err := task.Run(ctx, postRequest, responseDoneAndCloseWriter); err != nil {
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*ClientConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return NewClient(ctx, config.(*ClientConfig))
+	}))
+}
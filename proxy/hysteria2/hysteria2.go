@@ -0,0 +1,3 @@
+// Package hysteria2 is the proxy of Hysteria2, which relays TCP by the streams and UDP by the datagrams of the
+// connections of the Hysteria2 transport.
+package hysteria2
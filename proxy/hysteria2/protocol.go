@@ -0,0 +1,228 @@
+package hysteria2
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/apernet/quic-go/quicvarint"
+	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const (
+	maxAddressLength = 2048
+	maxMessageLength = 2048
+	maxPaddingLength = 4096
+
+	tcpStatusOK    = 0
+	tcpStatusError = 1
+
+	// udpHeaderSize is the size of a UDP message without its address.
+	udpHeaderSize = 4 + 2 + 1 + 1
+)
+
+func appendPadding(b []byte, minSize, maxSize int) []byte {
+	padding := make([]byte, minSize+dice.Roll(maxSize-minSize))
+	rand.Read(padding)
+	b = quicvarint.Append(b, uint64(len(padding)))
+	return append(b, padding...)
+}
+
+func readString(r quicvarint.Reader, maxLength uint64) (string, error) {
+	length, err := quicvarint.Read(r)
+	if err != nil {
+		return "", err
+	}
+	if length > maxLength {
+		return "", errors.New("length ", length, " is larger than ", maxLength)
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func skipPadding(r quicvarint.Reader) error {
+	length, err := quicvarint.Read(r)
+	if err != nil {
+		return err
+	}
+	if length > maxPaddingLength {
+		return errors.New("padding ", length, " is larger than ", maxPaddingLength)
+	}
+	_, err = io.CopyN(io.Discard, r, int64(length))
+	return err
+}
+
+// WriteTCPRequest writes the request of a stream for address, as "host:port".
+func WriteTCPRequest(w io.Writer, address string) error {
+	b := quicvarint.Append(nil, uint64(len(address)))
+	b = append(b, address...)
+	b = appendPadding(b, 64, 512)
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadTCPRequest reads the request of a stream, returning its address.
+func ReadTCPRequest(r io.Reader) (string, error) {
+	qr := quicvarint.NewReader(r)
+	address, err := readString(qr, maxAddressLength)
+	if err != nil {
+		return "", errors.New("failed to read address").Base(err)
+	}
+	if err := skipPadding(qr); err != nil {
+		return "", errors.New("failed to read padding").Base(err)
+	}
+	return address, nil
+}
+
+// WriteTCPResponse writes the response to the request of a stream, with the message of its error if not ok.
+func WriteTCPResponse(w io.Writer, ok bool, message string) error {
+	b := []byte{tcpStatusOK}
+	if !ok {
+		b[0] = tcpStatusError
+	}
+	b = quicvarint.Append(b, uint64(len(message)))
+	b = append(b, message...)
+	b = appendPadding(b, 128, 1024)
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadTCPResponse reads the response to the request of a stream.
+func ReadTCPResponse(r io.Reader) (bool, string, error) {
+	qr := quicvarint.NewReader(r)
+	status, err := qr.ReadByte()
+	if err != nil {
+		return false, "", errors.New("failed to read status").Base(err)
+	}
+	message, err := readString(qr, maxMessageLength)
+	if err != nil {
+		return false, "", errors.New("failed to read message").Base(err)
+	}
+	if err := skipPadding(qr); err != nil {
+		return false, "", errors.New("failed to read padding").Base(err)
+	}
+	return status == tcpStatusOK, message, nil
+}
+
+// UDPMessage is a UDP packet, or a fragment of it, in a datagram.
+type UDPMessage struct {
+	SessionID uint32
+	PacketID  uint16
+	FragID    uint8
+	FragCount uint8
+	Address   string
+	Payload   []byte
+}
+
+func (m *UDPMessage) headerSize() int {
+	return udpHeaderSize + int(quicvarint.Len(uint64(len(m.Address)))) + len(m.Address)
+}
+
+// Bytes returns the datagram of m.
+func (m *UDPMessage) Bytes() []byte {
+	b := make([]byte, udpHeaderSize, m.headerSize()+len(m.Payload))
+	binary.BigEndian.PutUint32(b, m.SessionID)
+	binary.BigEndian.PutUint16(b[4:], m.PacketID)
+	b[6] = m.FragID
+	b[7] = m.FragCount
+	b = quicvarint.Append(b, uint64(len(m.Address)))
+	b = append(b, m.Address...)
+	return append(b, m.Payload...)
+}
+
+// ParseUDPMessage parses a datagram. The payload of the message is a part of b.
+func ParseUDPMessage(b []byte) (*UDPMessage, error) {
+	if len(b) < udpHeaderSize {
+		return nil, errors.New("UDP message is too short")
+	}
+	m := &UDPMessage{
+		SessionID: binary.BigEndian.Uint32(b),
+		PacketID:  binary.BigEndian.Uint16(b[4:]),
+		FragID:    b[6],
+		FragCount: b[7],
+	}
+	length, n, err := quicvarint.Parse(b[udpHeaderSize:])
+	if err != nil {
+		return nil, errors.New("failed to read address length").Base(err)
+	}
+	b = b[udpHeaderSize+n:]
+	if length == 0 || length > maxAddressLength || length > uint64(len(b)) {
+		return nil, errors.New("invalid address length ", length)
+	}
+	m.Address = string(b[:length])
+	m.Payload = b[length:]
+	if m.FragCount == 0 || m.FragID >= m.FragCount {
+		return nil, errors.New("invalid fragment ", m.FragID, " of ", m.FragCount)
+	}
+	return m, nil
+}
+
+// FragmentUDPMessage splits m into the messages no larger than maxSize, or returns it as is if it fits.
+func FragmentUDPMessage(m *UDPMessage, maxSize int) []*UDPMessage {
+	if m.headerSize()+len(m.Payload) <= maxSize {
+		return []*UDPMessage{m}
+	}
+	size := maxSize - m.headerSize()
+	if size <= 0 {
+		return nil
+	}
+	count := (len(m.Payload) + size - 1) / size
+	if count > 255 {
+		return nil
+	}
+	fragments := make([]*UDPMessage, 0, count)
+	for i := 0; i < count; i++ {
+		fragment := *m
+		fragment.FragID = uint8(i)
+		fragment.FragCount = uint8(count)
+		fragment.Payload = m.Payload[i*size : min((i+1)*size, len(m.Payload))]
+		fragments = append(fragments, &fragment)
+	}
+	return fragments
+}
+
+// Defragger assembles the fragments of the latest packet of a UDP session.
+type Defragger struct {
+	packetID  uint16
+	fragments []*UDPMessage
+	count     int
+	size      int
+}
+
+// Feed returns the packet m completes, or nil if it's yet to be completed.
+func (d *Defragger) Feed(m *UDPMessage) *UDPMessage {
+	if m.FragCount == 1 {
+		return m
+	}
+	if d.fragments == nil || m.PacketID != d.packetID || int(m.FragCount) != len(d.fragments) {
+		// A new packet, so the previous one is lost.
+		d.packetID = m.PacketID
+		d.fragments = make([]*UDPMessage, m.FragCount)
+		d.count = 0
+		d.size = 0
+	}
+	if d.fragments[m.FragID] != nil {
+		return nil
+	}
+	// The payload is a part of a datagram, which quic-go doesn't reuse.
+	d.fragments[m.FragID] = m
+	d.count++
+	d.size += len(m.Payload)
+	if d.count < len(d.fragments) {
+		return nil
+	}
+	payload := make([]byte, 0, d.size)
+	for _, fragment := range d.fragments {
+		payload = append(payload, fragment.Payload...)
+	}
+	packet := *m
+	packet.FragID = 0
+	packet.FragCount = 1
+	packet.Payload = payload
+	d.fragments = nil
+	return &packet
+}
@@ -0,0 +1,97 @@
+package hysteria2_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/proxy/hysteria2"
+)
+
+func TestTCPRequest(t *testing.T) {
+	var buffer bytes.Buffer
+	common.Must(WriteTCPRequest(&buffer, "example.com:443"))
+	common.Must(WriteTCPResponse(&buffer, false, "connection refused"))
+
+	address, err := ReadTCPRequest(&buffer)
+	common.Must(err)
+	if address != "example.com:443" {
+		t.Error("address: ", address)
+	}
+
+	ok, message, err := ReadTCPResponse(&buffer)
+	common.Must(err)
+	if ok || message != "connection refused" {
+		t.Error("response: ", ok, " ", message)
+	}
+	if buffer.Len() != 0 {
+		t.Error("unread bytes: ", buffer.Len())
+	}
+}
+
+func TestUDPMessage(t *testing.T) {
+	message := &UDPMessage{
+		SessionID: 1,
+		PacketID:  2,
+		FragCount: 1,
+		Address:   "127.0.0.1:53",
+		Payload:   []byte("test string"),
+	}
+	parsed, err := ParseUDPMessage(message.Bytes())
+	common.Must(err)
+	if r := cmp.Diff(parsed, message); r != "" {
+		t.Error(r)
+	}
+
+	if _, err := ParseUDPMessage(message.Bytes()[:10]); err == nil {
+		t.Error("expected error for truncated message")
+	}
+}
+
+func TestUDPFragment(t *testing.T) {
+	payload := make([]byte, 3000)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	message := &UDPMessage{
+		SessionID: 1,
+		PacketID:  2,
+		FragCount: 1,
+		Address:   "127.0.0.1:53",
+		Payload:   payload,
+	}
+	fragments := FragmentUDPMessage(message, 1200)
+	if len(fragments) != 3 {
+		t.Fatal("fragments: ", len(fragments))
+	}
+
+	var defragger Defragger
+	// A fragment of another packet is dropped by the fragments of a newer one.
+	defragger.Feed(&UDPMessage{PacketID: 1, FragID: 0, FragCount: 2, Payload: []byte("lost")})
+	for i := len(fragments) - 1; i >= 0; i-- {
+		b := fragments[i].Bytes()
+		if len(b) > 1200 {
+			t.Error("fragment size: ", len(b))
+		}
+		fragment, err := ParseUDPMessage(b)
+		common.Must(err)
+		packet := defragger.Feed(fragment)
+		if i > 0 {
+			if packet != nil {
+				t.Error("unexpected packet before the last fragment")
+			}
+			continue
+		}
+		if packet == nil {
+			t.Fatal("expected packet after the last fragment")
+		}
+		if r := cmp.Diff(packet, message); r != "" {
+			t.Error(r)
+		}
+	}
+
+	if FragmentUDPMessage(message, 20) != nil {
+		t.Error("expected no fragments for a size smaller than the header")
+	}
+}
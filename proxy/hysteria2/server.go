@@ -0,0 +1,363 @@
+package hysteria2
+
+import (
+	"context"
+	goerrors "errors"
+	"sync"
+	"time"
+
+	"github.com/apernet/quic-go"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	udp_proto "github.com/xtls/xray-core/common/protocol/udp"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/internet/hysteria2"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/udp"
+)
+
+// udpSessionTimeout is how long the UDP session of a client is kept without any packet.
+const udpSessionTimeout = 2 * time.Minute
+
+func init() {
+	common.Must(common.RegisterConfig((*ServerConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return NewServer(ctx, config.(*ServerConfig))
+	}))
+}
+
+// Server is an inbound connection handler that handles the connections of the Hysteria2 transport.
+type Server struct {
+	policyManager policy.Manager
+	validator     *Validator
+	usersAccess   sync.Mutex
+}
+
+// NewServer creates a new Hysteria2 inbound handler.
+func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
+	validator := new(Validator)
+	for _, user := range config.Users {
+		u, err := user.ToMemoryUser()
+		if err != nil {
+			return nil, errors.New("failed to get Hysteria2 user").Base(err).AtError()
+		}
+		if err := validator.Add(u); err != nil {
+			return nil, errors.New("failed to add user").Base(err).AtError()
+		}
+	}
+
+	v := core.MustFromContext(ctx)
+	return &Server{
+		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		validator:     validator,
+	}, nil
+}
+
+// Authenticate implements internet.Authenticator.
+func (s *Server) Authenticate(credential string) bool {
+	return s.validator.Get(credential) != nil
+}
+
+// AddUser implements proxy.UserManager.AddUser().
+func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
+	if _, ok := u.Account.(*MemoryAccount); !ok {
+		return errors.New("User ", u.Email, " is not a Hysteria2 user.")
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return s.validator.Add(u)
+}
+
+// RemoveUser implements proxy.UserManager.RemoveUser().
+func (s *Server) RemoveUser(ctx context.Context, e string) error {
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return s.validator.Del(e)
+}
+
+// SyncUsers implements proxy.UserManager.SyncUsers().
+func (s *Server) SyncUsers(ctx context.Context, users []*protocol.MemoryUser) (int, int, error) {
+	for _, u := range users {
+		if _, ok := u.Account.(*MemoryAccount); !ok {
+			return 0, 0, errors.New("User ", u.Email, " is not a Hysteria2 user.")
+		}
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return proxy.ApplyUsers(s.validator.GetAll(), users, s.validator)
+}
+
+// UpdateUser implements proxy.UserManager.UpdateUser().
+func (s *Server) UpdateUser(ctx context.Context, u *protocol.MemoryUser, overlap time.Duration) error {
+	if _, ok := u.Account.(*MemoryAccount); !ok {
+		return errors.New("User ", u.Email, " is not a Hysteria2 user.")
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return s.validator.Update(u, overlap)
+}
+
+// GetUser implements proxy.UserManager.GetUser().
+func (s *Server) GetUser(ctx context.Context, email string) *protocol.MemoryUser {
+	return s.validator.GetByEmail(email)
+}
+
+// GetUsers implements proxy.UserManager.GetUsers().
+func (s *Server) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return s.validator.GetAll()
+}
+
+// GetUsersCount implements proxy.UserManager.GetUsersCount().
+func (s *Server) GetUsersCount(context.Context) int64 {
+	return s.validator.GetCount()
+}
+
+// Network implements proxy.Inbound.Network().
+func (s *Server) Network() []net.Network {
+	return []net.Network{net.Network_TCP}
+}
+
+// Process implements proxy.Inbound.Process(). conn is a whole Hysteria2 connection, whose TCP streams and UDP
+// sessions are dispatched until it's closed.
+func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Connection, dispatcher routing.Dispatcher) error {
+	iConn := conn
+	statConn, ok := iConn.(*stat.CounterConnection)
+	if ok {
+		iConn = statConn.Connection
+	}
+	hyConn, ok := iConn.(*hysteria2.Conn)
+	if !ok {
+		return errors.New("Hysteria2 inbound requires the Hysteria2 transport")
+	}
+
+	user := s.validator.Get(hyConn.Auth())
+	if user == nil {
+		return errors.New("user of ", conn.RemoteAddr(), " is removed")
+	}
+	inbound := session.InboundFromContext(ctx)
+	inbound.Name = "hysteria2"
+	inbound.CanSpliceCopy = 3
+	inbound.User = user
+
+	release, err := policy.TrackUser(s.policyManager, user, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   conn.RemoteAddr(),
+			To:     "",
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  user.Email,
+		})
+		return errors.New("rejected request from ", conn.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer release()
+
+	sessionPolicy := policy.ForUser(s.policyManager, user)
+	go s.handleUDP(ctx, hyConn, user, dispatcher)
+	for {
+		stream, err := hyConn.AcceptTCP(ctx)
+		if err != nil {
+			return nil
+		}
+		if statConn != nil {
+			stream = &stat.CounterConnection{
+				Connection:   stream,
+				ReadCounter:  statConn.ReadCounter,
+				WriteCounter: statConn.WriteCounter,
+			}
+		}
+		go func() {
+			if err := s.handleStream(ctx, sessionPolicy, user, stream, dispatcher); err != nil {
+				errors.LogInfoInner(ctx, err, "stream ends")
+			}
+			stream.Close()
+		}()
+	}
+}
+
+// streamContext returns the context of a stream or a UDP session of the connection of ctx.
+func streamContext(ctx context.Context) context.Context {
+	ctx = session.ContextCloneOutbounds(ctx)
+	if content := session.ContentFromContext(ctx); content != nil {
+		ctx = session.ContextWithContent(ctx, &session.Content{
+			SniffingRequest: content.SniffingRequest,
+		})
+	}
+	return ctx
+}
+
+func (s *Server) handleStream(ctx context.Context, sessionPolicy policy.Session, user *protocol.MemoryUser, stream stat.Connection, dispatcher routing.Dispatcher) error {
+	if err := stream.SetReadDeadline(time.Now().Add(sessionPolicy.Timeouts.Handshake)); err != nil {
+		return errors.New("unable to set read deadline").Base(err).AtWarning()
+	}
+	address, err := ReadTCPRequest(stream)
+	if err != nil {
+		return errors.New("failed to read request").Base(err)
+	}
+	if err := stream.SetReadDeadline(time.Time{}); err != nil {
+		return errors.New("unable to set read deadline").Base(err).AtWarning()
+	}
+	destination, err := net.ParseDestination("tcp:" + address)
+	if err != nil {
+		WriteTCPResponse(stream, false, err.Error())
+		return errors.New("invalid address ", address).Base(err)
+	}
+
+	ctx = streamContext(ctx)
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   stream.RemoteAddr(),
+		To:     destination,
+		Status: log.AccessAccepted,
+		Reason: "",
+		Email:  user.Email,
+	})
+	errors.LogInfo(ctx, "received request for ", destination)
+
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
+
+	link, err := dispatcher.Dispatch(ctx, destination)
+	if err != nil {
+		WriteTCPResponse(stream, false, err.Error())
+		return errors.New("failed to dispatch request to ", destination).Base(err)
+	}
+	// The outbound connects in the background, so the client sends its payload right away.
+	if err := WriteTCPResponse(stream, true, ""); err != nil {
+		common.Must(common.Interrupt(link.Reader))
+		common.Must(common.Interrupt(link.Writer))
+		return errors.New("failed to write response").Base(err)
+	}
+
+	requestDone := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		if err := buf.Copy(buf.NewReader(stream), link.Writer, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transfer request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		if err := buf.Copy(link.Reader, buf.NewWriter(stream), buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to write response").Base(err)
+		}
+		return nil
+	}
+
+	requestDonePost := task.OnSuccess(requestDone, task.Close(link.Writer))
+	if err := task.Run(ctx, requestDonePost, responseDone); err != nil {
+		common.Must(common.Interrupt(link.Reader))
+		common.Must(common.Interrupt(link.Writer))
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
+
+type serverUDPSession struct {
+	dispatcher *udp.Dispatcher
+	defragger  Defragger
+	lastActive time.Time
+}
+
+// handleUDP dispatches the UDP sessions in the datagrams of conn.
+func (s *Server) handleUDP(ctx context.Context, conn *hysteria2.Conn, user *protocol.MemoryUser, dispatcher routing.Dispatcher) {
+	sessions := make(map[uint32]*serverUDPSession)
+	for {
+		datagram, err := conn.ReceiveDatagram(ctx)
+		if err != nil {
+			return
+		}
+		message, err := ParseUDPMessage(datagram)
+		if err != nil {
+			errors.LogDebugInner(ctx, err, "invalid UDP message")
+			continue
+		}
+		now := time.Now()
+		udpSession := sessions[message.SessionID]
+		if udpSession == nil {
+			for id, s := range sessions {
+				if now.Sub(s.lastActive) > udpSessionTimeout {
+					s.dispatcher.RemoveRay()
+					delete(sessions, id)
+				}
+			}
+			udpSession = &serverUDPSession{
+				dispatcher: udp.NewDispatcher(dispatcher, newUDPResponder(conn, message.SessionID)),
+			}
+			sessions[message.SessionID] = udpSession
+		}
+		udpSession.lastActive = now
+
+		message = udpSession.defragger.Feed(message)
+		if message == nil {
+			continue
+		}
+		destination, err := net.ParseDestination("udp:" + message.Address)
+		if err != nil {
+			errors.LogDebugInner(ctx, err, "invalid address ", message.Address)
+			continue
+		}
+		payload := buf.FromBytes(message.Payload)
+		payload.UDP = &destination
+
+		packetCtx := log.ContextWithAccessMessage(streamContext(ctx), &log.AccessMessage{
+			From:   conn.RemoteAddr(),
+			To:     destination,
+			Status: log.AccessAccepted,
+			Reason: "",
+			Email:  user.Email,
+		})
+		udpSession.dispatcher.Dispatch(packetCtx, destination, payload)
+	}
+}
+
+// newUDPResponder returns the callback sending the responses of a UDP session back to the client.
+func newUDPResponder(conn *hysteria2.Conn, sessionID uint32) udp.ResponseCallback {
+	var access sync.Mutex
+	var packetID uint16
+	return func(ctx context.Context, packet *udp_proto.Packet) {
+		defer packet.Payload.Release()
+		access.Lock()
+		packetID++
+		message := &UDPMessage{
+			SessionID: sessionID,
+			PacketID:  packetID,
+			FragCount: 1,
+			Address:   packet.Source.NetAddr(),
+			Payload:   packet.Payload.Bytes(),
+		}
+		access.Unlock()
+		if err := sendUDPMessage(conn.SendDatagram, message); err != nil {
+			errors.LogInfoInner(ctx, err, "failed to write UDP response")
+		}
+	}
+}
+
+// sendUDPMessage sends message by send, fragmenting it if it doesn't fit in a datagram.
+func sendUDPMessage(send func([]byte) error, message *UDPMessage) error {
+	err := send(message.Bytes())
+	var tooLarge *quic.DatagramTooLargeError
+	if !goerrors.As(err, &tooLarge) {
+		return err
+	}
+	fragments := FragmentUDPMessage(message, int(tooLarge.MaxDataLen))
+	if fragments == nil {
+		return errors.New("UDP packet of ", len(message.Payload), " bytes is too large")
+	}
+	for _, fragment := range fragments {
+		if err := send(fragment.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package hysteria2
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/proxy"
+)
+
+// Validator stores valid Hysteria2 users.
+type Validator struct {
+	access sync.RWMutex
+	email  map[string]*protocol.MemoryUser
+	users  map[string]*protocol.MemoryUser
+	// retired holds the password a user changed from, while it is still accepted.
+	retired map[string]*retiredPassword
+}
+
+type retiredPassword struct {
+	password string
+}
+
+// initMaps creates the maps of a zero Validator. The caller must hold the lock.
+func (v *Validator) initMaps() {
+	if v.users == nil {
+		v.email = make(map[string]*protocol.MemoryUser)
+		v.users = make(map[string]*protocol.MemoryUser)
+		v.retired = make(map[string]*retiredPassword)
+	}
+}
+
+// Add a Hysteria2 user, Email must be empty or unique.
+func (v *Validator) Add(u *protocol.MemoryUser) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.add(u)
+}
+
+func (v *Validator) add(u *protocol.MemoryUser) error {
+	password := u.Account.(*MemoryAccount).Password
+	if _, found := v.users[password]; found {
+		return errors.New("User ", u.Email, " has the password of another user.")
+	}
+	if u.Email != "" {
+		le := strings.ToLower(u.Email)
+		if _, found := v.email[le]; found {
+			return errors.New("User ", u.Email, " already exists.")
+		}
+		v.email[le] = u
+	}
+	v.users[password] = u
+	return nil
+}
+
+// Del a Hysteria2 user with a non-empty Email.
+func (v *Validator) Del(e string) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.del(e)
+}
+
+func (v *Validator) del(e string) error {
+	if e == "" {
+		return errors.New("Email must not be empty.")
+	}
+	le := strings.ToLower(e)
+	u := v.email[le]
+	if u == nil {
+		return errors.New("User ", e, " not found.")
+	}
+	delete(v.email, le)
+	delete(v.users, u.Account.(*MemoryAccount).Password)
+	v.unretire(le)
+	return nil
+}
+
+// Update replaces the Hysteria2 user with the same non-empty Email. If the password changes, the old one is still
+// accepted for the updated user for overlap.
+func (v *Validator) Update(u *protocol.MemoryUser, overlap time.Duration) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.update(u, overlap)
+}
+
+func (v *Validator) update(u *protocol.MemoryUser, overlap time.Duration) error {
+	if u.Email == "" {
+		return errors.New("Email must not be empty.")
+	}
+	le := strings.ToLower(u.Email)
+	old := v.email[le]
+	if old == nil {
+		return errors.New("User ", u.Email, " not found.")
+	}
+	oldPassword := old.Account.(*MemoryAccount).Password
+	newPassword := u.Account.(*MemoryAccount).Password
+
+	if other := v.users[newPassword]; other != nil && !strings.EqualFold(other.Email, u.Email) {
+		return errors.New("User ", u.Email, " has the password of another user.")
+	}
+	if oldPassword == newPassword {
+		v.users[newPassword] = u
+		v.email[le] = u
+		return nil
+	}
+	v.unretire(le)
+	v.users[newPassword] = u
+	v.email[le] = u
+	if overlap <= 0 {
+		delete(v.users, oldPassword)
+		return nil
+	}
+	v.users[oldPassword] = u
+	r := &retiredPassword{password: oldPassword}
+	v.retired[le] = r
+	time.AfterFunc(overlap, func() {
+		v.access.Lock()
+		defer v.access.Unlock()
+		if v.retired[le] == r {
+			delete(v.retired, le)
+			delete(v.users, oldPassword)
+		}
+	})
+	return nil
+}
+
+// unretire stops accepting the password the user changed from. The caller must hold the lock.
+func (v *Validator) unretire(le string) {
+	if r, found := v.retired[le]; found {
+		delete(v.retired, le)
+		delete(v.users, r.password)
+	}
+}
+
+// Replace implements proxy.UserSet, with a proxy.UserEditor of a copy of the users.
+func (v *Validator) Replace(remove []string, update, add []*protocol.MemoryUser) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	next := &Validator{
+		email:   maps.Clone(v.email),
+		users:   maps.Clone(v.users),
+		retired: maps.Clone(v.retired),
+	}
+	next.initMaps()
+	err := proxy.UserEditor{
+		Remove: next.del,
+		Update: func(u *protocol.MemoryUser) error { return next.update(u, 0) },
+		Add:    next.add,
+	}.Replace(remove, update, add)
+	if err != nil {
+		return err
+	}
+	v.email, v.users, v.retired = next.email, next.users, next.retired
+	return nil
+}
+
+// Get a Hysteria2 user with password, nil if user doesn't exist.
+func (v *Validator) Get(password string) *protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return v.users[password]
+}
+
+// GetByEmail returns a Hysteria2 user with email, nil if user doesn't exist.
+func (v *Validator) GetByEmail(email string) *protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return v.email[strings.ToLower(email)]
+}
+
+// GetAll returns all users.
+func (v *Validator) GetAll() []*protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return slices.Collect(maps.Values(v.email))
+}
+
+// GetCount returns the count of users.
+func (v *Validator) GetCount() int64 {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return int64(len(v.email))
+}
@@ -0,0 +1,44 @@
+package hysteria2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol"
+	. "github.com/xtls/xray-core/proxy/hysteria2"
+)
+
+func toAccount(a *Account) protocol.Account {
+	account, err := a.AsAccount()
+	common.Must(err)
+	return account
+}
+
+func TestValidatorUpdate(t *testing.T) {
+	v := new(Validator)
+	user := &protocol.MemoryUser{Email: "love@xray.com", Account: toAccount(&Account{Password: "old"})}
+	common.Must(v.Add(user))
+
+	if err := v.Add(&protocol.MemoryUser{Email: "other@xray.com", Account: toAccount(&Account{Password: "old"})}); err == nil {
+		t.Error("expected error for duplicate password")
+	}
+
+	rotated := &protocol.MemoryUser{Email: "love@xray.com", Level: 1, Account: toAccount(&Account{Password: "new"})}
+	common.Must(v.Update(rotated, 100*time.Millisecond))
+	if v.Get("new") != rotated {
+		t.Error("expected new password to be accepted")
+	}
+	if v.Get("old") != rotated {
+		t.Error("expected old password to be accepted for the updated user during overlap")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if v.Get("old") != nil {
+		t.Error("expected old password to be rejected after overlap")
+	}
+
+	common.Must(v.Del("love@xray.com"))
+	if v.Get("new") != nil || v.GetCount() != 0 {
+		t.Error("expected user to be removed")
+	}
+}
@@ -0,0 +1,198 @@
+package masque
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/retry"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/masque"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// Client is an outbound connection handler for MASQUE proxies.
+type Client struct {
+	serverPicker  protocol.ServerPicker
+	policyManager policy.Manager
+}
+
+// NewClient creates a new MASQUE client.
+func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
+	serverList := protocol.NewServerList()
+	for _, rec := range config.Server {
+		s, err := protocol.NewServerSpecFromPB(rec)
+		if err != nil {
+			return nil, errors.New("failed to parse server spec").Base(err)
+		}
+		serverList.AddServer(s)
+	}
+	if serverList.Size() == 0 {
+		return nil, errors.New("0 server")
+	}
+
+	v := core.MustFromContext(ctx)
+	return &Client{
+		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
+		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+	}, nil
+}
+
+// Process implements OutboundHandler.Process().
+func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
+	outbounds := session.OutboundsFromContext(ctx)
+	ob := outbounds[len(outbounds)-1]
+	if !ob.Target.IsValid() {
+		return errors.New("target not specified")
+	}
+	ob.Name = "masque"
+	ob.CanSpliceCopy = 3
+	destination := ob.Target
+
+	server := c.serverPicker.PickServer()
+	user := server.PickUser()
+	var authorization string
+	if user != nil && user.Account != nil {
+		authorization = user.Account.(*Account).authorization()
+	}
+	dial := func(target net.Destination) (stat.Connection, error) {
+		return dialer.Dial(masque.ContextWithRequest(ctx, target, authorization), server.Destination())
+	}
+
+	sessionPolicy := policy.ForUser(c.policyManager, user)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+
+	if destination.Network == net.Network_UDP {
+		return c.processUDP(ctx, sessionPolicy, timer, dial, destination, link)
+	}
+
+	var conn stat.Connection
+	err := retry.ExponentialBackoff(5, 100).On(func() error {
+		rawConn, err := dial(destination)
+		if err != nil {
+			return err
+		}
+		conn = rawConn
+		return nil
+	})
+	if err != nil {
+		return errors.New("failed to find an available destination").AtWarning().Base(err)
+	}
+	errors.LogInfo(ctx, "tunneling request to ", destination, " via ", server.Destination().NetAddr())
+	defer conn.Close()
+
+	postRequest := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		if err := buf.Copy(link.Reader, buf.NewWriter(conn), buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transfer request payload").Base(err).AtInfo()
+		}
+		return nil
+	}
+
+	getResponse := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		return buf.Copy(buf.NewReader(conn), link.Writer, buf.UpdateActivity(timer))
+	}
+
+	responseDoneAndCloseWriter := task.OnSuccess(getResponse, task.Close(link.Writer))
+	if err := task.Run(ctx, postRequest, responseDoneAndCloseWriter); err != nil {
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
+
+// processUDP relays the packets to every target by a request of CONNECT-UDP, made on its first packet.
+func (c *Client) processUDP(ctx context.Context, sessionPolicy policy.Session, timer *signal.ActivityTimer, dial func(net.Destination) (stat.Connection, error), destination net.Destination, link *transport.Link) error {
+	var access sync.Mutex
+	conns := make(map[net.Destination]stat.Connection)
+	defer func() {
+		access.Lock()
+		defer access.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	getConn := func(target net.Destination) (stat.Connection, error) {
+		access.Lock()
+		defer access.Unlock()
+		if conn := conns[target]; conn != nil {
+			return conn, nil
+		}
+		conn, err := dial(target)
+		if err != nil {
+			return nil, err
+		}
+		conns[target] = conn
+		go func() {
+			reader := &buf.PacketReader{Reader: conn}
+			for {
+				mb, err := reader.ReadMultiBuffer()
+				if err != nil {
+					return
+				}
+				for _, b := range mb {
+					b.UDP = &target
+				}
+				if err := link.Writer.WriteMultiBuffer(mb); err != nil {
+					return
+				}
+				timer.Update()
+			}
+		}()
+		return conn, nil
+	}
+
+	postRequest := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		for {
+			mb, err := link.Reader.ReadMultiBuffer()
+			if err != nil {
+				return nil
+			}
+			timer.Update()
+			for _, b := range mb {
+				target := destination
+				if b.UDP != nil {
+					target = *b.UDP
+				}
+				conn, err := getConn(target)
+				if err != nil {
+					errors.LogInfoInner(ctx, err, "failed to request CONNECT-UDP to ", target)
+				} else if _, err := conn.Write(b.Bytes()); err != nil {
+					errors.LogInfoInner(ctx, err, "failed to send UDP packet to ", target)
+				}
+				b.Release()
+			}
+		}
+	}
+
+	// The responses are written by the readers of the requests, until the session is idle.
+	getResponse := func() error {
+		<-ctx.Done()
+		return nil
+	}
+
+	responseDoneAndCloseWriter := task.OnSuccess(getResponse, task.Close(link.Writer))
+	if err := task.Run(ctx, postRequest, responseDoneAndCloseWriter); err != nil {
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*ClientConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return NewClient(ctx, config.(*ClientConfig))
+	}))
+}
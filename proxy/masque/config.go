@@ -0,0 +1,55 @@
+package masque
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/xtls/xray-core/common/protocol"
+)
+
+func (a *Account) Equals(another protocol.Account) bool {
+	if account, ok := another.(*Account); ok {
+		return a.Username == account.Username
+	}
+	return false
+}
+
+func (a *Account) ToProto() proto.Message {
+	return a
+}
+
+func (a *Account) AsAccount() (protocol.Account, error) {
+	return a, nil
+}
+
+// authorization returns the Proxy-Authorization of a.
+func (a *Account) authorization() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.GetUsername()+":"+a.GetPassword()))
+}
+
+func parseBasicAuth(auth string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if !strings.HasPrefix(auth, prefix) {
+		return
+	}
+	c, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return
+	}
+	username, password, ok = strings.Cut(string(c), ":")
+	return
+}
+
+func (sc *ServerConfig) HasAccount(username, password string) bool {
+	if sc.Accounts == nil {
+		return false
+	}
+
+	p, found := sc.Accounts[username]
+	if !found {
+		return false
+	}
+	return p == password
+}
@@ -0,0 +1,3 @@
+// Package masque is the proxy of MASQUE, which relays TCP by the requests of CONNECT and UDP by those of CONNECT-UDP
+// (RFC 9298) of the MASQUE transport.
+package masque
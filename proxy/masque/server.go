@@ -0,0 +1,133 @@
+package masque
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport/internet/masque"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*ServerConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return NewServer(ctx, config.(*ServerConfig))
+	}))
+}
+
+// Server is an inbound connection handler that handles the requests of the MASQUE transport.
+type Server struct {
+	config        *ServerConfig
+	policyManager policy.Manager
+}
+
+// NewServer creates a new MASQUE inbound handler.
+func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
+	v := core.MustFromContext(ctx)
+	return &Server{
+		config:        config,
+		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+	}, nil
+}
+
+// Authenticate implements internet.Authenticator, with the Proxy-Authorization of a request.
+func (s *Server) Authenticate(credential string) bool {
+	if len(s.config.Accounts) == 0 {
+		return true
+	}
+	user, pass, ok := parseBasicAuth(credential)
+	return ok && s.config.HasAccount(user, pass)
+}
+
+// Network implements proxy.Inbound.Network().
+func (s *Server) Network() []net.Network {
+	return []net.Network{net.Network_TCP}
+}
+
+// Process implements proxy.Inbound.Process(). conn is a request of CONNECT, or of CONNECT-UDP whose every Read and
+// Write is a UDP packet.
+func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Connection, dispatcher routing.Dispatcher) error {
+	iConn := conn
+	if statConn, ok := iConn.(*stat.CounterConnection); ok {
+		iConn = statConn.Connection
+	}
+	mConn, ok := iConn.(*masque.Conn)
+	if !ok {
+		return errors.New("MASQUE inbound requires the MASQUE transport")
+	}
+
+	inbound := session.InboundFromContext(ctx)
+	inbound.Name = "masque"
+	inbound.CanSpliceCopy = 3
+	inbound.User = &protocol.MemoryUser{
+		Level: s.config.UserLevel,
+	}
+	if len(s.config.Accounts) > 0 {
+		user, _, _ := parseBasicAuth(mConn.Credential())
+		inbound.User.Email = user
+	}
+
+	destination := mConn.Target()
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   conn.RemoteAddr(),
+		To:     destination,
+		Status: log.AccessAccepted,
+		Reason: "",
+		Email:  inbound.User.Email,
+	})
+	errors.LogInfo(ctx, "received request for ", destination)
+
+	sessionPolicy := s.policyManager.ForLevel(s.config.UserLevel)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
+
+	link, err := dispatcher.Dispatch(ctx, destination)
+	if err != nil {
+		return errors.New("failed to dispatch request to ", destination).Base(err)
+	}
+
+	var reader buf.Reader
+	var writer buf.Writer
+	if destination.Network == net.Network_UDP {
+		reader = &buf.PacketReader{Reader: conn}
+		writer = &buf.SequentialWriter{Writer: conn}
+	} else {
+		reader = buf.NewReader(conn)
+		writer = buf.NewWriter(conn)
+	}
+
+	requestDone := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		if err := buf.Copy(reader, link.Writer, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transfer request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		if err := buf.Copy(link.Reader, writer, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to write response").Base(err)
+		}
+		return nil
+	}
+
+	requestDonePost := task.OnSuccess(requestDone, task.Close(link.Writer))
+	if err := task.Run(ctx, requestDonePost, responseDone); err != nil {
+		common.Must(common.Interrupt(link.Reader))
+		common.Must(common.Interrupt(link.Writer))
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
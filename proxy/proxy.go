@@ -23,6 +23,8 @@ import (
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport"
@@ -87,6 +89,22 @@ type UserManager interface {
 
 	// Get users count.
 	GetUsersCount(context.Context) int64
+
+	// SyncUsers replaces all users with the given ones, see ApplyUsers. Either all changes are applied, or none.
+	// It returns the number of users added and removed.
+	SyncUsers(context.Context, []*protocol.MemoryUser) (added int, removed int, err error)
+
+	// UpdateUser replaces the user with the same email by the given one, so that the user is never unknown in between.
+	// If the credentials change, the old ones are still accepted for overlap.
+	UpdateUser(ctx context.Context, user *protocol.MemoryUser, overlap time.Duration) error
+}
+
+// PoolableOutbound is an Outbound that sends every request in a new connection to its server, with a stream of its own
+// protocol, so that the connections may be dialed ahead by a connection pool.
+type PoolableOutbound interface {
+	Outbound
+	// Poolable marks the Outbound as poolable.
+	Poolable()
 }
 
 type GetInbound interface {
@@ -554,6 +572,27 @@ func UnwrapRawConn(conn net.Conn) (net.Conn, stats.Counter, stats.Counter) {
 	return conn, readCounter, writerCounter
 }
 
+// isRateLimited returns whether the throughput of the user of the inbound in ctx is limited. The policy is checked
+// rather than the writer, as the rate limiters of the dispatcher may be wrapped by other writers or be on the reader
+// of the other side, and either way must not be bypassed by splice.
+func isRateLimited(ctx context.Context) bool {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || inbound.User == nil {
+		return false
+	}
+	instance := core.FromContext(ctx)
+	if instance == nil {
+		return false
+	}
+	pm, ok := instance.GetFeature(policy.ManagerType()).(policy.Manager)
+	if !ok {
+		return false
+	}
+	uplink, downlink, release := policy.UserRateLimiters(pm, inbound.User)
+	release()
+	return uplink != nil || downlink != nil
+}
+
 // CopyRawConnIfExist use the most efficient copy method.
 // - If caller don't want to turn on splice, do not pass in both reader conn and writer conn
 // - writer are from *transport.Link
@@ -568,6 +607,9 @@ func CopyRawConnIfExist(ctx context.Context, readerConn net.Conn, writerConn net
 	if !ok || readerConn == nil || writerConn == nil {
 		return readV(ctx, reader, writer, timer, readCounter)
 	}
+	if isRateLimited(ctx) {
+		return readV(ctx, reader, writer, timer, readCounter)
+	}
 	inbound := session.InboundFromContext(ctx)
 	if inbound == nil || inbound.CanSpliceCopy == 3 {
 		return readV(ctx, reader, writer, timer, readCounter)
@@ -593,7 +635,6 @@ func CopyRawConnIfExist(ctx context.Context, readerConn net.Conn, writerConn net
 		}
 		if splice {
 			errors.LogInfo(ctx, "CopyRawConn splice")
-			statWriter, _ := writer.(*dispatcher.SizeStatWriter)
 			//runtime.Gosched() // necessary
 			time.Sleep(time.Millisecond)    // without this, there will be a rare ssl error for freedom splice
 			timer.SetTimeout(8 * time.Hour) // prevent leak, just in case
@@ -607,9 +648,7 @@ func CopyRawConnIfExist(ctx context.Context, readerConn net.Conn, writerConn net
 			if writeCounter != nil {
 				writeCounter.Add(w) // inbound stats
 			}
-			if statWriter != nil {
-				statWriter.Counter.Add(w) // user stats
-			}
+			dispatcher.AddToSizeStatWriters(writer, w) // user stats
 			if err != nil && errors.Cause(err) != io.EOF {
 				return err
 			}
@@ -48,6 +48,9 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	return client, nil
 }
 
+// Poolable implements proxy.PoolableOutbound.
+func (c *Client) Poolable() {}
+
 // Process implements OutboundHandler.Process().
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
@@ -106,7 +109,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 		newCtx, newCancel = context.WithCancel(context.Background())
 	}
 
-	sessionPolicy := c.policyManager.ForLevel(user.Level)
+	sessionPolicy := policy.ForUser(c.policyManager, user)
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, func() {
 		cancel()
@@ -2,6 +2,7 @@ package shadowsocks
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/xtls/xray-core/common"
@@ -17,6 +18,7 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/udp"
 )
@@ -24,6 +26,7 @@ import (
 type Server struct {
 	config        *ServerConfig
 	validator     *Validator
+	usersAccess   sync.Mutex
 	policyManager policy.Manager
 	cone          bool
 }
@@ -55,14 +58,40 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 
 // AddUser implements proxy.UserManager.AddUser().
 func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
 	return s.validator.Add(u)
 }
 
 // RemoveUser implements proxy.UserManager.RemoveUser().
 func (s *Server) RemoveUser(ctx context.Context, e string) error {
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
 	return s.validator.Del(e)
 }
 
+// SyncUsers implements proxy.UserManager.SyncUsers().
+func (s *Server) SyncUsers(ctx context.Context, users []*protocol.MemoryUser) (int, int, error) {
+	for _, u := range users {
+		if _, ok := u.Account.(*MemoryAccount); !ok {
+			return 0, 0, errors.New("User ", u.Email, " is not a Shadowsocks user.")
+		}
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return proxy.ApplyUsers(s.validator.GetAll(), users, s.validator)
+}
+
+// UpdateUser implements proxy.UserManager.UpdateUser().
+func (s *Server) UpdateUser(ctx context.Context, u *protocol.MemoryUser, overlap time.Duration) error {
+	if _, ok := u.Account.(*MemoryAccount); !ok {
+		return errors.New("User ", u.Email, " is not a Shadowsocks user.")
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return s.validator.Update(u, overlap)
+}
+
 // GetUser implements proxy.UserManager.GetUser().
 func (s *Server) GetUser(ctx context.Context, email string) *protocol.MemoryUser {
 	return s.validator.GetByEmail(email)
@@ -130,6 +159,10 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn stat.Connection, dis
 	})
 
 	inbound := session.InboundFromContext(ctx)
+	release := func() {}
+	defer func() {
+		release()
+	}()
 	var dest *net.Destination
 	reader := buf.NewPacketReader(conn)
 	for {
@@ -150,7 +183,11 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn stat.Connection, dis
 			} else {
 				request, data, err = DecodeUDPPacket(s.validator, payload)
 				if err == nil {
-					inbound.User = request.User
+					if release, err = policy.TrackUser(s.policyManager, request.User, inbound.Source); err == nil {
+						inbound.User = request.User
+					} else {
+						release = func() {}
+					}
 				}
 			}
 
@@ -222,6 +259,19 @@ func (s *Server) handleConnection(ctx context.Context, conn stat.Connection, dis
 	inbound.User = request.User
 
 	dest := request.Destination()
+	release, err := policy.TrackUser(s.policyManager, request.User, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   conn.RemoteAddr(),
+			To:     dest,
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  request.User.Email,
+		})
+		return errors.New("rejected request from ", conn.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer release()
+
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
 		From:   conn.RemoteAddr(),
 		To:     dest,
@@ -231,7 +281,7 @@ func (s *Server) handleConnection(ctx context.Context, conn stat.Connection, dis
 	})
 	errors.LogInfo(ctx, "tunnelling request to ", dest)
 
-	sessionPolicy = s.policyManager.ForLevel(request.User.Level)
+	sessionPolicy = policy.ForUser(s.policyManager, request.User)
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
 
@@ -5,18 +5,23 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"hash/crc64"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common/dice"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/proxy"
 )
 
 // Validator stores valid Shadowsocks users.
 type Validator struct {
 	sync.RWMutex
 	users []*protocol.MemoryUser
+	// retired holds the users with the key they changed from, while it is still accepted.
+	retired []*protocol.MemoryUser
 
 	behaviorSeed  uint64
 	behaviorFused bool
@@ -28,7 +33,10 @@ var ErrNotFound = errors.New("Not Found")
 func (v *Validator) Add(u *protocol.MemoryUser) error {
 	v.Lock()
 	defer v.Unlock()
+	return v.add(u)
+}
 
+func (v *Validator) add(u *protocol.MemoryUser) error {
 	account := u.Account.(*MemoryAccount)
 	if !account.Cipher.IsAEAD() && len(v.users) > 0 {
 		return errors.New("The cipher is not support Single-port Multi-user")
@@ -46,13 +54,15 @@ func (v *Validator) Add(u *protocol.MemoryUser) error {
 
 // Del a Shadowsocks user with a non-empty Email.
 func (v *Validator) Del(email string) error {
-	if email == "" {
-		return errors.New("Email must not be empty.")
-	}
-
 	v.Lock()
 	defer v.Unlock()
+	return v.del(email)
+}
 
+func (v *Validator) del(email string) error {
+	if email == "" {
+		return errors.New("Email must not be empty.")
+	}
 	email = strings.ToLower(email)
 	idx := -1
 	for i, u := range v.users {
@@ -70,10 +80,98 @@ func (v *Validator) Del(email string) error {
 	v.users[idx] = v.users[ulen-1]
 	v.users[ulen-1] = nil
 	v.users = v.users[:ulen-1]
+	v.unretire(func(u *protocol.MemoryUser) bool {
+		return strings.EqualFold(u.Email, email)
+	})
+
+	return nil
+}
+
+// Update replaces the Shadowsocks user with the same non-empty Email. If the key changes, the old one is still
+// accepted for the updated user for overlap.
+func (v *Validator) Update(u *protocol.MemoryUser, overlap time.Duration) error {
+	v.Lock()
+	defer v.Unlock()
+	return v.update(u, overlap)
+}
+
+func (v *Validator) update(u *protocol.MemoryUser, overlap time.Duration) error {
+	if u.Email == "" {
+		return errors.New("Email must not be empty.")
+	}
+	idx := -1
+	for i, user := range v.users {
+		if strings.EqualFold(user.Email, u.Email) {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return errors.New("User ", u.Email, " not found.")
+	}
+	if !u.Account.(*MemoryAccount).Cipher.IsAEAD() && len(v.users) > 1 {
+		return errors.New("The cipher is not support Single-port Multi-user")
+	}
+	old := v.users[idx]
+	v.users[idx] = u
+	if old.Account.Equals(u.Account) {
+		return nil
+	}
+	v.unretire(func(r *protocol.MemoryUser) bool {
+		return strings.EqualFold(r.Email, u.Email)
+	})
+	if overlap <= 0 || !old.Account.(*MemoryAccount).Cipher.IsAEAD() {
+		return nil
+	}
+	// The old key is needed to decode the requests made with it.
+	retired := *u
+	retired.Account = old.Account
+	v.retired = append(v.retired, &retired)
+	time.AfterFunc(overlap, func() {
+		v.Lock()
+		defer v.Unlock()
+		v.unretire(func(r *protocol.MemoryUser) bool {
+			return r == &retired
+		})
+	})
+	return nil
+}
+
+// Replace implements proxy.UserSet, with a proxy.UserEditor of a copy of the users.
+func (v *Validator) Replace(remove []string, update, add []*protocol.MemoryUser) error {
+	v.Lock()
+	defer v.Unlock()
 
+	next := &Validator{
+		users:         slices.Clone(v.users),
+		retired:       slices.Clone(v.retired),
+		behaviorSeed:  v.behaviorSeed,
+		behaviorFused: v.behaviorFused,
+	}
+	err := proxy.UserEditor{
+		Remove: next.del,
+		Update: func(u *protocol.MemoryUser) error { return next.update(u, 0) },
+		Add:    next.add,
+	}.Replace(remove, update, add)
+	if err != nil {
+		return err
+	}
+	v.users, v.retired, v.behaviorSeed = next.users, next.retired, next.behaviorSeed
 	return nil
 }
 
+// unretire stops accepting the old keys of the matched users. The caller must hold the lock.
+func (v *Validator) unretire(match func(*protocol.MemoryUser) bool) {
+	retired := v.retired[:0]
+	for _, r := range v.retired {
+		if !match(r) {
+			retired = append(retired, r)
+		}
+	}
+	clear(v.retired[len(retired):])
+	v.retired = retired
+}
+
 // GetByEmail Get a Shadowsocks user with a non-empty Email.
 func (v *Validator) GetByEmail(email string) *protocol.MemoryUser {
 	if email == "" {
@@ -113,7 +211,11 @@ func (v *Validator) Get(bs []byte, command protocol.RequestCommand) (u *protocol
 	v.RLock()
 	defer v.RUnlock()
 
-	for _, user := range v.users {
+	users := v.users
+	if len(v.retired) > 0 {
+		users = append(users[:len(users):len(users)], v.retired...)
+	}
+	for _, user := range users {
 		if account := user.Account.(*MemoryAccount); account.Cipher.IsAEAD() {
 			// AEAD payload decoding requires the payload to be over 32 bytes
 			if len(bs) < 32 {
@@ -0,0 +1,126 @@
+package shadowsocks_2022
+
+import (
+	"context"
+	"crypto/aes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"github.com/sagernet/sing-shadowsocks/shadowaead"
+	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
+	B "github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/proxy/externalauth"
+	"lukechampine.com/blake3"
+)
+
+// externalUser is a user authorized by the external auth backend.
+type externalUser struct {
+	user   *protocol.MemoryUser
+	key    string
+	hash   [aes.BlockSize]byte
+	expire time.Time
+}
+
+// identityHash returns the hash of the user key that identifies the user in requests.
+func identityHash(key string, keyLength int) ([aes.BlockSize]byte, error) {
+	var hash [aes.BlockSize]byte
+	uPSK, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return hash, errors.New("failed to decode key").Base(err)
+	}
+	if len(uPSK) < keyLength {
+		return hash, errors.New("key is too short")
+	} else if len(uPSK) > keyLength {
+		uPSK = shadowaead_2022.Key(uPSK, keyLength)
+	}
+	hash512 := blake3.Sum512(uPSK)
+	copy(hash[:], hash512[:])
+	return hash, nil
+}
+
+// authorizeExternal reads the request header to find the user it is from, and if it's not a known one, asks the
+// external auth backend about it. It returns the connection with the header to be read again.
+func (i *MultiUserInbound) authorizeExternal(ctx context.Context, conn net.Conn) (net.Conn, error) {
+	keyLength := len(i.psk)
+	header := make([]byte, keyLength+aes.BlockSize+shadowaead.Overhead+shadowaead_2022.RequestHeaderFixedChunkLength)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, errors.New("failed to read request header").Base(err)
+	}
+
+	// The identity header is encrypted with a subkey of the PSK and the salt.
+	keyMaterial := make([]byte, keyLength*2)
+	copy(keyMaterial, i.psk)
+	copy(keyMaterial[keyLength:], header[:keyLength])
+	identitySubkey := make([]byte, keyLength)
+	blake3.DeriveKey(identitySubkey, "shadowsocks 2022 identity subkey", keyMaterial)
+	block, err := aes.NewCipher(identitySubkey)
+	if err != nil {
+		return nil, err
+	}
+	var hash [aes.BlockSize]byte
+	block.Decrypt(hash[:], header[keyLength:keyLength+aes.BlockSize])
+
+	i.Lock()
+	known := i.known[hash]
+	i.Unlock()
+	if !known {
+		user := i.external.Get(hex.EncodeToString(hash[:]), func(r *externalauth.AuthorizeResponse) (*protocol.MemoryUser, error) {
+			h, err := identityHash(r.Key, keyLength)
+			if err != nil {
+				return nil, err
+			}
+			if h != hash {
+				return nil, errors.New("key doesn't match the credential")
+			}
+			return &protocol.MemoryUser{
+				Account: &MemoryAccount{Key: r.Key},
+				Email:   r.Email,
+				Level:   r.Level,
+			}, nil
+		})
+		i.setExternal(ctx, hash, user)
+	}
+	return bufio.NewCachedConn(conn, B.As(header)), nil
+}
+
+// setExternal updates the external user with the hash, and drops the ones expired meanwhile.
+func (i *MultiUserInbound) setExternal(ctx context.Context, hash [aes.BlockSize]byte, user *protocol.MemoryUser) {
+	i.Lock()
+	defer i.Unlock()
+
+	now := time.Now()
+	changed := false
+	external := make([]*externalUser, 0, len(i.externalUsers)+1)
+	for _, e := range i.externalUsers {
+		if e.hash == hash && e.user == user {
+			return
+		}
+		if e.hash == hash || now.After(e.expire) {
+			changed = true
+			continue
+		}
+		external = append(external, e)
+	}
+	if user != nil {
+		external = append(external, &externalUser{
+			user:   user,
+			key:    user.Account.(*MemoryAccount).Key,
+			hash:   hash,
+			expire: now.Add(i.external.TTL()),
+		})
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	i.externalUsers = external
+	if err := i.updateService(); err != nil {
+		errors.LogWarningInner(ctx, err, "failed to update users")
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/singbridge"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/transport/internet/stat"
 )
@@ -34,6 +36,8 @@ type Inbound struct {
 	service  shadowsocks.Service
 	email    string
 	level    int
+
+	policyManager policy.Manager
 }
 
 func NewServer(ctx context.Context, config *ServerConfig) (*Inbound, error) {
@@ -45,9 +49,10 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Inbound, error) {
 		}
 	}
 	inbound := &Inbound{
-		networks: networks,
-		email:    config.Email,
-		level:    int(config.Level),
+		networks:      networks,
+		email:         config.Email,
+		level:         int(config.Level),
+		policyManager: core.MustFromContext(ctx).GetFeature(policy.ManagerType()).(policy.Manager),
 	}
 	if !C.Contains(shadowaead_2022.List, config.Method) {
 		return nil, errors.New("unsupported method ", config.Method)
@@ -107,6 +112,18 @@ func (i *Inbound) NewConnection(ctx context.Context, conn net.Conn, metadata M.M
 		Email: i.email,
 		Level: uint32(i.level),
 	}
+	release, err := policy.TrackUser(i.policyManager, inbound.User, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   metadata.Source,
+			To:     metadata.Destination,
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  inbound.User.Email,
+		})
+		return errors.New("rejected request from ", metadata.Source).Base(err).AtInfo()
+	}
+	defer release()
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
 		From:   metadata.Source,
 		To:     metadata.Destination,
@@ -128,6 +145,18 @@ func (i *Inbound) NewPacketConnection(ctx context.Context, conn N.PacketConn, me
 		Email: i.email,
 		Level: uint32(i.level),
 	}
+	release, err := policy.TrackUser(i.policyManager, inbound.User, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   metadata.Source,
+			To:     metadata.Destination,
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  inbound.User.Email,
+		})
+		return errors.New("rejected request from ", metadata.Source).Base(err).AtInfo()
+	}
+	defer release()
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
 		From:   metadata.Source,
 		To:     metadata.Destination,
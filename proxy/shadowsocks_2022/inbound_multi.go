@@ -2,13 +2,14 @@ package shadowsocks_2022
 
 import (
 	"context"
+	"crypto/aes"
 	"encoding/base64"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
-	C "github.com/sagernet/sing/common"
 	A "github.com/sagernet/sing/common/auth"
 	B "github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
@@ -24,7 +25,11 @@ import (
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/singbridge"
 	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/externalauth"
 	"github.com/xtls/xray-core/transport/internet/stat"
 )
 
@@ -38,7 +43,16 @@ type MultiUserInbound struct {
 	sync.Mutex
 	networks []net.Network
 	users    []*protocol.MemoryUser
+	retired  []*retiredUser
 	service  *shadowaead_2022.MultiService[int]
+	psk      []byte
+
+	// external, if not nil, is asked about the users not known, whose identity hashes are in known.
+	external      *externalauth.Authenticator
+	known         map[[aes.BlockSize]byte]bool
+	externalUsers []*externalUser
+
+	policyManager policy.Manager
 }
 
 func NewMultiServer(ctx context.Context, config *MultiUserServerConfig) (*MultiUserInbound, error) {
@@ -63,8 +77,9 @@ func NewMultiServer(ctx context.Context, config *MultiUserServerConfig) (*MultiU
 	}
 
 	inbound := &MultiUserInbound{
-		networks: networks,
-		users:    memUsers,
+		networks:      networks,
+		users:         memUsers,
+		policyManager: core.MustFromContext(ctx).GetFeature(policy.ManagerType()).(policy.Manager),
 	}
 	if config.Key == "" {
 		return nil, errors.New("missing key")
@@ -77,18 +92,37 @@ func NewMultiServer(ctx context.Context, config *MultiUserServerConfig) (*MultiU
 	if err != nil {
 		return nil, errors.New("create service").Base(err)
 	}
-	err = service.UpdateUsersWithPasswords(
-		C.MapIndexed(memUsers, func(index int, it *protocol.MemoryUser) int { return index }),
-		C.Map(memUsers, func(it *protocol.MemoryUser) string { return it.Account.(*MemoryAccount).Key }),
-	)
-	if err != nil {
-		return nil, errors.New("create service").Base(err)
+	inbound.service = service
+
+	// The PSK is as long as the keys of the method, which only allows AES for multiple users.
+	keyLength := 32
+	if config.Method == "2022-blake3-aes-128-gcm" {
+		keyLength = 16
+	}
+	if len(psk) > keyLength {
+		psk = shadowaead_2022.Key(psk, keyLength)
+	}
+	inbound.psk = psk
+	if config.ExternalAuth != nil {
+		if inbound.external, err = externalauth.New(config.ExternalAuth, "shadowsocks-2022"); err != nil {
+			return nil, errors.New("failed to create external auth").Base(err)
+		}
 	}
 
-	inbound.service = service
+	if err := inbound.updateService(); err != nil {
+		return nil, errors.New("create service").Base(err)
+	}
 	return inbound, nil
 }
 
+// Close implements common.Closable.Close().
+func (i *MultiUserInbound) Close() error {
+	if i.external != nil {
+		return i.external.Close()
+	}
+	return nil
+}
+
 // AddUser implements proxy.UserManager.AddUser().
 func (i *MultiUserInbound) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
 	i.Lock()
@@ -103,12 +137,7 @@ func (i *MultiUserInbound) AddUser(ctx context.Context, u *protocol.MemoryUser)
 	}
 	i.users = append(i.users, u)
 
-	// sync to multi service
-	// Considering implements shadowsocks2022 in xray-core may have better performance.
-	i.service.UpdateUsersWithPasswords(
-		C.MapIndexed(i.users, func(index int, it *protocol.MemoryUser) int { return index }),
-		C.Map(i.users, func(it *protocol.MemoryUser) string { return it.Account.(*MemoryAccount).Key }),
-	)
+	i.updateService()
 
 	return nil
 }
@@ -139,14 +168,177 @@ func (i *MultiUserInbound) RemoveUser(ctx context.Context, email string) error {
 	i.users[idx] = i.users[ulen-1]
 	i.users[ulen-1] = nil
 	i.users = i.users[:ulen-1]
+	i.retired = i.withoutRetired(func(r *retiredUser) bool {
+		return strings.EqualFold(r.user.Email, email)
+	})
+
+	i.updateService()
+
+	return nil
+}
+
+// SyncUsers implements proxy.UserManager.SyncUsers().
+func (i *MultiUserInbound) SyncUsers(ctx context.Context, users []*protocol.MemoryUser) (int, int, error) {
+	for _, u := range users {
+		if _, ok := u.Account.(*MemoryAccount); !ok {
+			return 0, 0, errors.New("User ", u.Email, " is not a Shadowsocks 2022 user.")
+		}
+	}
+
+	i.Lock()
+	defer i.Unlock()
+
+	remove, add, err := proxy.DiffUsers(i.users, users)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(remove) == 0 && len(add) == 0 {
+		return 0, 0, nil
+	}
+	removed := make(map[*protocol.MemoryUser]bool, len(remove))
+	for _, u := range remove {
+		removed[u] = true
+	}
+	newUsers := make([]*protocol.MemoryUser, 0, len(i.users)-len(remove)+len(add))
+	for _, u := range i.users {
+		if !removed[u] {
+			newUsers = append(newUsers, u)
+		}
+	}
+	newUsers = append(newUsers, add...)
+
+	// The users are only replaced if the service accepts all of them.
+	oldUsers, oldRetired := i.users, i.retired
+	i.users = newUsers
+	i.retired = i.withoutRetired(func(r *retiredUser) bool {
+		return removed[r.user]
+	})
+	if err := i.updateService(); err != nil {
+		i.users, i.retired = oldUsers, oldRetired
+		return 0, 0, errors.New("failed to update users").Base(err)
+	}
+	return len(add), len(remove), nil
+}
+
+// UpdateUser implements proxy.UserManager.UpdateUser().
+func (i *MultiUserInbound) UpdateUser(ctx context.Context, u *protocol.MemoryUser, overlap time.Duration) error {
+	account, ok := u.Account.(*MemoryAccount)
+	if !ok {
+		return errors.New("User ", u.Email, " is not a Shadowsocks 2022 user.")
+	}
+	if u.Email == "" {
+		return errors.New("Email must not be empty.")
+	}
+
+	i.Lock()
+	defer i.Unlock()
 
-	// sync to multi service
-	// Considering implements shadowsocks2022 in xray-core may have better performance.
-	i.service.UpdateUsersWithPasswords(
-		C.MapIndexed(i.users, func(index int, it *protocol.MemoryUser) int { return index }),
-		C.Map(i.users, func(it *protocol.MemoryUser) string { return it.Account.(*MemoryAccount).Key }),
-	)
+	idx := -1
+	for ii, user := range i.users {
+		if strings.EqualFold(user.Email, u.Email) {
+			idx = ii
+			break
+		}
+	}
+	if idx == -1 {
+		return errors.New("User ", u.Email, " not found.")
+	}
+	old := i.users[idx]
+	oldKey := old.Account.(*MemoryAccount).Key
+	if oldKey == account.Key {
+		i.users[idx] = u
+		return nil
+	}
+
+	oldRetired := i.retired
+	i.users[idx] = u
+	i.retired = i.withoutRetired(func(r *retiredUser) bool {
+		return strings.EqualFold(r.user.Email, u.Email)
+	})
+	var r *retiredUser
+	if overlap > 0 {
+		r = &retiredUser{user: u, key: oldKey}
+		i.retired = append(i.retired, r)
+	}
+	if err := i.updateService(); err != nil {
+		i.users[idx], i.retired = old, oldRetired
+		return errors.New("failed to update user ", u.Email).Base(err)
+	}
+	if r != nil {
+		time.AfterFunc(overlap, func() {
+			i.Lock()
+			defer i.Unlock()
+			if retired := i.withoutRetired(func(rr *retiredUser) bool { return rr == r }); len(retired) < len(i.retired) {
+				i.retired = retired
+				i.updateService()
+			}
+		})
+	}
+	return nil
+}
+
+// retiredUser is a key a user changed from, while it is still accepted.
+type retiredUser struct {
+	user *protocol.MemoryUser
+	key  string
+}
+
+// withoutRetired returns the retired keys that don't match. The caller must hold the lock.
+func (i *MultiUserInbound) withoutRetired(match func(*retiredUser) bool) []*retiredUser {
+	retired := make([]*retiredUser, 0, len(i.retired))
+	for _, r := range i.retired {
+		if !match(r) {
+			retired = append(retired, r)
+		}
+	}
+	return retired
+}
 
+// updateService syncs the users, retired keys and external users to the multi service, which identifies them by
+// their index in the lists in turn. The caller must hold the lock.
+// Considering implements shadowsocks2022 in xray-core may have better performance.
+func (i *MultiUserInbound) updateService() error {
+	size := len(i.users) + len(i.retired) + len(i.externalUsers)
+	indices := make([]int, 0, size)
+	keys := make([]string, 0, size)
+	for _, u := range i.users {
+		indices = append(indices, len(indices))
+		keys = append(keys, u.Account.(*MemoryAccount).Key)
+	}
+	for _, r := range i.retired {
+		indices = append(indices, len(indices))
+		keys = append(keys, r.key)
+	}
+	if i.external != nil {
+		known := make(map[[aes.BlockSize]byte]bool, len(keys))
+		for _, key := range keys {
+			if hash, err := identityHash(key, len(i.psk)); err == nil {
+				known[hash] = true
+			}
+		}
+		i.known = known
+	}
+	for _, e := range i.externalUsers {
+		indices = append(indices, len(indices))
+		keys = append(keys, e.key)
+	}
+	return i.service.UpdateUsersWithPasswords(indices, keys)
+}
+
+// userByIndex returns the user the multi service identified by index, or nil if there is no such user anymore.
+func (i *MultiUserInbound) userByIndex(idx int) *protocol.MemoryUser {
+	i.Lock()
+	defer i.Unlock()
+
+	if idx < len(i.users) {
+		return i.users[idx]
+	}
+	if idx -= len(i.users); idx < len(i.retired) {
+		return i.retired[idx].user
+	}
+	if idx -= len(i.retired); idx < len(i.externalUsers) {
+		return i.externalUsers[idx].user
+	}
 	return nil
 }
 
@@ -200,7 +392,14 @@ func (i *MultiUserInbound) Process(ctx context.Context, network net.Network, con
 	ctx = session.ContextWithDispatcher(ctx, dispatcher)
 
 	if network == net.Network_TCP {
-		return singbridge.ReturnError(i.service.NewConnection(ctx, connection, metadata))
+		var conn net.Conn = connection
+		if i.external != nil {
+			var err error
+			if conn, err = i.authorizeExternal(ctx, connection); err != nil {
+				return err
+			}
+		}
+		return singbridge.ReturnError(i.service.NewConnection(ctx, conn, metadata))
 	} else {
 		reader := buf.NewReader(connection)
 		pc := &natPacketConn{connection}
@@ -227,8 +426,23 @@ func (i *MultiUserInbound) Process(ctx context.Context, network net.Network, con
 func (i *MultiUserInbound) NewConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
 	inbound := session.InboundFromContext(ctx)
 	userInt, _ := A.UserFromContext[int](ctx)
-	user := i.users[userInt]
+	user := i.userByIndex(userInt)
+	if user == nil {
+		return errors.New("user ", userInt, " is removed")
+	}
 	inbound.User = user
+	release, err := policy.TrackUser(i.policyManager, inbound.User, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   metadata.Source,
+			To:     metadata.Destination,
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  inbound.User.Email,
+		})
+		return errors.New("rejected request from ", metadata.Source).Base(err).AtInfo()
+	}
+	defer release()
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
 		From:   metadata.Source,
 		To:     metadata.Destination,
@@ -252,8 +466,23 @@ func (i *MultiUserInbound) NewConnection(ctx context.Context, conn net.Conn, met
 func (i *MultiUserInbound) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata M.Metadata) error {
 	inbound := session.InboundFromContext(ctx)
 	userInt, _ := A.UserFromContext[int](ctx)
-	user := i.users[userInt]
+	user := i.userByIndex(userInt)
+	if user == nil {
+		return errors.New("user ", userInt, " is removed")
+	}
 	inbound.User = user
+	release, err := policy.TrackUser(i.policyManager, inbound.User, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   metadata.Source,
+			To:     metadata.Destination,
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  inbound.User.Email,
+		})
+		return errors.New("rejected request from ", metadata.Source).Base(err).AtInfo()
+	}
+	defer release()
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
 		From:   metadata.Source,
 		To:     metadata.Destination,
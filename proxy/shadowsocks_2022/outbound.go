@@ -61,6 +61,9 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Outbound, error) {
 	return o, nil
 }
 
+// Poolable implements proxy.PoolableOutbound.
+func (o *Outbound) Poolable() {}
+
 func (o *Outbound) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	var inboundConn net.Conn
 	inbound := session.InboundFromContext(ctx)
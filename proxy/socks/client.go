@@ -104,7 +104,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	user := server.PickUser()
 	if user != nil {
 		request.User = user
-		p = c.policyManager.ForLevel(user.Level)
+		p = policy.ForUser(c.policyManager, user)
 	}
 
 	if err := conn.SetDeadline(time.Now().Add(p.Timeouts.Handshake)); err != nil {
@@ -5,6 +5,7 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/xtls/xray-core/common"
@@ -21,6 +22,8 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/externalauth"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
@@ -37,6 +40,7 @@ func init() {
 type Server struct {
 	policyManager policy.Manager
 	validator     *Validator
+	usersAccess   sync.Mutex
 	fallbacks     map[string]map[string]map[string]*Fallback // or nil
 	cone          bool
 }
@@ -54,6 +58,13 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 			return nil, errors.New("failed to add user").Base(err).AtError()
 		}
 	}
+	if config.ExternalAuth != nil {
+		external, err := externalauth.New(config.ExternalAuth, "trojan")
+		if err != nil {
+			return nil, errors.New("failed to create external auth").Base(err).AtError()
+		}
+		validator.External = external
+	}
 
 	v := core.MustFromContext(ctx)
 	server := &Server{
@@ -115,16 +126,50 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	return server, nil
 }
 
+// Close implements common.Closable.Close().
+func (s *Server) Close() error {
+	if s.validator.External != nil {
+		return s.validator.External.Close()
+	}
+	return nil
+}
+
 // AddUser implements proxy.UserManager.AddUser().
 func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
 	return s.validator.Add(u)
 }
 
 // RemoveUser implements proxy.UserManager.RemoveUser().
 func (s *Server) RemoveUser(ctx context.Context, e string) error {
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
 	return s.validator.Del(e)
 }
 
+// SyncUsers implements proxy.UserManager.SyncUsers().
+func (s *Server) SyncUsers(ctx context.Context, users []*protocol.MemoryUser) (int, int, error) {
+	for _, u := range users {
+		if _, ok := u.Account.(*MemoryAccount); !ok {
+			return 0, 0, errors.New("User ", u.Email, " is not a Trojan user.")
+		}
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return proxy.ApplyUsers(s.validator.GetAll(), users, s.validator)
+}
+
+// UpdateUser implements proxy.UserManager.UpdateUser().
+func (s *Server) UpdateUser(ctx context.Context, u *protocol.MemoryUser, overlap time.Duration) error {
+	if _, ok := u.Account.(*MemoryAccount); !ok {
+		return errors.New("User ", u.Email, " is not a Trojan user.")
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return s.validator.Update(u, overlap)
+}
+
 // GetUser implements proxy.UserManager.GetUser().
 func (s *Server) GetUser(ctx context.Context, email string) *protocol.MemoryUser {
 	return s.validator.GetByEmail(email)
@@ -230,7 +275,21 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 	inbound.Name = "trojan"
 	inbound.CanSpliceCopy = 3
 	inbound.User = user
-	sessionPolicy = s.policyManager.ForLevel(user.Level)
+
+	release, err := policy.TrackUser(s.policyManager, user, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   conn.RemoteAddr(),
+			To:     destination,
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  user.Email,
+		})
+		return errors.New("rejected request from ", conn.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer release()
+
+	sessionPolicy = policy.ForUser(s.policyManager, user)
 
 	if destination.Network == net.Network_UDP { // handle udp request
 		return s.handleUDPPayload(ctx, &PacketReader{Reader: clientReader}, &PacketWriter{Writer: conn}, dispatcher)
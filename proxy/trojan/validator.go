@@ -1,81 +1,217 @@
 package trojan
 
 import (
+	"encoding/hex"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/externalauth"
 )
 
 // Validator stores valid trojan users.
 type Validator struct {
-	// Considering email's usage here, map + sync.Mutex/RWMutex may have better performance.
-	email sync.Map
-	users sync.Map
+	access sync.RWMutex
+	email  map[string]*protocol.MemoryUser
+	users  map[string]*protocol.MemoryUser
+	// retired holds the hashed key a user changed from, while it is still accepted.
+	retired map[string]*retiredKey
+
+	// External, if not nil, is asked about the keys of no user.
+	External *externalauth.Authenticator
+}
+
+type retiredKey struct {
+	key string
+}
+
+// initMaps creates the maps of a zero Validator. The caller must hold the lock.
+func (v *Validator) initMaps() {
+	if v.users == nil {
+		v.email = make(map[string]*protocol.MemoryUser)
+		v.users = make(map[string]*protocol.MemoryUser)
+		v.retired = make(map[string]*retiredKey)
+	}
 }
 
-// Add a trojan user, Email must be empty or unique.
+// Add a trojan user, Email must be empty or unique, and password unique.
 func (v *Validator) Add(u *protocol.MemoryUser) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.add(u)
+}
+
+func (v *Validator) add(u *protocol.MemoryUser) error {
+	key := hexString(u.Account.(*MemoryAccount).Key)
+	if _, found := v.users[key]; found {
+		return errors.New("User ", u.Email, " has the password of another user.")
+	}
 	if u.Email != "" {
-		_, loaded := v.email.LoadOrStore(strings.ToLower(u.Email), u)
-		if loaded {
+		le := strings.ToLower(u.Email)
+		if _, found := v.email[le]; found {
 			return errors.New("User ", u.Email, " already exists.")
 		}
+		v.email[le] = u
 	}
-	v.users.Store(hexString(u.Account.(*MemoryAccount).Key), u)
+	v.users[key] = u
 	return nil
 }
 
 // Del a trojan user with a non-empty Email.
 func (v *Validator) Del(e string) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.del(e)
+}
+
+func (v *Validator) del(e string) error {
 	if e == "" {
 		return errors.New("Email must not be empty.")
 	}
 	le := strings.ToLower(e)
-	u, _ := v.email.Load(le)
+	u := v.email[le]
 	if u == nil {
 		return errors.New("User ", e, " not found.")
 	}
-	v.email.Delete(le)
-	v.users.Delete(hexString(u.(*protocol.MemoryUser).Account.(*MemoryAccount).Key))
+	delete(v.email, le)
+	delete(v.users, hexString(u.Account.(*MemoryAccount).Key))
+	v.unretire(le)
+	return nil
+}
+
+// Update replaces the trojan user with the same non-empty Email. If the password changes, the old one is still
+// accepted for the updated user for overlap.
+func (v *Validator) Update(u *protocol.MemoryUser, overlap time.Duration) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.update(u, overlap)
+}
+
+func (v *Validator) update(u *protocol.MemoryUser, overlap time.Duration) error {
+	if u.Email == "" {
+		return errors.New("Email must not be empty.")
+	}
+	le := strings.ToLower(u.Email)
+	old := v.email[le]
+	if old == nil {
+		return errors.New("User ", u.Email, " not found.")
+	}
+	oldKey := hexString(old.Account.(*MemoryAccount).Key)
+	newKey := hexString(u.Account.(*MemoryAccount).Key)
+
+	if other := v.users[newKey]; other != nil && !strings.EqualFold(other.Email, u.Email) {
+		return errors.New("User ", u.Email, " has the password of another user.")
+	}
+	if oldKey == newKey {
+		v.users[newKey] = u
+		v.email[le] = u
+		return nil
+	}
+	v.unretire(le)
+	v.users[newKey] = u
+	v.email[le] = u
+	if overlap <= 0 {
+		delete(v.users, oldKey)
+		return nil
+	}
+	v.users[oldKey] = u
+	r := &retiredKey{key: oldKey}
+	v.retired[le] = r
+	time.AfterFunc(overlap, func() {
+		v.access.Lock()
+		defer v.access.Unlock()
+		if v.retired[le] == r {
+			delete(v.retired, le)
+			delete(v.users, oldKey)
+		}
+	})
+	return nil
+}
+
+// unretire stops accepting the key the user changed from. The caller must hold the lock.
+func (v *Validator) unretire(le string) {
+	if r, found := v.retired[le]; found {
+		delete(v.retired, le)
+		delete(v.users, r.key)
+	}
+}
+
+// Replace implements proxy.UserSet, with a proxy.UserEditor of a copy of the users.
+func (v *Validator) Replace(remove []string, update, add []*protocol.MemoryUser) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	next := &Validator{
+		email:   maps.Clone(v.email),
+		users:   maps.Clone(v.users),
+		retired: maps.Clone(v.retired),
+	}
+	next.initMaps()
+	err := proxy.UserEditor{
+		Remove: next.del,
+		Update: func(u *protocol.MemoryUser) error { return next.update(u, 0) },
+		Add:    next.add,
+	}.Replace(remove, update, add)
+	if err != nil {
+		return err
+	}
+	v.email, v.users, v.retired = next.email, next.users, next.retired
 	return nil
 }
 
 // Get a trojan user with hashed key, nil if user doesn't exist.
 func (v *Validator) Get(hash string) *protocol.MemoryUser {
-	u, _ := v.users.Load(hash)
+	v.access.RLock()
+	u := v.users[hash]
+	v.access.RUnlock()
 	if u != nil {
-		return u.(*protocol.MemoryUser)
+		return u
+	}
+	if v.External != nil {
+		// The key is the hex SHA224 of the password, as sent by the client.
+		key, err := hex.DecodeString(hash)
+		if err != nil {
+			return nil
+		}
+		if _, err := hex.DecodeString(string(key)); err != nil {
+			return nil
+		}
+		return v.External.Get(string(key), func(r *externalauth.AuthorizeResponse) (*protocol.MemoryUser, error) {
+			return &protocol.MemoryUser{
+				Account: &MemoryAccount{Key: key},
+				Email:   r.Email,
+				Level:   r.Level,
+			}, nil
+		})
 	}
 	return nil
 }
 
 // Get a trojan user with hashed key, nil if user doesn't exist.
 func (v *Validator) GetByEmail(email string) *protocol.MemoryUser {
-	u, _ := v.email.Load(email)
-	if u != nil {
-		return u.(*protocol.MemoryUser)
-	}
-	return nil
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return v.email[email]
 }
 
 // Get all users
 func (v *Validator) GetAll() []*protocol.MemoryUser {
-	var u = make([]*protocol.MemoryUser, 0, 100)
-	v.email.Range(func(key, value interface{}) bool {
-		u = append(u, value.(*protocol.MemoryUser))
-		return true
-	})
-	return u
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return slices.Collect(maps.Values(v.email))
 }
 
 // Get users count
 func (v *Validator) GetCount() int64 {
-	var c int64 = 0
-	v.email.Range(func(key, value interface{}) bool {
-		c++
-		return true
-	})
-	return c
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return int64(len(v.email))
 }
@@ -0,0 +1,67 @@
+package trojan_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol"
+	. "github.com/xtls/xray-core/proxy/trojan"
+)
+
+func hashOf(u *protocol.MemoryUser) string {
+	return fmt.Sprintf("%x", u.Account.(*MemoryAccount).Key)
+}
+
+func TestValidatorUpdate(t *testing.T) {
+	v := new(Validator)
+	user := &protocol.MemoryUser{Email: "love@xray.com", Account: toAccount(&Account{Password: "old"})}
+	common.Must(v.Add(user))
+
+	leveled := &protocol.MemoryUser{Email: "love@xray.com", Level: 1, Account: toAccount(&Account{Password: "old"})}
+	common.Must(v.Update(leveled, 0))
+	if v.Get(hashOf(user)) != leveled || v.GetByEmail("love@xray.com") != leveled {
+		t.Error("expected user to be updated")
+	}
+
+	rotated := &protocol.MemoryUser{Email: "love@xray.com", Level: 1, Account: toAccount(&Account{Password: "new"})}
+	common.Must(v.Update(rotated, 100*time.Millisecond))
+	if v.Get(hashOf(rotated)) != rotated {
+		t.Error("expected new password to be accepted")
+	}
+	if v.Get(hashOf(user)) != rotated {
+		t.Error("expected old password to be accepted for the updated user during overlap")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if v.Get(hashOf(user)) != nil {
+		t.Error("expected old password to be rejected after overlap")
+	}
+
+	common.Must(v.Update(&protocol.MemoryUser{Email: "love@xray.com", Account: toAccount(&Account{Password: "newer"})}, time.Hour))
+	common.Must(v.Del("love@xray.com"))
+	if v.Get(hashOf(rotated)) != nil {
+		t.Error("expected old password to be rejected after removal")
+	}
+
+	if err := v.Update(rotated, 0); err == nil {
+		t.Error("expected error for unknown user")
+	}
+}
+
+func TestValidatorCollision(t *testing.T) {
+	v := new(Validator)
+	common.Must(v.Add(&protocol.MemoryUser{Email: "a@xray.com", Account: toAccount(&Account{Password: "a"})}))
+	common.Must(v.Add(&protocol.MemoryUser{Email: "b@xray.com", Account: toAccount(&Account{Password: "b"})}))
+
+	if err := v.Add(&protocol.MemoryUser{Email: "c@xray.com", Account: toAccount(&Account{Password: "a"})}); err == nil {
+		t.Error("expected error for the password of another user")
+	}
+	stolen := &protocol.MemoryUser{Email: "b@xray.com", Account: toAccount(&Account{Password: "a"})}
+	if err := v.Update(stolen, 0); err == nil {
+		t.Error("expected error for the password of another user")
+	}
+	if u := v.Get(hashOf(stolen)); u == nil || u.Email != "a@xray.com" {
+		t.Error("expected the password to stay with its user")
+	}
+}
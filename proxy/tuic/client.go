@@ -0,0 +1,427 @@
+package tuic
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/retry"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tuic"
+)
+
+const defaultHeartbeat = 10 * time.Second
+
+// Client is an outbound connection handler for TUIC protocol.
+type Client struct {
+	serverPicker  protocol.ServerPicker
+	policyManager policy.Manager
+	udpRelayMode  UDPRelayMode
+	heartbeat     time.Duration
+
+	access   sync.Mutex
+	sessions map[sessionKey]*sessionEntry
+}
+
+type sessionKey struct {
+	net.Destination
+	account *MemoryAccount
+}
+
+type sessionEntry struct {
+	access  sync.Mutex
+	session *clientSession
+}
+
+// NewClient creates a new TUIC client.
+func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
+	serverList := protocol.NewServerList()
+	for _, rec := range config.Server {
+		s, err := protocol.NewServerSpecFromPB(rec)
+		if err != nil {
+			return nil, errors.New("failed to parse server spec").Base(err)
+		}
+		serverList.AddServer(s)
+	}
+	if serverList.Size() == 0 {
+		return nil, errors.New("0 server")
+	}
+
+	v := core.MustFromContext(ctx)
+	c := &Client{
+		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
+		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		udpRelayMode:  config.UdpRelayMode,
+		heartbeat:     defaultHeartbeat,
+		sessions:      make(map[sessionKey]*sessionEntry),
+	}
+	if config.Heartbeat > 0 {
+		c.heartbeat = time.Duration(config.Heartbeat) * time.Second
+	}
+	return c, nil
+}
+
+// Process implements OutboundHandler.Process().
+func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
+	outbounds := session.OutboundsFromContext(ctx)
+	ob := outbounds[len(outbounds)-1]
+	if !ob.Target.IsValid() {
+		return errors.New("target not specified")
+	}
+	ob.Name = "tuic"
+	ob.CanSpliceCopy = 3
+	destination := ob.Target
+
+	var server *protocol.ServerSpec
+	var user *protocol.MemoryUser
+	var s *clientSession
+	err := retry.ExponentialBackoff(5, 100).On(func() error {
+		server = c.serverPicker.PickServer()
+		user = server.PickUser()
+		account, ok := user.Account.(*MemoryAccount)
+		if !ok {
+			return errors.New("user account is not valid")
+		}
+		var err error
+		s, err = c.getSession(ctx, dialer, server.Destination(), account)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to find an available destination").AtWarning().Base(err)
+	}
+	errors.LogInfo(ctx, "tunneling request to ", destination, " via ", server.Destination().NetAddr())
+	defer s.release()
+
+	sessionPolicy := policy.ForUser(c.policyManager, user)
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+
+	if destination.Network == net.Network_UDP {
+		return c.processUDP(ctx, sessionPolicy, timer, s, destination, link)
+	}
+
+	conn, err := s.conn.OpenStream(ctx)
+	if err != nil {
+		return errors.New("failed to open stream").Base(err)
+	}
+	if s.statConn != nil {
+		conn = &stat.CounterConnection{
+			Connection:   conn,
+			ReadCounter:  s.statConn.ReadCounter,
+			WriteCounter: s.statConn.WriteCounter,
+		}
+	}
+	defer conn.Close()
+
+	postRequest := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		if err := WriteConnect(conn, destination); err != nil {
+			return errors.New("failed to write request").Base(err).AtWarning()
+		}
+		if err := buf.Copy(link.Reader, buf.NewWriter(conn), buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transfer request payload").Base(err).AtInfo()
+		}
+		return nil
+	}
+
+	getResponse := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		return buf.Copy(buf.NewReader(conn), link.Writer, buf.UpdateActivity(timer))
+	}
+
+	responseDoneAndCloseWriter := task.OnSuccess(getResponse, task.Close(link.Writer))
+	if err := task.Run(ctx, postRequest, responseDoneAndCloseWriter); err != nil {
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
+
+func (c *Client) processUDP(ctx context.Context, sessionPolicy policy.Session, timer *signal.ActivityTimer, s *clientSession, destination net.Destination, link *transport.Link) error {
+	association := s.associate()
+	defer s.dissociate(association)
+
+	var packetID uint16
+	postRequest := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		for {
+			mb, err := link.Reader.ReadMultiBuffer()
+			if err != nil {
+				return nil
+			}
+			timer.Update()
+			for _, b := range mb {
+				target := destination
+				if b.UDP != nil {
+					target = *b.UDP
+				}
+				packetID++
+				packet := &Packet{
+					AssocID:   association.id,
+					PacketID:  packetID,
+					FragTotal: 1,
+					Address:   &target,
+					Data:      b.Bytes(),
+				}
+				if err := sendPacket(ctx, s.conn, c.udpRelayMode, packet); err != nil {
+					errors.LogInfoInner(ctx, err, "failed to send UDP packet to ", target)
+				}
+				b.Release()
+			}
+		}
+	}
+
+	getResponse := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		var defragger Defragger
+		for {
+			var packet *Packet
+			select {
+			case packet = <-association.packets:
+			case <-s.conn.Done():
+				return nil
+			case <-ctx.Done():
+				return nil
+			}
+			if packet = defragger.Feed(packet); packet == nil {
+				continue
+			}
+			b := buf.FromBytes(packet.Data)
+			b.UDP = packet.Address
+			if err := link.Writer.WriteMultiBuffer(buf.MultiBuffer{b}); err != nil {
+				return err
+			}
+			timer.Update()
+		}
+	}
+
+	responseDoneAndCloseWriter := task.OnSuccess(getResponse, task.Close(link.Writer))
+	if err := task.Run(ctx, postRequest, responseDoneAndCloseWriter); err != nil {
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
+
+// getSession returns the connection to dest authenticated by account, which is shared by every request to them.
+func (c *Client) getSession(ctx context.Context, dialer internet.Dialer, dest net.Destination, account *MemoryAccount) (*clientSession, error) {
+	key := sessionKey{dest, account}
+	c.access.Lock()
+	entry := c.sessions[key]
+	if entry == nil {
+		entry = new(sessionEntry)
+		c.sessions[key] = entry
+	}
+	c.access.Unlock()
+
+	entry.access.Lock()
+	defer entry.access.Unlock()
+	if entry.session == nil || entry.session.closed() {
+		conn, err := dialer.Dial(ctx, dest)
+		if err != nil {
+			return nil, err
+		}
+		statConn, _ := conn.(*stat.CounterConnection)
+		iConn := conn
+		if statConn != nil {
+			iConn = statConn.Connection
+		}
+		tuicConn, ok := iConn.(*tuic.Conn)
+		if !ok {
+			conn.Close()
+			return nil, errors.New("TUIC outbound requires the TUIC transport")
+		}
+		entry.session = newClientSession(context.WithoutCancel(ctx), tuicConn, statConn, account, c.heartbeat)
+	}
+	entry.session.acquire()
+	return entry.session, nil
+}
+
+// clientSession is an authenticated connection of a client, kept alive by heartbeats while it's in use.
+type clientSession struct {
+	ctx      context.Context
+	conn     *tuic.Conn
+	statConn *stat.CounterConnection
+
+	access       sync.Mutex
+	refs         int
+	associations map[uint16]*clientAssociation
+	nextAssocID  uint16
+}
+
+type clientAssociation struct {
+	id      uint16
+	packets chan *Packet
+}
+
+func newClientSession(ctx context.Context, conn *tuic.Conn, statConn *stat.CounterConnection, account *MemoryAccount, heartbeat time.Duration) *clientSession {
+	s := &clientSession{
+		ctx:          ctx,
+		conn:         conn,
+		statConn:     statConn,
+		associations: make(map[uint16]*clientAssociation),
+	}
+	go s.authenticate(account)
+	go s.keepAlive(heartbeat)
+	go s.receiveDatagrams()
+	go s.acceptUniStreams()
+	return s
+}
+
+// authenticate sends the authentication once the handshake completes, while the requests may be sent in 0-RTT.
+func (s *clientSession) authenticate(account *MemoryAccount) {
+	err := func() error {
+		token, err := s.conn.ExportKeyingMaterial(string(account.UUID.Bytes()), []byte(account.Password), TokenLength)
+		if err != nil {
+			return err
+		}
+		stream, err := s.conn.OpenUniStream(s.ctx)
+		if err != nil {
+			return err
+		}
+		defer stream.Close()
+		return WriteAuthenticate(stream, account.UUID, token)
+	}()
+	if err != nil {
+		errors.LogWarningInner(s.ctx, err, "failed to authenticate to TUIC server ", s.conn.RemoteAddr())
+		s.conn.Close()
+	}
+}
+
+func (s *clientSession) keepAlive(heartbeat time.Duration) {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.conn.Done():
+			return
+		}
+		s.access.Lock()
+		inUse := s.refs > 0
+		s.access.Unlock()
+		if inUse {
+			s.conn.SendDatagram(Heartbeat)
+		}
+	}
+}
+
+func (s *clientSession) closed() bool {
+	select {
+	case <-s.conn.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *clientSession) acquire() {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.refs++
+}
+
+// release stops the heartbeats when the connection isn't in use, so that it's closed by the idle timeout.
+func (s *clientSession) release() {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.refs--
+}
+
+func (s *clientSession) associate() *clientAssociation {
+	s.access.Lock()
+	defer s.access.Unlock()
+	for s.associations[s.nextAssocID] != nil {
+		s.nextAssocID++
+	}
+	association := &clientAssociation{
+		id:      s.nextAssocID,
+		packets: make(chan *Packet, 256),
+	}
+	s.associations[association.id] = association
+	s.nextAssocID++
+	return association
+}
+
+func (s *clientSession) dissociate(association *clientAssociation) {
+	s.access.Lock()
+	delete(s.associations, association.id)
+	s.access.Unlock()
+	stream, err := s.conn.OpenUniStream(s.ctx)
+	if err != nil {
+		return
+	}
+	defer stream.Close()
+	WriteDissociate(stream, association.id)
+}
+
+// deliver passes packet to its association.
+func (s *clientSession) deliver(packet *Packet) {
+	s.access.Lock()
+	association := s.associations[packet.AssocID]
+	s.access.Unlock()
+	if association == nil {
+		return
+	}
+	select {
+	case association.packets <- packet:
+	default: // dropped like a UDP packet
+	}
+}
+
+func (s *clientSession) receiveDatagrams() {
+	for {
+		datagram, err := s.conn.ReceiveDatagram(s.ctx)
+		if err != nil {
+			return
+		}
+		r := bytes.NewReader(datagram)
+		if command, err := ReadCommand(r); err != nil || command != CommandPacket {
+			continue
+		}
+		packet, err := ReadPacket(r)
+		if err != nil {
+			errors.LogDebugInner(s.ctx, err, "invalid UDP packet")
+			continue
+		}
+		s.deliver(packet)
+	}
+}
+
+func (s *clientSession) acceptUniStreams() {
+	for {
+		stream, err := s.conn.AcceptUniStream(s.ctx)
+		if err != nil {
+			return
+		}
+		go func() {
+			defer stream.CancelRead(0)
+			if command, err := ReadCommand(stream); err != nil || command != CommandPacket {
+				return
+			}
+			packet, err := ReadPacket(stream)
+			if err != nil {
+				errors.LogDebugInner(s.ctx, err, "invalid UDP packet")
+				return
+			}
+			s.deliver(packet)
+		}()
+	}
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*ClientConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return NewClient(ctx, config.(*ClientConfig))
+	}))
+}
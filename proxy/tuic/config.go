@@ -0,0 +1,42 @@
+package tuic
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/uuid"
+)
+
+// MemoryAccount is an account type converted from Account.
+type MemoryAccount struct {
+	UUID     uuid.UUID
+	Password string
+}
+
+// AsAccount implements protocol.AsAccount.
+func (a *Account) AsAccount() (protocol.Account, error) {
+	id, err := uuid.ParseString(a.GetUuid())
+	if err != nil {
+		return nil, errors.New("failed to parse UUID").Base(err).AtError()
+	}
+	return &MemoryAccount{
+		UUID:     id,
+		Password: a.GetPassword(),
+	}, nil
+}
+
+// Equals implements protocol.Account.Equals().
+func (a *MemoryAccount) Equals(another protocol.Account) bool {
+	if account, ok := another.(*MemoryAccount); ok {
+		return a.UUID == account.UUID && a.Password == account.Password
+	}
+	return false
+}
+
+func (a *MemoryAccount) ToProto() proto.Message {
+	return &Account{
+		Uuid:     a.UUID.String(),
+		Password: a.Password,
+	}
+}
@@ -0,0 +1,295 @@
+package tuic
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/uuid"
+)
+
+const (
+	Version = 5
+
+	CommandAuthenticate = 0x00
+	CommandConnect      = 0x01
+	CommandPacket       = 0x02
+	CommandDissociate   = 0x03
+	CommandHeartbeat    = 0x04
+
+	addressTypeDomain = 0x00
+	addressTypeIPv4   = 0x01
+	addressTypeIPv6   = 0x02
+	addressTypeNone   = 0xff
+
+	// TokenLength is the length of the token a client authenticates with.
+	TokenLength = 32
+
+	// packetHeaderSize is the size of a packet command without its address and data.
+	packetHeaderSize = 2 + 2 + 2 + 1 + 1 + 2
+)
+
+func appendAddress(b []byte, dest *net.Destination) []byte {
+	if dest == nil {
+		return append(b, addressTypeNone)
+	}
+	switch dest.Address.Family() {
+	case net.AddressFamilyIPv4:
+		b = append(append(b, addressTypeIPv4), dest.Address.IP()...)
+	case net.AddressFamilyIPv6:
+		b = append(append(b, addressTypeIPv6), dest.Address.IP()...)
+	default:
+		domain := dest.Address.Domain()
+		b = append(append(b, addressTypeDomain, byte(len(domain))), domain...)
+	}
+	return binary.BigEndian.AppendUint16(b, uint16(dest.Port))
+}
+
+func addressSize(dest *net.Destination) int {
+	if dest == nil {
+		return 1
+	}
+	switch dest.Address.Family() {
+	case net.AddressFamilyIPv4:
+		return 1 + net.IPv4len + 2
+	case net.AddressFamilyIPv6:
+		return 1 + net.IPv6len + 2
+	default:
+		return 1 + 1 + len(dest.Address.Domain()) + 2
+	}
+}
+
+// readAddress reads an address of network, nil if it's none.
+func readAddress(r io.Reader, network net.Network) (*net.Destination, error) {
+	var b [256]byte
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return nil, err
+	}
+	var address net.Address
+	switch b[0] {
+	case addressTypeNone:
+		return nil, nil
+	case addressTypeIPv4:
+		if _, err := io.ReadFull(r, b[:net.IPv4len]); err != nil {
+			return nil, err
+		}
+		address = net.IPAddress(b[:net.IPv4len])
+	case addressTypeIPv6:
+		if _, err := io.ReadFull(r, b[:net.IPv6len]); err != nil {
+			return nil, err
+		}
+		address = net.IPAddress(b[:net.IPv6len])
+	case addressTypeDomain:
+		if _, err := io.ReadFull(r, b[:1]); err != nil {
+			return nil, err
+		}
+		length := int(b[0])
+		if _, err := io.ReadFull(r, b[:length]); err != nil {
+			return nil, err
+		}
+		address = net.ParseAddress(string(b[:length]))
+	default:
+		return nil, errors.New("unknown address type ", b[0])
+	}
+	if _, err := io.ReadFull(r, b[:2]); err != nil {
+		return nil, err
+	}
+	dest := net.Destination{
+		Network: network,
+		Address: address,
+		Port:    net.PortFromBytes(b[:2]),
+	}
+	return &dest, nil
+}
+
+// ReadCommand reads the version and the type of a command.
+func ReadCommand(r io.Reader) (byte, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	if b[0] != Version {
+		return 0, errors.New("unknown version ", b[0])
+	}
+	return b[1], nil
+}
+
+// WriteAuthenticate writes the command authenticating a connection.
+func WriteAuthenticate(w io.Writer, id uuid.UUID, token []byte) error {
+	b := make([]byte, 0, 2+len(id)+TokenLength)
+	b = append(b, Version, CommandAuthenticate)
+	b = append(b, id.Bytes()...)
+	b = append(b, token...)
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadAuthenticate reads the command authenticating a connection, after its type.
+func ReadAuthenticate(r io.Reader) (uuid.UUID, []byte, error) {
+	var id uuid.UUID
+	if _, err := io.ReadFull(r, id[:]); err != nil {
+		return id, nil, err
+	}
+	token := make([]byte, TokenLength)
+	if _, err := io.ReadFull(r, token); err != nil {
+		return id, nil, err
+	}
+	return id, token, nil
+}
+
+// WriteConnect writes the command starting a stream relaying TCP to dest.
+func WriteConnect(w io.Writer, dest net.Destination) error {
+	_, err := w.Write(appendAddress([]byte{Version, CommandConnect}, &dest))
+	return err
+}
+
+// ReadConnect reads the command starting a stream relaying TCP, after its type.
+func ReadConnect(r io.Reader) (net.Destination, error) {
+	dest, err := readAddress(r, net.Network_TCP)
+	if err != nil {
+		return net.Destination{}, err
+	}
+	if dest == nil {
+		return net.Destination{}, errors.New("no address to connect to")
+	}
+	return *dest, nil
+}
+
+// WriteDissociate writes the command ending a UDP association.
+func WriteDissociate(w io.Writer, assocID uint16) error {
+	b := binary.BigEndian.AppendUint16([]byte{Version, CommandDissociate}, assocID)
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadDissociate reads the command ending a UDP association, after its type.
+func ReadDissociate(r io.Reader) (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b[:]), nil
+}
+
+// Heartbeat is the datagram keeping a connection alive.
+var Heartbeat = []byte{Version, CommandHeartbeat}
+
+// Packet is a UDP packet of an association, or a fragment of it. Only the first fragment has the address.
+type Packet struct {
+	AssocID   uint16
+	PacketID  uint16
+	FragTotal uint8
+	FragID    uint8
+	Address   *net.Destination
+	Data      []byte
+}
+
+// Bytes returns the command of p.
+func (p *Packet) Bytes() []byte {
+	b := make([]byte, 0, packetHeaderSize+addressSize(p.Address)+len(p.Data))
+	b = append(b, Version, CommandPacket)
+	b = binary.BigEndian.AppendUint16(b, p.AssocID)
+	b = binary.BigEndian.AppendUint16(b, p.PacketID)
+	b = append(b, p.FragTotal, p.FragID)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(p.Data)))
+	b = appendAddress(b, p.Address)
+	return append(b, p.Data...)
+}
+
+// ReadPacket reads the command of a UDP packet, after its type.
+func ReadPacket(r io.Reader) (*Packet, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, err
+	}
+	p := &Packet{
+		AssocID:   binary.BigEndian.Uint16(b[0:]),
+		PacketID:  binary.BigEndian.Uint16(b[2:]),
+		FragTotal: b[4],
+		FragID:    b[5],
+	}
+	if p.FragTotal == 0 || p.FragID >= p.FragTotal {
+		return nil, errors.New("invalid fragment ", p.FragID, " of ", p.FragTotal)
+	}
+	var err error
+	if p.Address, err = readAddress(r, net.Network_UDP); err != nil {
+		return nil, errors.New("failed to read address").Base(err)
+	}
+	if p.FragID == 0 && p.Address == nil {
+		return nil, errors.New("no address of packet")
+	}
+	p.Data = make([]byte, binary.BigEndian.Uint16(b[6:]))
+	if _, err := io.ReadFull(r, p.Data); err != nil {
+		return nil, errors.New("failed to read data").Base(err)
+	}
+	return p, nil
+}
+
+// FragmentPacket splits p into the packets whose commands are no larger than maxSize, or returns it as is if it fits.
+func FragmentPacket(p *Packet, maxSize int) []*Packet {
+	// Every fragment fits, if the first one with the address does.
+	size := maxSize - packetHeaderSize - addressSize(p.Address)
+	if len(p.Data) <= size {
+		return []*Packet{p}
+	}
+	if size <= 0 {
+		return nil
+	}
+	count := (len(p.Data) + size - 1) / size
+	if count > 255 {
+		return nil
+	}
+	fragments := make([]*Packet, 0, count)
+	for i := 0; i < count; i++ {
+		fragment := *p
+		fragment.FragTotal = uint8(count)
+		fragment.FragID = uint8(i)
+		fragment.Data = p.Data[i*size : min((i+1)*size, len(p.Data))]
+		if i > 0 {
+			fragment.Address = nil
+		}
+		fragments = append(fragments, &fragment)
+	}
+	return fragments
+}
+
+// Defragger assembles the fragments of the latest packet of an association.
+type Defragger struct {
+	packetID  uint16
+	fragments []*Packet
+	count     int
+	size      int
+}
+
+// Feed returns the packet p completes, or nil if it's yet to be completed.
+func (d *Defragger) Feed(p *Packet) *Packet {
+	if p.FragTotal == 1 {
+		return p
+	}
+	if d.fragments == nil || p.PacketID != d.packetID || int(p.FragTotal) != len(d.fragments) {
+		// A new packet, so the previous one is lost.
+		d.packetID = p.PacketID
+		d.fragments = make([]*Packet, p.FragTotal)
+		d.count = 0
+		d.size = 0
+	}
+	if d.fragments[p.FragID] != nil {
+		return nil
+	}
+	d.fragments[p.FragID] = p
+	d.count++
+	d.size += len(p.Data)
+	if d.count < len(d.fragments) {
+		return nil
+	}
+	data := make([]byte, 0, d.size)
+	for _, fragment := range d.fragments {
+		data = append(data, fragment.Data...)
+	}
+	packet := *d.fragments[0]
+	packet.FragTotal = 1
+	packet.Data = data
+	d.fragments = nil
+	return &packet
+}
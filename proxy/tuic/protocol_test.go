@@ -0,0 +1,111 @@
+package tuic_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/uuid"
+	. "github.com/xtls/xray-core/proxy/tuic"
+)
+
+func TestCommands(t *testing.T) {
+	id := uuid.New()
+	token := bytes.Repeat([]byte{1}, TokenLength)
+	destination := net.TCPDestination(net.DomainAddress("example.com"), 443)
+
+	var buffer bytes.Buffer
+	common.Must(WriteAuthenticate(&buffer, id, token))
+	common.Must(WriteConnect(&buffer, destination))
+	common.Must(WriteDissociate(&buffer, 7))
+
+	command, err := ReadCommand(&buffer)
+	common.Must(err)
+	if command != CommandAuthenticate {
+		t.Fatal("command: ", command)
+	}
+	readID, readToken, err := ReadAuthenticate(&buffer)
+	common.Must(err)
+	if readID != id || !bytes.Equal(readToken, token) {
+		t.Error("authenticate: ", readID, " ", readToken)
+	}
+
+	command, err = ReadCommand(&buffer)
+	common.Must(err)
+	if command != CommandConnect {
+		t.Fatal("command: ", command)
+	}
+	readDestination, err := ReadConnect(&buffer)
+	common.Must(err)
+	if r := cmp.Diff(readDestination, destination); r != "" {
+		t.Error(r)
+	}
+
+	command, err = ReadCommand(&buffer)
+	common.Must(err)
+	if command != CommandDissociate {
+		t.Fatal("command: ", command)
+	}
+	assocID, err := ReadDissociate(&buffer)
+	common.Must(err)
+	if assocID != 7 {
+		t.Error("association: ", assocID)
+	}
+
+	if _, err := ReadCommand(bytes.NewReader([]byte{4, CommandConnect})); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+func TestPacketFragment(t *testing.T) {
+	data := make([]byte, 3000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	destination := net.UDPDestination(net.IPAddress([]byte{1, 2, 3, 4}), 53)
+	packet := &Packet{
+		AssocID:   1,
+		PacketID:  2,
+		FragTotal: 1,
+		Address:   &destination,
+		Data:      data,
+	}
+	fragments := FragmentPacket(packet, 1200)
+	if len(fragments) != 3 {
+		t.Fatal("fragments: ", len(fragments))
+	}
+
+	var defragger Defragger
+	for i := len(fragments) - 1; i >= 0; i-- {
+		b := fragments[i].Bytes()
+		if len(b) > 1200 {
+			t.Error("fragment size: ", len(b))
+		}
+		r := bytes.NewReader(b)
+		command, err := ReadCommand(r)
+		common.Must(err)
+		if command != CommandPacket {
+			t.Fatal("command: ", command)
+		}
+		fragment, err := ReadPacket(r)
+		common.Must(err)
+		if (i == 0) != (fragment.Address != nil) {
+			t.Error("only the first fragment has the address")
+		}
+		assembled := defragger.Feed(fragment)
+		if i > 0 {
+			if assembled != nil {
+				t.Error("unexpected packet before the last fragment")
+			}
+			continue
+		}
+		if assembled == nil {
+			t.Fatal("expected packet after the last fragment")
+		}
+		if r := cmp.Diff(assembled, packet); r != "" {
+			t.Error(r)
+		}
+	}
+}
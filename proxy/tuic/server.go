@@ -0,0 +1,495 @@
+package tuic
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"sync"
+	"time"
+
+	"github.com/apernet/quic-go"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	udp_proto "github.com/xtls/xray-core/common/protocol/udp"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tuic"
+	"github.com/xtls/xray-core/transport/internet/udp"
+)
+
+const (
+	defaultAuthTimeout = 3 * time.Second
+	// udpSessionTimeout is how long an association of a client is kept without any packet.
+	udpSessionTimeout = 2 * time.Minute
+
+	errorCodeAuthFailed  = 0x100
+	errorCodeAuthTimeout = 0x101
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*ServerConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return NewServer(ctx, config.(*ServerConfig))
+	}))
+}
+
+// Server is an inbound connection handler that handles the connections of the TUIC transport.
+type Server struct {
+	policyManager policy.Manager
+	validator     *Validator
+	usersAccess   sync.Mutex
+	authTimeout   time.Duration
+}
+
+// NewServer creates a new TUIC inbound handler.
+func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
+	validator := new(Validator)
+	for _, user := range config.Users {
+		u, err := user.ToMemoryUser()
+		if err != nil {
+			return nil, errors.New("failed to get TUIC user").Base(err).AtError()
+		}
+		if err := validator.Add(u); err != nil {
+			return nil, errors.New("failed to add user").Base(err).AtError()
+		}
+	}
+
+	v := core.MustFromContext(ctx)
+	s := &Server{
+		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		validator:     validator,
+		authTimeout:   defaultAuthTimeout,
+	}
+	if config.AuthTimeout > 0 {
+		s.authTimeout = time.Duration(config.AuthTimeout) * time.Second
+	}
+	return s, nil
+}
+
+// AddUser implements proxy.UserManager.AddUser().
+func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
+	if _, ok := u.Account.(*MemoryAccount); !ok {
+		return errors.New("User ", u.Email, " is not a TUIC user.")
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return s.validator.Add(u)
+}
+
+// RemoveUser implements proxy.UserManager.RemoveUser().
+func (s *Server) RemoveUser(ctx context.Context, e string) error {
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return s.validator.Del(e)
+}
+
+// SyncUsers implements proxy.UserManager.SyncUsers().
+func (s *Server) SyncUsers(ctx context.Context, users []*protocol.MemoryUser) (int, int, error) {
+	for _, u := range users {
+		if _, ok := u.Account.(*MemoryAccount); !ok {
+			return 0, 0, errors.New("User ", u.Email, " is not a TUIC user.")
+		}
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return proxy.ApplyUsers(s.validator.GetAll(), users, s.validator)
+}
+
+// UpdateUser implements proxy.UserManager.UpdateUser().
+func (s *Server) UpdateUser(ctx context.Context, u *protocol.MemoryUser, overlap time.Duration) error {
+	if _, ok := u.Account.(*MemoryAccount); !ok {
+		return errors.New("User ", u.Email, " is not a TUIC user.")
+	}
+	s.usersAccess.Lock()
+	defer s.usersAccess.Unlock()
+	return s.validator.Update(u, overlap)
+}
+
+// GetUser implements proxy.UserManager.GetUser().
+func (s *Server) GetUser(ctx context.Context, email string) *protocol.MemoryUser {
+	return s.validator.GetByEmail(email)
+}
+
+// GetUsers implements proxy.UserManager.GetUsers().
+func (s *Server) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return s.validator.GetAll()
+}
+
+// GetUsersCount implements proxy.UserManager.GetUsersCount().
+func (s *Server) GetUsersCount(context.Context) int64 {
+	return s.validator.GetCount()
+}
+
+// Network implements proxy.Inbound.Network().
+func (s *Server) Network() []net.Network {
+	return []net.Network{net.Network_TCP}
+}
+
+// Process implements proxy.Inbound.Process(). conn is a whole TUIC connection, which is authenticated first, and
+// whose TCP streams and UDP associations are dispatched until it's closed.
+func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Connection, dispatcher routing.Dispatcher) error {
+	iConn := conn
+	statConn, ok := iConn.(*stat.CounterConnection)
+	if ok {
+		iConn = statConn.Connection
+	}
+	tuicConn, ok := iConn.(*tuic.Conn)
+	if !ok {
+		return errors.New("TUIC inbound requires the TUIC transport")
+	}
+
+	inbound := session.InboundFromContext(ctx)
+	inbound.Name = "tuic"
+	inbound.CanSpliceCopy = 3
+
+	c := &serverConn{
+		server:        s,
+		ctx:           ctx,
+		conn:          tuicConn,
+		dispatcher:    dispatcher,
+		authenticated: make(chan struct{}),
+		associations:  make(map[uint16]*serverAssociation),
+	}
+	defer c.dissociateAll()
+	go c.acceptUniStreams()
+	go c.receiveDatagrams()
+
+	timer := time.NewTimer(s.authTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.authenticated:
+	case <-timer.C:
+		tuicConn.CloseWithError(errorCodeAuthTimeout, "authentication timeout")
+		return errors.New("authentication timeout of ", conn.RemoteAddr())
+	case <-tuicConn.Done():
+		return nil
+	}
+
+	user := c.user
+	inbound.User = user
+	release, err := policy.TrackUser(s.policyManager, user, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   conn.RemoteAddr(),
+			To:     "",
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  user.Email,
+		})
+		return errors.New("rejected request from ", conn.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer release()
+
+	sessionPolicy := policy.ForUser(s.policyManager, user)
+	for {
+		stream, err := tuicConn.AcceptStream(ctx)
+		if err != nil {
+			return nil
+		}
+		if statConn != nil {
+			stream = &stat.CounterConnection{
+				Connection:   stream,
+				ReadCounter:  statConn.ReadCounter,
+				WriteCounter: statConn.WriteCounter,
+			}
+		}
+		go func() {
+			if err := s.handleStream(ctx, sessionPolicy, user, stream, dispatcher); err != nil {
+				errors.LogInfoInner(ctx, err, "stream ends")
+			}
+			stream.Close()
+		}()
+	}
+}
+
+// streamContext returns the context of a stream or a UDP association of the connection of ctx.
+func streamContext(ctx context.Context) context.Context {
+	ctx = session.ContextCloneOutbounds(ctx)
+	if content := session.ContentFromContext(ctx); content != nil {
+		ctx = session.ContextWithContent(ctx, &session.Content{
+			SniffingRequest: content.SniffingRequest,
+		})
+	}
+	return ctx
+}
+
+func (s *Server) handleStream(ctx context.Context, sessionPolicy policy.Session, user *protocol.MemoryUser, stream stat.Connection, dispatcher routing.Dispatcher) error {
+	if err := stream.SetReadDeadline(time.Now().Add(sessionPolicy.Timeouts.Handshake)); err != nil {
+		return errors.New("unable to set read deadline").Base(err).AtWarning()
+	}
+	command, err := ReadCommand(stream)
+	if err != nil {
+		return errors.New("failed to read command").Base(err)
+	}
+	if command != CommandConnect {
+		return errors.New("unexpected command ", command, " in stream")
+	}
+	destination, err := ReadConnect(stream)
+	if err != nil {
+		return errors.New("failed to read address").Base(err)
+	}
+	if err := stream.SetReadDeadline(time.Time{}); err != nil {
+		return errors.New("unable to set read deadline").Base(err).AtWarning()
+	}
+
+	ctx = streamContext(ctx)
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   stream.RemoteAddr(),
+		To:     destination,
+		Status: log.AccessAccepted,
+		Reason: "",
+		Email:  user.Email,
+	})
+	errors.LogInfo(ctx, "received request for ", destination)
+
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
+
+	link, err := dispatcher.Dispatch(ctx, destination)
+	if err != nil {
+		return errors.New("failed to dispatch request to ", destination).Base(err)
+	}
+
+	requestDone := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.DownlinkOnly)
+		if err := buf.Copy(buf.NewReader(stream), link.Writer, buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to transfer request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		defer timer.SetTimeout(sessionPolicy.Timeouts.UplinkOnly)
+		if err := buf.Copy(link.Reader, buf.NewWriter(stream), buf.UpdateActivity(timer)); err != nil {
+			return errors.New("failed to write response").Base(err)
+		}
+		return nil
+	}
+
+	requestDonePost := task.OnSuccess(requestDone, task.Close(link.Writer))
+	if err := task.Run(ctx, requestDonePost, responseDone); err != nil {
+		common.Must(common.Interrupt(link.Reader))
+		common.Must(common.Interrupt(link.Writer))
+		return errors.New("connection ends").Base(err)
+	}
+	return nil
+}
+
+// serverConn is the state of a connection of a client.
+type serverConn struct {
+	server     *Server
+	ctx        context.Context
+	conn       *tuic.Conn
+	dispatcher routing.Dispatcher
+
+	authOnce      sync.Once
+	authenticated chan struct{}
+	user          *protocol.MemoryUser
+
+	access       sync.Mutex
+	associations map[uint16]*serverAssociation
+}
+
+type serverAssociation struct {
+	dispatcher *udp.Dispatcher
+	defragger  Defragger
+	lastActive time.Time
+}
+
+func (c *serverConn) authenticate(id uuid.UUID, token []byte) {
+	user := c.server.validator.Get(id, func(password string) bool {
+		expected, err := c.conn.ExportKeyingMaterial(string(id.Bytes()), []byte(password), TokenLength)
+		return err == nil && subtle.ConstantTimeCompare(expected, token) == 1
+	})
+	if user == nil {
+		log.Record(&log.AccessMessage{
+			From:   c.conn.RemoteAddr(),
+			To:     "",
+			Status: log.AccessRejected,
+			Reason: errors.New("invalid user ", id.String()),
+		})
+		c.conn.CloseWithError(errorCodeAuthFailed, "authentication failed")
+		return
+	}
+	c.authOnce.Do(func() {
+		c.user = user
+		close(c.authenticated)
+	})
+}
+
+// waitAuthenticated returns whether the connection is authenticated, waiting for it unless it's closed.
+func (c *serverConn) waitAuthenticated() bool {
+	select {
+	case <-c.authenticated:
+		return true
+	case <-c.conn.Done():
+		return false
+	}
+}
+
+func (c *serverConn) acceptUniStreams() {
+	for {
+		stream, err := c.conn.AcceptUniStream(c.ctx)
+		if err != nil {
+			return
+		}
+		go func() {
+			if err := c.handleUniStream(stream); err != nil {
+				errors.LogDebugInner(c.ctx, err, "invalid unidirectional stream")
+			}
+			stream.CancelRead(0)
+		}()
+	}
+}
+
+func (c *serverConn) handleUniStream(stream quic.ReceiveStream) error {
+	command, err := ReadCommand(stream)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case CommandAuthenticate:
+		id, token, err := ReadAuthenticate(stream)
+		if err != nil {
+			return err
+		}
+		c.authenticate(id, token)
+	case CommandPacket:
+		packet, err := ReadPacket(stream)
+		if err != nil {
+			return err
+		}
+		if c.waitAuthenticated() {
+			c.handlePacket(packet, UDPRelayMode_QUIC)
+		}
+	case CommandDissociate:
+		id, err := ReadDissociate(stream)
+		if err != nil {
+			return err
+		}
+		if c.waitAuthenticated() {
+			c.dissociate(id)
+		}
+	default:
+		return errors.New("unexpected command ", command, " in unidirectional stream")
+	}
+	return nil
+}
+
+func (c *serverConn) receiveDatagrams() {
+	for {
+		datagram, err := c.conn.ReceiveDatagram(c.ctx)
+		if err != nil {
+			return
+		}
+		if !c.waitAuthenticated() {
+			return
+		}
+		r := bytes.NewReader(datagram)
+		command, err := ReadCommand(r)
+		if err != nil {
+			errors.LogDebugInner(c.ctx, err, "invalid datagram")
+			continue
+		}
+		switch command {
+		case CommandHeartbeat:
+		case CommandPacket:
+			packet, err := ReadPacket(r)
+			if err != nil {
+				errors.LogDebugInner(c.ctx, err, "invalid UDP packet")
+				continue
+			}
+			c.handlePacket(packet, UDPRelayMode_NATIVE)
+		default:
+			errors.LogDebug(c.ctx, "unexpected command ", command, " in datagram")
+		}
+	}
+}
+
+// handlePacket dispatches a packet of an association, whose responses are sent in mode, as the client sends it.
+func (c *serverConn) handlePacket(packet *Packet, mode UDPRelayMode) {
+	c.access.Lock()
+	now := time.Now()
+	association := c.associations[packet.AssocID]
+	if association == nil {
+		for id, a := range c.associations {
+			if now.Sub(a.lastActive) > udpSessionTimeout {
+				a.dispatcher.RemoveRay()
+				delete(c.associations, id)
+			}
+		}
+		association = &serverAssociation{
+			dispatcher: udp.NewDispatcher(c.dispatcher, c.newUDPResponder(packet.AssocID, mode)),
+		}
+		c.associations[packet.AssocID] = association
+	}
+	association.lastActive = now
+	packet = association.defragger.Feed(packet)
+	c.access.Unlock()
+	if packet == nil {
+		return
+	}
+
+	destination := *packet.Address
+	payload := buf.FromBytes(packet.Data)
+	payload.UDP = &destination
+	packetCtx := log.ContextWithAccessMessage(streamContext(c.ctx), &log.AccessMessage{
+		From:   c.conn.RemoteAddr(),
+		To:     destination,
+		Status: log.AccessAccepted,
+		Reason: "",
+		Email:  c.user.Email,
+	})
+	association.dispatcher.Dispatch(packetCtx, destination, payload)
+}
+
+func (c *serverConn) dissociate(id uint16) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if association := c.associations[id]; association != nil {
+		association.dispatcher.RemoveRay()
+		delete(c.associations, id)
+	}
+}
+
+func (c *serverConn) dissociateAll() {
+	c.access.Lock()
+	defer c.access.Unlock()
+	for id, association := range c.associations {
+		association.dispatcher.RemoveRay()
+		delete(c.associations, id)
+	}
+}
+
+// newUDPResponder returns the callback sending the responses of an association back to the client.
+func (c *serverConn) newUDPResponder(assocID uint16, mode UDPRelayMode) udp.ResponseCallback {
+	var access sync.Mutex
+	var packetID uint16
+	return func(ctx context.Context, packet *udp_proto.Packet) {
+		defer packet.Payload.Release()
+		access.Lock()
+		packetID++
+		p := &Packet{
+			AssocID:   assocID,
+			PacketID:  packetID,
+			FragTotal: 1,
+			Address:   &packet.Source,
+			Data:      packet.Payload.Bytes(),
+		}
+		access.Unlock()
+		if err := sendPacket(ctx, c.conn, mode, p); err != nil {
+			errors.LogInfoInner(ctx, err, "failed to write UDP response")
+		}
+	}
+}
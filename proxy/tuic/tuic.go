@@ -0,0 +1,3 @@
+// Package tuic is the proxy of TUIC v5, which relays TCP by the streams and UDP by the datagrams or the
+// unidirectional streams of the connections of the TUIC transport.
+package tuic
@@ -0,0 +1,39 @@
+package tuic
+
+import (
+	"context"
+	goerrors "errors"
+
+	"github.com/apernet/quic-go"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/transport/internet/tuic"
+)
+
+// sendPacket sends packet by a unidirectional stream, or by datagrams, fragmenting it if it doesn't fit in one.
+func sendPacket(ctx context.Context, conn *tuic.Conn, mode UDPRelayMode, packet *Packet) error {
+	if mode == UDPRelayMode_QUIC {
+		stream, err := conn.OpenUniStream(ctx)
+		if err != nil {
+			return err
+		}
+		defer stream.Close()
+		_, err = stream.Write(packet.Bytes())
+		return err
+	}
+
+	err := conn.SendDatagram(packet.Bytes())
+	var tooLarge *quic.DatagramTooLargeError
+	if !goerrors.As(err, &tooLarge) {
+		return err
+	}
+	fragments := FragmentPacket(packet, int(tooLarge.MaxDataLen))
+	if fragments == nil {
+		return errors.New("UDP packet of ", len(packet.Data), " bytes is too large")
+	}
+	for _, fragment := range fragments {
+		if err := conn.SendDatagram(fragment.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package tuic
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/proxy"
+)
+
+// Validator stores valid TUIC users.
+type Validator struct {
+	access sync.RWMutex
+	email  map[string]*protocol.MemoryUser
+	users  map[uuid.UUID]*protocol.MemoryUser
+	// retired holds the account a user changed from, while it is still accepted.
+	retired map[string]*MemoryAccount
+}
+
+// initMaps creates the maps of a zero Validator. The caller must hold the lock.
+func (v *Validator) initMaps() {
+	if v.users == nil {
+		v.email = make(map[string]*protocol.MemoryUser)
+		v.users = make(map[uuid.UUID]*protocol.MemoryUser)
+		v.retired = make(map[string]*MemoryAccount)
+	}
+}
+
+// Add a TUIC user, Email must be empty or unique.
+func (v *Validator) Add(u *protocol.MemoryUser) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.add(u)
+}
+
+func (v *Validator) add(u *protocol.MemoryUser) error {
+	id := u.Account.(*MemoryAccount).UUID
+	if _, found := v.users[id]; found {
+		return errors.New("User ", u.Email, " has the UUID of another user.")
+	}
+	if u.Email != "" {
+		le := strings.ToLower(u.Email)
+		if _, found := v.email[le]; found {
+			return errors.New("User ", u.Email, " already exists.")
+		}
+		v.email[le] = u
+	}
+	v.users[id] = u
+	return nil
+}
+
+// Del a TUIC user with a non-empty Email.
+func (v *Validator) Del(e string) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.del(e)
+}
+
+func (v *Validator) del(e string) error {
+	if e == "" {
+		return errors.New("Email must not be empty.")
+	}
+	le := strings.ToLower(e)
+	u := v.email[le]
+	if u == nil {
+		return errors.New("User ", e, " not found.")
+	}
+	delete(v.email, le)
+	delete(v.users, u.Account.(*MemoryAccount).UUID)
+	delete(v.retired, le)
+	return nil
+}
+
+// Update replaces the TUIC user with the same non-empty Email. If the UUID or the password changes, the old ones are
+// still accepted for the updated user for overlap.
+func (v *Validator) Update(u *protocol.MemoryUser, overlap time.Duration) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.update(u, overlap)
+}
+
+func (v *Validator) update(u *protocol.MemoryUser, overlap time.Duration) error {
+	if u.Email == "" {
+		return errors.New("Email must not be empty.")
+	}
+	le := strings.ToLower(u.Email)
+	old := v.email[le]
+	if old == nil {
+		return errors.New("User ", u.Email, " not found.")
+	}
+	oldAccount := old.Account.(*MemoryAccount)
+	newAccount := u.Account.(*MemoryAccount)
+
+	if other := v.users[newAccount.UUID]; other != nil && !strings.EqualFold(other.Email, u.Email) {
+		return errors.New("User ", u.Email, " has the UUID of another user.")
+	}
+	delete(v.retired, le)
+	if oldAccount.UUID != newAccount.UUID {
+		delete(v.users, oldAccount.UUID)
+	}
+	v.users[newAccount.UUID] = u
+	v.email[le] = u
+	if overlap <= 0 || oldAccount.Equals(newAccount) {
+		return nil
+	}
+	v.retired[le] = oldAccount
+	time.AfterFunc(overlap, func() {
+		v.access.Lock()
+		defer v.access.Unlock()
+		if v.retired[le] == oldAccount {
+			delete(v.retired, le)
+		}
+	})
+	return nil
+}
+
+// Replace implements proxy.UserSet, with a proxy.UserEditor of a copy of the users.
+func (v *Validator) Replace(remove []string, update, add []*protocol.MemoryUser) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	next := &Validator{
+		email:   maps.Clone(v.email),
+		users:   maps.Clone(v.users),
+		retired: maps.Clone(v.retired),
+	}
+	next.initMaps()
+	err := proxy.UserEditor{
+		Remove: next.del,
+		Update: func(u *protocol.MemoryUser) error { return next.update(u, 0) },
+		Add:    next.add,
+	}.Replace(remove, update, add)
+	if err != nil {
+		return err
+	}
+	v.email, v.users, v.retired = next.email, next.users, next.retired
+	return nil
+}
+
+// Get a TUIC user with UUID whose password passes verify, nil if user doesn't exist.
+func (v *Validator) Get(id uuid.UUID, verify func(password string) bool) *protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+
+	if u := v.users[id]; u != nil && verify(u.Account.(*MemoryAccount).Password) {
+		return u
+	}
+	for le, account := range v.retired {
+		if account.UUID == id && verify(account.Password) {
+			return v.email[le]
+		}
+	}
+	return nil
+}
+
+// GetByEmail returns a TUIC user with email, nil if user doesn't exist.
+func (v *Validator) GetByEmail(email string) *protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return v.email[strings.ToLower(email)]
+}
+
+// GetAll returns all users.
+func (v *Validator) GetAll() []*protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return slices.Collect(maps.Values(v.email))
+}
+
+// GetCount returns the count of users.
+func (v *Validator) GetCount() int64 {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return int64(len(v.email))
+}
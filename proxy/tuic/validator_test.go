@@ -0,0 +1,52 @@
+package tuic_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/uuid"
+	. "github.com/xtls/xray-core/proxy/tuic"
+)
+
+func toAccount(a *Account) protocol.Account {
+	account, err := a.AsAccount()
+	common.Must(err)
+	return account
+}
+
+func password(p string) func(string) bool {
+	return func(password string) bool {
+		return password == p
+	}
+}
+
+func TestValidatorUpdate(t *testing.T) {
+	id := uuid.New()
+	v := new(Validator)
+	user := &protocol.MemoryUser{Email: "love@xray.com", Account: toAccount(&Account{Uuid: id.String(), Password: "old"})}
+	common.Must(v.Add(user))
+
+	if v.Get(id, password("wrong")) != nil {
+		t.Error("expected wrong password to be rejected")
+	}
+
+	rotated := &protocol.MemoryUser{Email: "love@xray.com", Level: 1, Account: toAccount(&Account{Uuid: id.String(), Password: "new"})}
+	common.Must(v.Update(rotated, 100*time.Millisecond))
+	if v.Get(id, password("new")) != rotated {
+		t.Error("expected new password to be accepted")
+	}
+	if v.Get(id, password("old")) != rotated {
+		t.Error("expected old password to be accepted for the updated user during overlap")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if v.Get(id, password("old")) != nil {
+		t.Error("expected old password to be rejected after overlap")
+	}
+
+	common.Must(v.Del("love@xray.com"))
+	if v.Get(id, password("new")) != nil || v.GetCount() != 0 {
+		t.Error("expected user to be removed")
+	}
+}
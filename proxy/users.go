@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/protocol"
+	"google.golang.org/protobuf/proto"
+)
+
+// DiffUsers compares the current users of a UserManager with the desired ones, and returns the users to remove and
+// the users to add to get from one to the other. Users are matched by email, case-insensitively, so all desired
+// users must have a unique non-empty email. A user whose account or settings changed is in both lists.
+func DiffUsers(current, desired []*protocol.MemoryUser) (remove, add []*protocol.MemoryUser, err error) {
+	wanted := make(map[string]*protocol.MemoryUser, len(desired))
+	for _, u := range desired {
+		if u.Email == "" {
+			return nil, nil, errors.New("Email must not be empty.")
+		}
+		e := strings.ToLower(u.Email)
+		if _, found := wanted[e]; found {
+			return nil, nil, errors.New("User ", u.Email, " is duplicated.")
+		}
+		wanted[e] = u
+	}
+
+	kept := make(map[string]bool, len(current))
+	for _, u := range current {
+		if u.Email == "" {
+			continue
+		}
+		e := strings.ToLower(u.Email)
+		if w, found := wanted[e]; found && proto.Equal(protocol.ToProtoUser(u), protocol.ToProtoUser(w)) {
+			kept[e] = true
+			continue
+		}
+		remove = append(remove, u)
+	}
+	for _, u := range desired {
+		if !kept[strings.ToLower(u.Email)] {
+			add = append(add, u)
+		}
+	}
+	return remove, add, nil
+}
+
+// UserSet is a set of users that can be changed in one step.
+type UserSet interface {
+	// Replace removes the users with the given emails, updates the users with the emails of update as by an update
+	// without overlap, and adds the users of add. Either all changes are made at once, or none.
+	Replace(remove []string, update, add []*protocol.MemoryUser) error
+}
+
+// UserEditor is a UserSet that makes the changes one by one. A validator has it edit a copy of its users, which
+// replaces them only if all changes succeed.
+type UserEditor struct {
+	Remove func(email string) error
+	// Update replaces the user with the email of the given one, without overlap.
+	Update func(*protocol.MemoryUser) error
+	Add    func(*protocol.MemoryUser) error
+}
+
+// Replace implements UserSet. It stops at the first change that fails.
+func (e UserEditor) Replace(remove []string, update, add []*protocol.MemoryUser) error {
+	for _, email := range remove {
+		if err := e.Remove(email); err != nil {
+			return err
+		}
+	}
+	for _, u := range update {
+		if err := e.Update(u); err != nil {
+			return err
+		}
+	}
+	for _, u := range add {
+		if err := e.Add(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UserSetFunc is an adapter to use a function as a UserSet.
+type UserSetFunc func(remove []string, update, add []*protocol.MemoryUser) error
+
+// Replace implements UserSet.
+func (f UserSetFunc) Replace(remove []string, update, add []*protocol.MemoryUser) error {
+	return f(remove, update, add)
+}
+
+// ApplyUsers gets from the current users to the desired ones in s in one step, see DiffUsers. A user whose account
+// or settings changed is updated, so that it's never unknown in between, and counted as both added and removed.
+// The caller must keep the users from being changed by others meanwhile.
+func ApplyUsers(current, desired []*protocol.MemoryUser, s UserSet) (added int, removed int, err error) {
+	toRemove, toAdd, err := DiffUsers(current, desired)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(toRemove) == 0 && len(toAdd) == 0 {
+		return 0, 0, nil
+	}
+
+	removing := make(map[string]bool, len(toRemove))
+	for _, u := range toRemove {
+		removing[strings.ToLower(u.Email)] = true
+	}
+	var remove []string
+	var update, add []*protocol.MemoryUser
+	for _, u := range toAdd {
+		e := strings.ToLower(u.Email)
+		if removing[e] {
+			delete(removing, e)
+			update = append(update, u)
+		} else {
+			add = append(add, u)
+		}
+	}
+	for _, u := range toRemove {
+		if removing[strings.ToLower(u.Email)] {
+			remove = append(remove, u.Email)
+		}
+	}
+	if err := s.Replace(remove, update, add); err != nil {
+		return 0, 0, errors.New("failed to replace users").Base(err)
+	}
+	return len(toAdd), len(toRemove), nil
+}
@@ -0,0 +1,80 @@
+package proxy_test
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common/protocol"
+	. "github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/trojan"
+)
+
+func newTrojanUser(email, password string) *protocol.MemoryUser {
+	account, err := (&trojan.Account{Password: password}).AsAccount()
+	if err != nil {
+		panic(err)
+	}
+	return &protocol.MemoryUser{Email: email, Account: account}
+}
+
+func TestDiffUsers(t *testing.T) {
+	current := []*protocol.MemoryUser{
+		newTrojanUser("a@xray.com", "a"),
+		newTrojanUser("b@xray.com", "b"),
+		newTrojanUser("c@xray.com", "c"),
+	}
+	desired := []*protocol.MemoryUser{
+		newTrojanUser("a@xray.com", "a"),
+		newTrojanUser("b@xray.com", "b2"),
+		newTrojanUser("d@xray.com", "d"),
+	}
+	remove, add, err := DiffUsers(current, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remove) != 2 || remove[0].Email != "b@xray.com" || remove[1].Email != "c@xray.com" {
+		t.Error("unexpected users to remove: ", remove)
+	}
+	if len(add) != 2 || add[0] != desired[1] || add[1] != desired[2] {
+		t.Error("unexpected users to add: ", add)
+	}
+
+	if _, _, err := DiffUsers(current, []*protocol.MemoryUser{newTrojanUser("", "e")}); err == nil {
+		t.Error("expected error for empty email")
+	}
+	if _, _, err := DiffUsers(current, []*protocol.MemoryUser{newTrojanUser("e@xray.com", "e"), newTrojanUser("E@xray.com", "e")}); err == nil {
+		t.Error("expected error for duplicated email")
+	}
+}
+
+func TestApplyUsers(t *testing.T) {
+	v := new(trojan.Validator)
+	for _, u := range []*protocol.MemoryUser{newTrojanUser("a@xray.com", "a"), newTrojanUser("b@xray.com", "b")} {
+		if err := v.Add(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	desired := []*protocol.MemoryUser{newTrojanUser("b@xray.com", "b2"), newTrojanUser("c@xray.com", "c")}
+	added, removed, err := ApplyUsers(v.GetAll(), desired, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 || removed != 2 || v.GetCount() != 2 || v.GetByEmail("a@xray.com") != nil || v.GetByEmail("c@xray.com") == nil {
+		t.Error("unexpected result: ", added, " ", removed, " ", v.GetCount())
+	}
+	if v.GetByEmail("b@xray.com") != desired[0] {
+		t.Error("expected the changed user to be updated")
+	}
+
+	// A failed change leaves all users as they were.
+	failing := UserSetFunc(func(remove []string, update, add []*protocol.MemoryUser) error {
+		return v.Replace(append(remove, "x@xray.com"), update, add)
+	})
+	desired = []*protocol.MemoryUser{newTrojanUser("d@xray.com", "d")}
+	if _, _, err := ApplyUsers(v.GetAll(), desired, failing); err == nil {
+		t.Fatal("expected error")
+	}
+	if v.GetCount() != 2 || v.GetByEmail("b@xray.com") == nil || v.GetByEmail("c@xray.com") == nil || v.GetByEmail("d@xray.com") != nil {
+		t.Error("expected users to be kept, but got ", v.GetCount())
+	}
+}
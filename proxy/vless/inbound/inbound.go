@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -27,6 +28,7 @@ import (
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/externalauth"
 	"github.com/xtls/xray-core/proxy/vless"
 	"github.com/xtls/xray-core/proxy/vless/encoding"
 	"github.com/xtls/xray-core/transport/internet/reality"
@@ -56,6 +58,13 @@ func init() {
 				return nil, errors.New("failed to initiate user").Base(err).AtError()
 			}
 		}
+		if c.ExternalAuth != nil {
+			external, err := externalauth.New(c.ExternalAuth, "vless")
+			if err != nil {
+				return nil, errors.New("failed to create external auth").Base(err).AtError()
+			}
+			validator.External = external
+		}
 
 		return New(ctx, c, dc, validator)
 	}))
@@ -66,6 +75,7 @@ type Handler struct {
 	inboundHandlerManager feature_inbound.Manager
 	policyManager         policy.Manager
 	validator             vless.Validator
+	usersAccess           sync.Mutex
 	dns                   dns.Client
 	fallbacks             map[string]map[string]map[string]*Fallback // or nil
 	// regexps               map[string]*regexp.Regexp       // or nil
@@ -164,14 +174,40 @@ func (h *Handler) Close() error {
 
 // AddUser implements proxy.UserManager.AddUser().
 func (h *Handler) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
 	return h.validator.Add(u)
 }
 
 // RemoveUser implements proxy.UserManager.RemoveUser().
 func (h *Handler) RemoveUser(ctx context.Context, e string) error {
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
 	return h.validator.Del(e)
 }
 
+// SyncUsers implements proxy.UserManager.SyncUsers().
+func (h *Handler) SyncUsers(ctx context.Context, users []*protocol.MemoryUser) (int, int, error) {
+	for _, u := range users {
+		if _, ok := u.Account.(*vless.MemoryAccount); !ok {
+			return 0, 0, errors.New("User ", u.Email, " is not a VLESS user.")
+		}
+	}
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
+	return proxy.ApplyUsers(h.validator.GetAll(), users, h.validator)
+}
+
+// UpdateUser implements proxy.UserManager.UpdateUser().
+func (h *Handler) UpdateUser(ctx context.Context, u *protocol.MemoryUser, overlap time.Duration) error {
+	if _, ok := u.Account.(*vless.MemoryAccount); !ok {
+		return errors.New("User ", u.Email, " is not a VLESS user.")
+	}
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
+	return h.validator.Update(u, overlap)
+}
+
 // GetUser implements proxy.UserManager.GetUser().
 func (h *Handler) GetUser(ctx context.Context, email string) *protocol.MemoryUser {
 	return h.validator.GetByEmail(email)
@@ -514,7 +550,20 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 		ctx = session.ContextWithAllowedNetwork(ctx, net.Network_UDP)
 	}
 
-	sessionPolicy = h.policyManager.ForLevel(request.User.Level)
+	release, err := policy.TrackUser(h.policyManager, request.User, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   connection.RemoteAddr(),
+			To:     request.Destination(),
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  request.User.Email,
+		})
+		return errors.New("rejected request from ", connection.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer release()
+
+	sessionPolicy = policy.ForUser(h.policyManager, request.User)
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
 	inbound.Timer = timer
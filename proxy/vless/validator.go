@@ -1,91 +1,231 @@
 package vless
 
 import (
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/externalauth"
 )
 
 type Validator interface {
 	Get(id uuid.UUID) *protocol.MemoryUser
 	Add(u *protocol.MemoryUser) error
 	Del(email string) error
+	Update(u *protocol.MemoryUser, overlap time.Duration) error
 	GetByEmail(email string) *protocol.MemoryUser
 	GetAll() []*protocol.MemoryUser
 	GetCount() int64
+	Replace(remove []string, update, add []*protocol.MemoryUser) error
 }
 
 // MemoryValidator stores valid VLESS users.
 type MemoryValidator struct {
-	// Considering email's usage here, map + sync.Mutex/RWMutex may have better performance.
-	email sync.Map
-	users sync.Map
+	access sync.RWMutex
+	email  map[string]*protocol.MemoryUser
+	users  map[uuid.UUID]*protocol.MemoryUser
+	// retired holds the UUID a user changed from, while it is still accepted.
+	retired map[string]*retiredID
+
+	// External, if not nil, is asked about the UUIDs of no user.
+	External *externalauth.Authenticator
+}
+
+type retiredID struct {
+	id uuid.UUID
+}
+
+// initMaps creates the maps of a zero MemoryValidator. The caller must hold the lock.
+func (v *MemoryValidator) initMaps() {
+	if v.users == nil {
+		v.email = make(map[string]*protocol.MemoryUser)
+		v.users = make(map[uuid.UUID]*protocol.MemoryUser)
+		v.retired = make(map[string]*retiredID)
+	}
 }
 
-// Add a VLESS user, Email must be empty or unique.
+// Add a VLESS user, Email must be empty or unique, and UUID unique.
 func (v *MemoryValidator) Add(u *protocol.MemoryUser) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.add(u)
+}
+
+func (v *MemoryValidator) add(u *protocol.MemoryUser) error {
+	id := u.Account.(*MemoryAccount).ID.UUID()
+	if _, found := v.users[id]; found {
+		return errors.New("User ", u.Email, " has the UUID of another user.")
+	}
 	if u.Email != "" {
-		_, loaded := v.email.LoadOrStore(strings.ToLower(u.Email), u)
-		if loaded {
+		le := strings.ToLower(u.Email)
+		if _, found := v.email[le]; found {
 			return errors.New("User ", u.Email, " already exists.")
 		}
+		v.email[le] = u
 	}
-	v.users.Store(u.Account.(*MemoryAccount).ID.UUID(), u)
+	v.users[id] = u
 	return nil
 }
 
 // Del a VLESS user with a non-empty Email.
 func (v *MemoryValidator) Del(e string) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.del(e)
+}
+
+func (v *MemoryValidator) del(e string) error {
 	if e == "" {
 		return errors.New("Email must not be empty.")
 	}
 	le := strings.ToLower(e)
-	u, _ := v.email.Load(le)
+	u := v.email[le]
 	if u == nil {
 		return errors.New("User ", e, " not found.")
 	}
-	v.email.Delete(le)
-	v.users.Delete(u.(*protocol.MemoryUser).Account.(*MemoryAccount).ID.UUID())
+	delete(v.email, le)
+	delete(v.users, u.Account.(*MemoryAccount).ID.UUID())
+	v.unretire(le)
+	return nil
+}
+
+// Update replaces the VLESS user with the same non-empty Email. If the UUID changes, the old one is still accepted
+// for the updated user for overlap.
+func (v *MemoryValidator) Update(u *protocol.MemoryUser, overlap time.Duration) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.initMaps()
+	return v.update(u, overlap)
+}
+
+func (v *MemoryValidator) update(u *protocol.MemoryUser, overlap time.Duration) error {
+	if u.Email == "" {
+		return errors.New("Email must not be empty.")
+	}
+	le := strings.ToLower(u.Email)
+	old := v.email[le]
+	if old == nil {
+		return errors.New("User ", u.Email, " not found.")
+	}
+	oldID := old.Account.(*MemoryAccount).ID.UUID()
+	newID := u.Account.(*MemoryAccount).ID.UUID()
+
+	if other := v.users[newID]; other != nil && !strings.EqualFold(other.Email, u.Email) {
+		return errors.New("User ", u.Email, " has the UUID of another user.")
+	}
+	if oldID == newID {
+		v.users[newID] = u
+		v.email[le] = u
+		return nil
+	}
+	v.unretire(le)
+	v.users[newID] = u
+	v.email[le] = u
+	if overlap <= 0 {
+		delete(v.users, oldID)
+		return nil
+	}
+	v.users[oldID] = u
+	r := &retiredID{id: oldID}
+	v.retired[le] = r
+	time.AfterFunc(overlap, func() {
+		v.access.Lock()
+		defer v.access.Unlock()
+		if v.retired[le] == r {
+			delete(v.retired, le)
+			delete(v.users, oldID)
+		}
+	})
+	return nil
+}
+
+// unretire stops accepting the UUID the user changed from. The caller must hold the lock.
+func (v *MemoryValidator) unretire(le string) {
+	if r, found := v.retired[le]; found {
+		delete(v.retired, le)
+		delete(v.users, r.id)
+	}
+}
+
+// Replace implements proxy.UserSet, with a proxy.UserEditor of a copy of the users.
+func (v *MemoryValidator) Replace(remove []string, update, add []*protocol.MemoryUser) error {
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	next := &MemoryValidator{
+		email:   maps.Clone(v.email),
+		users:   maps.Clone(v.users),
+		retired: maps.Clone(v.retired),
+	}
+	next.initMaps()
+	err := proxy.UserEditor{
+		Remove: next.del,
+		Update: func(u *protocol.MemoryUser) error { return next.update(u, 0) },
+		Add:    next.add,
+	}.Replace(remove, update, add)
+	if err != nil {
+		return err
+	}
+	v.email, v.users, v.retired = next.email, next.users, next.retired
 	return nil
 }
 
 // Get a VLESS user with UUID, nil if user doesn't exist.
 func (v *MemoryValidator) Get(id uuid.UUID) *protocol.MemoryUser {
-	u, _ := v.users.Load(id)
+	v.access.RLock()
+	u := v.users[id]
+	v.access.RUnlock()
 	if u != nil {
-		return u.(*protocol.MemoryUser)
+		return u
+	}
+	if v.External != nil {
+		return v.External.Get(id.String(), func(r *externalauth.AuthorizeResponse) (*protocol.MemoryUser, error) {
+			return &protocol.MemoryUser{
+				Account: &MemoryAccount{
+					ID:   protocol.NewID(id),
+					Flow: r.Flow,
+				},
+				Email: r.Email,
+				Level: r.Level,
+			}, nil
+		})
 	}
 	return nil
 }
 
 // Get a VLESS user with email, nil if user doesn't exist.
 func (v *MemoryValidator) GetByEmail(email string) *protocol.MemoryUser {
-	u, _ := v.email.Load(email)
-	if u != nil {
-		return u.(*protocol.MemoryUser)
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return v.email[email]
+}
+
+// Close implements common.Closable.Close(). It closes the external authenticator, if any.
+func (v *MemoryValidator) Close() error {
+	if v.External != nil {
+		return v.External.Close()
 	}
 	return nil
 }
 
 // Get all users
 func (v *MemoryValidator) GetAll() []*protocol.MemoryUser {
-	var u = make([]*protocol.MemoryUser, 0, 100)
-	v.email.Range(func(key, value interface{}) bool {
-		u = append(u, value.(*protocol.MemoryUser))
-		return true
-	})
-	return u
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return slices.Collect(maps.Values(v.email))
 }
 
 // Get users count
 func (v *MemoryValidator) GetCount() int64 {
-	var c int64 = 0
-	v.email.Range(func(key, value interface{}) bool {
-		c++
-		return true
-	})
-	return c
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return int64(len(v.email))
 }
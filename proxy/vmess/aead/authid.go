@@ -9,6 +9,7 @@ import (
 	"errors"
 	"hash/crc32"
 	"io"
+	"maps"
 	"math"
 	"time"
 
@@ -94,6 +95,19 @@ func (a *AuthIDDecoderHolder) RemoveUser(key [16]byte) {
 	delete(a.decoders, string(key[:]))
 }
 
+// Ticket returns the ticket of the user with key, if there is one.
+func (a *AuthIDDecoderHolder) Ticket(key [16]byte) (interface{}, bool) {
+	if item, found := a.decoders[string(key[:])]; found {
+		return item.ticket, true
+	}
+	return nil, false
+}
+
+// Clone returns a copy of the holder, which shares the replay filter with it.
+func (a *AuthIDDecoderHolder) Clone() *AuthIDDecoderHolder {
+	return &AuthIDDecoderHolder{maps.Clone(a.decoders), a.filter}
+}
+
 func (a *AuthIDDecoderHolder) Match(authID [16]byte) (interface{}, error) {
 	for _, v := range a.decoders {
 		t, z, _, d := v.dec.Decode(authID)
@@ -21,6 +21,7 @@ import (
 	feature_inbound "github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/proxy/vmess"
 	"github.com/xtls/xray-core/proxy/vmess/encoding"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -87,6 +88,19 @@ func (v *userByEmail) Get(email string) *protocol.MemoryUser {
 	return v.cache[email]
 }
 
+func (v *userByEmail) Update(u *protocol.MemoryUser) bool {
+	email := strings.ToLower(u.Email)
+
+	v.Lock()
+	defer v.Unlock()
+
+	if _, found := v.cache[email]; !found {
+		return false
+	}
+	v.cache[email] = u
+	return true
+}
+
 func (v *userByEmail) Remove(email string) bool {
 	email = strings.ToLower(email)
 
@@ -106,6 +120,7 @@ type Handler struct {
 	inboundHandlerManager feature_inbound.Manager
 	clients               *vmess.TimedUserValidator
 	usersByEmail          *userByEmail
+	usersAccess           sync.Mutex
 	detours               *DetourConfig
 	sessionHistory        *encoding.SessionHistory
 }
@@ -149,6 +164,8 @@ func (*Handler) Network() []net.Network {
 }
 
 func (h *Handler) GetOrGenerateUser(email string) *protocol.MemoryUser {
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
 	user, existing := h.usersByEmail.GetOrGenerate(email)
 	if !existing {
 		h.clients.Add(user)
@@ -169,13 +186,31 @@ func (h *Handler) GetUsersCount(context.Context) int64 {
 }
 
 func (h *Handler) AddUser(ctx context.Context, user *protocol.MemoryUser) error {
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
+	return h.addUser(user)
+}
+
+func (h *Handler) addUser(user *protocol.MemoryUser) error {
 	if len(user.Email) > 0 && !h.usersByEmail.Add(user) {
 		return errors.New("User ", user.Email, " already exists.")
 	}
-	return h.clients.Add(user)
+	if err := h.clients.Add(user); err != nil {
+		if len(user.Email) > 0 {
+			h.usersByEmail.Remove(user.Email)
+		}
+		return err
+	}
+	return nil
 }
 
 func (h *Handler) RemoveUser(ctx context.Context, email string) error {
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
+	return h.removeUser(email)
+}
+
+func (h *Handler) removeUser(email string) error {
 	if email == "" {
 		return errors.New("Email must not be empty.")
 	}
@@ -186,6 +221,49 @@ func (h *Handler) RemoveUser(ctx context.Context, email string) error {
 	return nil
 }
 
+func (h *Handler) UpdateUser(ctx context.Context, user *protocol.MemoryUser, overlap time.Duration) error {
+	if _, ok := user.Account.(*vmess.MemoryAccount); !ok {
+		return errors.New("User ", user.Email, " is not a VMess user.")
+	}
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
+	if err := h.clients.Update(user, overlap); err != nil {
+		return err
+	}
+	h.usersByEmail.Update(user)
+	return nil
+}
+
+func (h *Handler) SyncUsers(ctx context.Context, users []*protocol.MemoryUser) (int, int, error) {
+	for _, u := range users {
+		if _, ok := u.Account.(*vmess.MemoryAccount); !ok {
+			return 0, 0, errors.New("User ", u.Email, " is not a VMess user.")
+		}
+	}
+	h.usersAccess.Lock()
+	defer h.usersAccess.Unlock()
+	return proxy.ApplyUsers(h.clients.GetUsers(), users, proxy.UserSetFunc(h.replaceUsers))
+}
+
+// replaceUsers implements proxy.UserSet. The caller must hold usersAccess.
+func (h *Handler) replaceUsers(remove []string, update, add []*protocol.MemoryUser) error {
+	if err := h.clients.Replace(remove, update, add); err != nil {
+		return err
+	}
+	for _, email := range remove {
+		h.usersByEmail.Remove(email)
+	}
+	for _, u := range update {
+		h.usersByEmail.Update(u)
+	}
+	for _, u := range add {
+		if len(u.Email) > 0 {
+			h.usersByEmail.Add(u)
+		}
+	}
+	return nil
+}
+
 func transferResponse(timer signal.ActivityUpdater, session *encoding.ServerSession, request *protocol.RequestHeader, response *protocol.ResponseHeader, input buf.Reader, output *buf.BufferedWriter) error {
 	session.EncodeResponseHeader(response, output)
 
@@ -277,7 +355,20 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 	inbound.CanSpliceCopy = 3
 	inbound.User = request.User
 
-	sessionPolicy = h.policyManager.ForLevel(request.User.Level)
+	release, err := policy.TrackUser(h.policyManager, request.User, inbound.Source)
+	if err != nil {
+		log.Record(&log.AccessMessage{
+			From:   connection.RemoteAddr(),
+			To:     request.Destination(),
+			Status: log.AccessRejected,
+			Reason: err,
+			Email:  request.User.Email,
+		})
+		return errors.New("rejected request from ", connection.RemoteAddr()).Base(err).AtInfo()
+	}
+	defer release()
+
+	sessionPolicy = policy.ForUser(h.policyManager, request.User)
 
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
@@ -57,6 +57,9 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 	return handler, nil
 }
 
+// Poolable implements proxy.PoolableOutbound.
+func (h *Handler) Poolable() {}
+
 // Process implements proxy.Outbound.Process().
 func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbounds := session.OutboundsFromContext(ctx)
@@ -141,7 +144,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	}
 
 	session := encoding.NewClientSession(ctx, int64(behaviorSeed))
-	sessionPolicy := h.policyManager.ForLevel(request.User.Level)
+	sessionPolicy := policy.ForUser(h.policyManager, request.User)
 
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, func() {
@@ -4,8 +4,11 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"hash/crc64"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common/dice"
 	"github.com/xtls/xray-core/common/errors"
@@ -17,6 +20,8 @@ import (
 type TimedUserValidator struct {
 	sync.RWMutex
 	users []*protocol.MemoryUser
+	// retired holds the key a user changed from, while it is still accepted.
+	retired map[string]*retiredKey
 
 	behaviorSeed  uint64
 	behaviorFused bool
@@ -24,10 +29,21 @@ type TimedUserValidator struct {
 	aeadDecoderHolder *aead.AuthIDDecoderHolder
 }
 
+type retiredKey struct {
+	key [16]byte
+}
+
+func cmdKeyOf(u *protocol.MemoryUser) [16]byte {
+	var cmdkeyfl [16]byte
+	copy(cmdkeyfl[:], u.Account.(*MemoryAccount).ID.CmdKey())
+	return cmdkeyfl
+}
+
 // NewTimedUserValidator creates a new TimedUserValidator.
 func NewTimedUserValidator() *TimedUserValidator {
 	tuv := &TimedUserValidator{
 		users:             make([]*protocol.MemoryUser, 0, 16),
+		retired:           make(map[string]*retiredKey),
 		aeadDecoderHolder: aead.NewAuthIDDecoderHolder(),
 	}
 	return tuv
@@ -36,13 +52,19 @@ func NewTimedUserValidator() *TimedUserValidator {
 func (v *TimedUserValidator) Add(u *protocol.MemoryUser) error {
 	v.Lock()
 	defer v.Unlock()
+	return v.add(u)
+}
 
-	v.users = append(v.users, u)
-
+func (v *TimedUserValidator) add(u *protocol.MemoryUser) error {
 	account, ok := u.Account.(*MemoryAccount)
 	if !ok {
 		return errors.New("account type is incorrect")
 	}
+	if _, found := v.aeadDecoderHolder.Ticket(cmdKeyOf(u)); found {
+		return errors.New("User ", u.Email, " has the ID of another user.")
+	}
+	v.users = append(v.users, u)
+
 	if !v.behaviorFused {
 		hashkdf := hmac.New(sha256.New, []byte("VMESSBSKDF"))
 		hashkdf.Write(account.ID.Bytes())
@@ -87,7 +109,10 @@ func (v *TimedUserValidator) GetAEAD(userHash []byte) (*protocol.MemoryUser, boo
 func (v *TimedUserValidator) Remove(email string) bool {
 	v.Lock()
 	defer v.Unlock()
+	return v.remove(email)
+}
 
+func (v *TimedUserValidator) remove(email string) bool {
 	email = strings.ToLower(email)
 	idx := -1
 	for i, u := range v.users {
@@ -107,10 +132,113 @@ func (v *TimedUserValidator) Remove(email string) bool {
 	v.users[idx] = v.users[ulen-1]
 	v.users[ulen-1] = nil
 	v.users = v.users[:ulen-1]
+	v.unretire(email)
 
 	return true
 }
 
+// Update replaces the user with the same email. If the ID changes, the old one is still accepted for the updated
+// user for overlap.
+func (v *TimedUserValidator) Update(u *protocol.MemoryUser, overlap time.Duration) error {
+	if _, ok := u.Account.(*MemoryAccount); !ok {
+		return errors.New("account type is incorrect")
+	}
+
+	v.Lock()
+	defer v.Unlock()
+	return v.update(u, overlap)
+}
+
+func (v *TimedUserValidator) update(u *protocol.MemoryUser, overlap time.Duration) error {
+	email := strings.ToLower(u.Email)
+	idx := -1
+	for i, user := range v.users {
+		if strings.EqualFold(user.Email, email) {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return errors.New("User ", u.Email, " not found.")
+	}
+	old := v.users[idx]
+	oldKey, newKey := cmdKeyOf(old), cmdKeyOf(u)
+	if other, found := v.aeadDecoderHolder.Ticket(newKey); found && !strings.EqualFold(other.(*protocol.MemoryUser).Email, email) {
+		return errors.New("User ", u.Email, " has the ID of another user.")
+	}
+	v.users[idx] = u
+
+	if oldKey == newKey {
+		v.aeadDecoderHolder.AddUser(newKey, u)
+		return nil
+	}
+	v.unretire(email)
+	v.aeadDecoderHolder.AddUser(newKey, u)
+	if overlap <= 0 {
+		v.aeadDecoderHolder.RemoveUser(oldKey)
+		return nil
+	}
+	// The old ID is needed to decode the requests made with it.
+	retired := *u
+	retired.Account = old.Account
+	v.aeadDecoderHolder.AddUser(oldKey, &retired)
+	r := &retiredKey{key: oldKey}
+	v.retired[email] = r
+	time.AfterFunc(overlap, func() {
+		v.Lock()
+		defer v.Unlock()
+		if v.retired[email] == r {
+			delete(v.retired, email)
+			v.aeadDecoderHolder.RemoveUser(oldKey)
+		}
+	})
+	return nil
+}
+
+// Replace removes the users with the given emails, updates the users with the emails of update as by Update without
+// overlap, and adds the users of add. The changes are made to a copy of the users, which replaces them if all changes
+// succeed.
+func (v *TimedUserValidator) Replace(remove []string, update, add []*protocol.MemoryUser) error {
+	v.Lock()
+	defer v.Unlock()
+
+	next := &TimedUserValidator{
+		users:             slices.Clone(v.users),
+		retired:           maps.Clone(v.retired),
+		behaviorSeed:      v.behaviorSeed,
+		behaviorFused:     v.behaviorFused,
+		aeadDecoderHolder: v.aeadDecoderHolder.Clone(),
+	}
+	for _, e := range remove {
+		if !next.remove(e) {
+			return errors.New("User ", e, " not found.")
+		}
+	}
+	for _, u := range update {
+		if _, ok := u.Account.(*MemoryAccount); !ok {
+			return errors.New("account type is incorrect")
+		}
+		if err := next.update(u, 0); err != nil {
+			return err
+		}
+	}
+	for _, u := range add {
+		if err := next.add(u); err != nil {
+			return err
+		}
+	}
+	v.users, v.retired, v.behaviorSeed, v.aeadDecoderHolder = next.users, next.retired, next.behaviorSeed, next.aeadDecoderHolder
+	return nil
+}
+
+// unretire stops accepting the key the user changed from. The caller must hold the lock.
+func (v *TimedUserValidator) unretire(email string) {
+	if r, found := v.retired[email]; found {
+		delete(v.retired, email)
+		v.aeadDecoderHolder.RemoveUser(r.key)
+	}
+}
+
 func (v *TimedUserValidator) GetBehaviorSeed() uint64 {
 	v.Lock()
 	defer v.Unlock()
@@ -1,12 +1,18 @@
 package vmess_test
 
 import (
+	"context"
 	"testing"
 
+	"github.com/xtls/xray-core/app/proxyman"
+	_ "github.com/xtls/xray-core/app/proxyman/inbound"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/core"
 	. "github.com/xtls/xray-core/proxy/vmess"
+	"github.com/xtls/xray-core/proxy/vmess/inbound"
 )
 
 func toAccount(a *Account) protocol.Account {
@@ -32,3 +38,45 @@ func BenchmarkUserValidator(b *testing.B) {
 		common.Close(v)
 	}
 }
+
+func TestUserValidatorCollision(t *testing.T) {
+	v := NewTimedUserValidator()
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	common.Must(v.Add(&protocol.MemoryUser{Email: "a@xray.com", Account: toAccount(&Account{Id: a.String()})}))
+	common.Must(v.Add(&protocol.MemoryUser{Email: "b@xray.com", Account: toAccount(&Account{Id: b.String()})}))
+
+	if err := v.Add(&protocol.MemoryUser{Email: "c@xray.com", Account: toAccount(&Account{Id: a.String()})}); err == nil {
+		t.Error("expected error for the ID of another user")
+	}
+	if err := v.Update(&protocol.MemoryUser{Email: "b@xray.com", Account: toAccount(&Account{Id: a.String()})}, 0); err == nil {
+		t.Error("expected error for the ID of another user")
+	}
+	if err := v.Update(&protocol.MemoryUser{Email: "a@xray.com", Level: 1, Account: toAccount(&Account{Id: a.String()})}, 0); err != nil {
+		t.Error("expected the user to keep its own ID, but got ", err)
+	}
+
+	// The handler keeps the user it looks up by email as the validator does.
+	instance, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+		},
+	})
+	common.Must(err)
+	obj, err := core.CreateObject(instance, &inbound.Config{
+		User: []*protocol.User{
+			{Email: "a@xray.com", Account: serial.ToTypedMessage(&Account{Id: a.String()})},
+			{Email: "b@xray.com", Account: serial.ToTypedMessage(&Account{Id: b.String()})},
+		},
+	})
+	common.Must(err)
+	h := obj.(*inbound.Handler)
+	if err := h.UpdateUser(context.Background(), &protocol.MemoryUser{Email: "b@xray.com", Level: 1, Account: toAccount(&Account{Id: a.String()})}, 0); err == nil {
+		t.Error("expected error for the ID of another user")
+	}
+	if u := h.GetUser(context.Background(), "b@xray.com"); u.Level != 0 || u.Account.(*MemoryAccount).ID.String() != b.String() {
+		t.Error("expected the user to be kept after a failed update, but got ", u)
+	}
+	if err := h.UpdateUser(context.Background(), &protocol.MemoryUser{Email: "c@xray.com", Account: toAccount(&Account{Id: c.String()})}, 0); err == nil {
+		t.Error("expected error for a user not found")
+	}
+}
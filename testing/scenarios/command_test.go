@@ -535,9 +535,12 @@ func TestCommanderStats(t *testing.T) {
 	if r := cmp.Diff(sresp.Stat, &statscmd.Stat{
 		Name:  name,
 		Value: 10240 * 1024,
-	}, cmpopts.IgnoreUnexported(statscmd.Stat{})); r != "" {
+	}, cmpopts.IgnoreUnexported(statscmd.Stat{}), cmpopts.IgnoreFields(statscmd.Stat{}, "LastReset")); r != "" {
 		t.Error(r)
 	}
+	if sresp.Stat.LastReset == 0 {
+		t.Error("expected reset time of the counter")
+	}
 
 	sresp, err = sClient.GetStats(context.Background(), &statscmd.GetStatsRequest{
 		Name: name,
@@ -546,7 +549,7 @@ func TestCommanderStats(t *testing.T) {
 	if r := cmp.Diff(sresp.Stat, &statscmd.Stat{
 		Name:  name,
 		Value: 0,
-	}, cmpopts.IgnoreUnexported(statscmd.Stat{})); r != "" {
+	}, cmpopts.IgnoreUnexported(statscmd.Stat{}), cmpopts.IgnoreFields(statscmd.Stat{}, "LastReset")); r != "" {
 		t.Error(r)
 	}
 
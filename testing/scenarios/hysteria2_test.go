@@ -0,0 +1,160 @@
+package scenarios
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	"github.com/xtls/xray-core/common/serial"
+	core "github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/proxy/dokodemo"
+	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/proxy/hysteria2"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+	"github.com/xtls/xray-core/testing/servers/udp"
+	"github.com/xtls/xray-core/transport/internet"
+	transport "github.com/xtls/xray-core/transport/internet/hysteria2"
+	"github.com/xtls/xray-core/transport/internet/tls"
+	"golang.org/x/sync/errgroup"
+)
+
+func hysteria2Configs(serverPort net.Port, clientPort net.Port, dest net.Destination) (*core.Config, *core.Config) {
+	streamSettings := func(security *tls.Config) *internet.StreamConfig {
+		return &internet.StreamConfig{
+			ProtocolName: "hysteria2",
+			TransportSettings: []*internet.TransportConfig{
+				{
+					ProtocolName: "hysteria2",
+					Settings: serial.ToTypedMessage(&transport.Config{
+						ObfsPassword: "obfuscation",
+					}),
+				},
+			},
+			SecurityType:     serial.GetMessageType(&tls.Config{}),
+			SecuritySettings: []*serial.TypedMessage{serial.ToTypedMessage(security)},
+		}
+	}
+
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					StreamSettings: streamSettings(&tls.Config{
+						Certificate: []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil))},
+					}),
+				}),
+				ProxySettings: serial.ToTypedMessage(&hysteria2.ServerConfig{
+					Users: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&hysteria2.Account{
+								Password: "password",
+							}),
+						},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(clientPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{dest.Network},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&hysteria2.ClientConfig{
+					Server: []*protocol.ServerEndpoint{
+						{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(serverPort),
+							User: []*protocol.User{
+								{
+									Account: serial.ToTypedMessage(&hysteria2.Account{
+										Password: "password",
+									}),
+								},
+							},
+						},
+					},
+				}),
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					StreamSettings: streamSettings(&tls.Config{
+						AllowInsecure: true,
+					}),
+				}),
+			},
+		},
+	}
+	return serverConfig, clientConfig
+}
+
+func TestHysteria2TCP(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	serverPort := udp.PickPort()
+	clientPort := tcp.PickPort()
+	serverConfig, clientConfig := hysteria2Configs(serverPort, clientPort, dest)
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	var errg errgroup.Group
+	for i := 0; i < 10; i++ {
+		errg.Go(testTCPConn(clientPort, 1024*1024, time.Second*20))
+	}
+	if err := errg.Wait(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHysteria2UDP(t *testing.T) {
+	udpServer := udp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := udpServer.Start()
+	common.Must(err)
+	defer udpServer.Close()
+
+	serverPort := udp.PickPort()
+	clientPort := udp.PickPort()
+	serverConfig, clientConfig := hysteria2Configs(serverPort, clientPort, dest)
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	var errg errgroup.Group
+	for i := 0; i < 10; i++ {
+		errg.Go(testUDPConn(clientPort, 1024, time.Second*5))
+	}
+	if err := errg.Wait(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,97 @@
+package udp
+
+import (
+	"sync"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+// Relay forwards the packets sent to each of its ports to a port of a server, from a port of its own, like a NAT in
+// front of the ports of a hop. It remembers the client addresses it forwarded for.
+type Relay struct {
+	ports []net.Port
+	conns []*net.UDPConn
+
+	access  sync.Mutex
+	clients map[string]bool
+}
+
+// NewRelay listens on a local port for each of targets, and forwards the packets to the target on 127.0.0.1.
+func NewRelay(targets []net.Port) (*Relay, error) {
+	r := &Relay{
+		clients: make(map[string]bool),
+	}
+	for _, target := range targets {
+		front, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.conns = append(r.conns, front)
+		back, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: net.LocalHostIP.IP(), Port: int(target)})
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.conns = append(r.conns, back)
+		r.ports = append(r.ports, net.Port(front.LocalAddr().(*net.UDPAddr).Port))
+		go r.relay(front, back)
+	}
+	return r, nil
+}
+
+func (r *Relay) relay(front, back *net.UDPConn) {
+	var access sync.Mutex
+	var client *net.UDPAddr
+	go func() {
+		b := make([]byte, 2048)
+		for {
+			n, err := back.Read(b)
+			if err != nil {
+				return
+			}
+			access.Lock()
+			addr := client
+			access.Unlock()
+			front.WriteToUDP(b[:n], addr)
+		}
+	}()
+	b := make([]byte, 2048)
+	for {
+		n, addr, err := front.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+		access.Lock()
+		client = addr
+		access.Unlock()
+		r.access.Lock()
+		r.clients[addr.String()] = true
+		r.access.Unlock()
+		back.Write(b[:n])
+	}
+}
+
+// PortList returns the ports of r, to hop on.
+func (r *Relay) PortList() *net.PortList {
+	ports := &net.PortList{}
+	for _, port := range r.ports {
+		ports.Range = append(ports.Range, &net.PortRange{From: uint32(port), To: uint32(port)})
+	}
+	return ports
+}
+
+// Clients returns the number of client addresses that sent through r.
+func (r *Relay) Clients() int {
+	r.access.Lock()
+	defer r.access.Unlock()
+	return len(r.clients)
+}
+
+// Close stops relaying.
+func (r *Relay) Close() error {
+	for _, conn := range r.conns {
+		conn.Close()
+	}
+	return nil
+}
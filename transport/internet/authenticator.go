@@ -0,0 +1,25 @@
+package internet
+
+import "context"
+
+type authenticatorKey struct{}
+
+// Authenticator is implemented by the inbounds whose users are authenticated by the transport, as in Hysteria2.
+type Authenticator interface {
+	// Authenticate returns whether credential is of a user.
+	Authenticate(credential string) bool
+}
+
+// ContextWithAuthenticator returns a context for ListenTCP, so that the transport accepts the clients authenticator
+// knows.
+func ContextWithAuthenticator(ctx context.Context, authenticator Authenticator) context.Context {
+	return context.WithValue(ctx, authenticatorKey{}, authenticator)
+}
+
+// AuthenticatorFromContext returns the Authenticator in ctx, or nil if not contained.
+func AuthenticatorFromContext(ctx context.Context) Authenticator {
+	if authenticator, ok := ctx.Value(authenticatorKey{}).(Authenticator); ok {
+		return authenticator
+	}
+	return nil
+}
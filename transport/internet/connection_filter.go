@@ -0,0 +1,57 @@
+package internet
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+type connectionFilterKey struct{}
+
+// ConnectionFilter decides whether to accept the connections from a source, before any handshake.
+type ConnectionFilter struct {
+	// Allow returns whether to accept a connection from source.
+	Allow func(source net.Addr) bool
+
+	installed atomic.Bool
+}
+
+// Installed returns whether a system listener filters its connections with f. If it doesn't, as when the transport
+// listens on UDP, or the source is only known from the PROXY protocol header, the connections must be checked later.
+func (f *ConnectionFilter) Installed() bool {
+	return f.installed.Load()
+}
+
+// ContextWithConnectionFilter returns a context for ListenTCP, so that the system listener drops the connections
+// filter rejects right after they are accepted.
+func ContextWithConnectionFilter(ctx context.Context, filter *ConnectionFilter) context.Context {
+	return context.WithValue(ctx, connectionFilterKey{}, filter)
+}
+
+// ConnectionFilterFromContext returns the ConnectionFilter in ctx, or nil if not contained.
+func ConnectionFilterFromContext(ctx context.Context) *ConnectionFilter {
+	if filter, ok := ctx.Value(connectionFilterKey{}).(*ConnectionFilter); ok {
+		return filter
+	}
+	return nil
+}
+
+// filteredListener closes the connections rejected by its filter as soon as they are accepted.
+type filteredListener struct {
+	net.Listener
+	filter *ConnectionFilter
+}
+
+func (l *filteredListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.filter.Allow(conn.RemoteAddr()) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
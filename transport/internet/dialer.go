@@ -9,6 +9,7 @@ import (
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/net/cnc"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/transport"
@@ -43,7 +44,16 @@ func RegisterTransportDialer(protocol string, dialer dialFunc) error {
 }
 
 // Dial dials a internet connection towards the given destination.
-func Dial(ctx context.Context, dest net.Destination, streamSettings *MemoryStreamConfig) (stat.Connection, error) {
+func Dial(ctx context.Context, dest net.Destination, streamSettings *MemoryStreamConfig) (conn stat.Connection, err error) {
+	ctx, span := trace.Start(ctx, "internet.dial")
+	span.SetAttribute("net.destination", dest)
+	if streamSettings != nil {
+		span.SetAttribute("xray.transport", streamSettings.ProtocolName)
+	}
+	defer func() {
+		span.End(err)
+	}()
+
 	if dest.Network == net.Network_TCP {
 		if streamSettings == nil {
 			s, err := ToMemoryStreamConfig(nil)
@@ -82,18 +92,18 @@ var (
 	obm       outbound.Manager
 )
 
-func lookupIP(domain string, strategy DomainStrategy, localAddr net.Address) ([]net.IP, error) {
+func lookupIP(ctx context.Context, domain string, strategy DomainStrategy, localAddr net.Address) ([]net.IP, error) {
 	if dnsClient == nil {
 		return nil, nil
 	}
 
-	ips, err := dnsClient.LookupIP(domain, dns.IPOption{
+	ips, err := dns.LookupIPWithContext(ctx, dnsClient, domain, dns.IPOption{
 		IPv4Enable: (localAddr == nil || localAddr.Family().IsIPv4()) && strategy.preferIP4(),
 		IPv6Enable: (localAddr == nil || localAddr.Family().IsIPv6()) && strategy.preferIP6(),
 	})
 	{ // Resolve fallback
 		if (len(ips) == 0 || err != nil) && strategy.hasFallback() && localAddr == nil {
-			ips, err = dnsClient.LookupIP(domain, dns.IPOption{
+			ips, err = dns.LookupIPWithContext(ctx, dnsClient, domain, dns.IPOption{
 				IPv4Enable: strategy.fallbackIP4(),
 				IPv6Enable: strategy.fallbackIP6(),
 			})
@@ -110,6 +120,16 @@ func canLookupIP(ctx context.Context, dst net.Destination, sockopt *SocketConfig
 	return sockopt.DomainStrategy.hasStrategy()
 }
 
+// LookupECHConfigList returns the ECHConfigList in the HTTPS record of domain and its TTL, looked up by the DNS
+// client of the instance.
+func LookupECHConfigList(domain string) ([]byte, uint32, error) {
+	lookup, ok := dnsClient.(dns.ECHConfigLookup)
+	if !ok {
+		return nil, 0, errors.New("DNS client can't look up ECH configs")
+	}
+	return lookup.LookupECHConfigList(domain)
+}
+
 func redirect(ctx context.Context, dst net.Destination, obt string) net.Conn {
 	errors.LogInfo(ctx, "redirecting request "+dst.String()+" to "+obt)
 	h := obm.GetHandler(obt)
@@ -153,7 +173,10 @@ func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig
 	}
 
 	if canLookupIP(ctx, dest, sockopt) {
-		ips, err := lookupIP(dest.Address.String(), sockopt.DomainStrategy, src)
+		spanCtx, span := trace.Start(ctx, "dns.lookup")
+		span.SetAttribute("dns.domain", dest.Address)
+		ips, err := lookupIP(spanCtx, dest.Address.String(), sockopt.DomainStrategy, src)
+		span.End(err)
 		if err == nil && len(ips) > 0 {
 			dest.Address = net.IPAddress(ips[dice.Roll(len(ips))])
 			errors.LogInfo(ctx, "replace destination with "+dest.String())
@@ -0,0 +1,85 @@
+package fallback
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httputil"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+type remoteAddrKey struct{}
+
+// NewHandler returns the handler that passes the requests to the web server of config.
+func NewHandler(config *Config) (http.Handler, error) {
+	if config.Dest == "" {
+		if config.Dir == "" {
+			return nil, errors.New("neither dest nor dir is set for fallback")
+		}
+		return http.FileServer(http.Dir(config.Dir)), nil
+	}
+
+	network := config.Type
+	if network == "" {
+		network = "tcp"
+	}
+	scheme := "http"
+	if config.Tls {
+		scheme = "https"
+	}
+	host := config.Dest
+	if network == "unix" {
+		host = "localhost"
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, network, config.Dest)
+			if err != nil {
+				return nil, err
+			}
+			if config.Xver != 0 {
+				remoteAddr, _ := ctx.Value(remoteAddrKey{}).(net.Addr)
+				localAddr, _ := ctx.Value(http.LocalAddrContextKey).(net.Addr)
+				if addr, ok := localAddr.(*net.UDPAddr); ok { // HTTP/3
+					localAddr = &net.TCPAddr{IP: addr.IP, Port: addr.Port}
+				}
+				if _, err := proxyproto.HeaderProxyFromAddrs(byte(config.Xver), remoteAddr, localAddr).WriteTo(conn); err != nil {
+					conn.Close()
+					return nil, errors.New("failed to set PROXY protocol v", config.Xver).Base(err)
+				}
+			}
+			return conn, nil
+		},
+		// The web server is a local one, whose certificate is for the domains of the inbound.
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		// Every connection carries the PROXY protocol header of a single client.
+		DisableKeepAlives: config.Xver != 0,
+	}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out.URL.Scheme = scheme
+			r.Out.URL.Host = host
+			r.Out.Host = r.In.Host
+		},
+		Transport: transport,
+		ErrorHandler: func(writer http.ResponseWriter, request *http.Request, err error) {
+			errors.LogInfoInner(request.Context(), err, "failed to fallback ", request.URL.Path, " to ", config.Dest)
+			writer.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		errors.LogInfo(request.Context(), "fallback ", request.Method, " ", request.URL.Path, " to ", config.Dest)
+		if config.Xver != 0 {
+			if remoteAddr, err := net.ResolveTCPAddr("tcp", request.RemoteAddr); err == nil {
+				request = request.WithContext(context.WithValue(request.Context(), remoteAddrKey{}, remoteAddr))
+			}
+		}
+		proxy.ServeHTTP(writer, request)
+	}), nil
+}
@@ -0,0 +1,98 @@
+package fallback_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/transport/internet/fallback"
+)
+
+func TestFallbackToDest(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	sourceCh := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		header, err := proxyproto.Read(reader)
+		if err != nil {
+			sourceCh <- err.Error()
+			return
+		}
+		sourceCh <- header.SourceAddr.String()
+		request, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		response := &http.Response{
+			StatusCode: http.StatusTeapot,
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"X-Host": {request.Host}, "X-Agent": {request.Header.Get("User-Agent")}},
+		}
+		response.Write(conn)
+	}()
+
+	handler, err := NewHandler(&Config{
+		Type: "tcp",
+		Dest: listener.Addr().String(),
+		Xver: 2,
+	})
+	common.Must(err)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	request, err := http.NewRequest("GET", server.URL+"/index.html", nil)
+	common.Must(err)
+	request.Host = "www.example.com"
+	request.Header.Set("User-Agent", "fallback-test")
+	response, err := http.DefaultClient.Do(request)
+	common.Must(err)
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusTeapot {
+		t.Error("status: ", response.StatusCode)
+	}
+	if host := response.Header.Get("X-Host"); host != "www.example.com" {
+		t.Error("host: ", host)
+	}
+	if agent := response.Header.Get("X-Agent"); agent != "fallback-test" {
+		t.Error("user agent: ", agent)
+	}
+	if source := <-sourceCh; source == listener.Addr().String() {
+		t.Error("source: ", source)
+	} else if host, _, err := net.SplitHostPort(source); err != nil || host != "127.0.0.1" {
+		t.Error("source: ", source)
+	}
+}
+
+func TestFallbackToDir(t *testing.T) {
+	dir := t.TempDir()
+	common.Must(os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello"), 0o644))
+
+	handler, err := NewHandler(&Config{Dir: dir})
+	common.Must(err)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/hello.txt", nil))
+	body, err := io.ReadAll(recorder.Result().Body)
+	common.Must(err)
+	if string(body) != "hello" {
+		t.Error("body: ", string(body))
+	}
+}
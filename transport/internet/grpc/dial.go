@@ -11,6 +11,7 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/grpc/encoding"
 	"github.com/xtls/xray-core/transport/internet/reality"
@@ -48,6 +49,15 @@ var (
 )
 
 func dialgRPC(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (net.Conn, error) {
+	// The span covers getting the shared connection ready and opening the stream on it.
+	_, span := trace.Start(ctx, "grpc.handshake")
+	span.SetAttribute("grpc.service", streamSettings.ProtocolSettings.(*Config).getServiceName())
+	conn, err := openStream(ctx, dest, streamSettings)
+	span.End(err)
+	return conn, err
+}
+
+func openStream(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (net.Conn, error) {
 	grpcSettings := streamSettings.ProtocolSettings.(*Config)
 
 	conn, err := getGrpcClient(ctx, dest, streamSettings)
@@ -130,7 +140,11 @@ func getGrpcClient(ctx context.Context, dest net.Destination, streamSettings *in
 					if config.ServerName == "" && address.Family().IsDomain() {
 						config.ServerName = address.Domain()
 					}
-					if fingerprint := tls.GetFingerprint(tlsConfig.Fingerprint); fingerprint != nil {
+					if err := tlsConfig.ApplyECH(gctx, config); err != nil {
+						c.Close()
+						return nil, err
+					}
+					if fingerprint := tlsConfig.ClientFingerprint(config); fingerprint != nil {
 						return tls.UClient(c, config, fingerprint), nil
 					} else { // Fallback to normal gRPC TLS
 						return tls.Client(c, config), nil
@@ -41,6 +41,13 @@ func (l Listener) TunMulti(server encoding.GRPCService_TunMultiServer) error {
 	return nil
 }
 
+// Drain implements internet.DrainableListener. The tunnels in progress go on until Close, which stops the gRPC server
+// even while it is waiting for them.
+func (l Listener) Drain() error {
+	go l.s.GracefulStop()
+	return nil
+}
+
 func (l Listener) Close() error {
 	l.s.Stop()
 	return nil
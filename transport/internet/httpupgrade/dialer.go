@@ -10,6 +10,7 @@ import (
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
@@ -51,12 +52,31 @@ func dialhttpUpgrade(ctx context.Context, dest net.Destination, streamSettings *
 		return nil, err
 	}
 
+	_, span := trace.Start(ctx, "httpupgrade.handshake")
+	span.SetAttribute("http.path", transportConfiguration.GetNormalizedPath())
+	conn, err := handshake(ctx, pconn, dest, streamSettings)
+	span.End(err)
+	if err != nil {
+		pconn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// handshake does the TLS handshake if any and sends the Upgrade request on pconn. The response is read here too,
+// unless there is early data.
+func handshake(ctx context.Context, pconn net.Conn, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (net.Conn, error) {
+	transportConfiguration := streamSettings.ProtocolSettings.(*Config)
+
 	var conn net.Conn
 	var requestURL url.URL
 	tConfig := tls.ConfigFromStreamSettings(streamSettings)
 	if tConfig != nil {
 		tlsConfig := tConfig.GetTLSConfig(tls.WithDestination(dest), tls.WithNextProto("http/1.1"))
-		if fingerprint := tls.GetFingerprint(tConfig.Fingerprint); fingerprint != nil {
+		if err := tConfig.ApplyECH(ctx, tlsConfig); err != nil {
+			return nil, err
+		}
+		if fingerprint := tConfig.ClientFingerprint(tlsConfig); fingerprint != nil {
 			conn = tls.UClient(pconn, tlsConfig, fingerprint)
 			if err := conn.(*tls.UConn).WebsocketHandshakeContext(ctx); err != nil {
 				return nil, err
@@ -89,8 +109,7 @@ func dialhttpUpgrade(ctx context.Context, dest net.Destination, streamSettings *
 	req.Header.Set("Connection", "Upgrade")
 	req.Header.Set("Upgrade", "websocket")
 
-	err = req.Write(conn)
-	if err != nil {
+	if err := req.Write(conn); err != nil {
 		return nil, err
 	}
 
@@ -101,8 +120,7 @@ func dialhttpUpgrade(ctx context.Context, dest net.Destination, streamSettings *
 	}
 
 	if transportConfiguration.Ed == 0 {
-		_, err = connRF.Read([]byte{})
-		if err != nil {
+		if _, err := connRF.Read([]byte{}); err != nil {
 			return nil, err
 		}
 	}
@@ -0,0 +1,38 @@
+package hysteria2
+
+import (
+	"time"
+
+	"github.com/apernet/quic-go/congestion"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/transport/internet"
+	hyCongestion "github.com/xtls/xray-core/transport/internet/hysteria2/congestion"
+)
+
+const defaultIdleTimeout = 30 * time.Second
+
+func (c *Config) idleTimeout() time.Duration {
+	if c.GetIdleTimeout() == 0 {
+		return defaultIdleTimeout
+	}
+	return time.Duration(c.IdleTimeout) * time.Second
+}
+
+// newCongestionControl returns the congestion control for sending at most at c.Up to a peer receiving at most at rx,
+// where 0 is unknown. brutal is whether AUTO is Brutal.
+func (c *Config) newCongestionControl(rx uint64, brutal bool) congestion.CongestionControl {
+	rate := c.GetUp()
+	if rate == 0 || (rx > 0 && rx < rate) {
+		rate = rx
+	}
+	if rate == 0 || c.GetCongestion() == Congestion_BBR || (c.GetCongestion() == Congestion_AUTO && !brutal) {
+		return hyCongestion.NewBBR()
+	}
+	return hyCongestion.NewBrutal(rate)
+}
+
+func init() {
+	common.Must(internet.RegisterProtocolConfigCreator(protocolName, func() interface{} {
+		return new(Config)
+	}))
+}
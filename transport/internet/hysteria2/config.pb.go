@@ -0,0 +1,290 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.1
+// 	protoc        v5.28.2
+// source: transport/internet/hysteria2/config.proto
+
+package hysteria2
+
+import (
+	fallback "github.com/xtls/xray-core/transport/internet/fallback"
+	udp "github.com/xtls/xray-core/transport/internet/udp"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Congestion int32
+
+const (
+	// BRUTAL if the client knows the bandwidth it sends at, or the server
+	// knows the one the client receives at, BBR otherwise.
+	Congestion_AUTO   Congestion = 0
+	Congestion_BBR    Congestion = 1
+	Congestion_BRUTAL Congestion = 2
+)
+
+// Enum value maps for Congestion.
+var (
+	Congestion_name = map[int32]string{
+		0: "AUTO",
+		1: "BBR",
+		2: "BRUTAL",
+	}
+	Congestion_value = map[string]int32{
+		"AUTO":   0,
+		"BBR":    1,
+		"BRUTAL": 2,
+	}
+)
+
+func (x Congestion) Enum() *Congestion {
+	p := new(Congestion)
+	*p = x
+	return p
+}
+
+func (x Congestion) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Congestion) Descriptor() protoreflect.EnumDescriptor {
+	return file_transport_internet_hysteria2_config_proto_enumTypes[0].Descriptor()
+}
+
+func (Congestion) Type() protoreflect.EnumType {
+	return &file_transport_internet_hysteria2_config_proto_enumTypes[0]
+}
+
+func (x Congestion) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Congestion.Descriptor instead.
+func (Congestion) EnumDescriptor() ([]byte, []int) {
+	return file_transport_internet_hysteria2_config_proto_rawDescGZIP(), []int{0}
+}
+
+type Config struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Password of the salamander obfuscation, which is off if empty.
+	ObfsPassword string `protobuf:"bytes,1,opt,name=obfs_password,json=obfsPassword,proto3" json:"obfs_password,omitempty"`
+	// Bandwidth to send and to receive at, in bytes per second, 0 for unknown.
+	Up         uint64     `protobuf:"varint,2,opt,name=up,proto3" json:"up,omitempty"`
+	Down       uint64     `protobuf:"varint,3,opt,name=down,proto3" json:"down,omitempty"`
+	Congestion Congestion `protobuf:"varint,4,opt,name=congestion,proto3,enum=xray.transport.internet.hysteria2.Congestion" json:"congestion,omitempty"`
+	// Whether a server uses BBR for every client, regardless of the bandwidth
+	// the client receives at.
+	IgnoreClientBandwidth bool `protobuf:"varint,5,opt,name=ignore_client_bandwidth,json=ignoreClientBandwidth,proto3" json:"ignore_client_bandwidth,omitempty"`
+	// Web server that a server passes the requests not authenticated to, so
+	// that it looks like an HTTP/3 site.
+	Masquerade *fallback.Config `protobuf:"bytes,6,opt,name=masquerade,proto3" json:"masquerade,omitempty"`
+	Hop        *udp.Hop         `protobuf:"bytes,7,opt,name=hop,proto3" json:"hop,omitempty"`
+	// Seconds a connection survives without any packet, 0 for 30.
+	IdleTimeout uint32 `protobuf:"varint,8,opt,name=idle_timeout,json=idleTimeout,proto3" json:"idle_timeout,omitempty"`
+}
+
+func (x *Config) Reset() {
+	*x = Config{}
+	mi := &file_transport_internet_hysteria2_config_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Config) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Config) ProtoMessage() {}
+
+func (x *Config) ProtoReflect() protoreflect.Message {
+	mi := &file_transport_internet_hysteria2_config_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Config.ProtoReflect.Descriptor instead.
+func (*Config) Descriptor() ([]byte, []int) {
+	return file_transport_internet_hysteria2_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Config) GetObfsPassword() string {
+	if x != nil {
+		return x.ObfsPassword
+	}
+	return ""
+}
+
+func (x *Config) GetUp() uint64 {
+	if x != nil {
+		return x.Up
+	}
+	return 0
+}
+
+func (x *Config) GetDown() uint64 {
+	if x != nil {
+		return x.Down
+	}
+	return 0
+}
+
+func (x *Config) GetCongestion() Congestion {
+	if x != nil {
+		return x.Congestion
+	}
+	return Congestion_AUTO
+}
+
+func (x *Config) GetIgnoreClientBandwidth() bool {
+	if x != nil {
+		return x.IgnoreClientBandwidth
+	}
+	return false
+}
+
+func (x *Config) GetMasquerade() *fallback.Config {
+	if x != nil {
+		return x.Masquerade
+	}
+	return nil
+}
+
+func (x *Config) GetHop() *udp.Hop {
+	if x != nil {
+		return x.Hop
+	}
+	return nil
+}
+
+func (x *Config) GetIdleTimeout() uint32 {
+	if x != nil {
+		return x.IdleTimeout
+	}
+	return 0
+}
+
+var File_transport_internet_hysteria2_config_proto protoreflect.FileDescriptor
+
+var file_transport_internet_hysteria2_config_proto_rawDesc = []byte{
+	0x0a, 0x29, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x6e, 0x65, 0x74, 0x2f, 0x68, 0x79, 0x73, 0x74, 0x65, 0x72, 0x69, 0x61, 0x32, 0x2f, 0x63,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x21, 0x78, 0x72, 0x61,
+	0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x6e, 0x65, 0x74, 0x2e, 0x68, 0x79, 0x73, 0x74, 0x65, 0x72, 0x69, 0x61, 0x32, 0x1a, 0x28,
+	0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e,
+	0x65, 0x74, 0x2f, 0x66, 0x61, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x2f, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x23, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70,
+	0x6f, 0x72, 0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2f, 0x75, 0x64, 0x70,
+	0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xf9, 0x02,
+	0x0a, 0x06, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x23, 0x0a, 0x0d, 0x6f, 0x62, 0x66, 0x73,
+	0x5f, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0c, 0x6f, 0x62, 0x66, 0x73, 0x50, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x12, 0x0e, 0x0a,
+	0x02, 0x75, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x75, 0x70, 0x12, 0x12, 0x0a,
+	0x04, 0x64, 0x6f, 0x77, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x64, 0x6f, 0x77,
+	0x6e, 0x12, 0x4d, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2d, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61,
+	0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e,
+	0x68, 0x79, 0x73, 0x74, 0x65, 0x72, 0x69, 0x61, 0x32, 0x2e, 0x43, 0x6f, 0x6e, 0x67, 0x65, 0x73,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x36, 0x0a, 0x17, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x65, 0x5f, 0x63, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x5f, 0x62, 0x61, 0x6e, 0x64, 0x77, 0x69, 0x64, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x15, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x65, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x42,
+	0x61, 0x6e, 0x64, 0x77, 0x69, 0x64, 0x74, 0x68, 0x12, 0x48, 0x0a, 0x0a, 0x6d, 0x61, 0x73, 0x71,
+	0x75, 0x65, 0x72, 0x61, 0x64, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x78,
+	0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x66, 0x61, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x2e,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x0a, 0x6d, 0x61, 0x73, 0x71, 0x75, 0x65, 0x72, 0x61,
+	0x64, 0x65, 0x12, 0x32, 0x0a, 0x03, 0x68, 0x6f, 0x70, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x20, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74,
+	0x2e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x75, 0x64, 0x70, 0x2e, 0x48, 0x6f,
+	0x70, 0x52, 0x03, 0x68, 0x6f, 0x70, 0x12, 0x21, 0x0a, 0x0c, 0x69, 0x64, 0x6c, 0x65, 0x5f, 0x74,
+	0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x69, 0x64,
+	0x6c, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x2a, 0x2b, 0x0a, 0x0a, 0x43, 0x6f, 0x6e,
+	0x67, 0x65, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x08, 0x0a, 0x04, 0x41, 0x55, 0x54, 0x4f, 0x10,
+	0x00, 0x12, 0x07, 0x0a, 0x03, 0x42, 0x42, 0x52, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x42, 0x52,
+	0x55, 0x54, 0x41, 0x4c, 0x10, 0x02, 0x42, 0x85, 0x01, 0x0a, 0x25, 0x63, 0x6f, 0x6d, 0x2e, 0x78,
+	0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x68, 0x79, 0x73, 0x74, 0x65, 0x72, 0x69, 0x61, 0x32,
+	0x50, 0x01, 0x5a, 0x36, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x78,
+	0x74, 0x6c, 0x73, 0x2f, 0x78, 0x72, 0x61, 0x79, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x74, 0x72,
+	0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74,
+	0x2f, 0x68, 0x79, 0x73, 0x74, 0x65, 0x72, 0x69, 0x61, 0x32, 0xaa, 0x02, 0x21, 0x58, 0x72, 0x61,
+	0x79, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x49, 0x6e, 0x74, 0x65,
+	0x72, 0x6e, 0x65, 0x74, 0x2e, 0x48, 0x79, 0x73, 0x74, 0x65, 0x72, 0x69, 0x61, 0x32, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_transport_internet_hysteria2_config_proto_rawDescOnce sync.Once
+	file_transport_internet_hysteria2_config_proto_rawDescData = file_transport_internet_hysteria2_config_proto_rawDesc
+)
+
+func file_transport_internet_hysteria2_config_proto_rawDescGZIP() []byte {
+	file_transport_internet_hysteria2_config_proto_rawDescOnce.Do(func() {
+		file_transport_internet_hysteria2_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_transport_internet_hysteria2_config_proto_rawDescData)
+	})
+	return file_transport_internet_hysteria2_config_proto_rawDescData
+}
+
+var file_transport_internet_hysteria2_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_transport_internet_hysteria2_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_transport_internet_hysteria2_config_proto_goTypes = []any{
+	(Congestion)(0),         // 0: xray.transport.internet.hysteria2.Congestion
+	(*Config)(nil),          // 1: xray.transport.internet.hysteria2.Config
+	(*fallback.Config)(nil), // 2: xray.transport.internet.fallback.Config
+	(*udp.Hop)(nil),         // 3: xray.transport.internet.udp.Hop
+}
+var file_transport_internet_hysteria2_config_proto_depIdxs = []int32{
+	0, // 0: xray.transport.internet.hysteria2.Config.congestion:type_name -> xray.transport.internet.hysteria2.Congestion
+	2, // 1: xray.transport.internet.hysteria2.Config.masquerade:type_name -> xray.transport.internet.fallback.Config
+	3, // 2: xray.transport.internet.hysteria2.Config.hop:type_name -> xray.transport.internet.udp.Hop
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_transport_internet_hysteria2_config_proto_init() }
+func file_transport_internet_hysteria2_config_proto_init() {
+	if File_transport_internet_hysteria2_config_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_transport_internet_hysteria2_config_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_transport_internet_hysteria2_config_proto_goTypes,
+		DependencyIndexes: file_transport_internet_hysteria2_config_proto_depIdxs,
+		EnumInfos:         file_transport_internet_hysteria2_config_proto_enumTypes,
+		MessageInfos:      file_transport_internet_hysteria2_config_proto_msgTypes,
+	}.Build()
+	File_transport_internet_hysteria2_config_proto = out.File
+	file_transport_internet_hysteria2_config_proto_rawDesc = nil
+	file_transport_internet_hysteria2_config_proto_goTypes = nil
+	file_transport_internet_hysteria2_config_proto_depIdxs = nil
+}
@@ -0,0 +1,391 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/apernet/quic-go/congestion"
+	"github.com/xtls/xray-core/common/dice"
+)
+
+const (
+	bbrHighGain  = 2.885
+	bbrDrainGain = 1 / bbrHighGain
+	bbrCwndGain  = 2
+	// bbrBandwidthRounds is how many round trips the maximum bandwidth is kept for.
+	bbrBandwidthRounds = 10
+	// bbrStartupRounds is how many round trips without growth end the startup.
+	bbrStartupRounds = 3
+	bbrStartupGrowth = 1.25
+	bbrMinRTTExpiry  = 10 * time.Second
+	bbrProbeRTTTime  = 200 * time.Millisecond
+	// bbrPacketExpiry is when a packet neither acknowledged nor lost is forgotten, as quic-go drops some silently.
+	bbrPacketExpiry        = 30 * time.Second
+	bbrInitialRTT          = 100 * time.Millisecond
+	bbrInitialWindowPacket = 32
+	bbrMinWindowPacket     = 4
+)
+
+var bbrPacingGainCycle = [...]float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+type bbrMode int
+
+const (
+	bbrStartup bbrMode = iota
+	bbrDrain
+	bbrProbeBW
+	bbrProbeRTT
+)
+
+// bbrPacket is the state of the connection when a packet was sent, to measure the delivery rate when it's acked.
+type bbrPacket struct {
+	sentTime      time.Time
+	delivered     congestion.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+	appLimited    bool
+}
+
+type bbrBandwidthSample struct {
+	round     uint64
+	bandwidth congestion.ByteCount
+}
+
+// BBR is the BBR congestion control of version 1, which sends at the maximum delivery rate measured in the last
+// round trips, probing for more bandwidth and for the minimum RTT from time to time.
+type BBR struct {
+	rttStats        congestion.RTTStatsProvider
+	maxDatagramSize congestion.ByteCount
+	pacer           *pacer
+	mode            bbrMode
+
+	packets         map[congestion.PacketNumber]*bbrPacket
+	delivered       congestion.ByteCount
+	deliveredTime   time.Time
+	firstSentTime   time.Time
+	appLimitedUntil congestion.ByteCount
+	bytesInFlight   congestion.ByteCount
+
+	round              uint64
+	roundStart         bool
+	nextRoundDelivered congestion.ByteCount
+	bandwidthSamples   [bbrBandwidthRounds]bbrBandwidthSample
+	maxBandwidth       congestion.ByteCount
+
+	minRTT        time.Duration
+	minRTTStamp   time.Time
+	minRTTExpired bool
+
+	pacingGain       float64
+	cwndGain         float64
+	congestionWindow congestion.ByteCount
+
+	fullBandwidth        congestion.ByteCount
+	fullBandwidthRounds  int
+	fullBandwidthReached bool
+
+	cycleIndex int
+	cycleStamp time.Time
+
+	probeRTTDone time.Time
+	priorWindow  congestion.ByteCount
+}
+
+// NewBBR returns a BBR in its startup.
+func NewBBR() *BBR {
+	b := &BBR{
+		maxDatagramSize: congestion.InitialPacketSizeIPv4,
+		packets:         make(map[congestion.PacketNumber]*bbrPacket),
+		mode:            bbrStartup,
+		pacingGain:      bbrHighGain,
+		cwndGain:        bbrHighGain,
+	}
+	b.congestionWindow = b.initialWindow()
+	b.pacer = newPacer(b.pacingRate)
+	return b
+}
+
+func (b *BBR) initialWindow() congestion.ByteCount {
+	return bbrInitialWindowPacket * b.maxDatagramSize
+}
+
+func (b *BBR) minWindow() congestion.ByteCount {
+	return bbrMinWindowPacket * b.maxDatagramSize
+}
+
+func (b *BBR) rtt() time.Duration {
+	if b.minRTT > 0 {
+		return b.minRTT
+	}
+	if b.rttStats != nil && b.rttStats.SmoothedRTT() > 0 {
+		return b.rttStats.SmoothedRTT()
+	}
+	return bbrInitialRTT
+}
+
+// bdp returns the bandwidth-delay product multiplied by gain.
+func (b *BBR) bdp(gain float64) congestion.ByteCount {
+	if b.maxBandwidth == 0 || b.minRTT == 0 {
+		return congestion.ByteCount(gain * float64(b.initialWindow()))
+	}
+	return congestion.ByteCount(gain * float64(b.maxBandwidth) * b.minRTT.Seconds())
+}
+
+func (b *BBR) pacingRate() congestion.ByteCount {
+	bandwidth := b.maxBandwidth
+	if bandwidth == 0 {
+		// Before any sample, the window is sent in a round trip.
+		bandwidth = congestion.ByteCount(float64(b.congestionWindow) / b.rtt().Seconds())
+	}
+	return congestion.ByteCount(b.pacingGain * float64(bandwidth))
+}
+
+func (b *BBR) SetRTTStatsProvider(provider congestion.RTTStatsProvider) {
+	b.rttStats = provider
+}
+
+func (b *BBR) TimeUntilSend(congestion.ByteCount) time.Time {
+	return b.pacer.TimeUntilSend()
+}
+
+func (b *BBR) HasPacingBudget(now time.Time) bool {
+	return b.pacer.Budget(now) >= b.maxDatagramSize
+}
+
+func (b *BBR) CanSend(bytesInFlight congestion.ByteCount) bool {
+	return bytesInFlight < b.congestionWindow
+}
+
+func (b *BBR) GetCongestionWindow() congestion.ByteCount {
+	return b.congestionWindow
+}
+
+func (b *BBR) OnPacketSent(sentTime time.Time, bytesInFlight congestion.ByteCount, pn congestion.PacketNumber, bytes congestion.ByteCount, isRetransmittable bool) {
+	// The sender has nothing more to send if neither the window nor the pacer holds it back, so the delivery rate
+	// of what's sent now measures the application rather than the path.
+	if bytesInFlight < b.congestionWindow && b.pacer.Budget(sentTime) >= b.pacer.maxBurstSize() {
+		b.appLimitedUntil = max(b.delivered+bytesInFlight, 1)
+	}
+	b.pacer.SentPacket(sentTime, bytes)
+	if !isRetransmittable {
+		return
+	}
+	// bytesInFlight includes this packet.
+	if bytesInFlight <= bytes {
+		b.firstSentTime = sentTime
+		b.deliveredTime = sentTime
+	}
+	b.bytesInFlight = bytesInFlight
+	b.packets[pn] = &bbrPacket{
+		sentTime:      sentTime,
+		delivered:     b.delivered,
+		deliveredTime: b.deliveredTime,
+		firstSentTime: b.firstSentTime,
+		appLimited:    b.appLimitedUntil != 0,
+	}
+}
+
+func (b *BBR) OnPacketAcked(pn congestion.PacketNumber, ackedBytes congestion.ByteCount, _ congestion.ByteCount, eventTime time.Time) {
+	p := b.packets[pn]
+	if p == nil {
+		return
+	}
+	delete(b.packets, pn)
+
+	b.delivered += ackedBytes
+	b.deliveredTime = eventTime
+	if p.sentTime.After(b.firstSentTime) {
+		b.firstSentTime = p.sentTime
+	}
+	if b.appLimitedUntil != 0 && b.delivered > b.appLimitedUntil {
+		b.appLimitedUntil = 0
+	}
+
+	if p.delivered >= b.nextRoundDelivered {
+		b.nextRoundDelivered = b.delivered
+		b.round++
+		b.roundStart = true
+	}
+
+	interval := max(p.sentTime.Sub(p.firstSentTime), eventTime.Sub(p.deliveredTime))
+	if interval > 0 {
+		rate := (b.delivered - p.delivered) * congestion.ByteCount(time.Second) / congestion.ByteCount(interval)
+		if !p.appLimited || rate >= b.maxBandwidth {
+			b.updateBandwidth(rate)
+		}
+	}
+
+	rtt := eventTime.Sub(p.sentTime)
+	expired := !b.minRTTStamp.IsZero() && eventTime.Sub(b.minRTTStamp) > bbrMinRTTExpiry
+	if expired {
+		b.minRTTExpired = true
+	}
+	if rtt > 0 && (b.minRTT == 0 || rtt <= b.minRTT || expired) {
+		b.minRTT = rtt
+		b.minRTTStamp = eventTime
+	}
+}
+
+func (b *BBR) updateBandwidth(rate congestion.ByteCount) {
+	sample := &b.bandwidthSamples[b.round%bbrBandwidthRounds]
+	if sample.round != b.round {
+		*sample = bbrBandwidthSample{round: b.round}
+	}
+	sample.bandwidth = max(sample.bandwidth, rate)
+
+	b.maxBandwidth = 0
+	for _, s := range b.bandwidthSamples {
+		if b.round-s.round < bbrBandwidthRounds {
+			b.maxBandwidth = max(b.maxBandwidth, s.bandwidth)
+		}
+	}
+}
+
+func (b *BBR) OnCongestionEvent(pn congestion.PacketNumber, _ congestion.ByteCount, _ congestion.ByteCount) {
+	delete(b.packets, pn)
+}
+
+func (b *BBR) OnCongestionEventEx(priorInFlight congestion.ByteCount, eventTime time.Time, ackedPackets []congestion.AckedPacketInfo, lostPackets []congestion.LostPacketInfo) {
+	var acked, lost congestion.ByteCount
+	for _, p := range ackedPackets {
+		acked += p.BytesAcked
+	}
+	for _, p := range lostPackets {
+		lost += p.BytesLost
+		delete(b.packets, p.PacketNumber)
+	}
+	b.bytesInFlight = max(priorInFlight-acked-lost, 0)
+
+	if b.roundStart {
+		b.checkFullBandwidth()
+		b.forgetPackets(eventTime)
+	}
+	b.updateMode(eventTime, lost > 0)
+	b.roundStart = false
+	b.updateWindow(acked)
+}
+
+func (b *BBR) checkFullBandwidth() {
+	if b.fullBandwidthReached || b.appLimitedUntil != 0 {
+		return
+	}
+	if float64(b.maxBandwidth) >= float64(b.fullBandwidth)*bbrStartupGrowth {
+		b.fullBandwidth = b.maxBandwidth
+		b.fullBandwidthRounds = 0
+		return
+	}
+	b.fullBandwidthRounds++
+	if b.fullBandwidthRounds >= bbrStartupRounds {
+		b.fullBandwidthReached = true
+	}
+}
+
+func (b *BBR) forgetPackets(now time.Time) {
+	for pn, p := range b.packets {
+		if now.Sub(p.sentTime) > bbrPacketExpiry {
+			delete(b.packets, pn)
+		}
+	}
+}
+
+func (b *BBR) updateMode(now time.Time, lost bool) {
+	switch b.mode {
+	case bbrStartup:
+		if b.fullBandwidthReached {
+			b.mode = bbrDrain
+			b.pacingGain = bbrDrainGain
+			b.cwndGain = bbrHighGain
+		}
+	case bbrDrain:
+		if b.bytesInFlight <= b.bdp(1) {
+			b.enterProbeBW(now)
+		}
+	case bbrProbeBW:
+		b.advanceCycle(now, lost)
+	}
+
+	if b.minRTTExpired && b.mode != bbrProbeRTT {
+		b.mode = bbrProbeRTT
+		b.pacingGain = 1
+		b.priorWindow = b.congestionWindow
+		b.probeRTTDone = time.Time{}
+	}
+	b.minRTTExpired = false
+
+	if b.mode == bbrProbeRTT {
+		if b.probeRTTDone.IsZero() {
+			if b.bytesInFlight <= b.minWindow() {
+				b.probeRTTDone = now.Add(bbrProbeRTTTime)
+			}
+		} else if now.After(b.probeRTTDone) {
+			b.minRTTStamp = now
+			b.congestionWindow = max(b.congestionWindow, b.priorWindow)
+			if b.fullBandwidthReached {
+				b.enterProbeBW(now)
+			} else {
+				b.mode = bbrStartup
+				b.pacingGain = bbrHighGain
+				b.cwndGain = bbrHighGain
+			}
+		}
+	}
+}
+
+func (b *BBR) enterProbeBW(now time.Time) {
+	b.mode = bbrProbeBW
+	b.cwndGain = bbrCwndGain
+	// Any phase but the draining one, so that the flows sharing a link don't probe together.
+	b.cycleIndex = dice.Roll(len(bbrPacingGainCycle) - 1)
+	if b.cycleIndex >= 1 {
+		b.cycleIndex++
+	}
+	b.pacingGain = bbrPacingGainCycle[b.cycleIndex]
+	b.cycleStamp = now
+}
+
+func (b *BBR) advanceCycle(now time.Time, lost bool) {
+	elapsed := now.Sub(b.cycleStamp) > b.rtt()
+	advance := elapsed
+	switch {
+	case b.pacingGain > 1:
+		advance = elapsed && (lost || b.bytesInFlight >= b.bdp(b.pacingGain))
+	case b.pacingGain < 1:
+		advance = elapsed || b.bytesInFlight <= b.bdp(1)
+	}
+	if advance {
+		b.cycleIndex = (b.cycleIndex + 1) % len(bbrPacingGainCycle)
+		b.pacingGain = bbrPacingGainCycle[b.cycleIndex]
+		b.cycleStamp = now
+	}
+}
+
+func (b *BBR) updateWindow(acked congestion.ByteCount) {
+	if b.mode == bbrProbeRTT {
+		b.congestionWindow = b.minWindow()
+		return
+	}
+	target := b.bdp(b.cwndGain) + 3*b.maxDatagramSize
+	if b.fullBandwidthReached {
+		b.congestionWindow = min(b.congestionWindow+acked, target)
+	} else if b.congestionWindow < target || b.delivered < b.initialWindow() {
+		b.congestionWindow += acked
+	}
+	b.congestionWindow = max(b.congestionWindow, b.minWindow())
+}
+
+func (b *BBR) SetMaxDatagramSize(size congestion.ByteCount) {
+	b.maxDatagramSize = size
+	b.pacer.SetMaxDatagramSize(size)
+	b.congestionWindow = max(b.congestionWindow, b.minWindow())
+}
+
+func (b *BBR) MaybeExitSlowStart() {}
+
+func (b *BBR) OnRetransmissionTimeout(bool) {}
+
+func (b *BBR) InSlowStart() bool {
+	return b.mode == bbrStartup
+}
+
+func (b *BBR) InRecovery() bool {
+	return false
+}
@@ -0,0 +1,129 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/apernet/quic-go/congestion"
+)
+
+const (
+	brutalSlots = 5
+	// brutalMinSamples is how many packets are needed in the slots to measure the ack rate.
+	brutalMinSamples = 50
+	// brutalMinAckRate caps the compensation for loss, which would flood the link otherwise.
+	brutalMinAckRate = 0.8
+	// brutalWindowGain makes the congestion window larger than the bandwidth-delay product, not to limit the rate.
+	brutalWindowGain = 2
+)
+
+type brutalSlot struct {
+	second int64
+	acked  uint64
+	lost   uint64
+}
+
+// Brutal sends at a fixed bandwidth regardless of loss, only raising its rate by the ratio of the lost packets in
+// the last seconds, so that the bandwidth is what's delivered.
+type Brutal struct {
+	rttStats        congestion.RTTStatsProvider
+	bandwidth       congestion.ByteCount
+	maxDatagramSize congestion.ByteCount
+	pacer           *pacer
+
+	slots   [brutalSlots]brutalSlot
+	ackRate float64
+}
+
+// NewBrutal returns a Brutal sending at bandwidth bytes per second.
+func NewBrutal(bandwidth uint64) *Brutal {
+	b := &Brutal{
+		bandwidth:       congestion.ByteCount(bandwidth),
+		maxDatagramSize: congestion.InitialPacketSizeIPv4,
+		ackRate:         1,
+	}
+	b.pacer = newPacer(func() congestion.ByteCount {
+		return congestion.ByteCount(float64(b.bandwidth) / b.ackRate)
+	})
+	return b
+}
+
+func (b *Brutal) SetRTTStatsProvider(provider congestion.RTTStatsProvider) {
+	b.rttStats = provider
+}
+
+func (b *Brutal) TimeUntilSend(congestion.ByteCount) time.Time {
+	return b.pacer.TimeUntilSend()
+}
+
+func (b *Brutal) HasPacingBudget(now time.Time) bool {
+	return b.pacer.Budget(now) >= b.maxDatagramSize
+}
+
+func (b *Brutal) CanSend(bytesInFlight congestion.ByteCount) bool {
+	return bytesInFlight < b.GetCongestionWindow()
+}
+
+func (b *Brutal) GetCongestionWindow() congestion.ByteCount {
+	var rtt time.Duration
+	if b.rttStats != nil {
+		rtt = b.rttStats.SmoothedRTT()
+	}
+	if rtt <= 0 {
+		return 10240
+	}
+	window := congestion.ByteCount(float64(b.bandwidth) * rtt.Seconds() * brutalWindowGain / b.ackRate)
+	return max(window, b.maxDatagramSize)
+}
+
+func (b *Brutal) OnPacketSent(sentTime time.Time, _ congestion.ByteCount, _ congestion.PacketNumber, bytes congestion.ByteCount, _ bool) {
+	b.pacer.SentPacket(sentTime, bytes)
+}
+
+func (b *Brutal) OnPacketAcked(congestion.PacketNumber, congestion.ByteCount, congestion.ByteCount, time.Time) {
+}
+
+func (b *Brutal) OnCongestionEvent(congestion.PacketNumber, congestion.ByteCount, congestion.ByteCount) {
+}
+
+func (b *Brutal) OnCongestionEventEx(_ congestion.ByteCount, eventTime time.Time, ackedPackets []congestion.AckedPacketInfo, lostPackets []congestion.LostPacketInfo) {
+	second := eventTime.Unix()
+	slot := &b.slots[second%brutalSlots]
+	if slot.second != second {
+		*slot = brutalSlot{second: second}
+	}
+	slot.acked += uint64(len(ackedPackets))
+	slot.lost += uint64(len(lostPackets))
+	b.updateAckRate(second)
+}
+
+func (b *Brutal) updateAckRate(second int64) {
+	var acked, lost uint64
+	for _, slot := range b.slots {
+		if second-slot.second < brutalSlots {
+			acked += slot.acked
+			lost += slot.lost
+		}
+	}
+	if acked+lost < brutalMinSamples {
+		b.ackRate = 1
+		return
+	}
+	b.ackRate = max(float64(acked)/float64(acked+lost), brutalMinAckRate)
+}
+
+func (b *Brutal) SetMaxDatagramSize(size congestion.ByteCount) {
+	b.maxDatagramSize = size
+	b.pacer.SetMaxDatagramSize(size)
+}
+
+func (b *Brutal) MaybeExitSlowStart() {}
+
+func (b *Brutal) OnRetransmissionTimeout(bool) {}
+
+func (b *Brutal) InSlowStart() bool {
+	return false
+}
+
+func (b *Brutal) InRecovery() bool {
+	return false
+}
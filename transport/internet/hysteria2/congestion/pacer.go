@@ -0,0 +1,71 @@
+package congestion
+
+import (
+	"math"
+	"time"
+
+	"github.com/apernet/quic-go/congestion"
+)
+
+// maxBurstPackets is how many packets can be sent at once after an idle period.
+const maxBurstPackets = 10
+
+// pacer spreads the packets of a connection over time, at the rate of a bandwidth in bytes per second.
+type pacer struct {
+	budgetAtLastSent congestion.ByteCount
+	maxDatagramSize  congestion.ByteCount
+	lastSentTime     time.Time
+	bandwidth        func() congestion.ByteCount
+}
+
+func newPacer(bandwidth func() congestion.ByteCount) *pacer {
+	p := &pacer{
+		maxDatagramSize: congestion.InitialPacketSizeIPv4,
+		bandwidth:       bandwidth,
+	}
+	p.budgetAtLastSent = p.maxBurstSize()
+	return p
+}
+
+func (p *pacer) SentPacket(sendTime time.Time, size congestion.ByteCount) {
+	budget := p.Budget(sendTime)
+	if size > budget {
+		p.budgetAtLastSent = 0
+	} else {
+		p.budgetAtLastSent = budget - size
+	}
+	p.lastSentTime = sendTime
+}
+
+// Budget returns how many bytes can be sent at now.
+func (p *pacer) Budget(now time.Time) congestion.ByteCount {
+	if p.lastSentTime.IsZero() {
+		return p.maxBurstSize()
+	}
+	budget := p.budgetAtLastSent + p.bandwidth()*congestion.ByteCount(now.Sub(p.lastSentTime).Nanoseconds())/1e9
+	if budget < 0 { // overflow
+		budget = math.MaxInt64
+	}
+	return min(p.maxBurstSize(), budget)
+}
+
+func (p *pacer) maxBurstSize() congestion.ByteCount {
+	return max(
+		congestion.ByteCount((congestion.MinPacingDelay+time.Millisecond).Nanoseconds())*p.bandwidth()/1e9,
+		maxBurstPackets*p.maxDatagramSize,
+	)
+}
+
+// TimeUntilSend returns when the next packet can be sent, or zero if it can be sent now.
+func (p *pacer) TimeUntilSend() time.Time {
+	if p.budgetAtLastSent >= p.maxDatagramSize {
+		return time.Time{}
+	}
+	bandwidth := max(p.bandwidth(), 1)
+	delay := time.Duration(math.Ceil(float64(p.maxDatagramSize-p.budgetAtLastSent) * 1e9 / float64(bandwidth)))
+	return p.lastSentTime.Add(max(congestion.MinPacingDelay, delay))
+}
+
+func (p *pacer) SetMaxDatagramSize(size congestion.ByteCount) {
+	p.maxDatagramSize = size
+}
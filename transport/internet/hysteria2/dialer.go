@@ -0,0 +1,387 @@
+package hysteria2
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apernet/quic-go"
+	"github.com/apernet/quic-go/http3"
+	"github.com/apernet/quic-go/quicvarint"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/udp"
+)
+
+type dialerConf struct {
+	net.Destination
+	*internet.MemoryStreamConfig
+	auth string
+}
+
+type dialerEntry struct {
+	access  sync.Mutex
+	session *clientSession
+}
+
+var (
+	globalDialerMap    map[dialerConf]*dialerEntry
+	globalDialerAccess sync.Mutex
+)
+
+// Dial returns a ClientConn of the connection to dest authenticated by the auth in ctx, which is shared by every
+// call for the same dest and streamSettings.
+func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (stat.Connection, error) {
+	key := dialerConf{dest, streamSettings, authFromContext(ctx)}
+
+	globalDialerAccess.Lock()
+	if globalDialerMap == nil {
+		globalDialerMap = make(map[dialerConf]*dialerEntry)
+	}
+	entry := globalDialerMap[key]
+	if entry == nil {
+		entry = new(dialerEntry)
+		globalDialerMap[key] = entry
+	}
+	globalDialerAccess.Unlock()
+
+	entry.access.Lock()
+	defer entry.access.Unlock()
+	if entry.session == nil || entry.session.conn.Context().Err() != nil {
+		session, err := dialSession(context.WithoutCancel(ctx), dest, streamSettings, key.auth)
+		if err != nil {
+			return nil, err
+		}
+		entry.session = session
+	}
+	return entry.session.newConn(ctx), nil
+}
+
+func dialSession(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig, auth string) (*clientSession, error) {
+	config := streamSettings.ProtocolSettings.(*Config)
+	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
+	if tlsConfig == nil {
+		return nil, errors.New("Hysteria2 requires TLS")
+	}
+	gotlsConfig := tlsConfig.GetTLSConfig(tls.WithDestination(dest))
+	gotlsConfig.NextProtos = []string{"h3"}
+	if err := tlsConfig.ApplyECH(ctx, gotlsConfig); err != nil {
+		return nil, err
+	}
+
+	dest.Network = net.Network_UDP
+	var packetConn net.PacketConn
+	var remoteAddr net.Addr
+	if config.Hop != nil {
+		conn, err := udp.DialHop(ctx, dest, config.Hop, streamSettings.SocketSettings)
+		if err != nil {
+			return nil, err
+		}
+		packetConn, remoteAddr = conn, conn.RemoteAddr()
+	} else {
+		conn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+		if err != nil {
+			return nil, errors.New("failed to dial ", dest).Base(err)
+		}
+		packetConn, remoteAddr = &internet.FakePacketConn{Conn: conn}, conn.RemoteAddr()
+	}
+	if config.ObfsPassword != "" {
+		conn, err := newSalamanderConn(packetConn, config.ObfsPassword)
+		if err != nil {
+			packetConn.Close()
+			return nil, err
+		}
+		packetConn = conn
+	}
+
+	quicConn, err := quic.DialEarly(ctx, packetConn, remoteAddr, gotlsConfig, &quic.Config{
+		InitialStreamReceiveWindow:     streamReceiveWindow,
+		MaxStreamReceiveWindow:         streamReceiveWindow,
+		InitialConnectionReceiveWindow: connReceiveWindow,
+		MaxConnectionReceiveWindow:     connReceiveWindow,
+		MaxIdleTimeout:                 config.idleTimeout(),
+		KeepAlivePeriod:                keepAlivePeriod,
+		EnableDatagrams:                true,
+	})
+	if err != nil {
+		packetConn.Close()
+		return nil, errors.New("failed to dial QUIC to ", dest).Base(err)
+	}
+	go func() {
+		<-quicConn.Context().Done()
+		packetConn.Close()
+	}()
+
+	request := &http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Scheme: "https", Host: authHost, Path: authPath},
+		Header: http.Header{
+			headerAuth:    {auth},
+			headerCCRX:    {strconv.FormatUint(config.Down, 10)},
+			headerPadding: {padding(256, 2048)},
+		},
+	}
+	response, err := (&http3.Transport{}).NewClientConn(quicConn).RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		quicConn.CloseWithError(0, "")
+		return nil, errors.New("failed to authenticate to ", dest).Base(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != authStatus {
+		quicConn.CloseWithError(0, "")
+		return nil, errors.New("failed to authenticate to ", dest, ": ", response.Status)
+	}
+
+	rx := response.Header.Get(headerCCRX)
+	serverRx, _ := strconv.ParseUint(rx, 10, 64)
+	quicConn.SetCongestionControl(config.newCongestionControl(serverRx, config.Up > 0 && rx != "auto"))
+	errors.LogInfo(ctx, "authenticated to Hysteria2 server ", dest)
+
+	s := &clientSession{
+		conn:        quicConn,
+		udp:         response.Header.Get(headerUDP) == "true",
+		idleTimeout: config.idleTimeout(),
+		udpSessions: make(map[uint32]*UDPSession),
+	}
+	if s.udp {
+		go s.receiveDatagrams()
+	}
+	return s, nil
+}
+
+// clientSession is an authenticated connection of a client, closed when no ClientConn uses it for the idle timeout.
+type clientSession struct {
+	conn        quic.Connection
+	udp         bool
+	idleTimeout time.Duration
+
+	access      sync.Mutex
+	refs        int
+	idleTimer   *time.Timer
+	udpSessions map[uint32]*UDPSession
+	nextUDPID   uint32
+}
+
+func (s *clientSession) newConn(ctx context.Context) *ClientConn {
+	s.access.Lock()
+	s.refs++
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+		s.idleTimer = nil
+	}
+	s.access.Unlock()
+	return &ClientConn{
+		ctx:     ctx,
+		session: s,
+	}
+}
+
+func (s *clientSession) release() {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.refs--
+	if s.refs == 0 {
+		s.idleTimer = time.AfterFunc(s.idleTimeout, func() {
+			s.access.Lock()
+			defer s.access.Unlock()
+			if s.refs == 0 {
+				s.conn.CloseWithError(0, "")
+			}
+		})
+	}
+}
+
+// receiveDatagrams passes the datagrams to the UDP sessions of their IDs.
+func (s *clientSession) receiveDatagrams() {
+	for {
+		message, err := s.conn.ReceiveDatagram(context.Background())
+		if err != nil {
+			return
+		}
+		if len(message) < 4 {
+			continue
+		}
+		id := uint32(message[0])<<24 | uint32(message[1])<<16 | uint32(message[2])<<8 | uint32(message[3])
+		s.access.Lock()
+		session := s.udpSessions[id]
+		s.access.Unlock()
+		if session == nil {
+			continue
+		}
+		select {
+		case session.messages <- message:
+		default: // dropped like a UDP packet
+		}
+	}
+}
+
+// ClientConn is a use of the connection of a client. Its Read and Write are of a TCP stream opened on first use, whose
+// frame type is written, and ListenUDP returns the datagrams of a UDP session. Closing it doesn't close the connection.
+type ClientConn struct {
+	ctx     context.Context
+	session *clientSession
+
+	access sync.Mutex
+	stream quic.Stream
+	closed bool
+}
+
+func (c *ClientConn) getStream() (quic.Stream, error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.closed {
+		return nil, io.ErrClosedPipe
+	}
+	if c.stream != nil {
+		return c.stream, nil
+	}
+	stream, err := c.session.conn.OpenStreamSync(c.ctx)
+	if err != nil {
+		return nil, errors.New("failed to open stream").Base(err)
+	}
+	if _, err := stream.Write(quicvarint.Append(nil, tcpRequestFrameType)); err != nil {
+		stream.CancelRead(0)
+		stream.Close()
+		return nil, err
+	}
+	c.stream = stream
+	return stream, nil
+}
+
+// openedStream returns the stream, or nil if it isn't opened, as for a UDP session.
+func (c *ClientConn) openedStream() quic.Stream {
+	c.access.Lock()
+	defer c.access.Unlock()
+	return c.stream
+}
+
+func (c *ClientConn) Read(b []byte) (int, error) {
+	stream, err := c.getStream()
+	if err != nil {
+		return 0, err
+	}
+	return stream.Read(b)
+}
+
+func (c *ClientConn) Write(b []byte) (int, error) {
+	stream, err := c.getStream()
+	if err != nil {
+		return 0, err
+	}
+	return stream.Write(b)
+}
+
+func (c *ClientConn) Close() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.stream != nil {
+		c.stream.CancelRead(0)
+		c.stream.Close()
+	}
+	c.session.release()
+	return nil
+}
+
+func (c *ClientConn) LocalAddr() net.Addr {
+	return c.session.conn.LocalAddr()
+}
+
+func (c *ClientConn) RemoteAddr() net.Addr {
+	return c.session.conn.RemoteAddr()
+}
+
+func (c *ClientConn) SetDeadline(t time.Time) error {
+	if stream := c.openedStream(); stream != nil {
+		return stream.SetDeadline(t)
+	}
+	return nil
+}
+
+func (c *ClientConn) SetReadDeadline(t time.Time) error {
+	if stream := c.openedStream(); stream != nil {
+		return stream.SetReadDeadline(t)
+	}
+	return nil
+}
+
+func (c *ClientConn) SetWriteDeadline(t time.Time) error {
+	if stream := c.openedStream(); stream != nil {
+		return stream.SetWriteDeadline(t)
+	}
+	return nil
+}
+
+// ListenUDP returns a new UDP session, if the server relays UDP.
+func (c *ClientConn) ListenUDP() (*UDPSession, error) {
+	if !c.session.udp {
+		return nil, errors.New("UDP is disabled by the server")
+	}
+	s := c.session
+	s.access.Lock()
+	defer s.access.Unlock()
+	for s.udpSessions[s.nextUDPID] != nil {
+		s.nextUDPID++
+	}
+	session := &UDPSession{
+		ID:       s.nextUDPID,
+		client:   s,
+		messages: make(chan []byte, 256),
+		done:     make(chan struct{}),
+	}
+	s.udpSessions[session.ID] = session
+	s.nextUDPID++
+	return session, nil
+}
+
+// UDPSession is the datagrams of a client starting with the ID of a UDP session.
+type UDPSession struct {
+	ID       uint32
+	client   *clientSession
+	messages chan []byte
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Send sends message, which starts with the ID of s.
+func (s *UDPSession) Send(message []byte) error {
+	return s.client.conn.SendDatagram(message)
+}
+
+// Receive returns the next message of s.
+func (s *UDPSession) Receive() ([]byte, error) {
+	select {
+	case message := <-s.messages:
+		return message, nil
+	case <-s.done:
+		return nil, io.EOF
+	case <-s.client.conn.Context().Done():
+		return nil, context.Cause(s.client.conn.Context())
+	}
+}
+
+// Close stops receiving the messages of s.
+func (s *UDPSession) Close() error {
+	s.once.Do(func() {
+		s.client.access.Lock()
+		delete(s.client.udpSessions, s.ID)
+		s.client.access.Unlock()
+		close(s.done)
+	})
+	return nil
+}
+
+func init() {
+	common.Must(internet.RegisterTransportDialer(protocolName, Dial))
+}
@@ -0,0 +1,161 @@
+package hysteria2
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/apernet/quic-go"
+	"github.com/apernet/quic-go/http3"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/fallback"
+	"github.com/xtls/xray-core/transport/internet/quichub"
+	"github.com/xtls/xray-core/transport/internet/udp"
+)
+
+// Listener is a Hysteria2 server, passing the authenticated connections as Conn.
+type Listener struct {
+	*quichub.Hub[quic.EarlyConnection, quic.ApplicationErrorCode]
+	ctx           context.Context
+	config        *Config
+	addConn       internet.ConnHandler
+	authenticator internet.Authenticator
+	masquerade    http.Handler
+}
+
+// Listen listens UDP on address:port, or on the ports to hop too, for Hysteria2.
+func Listen(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
+	config := streamSettings.ProtocolSettings.(*Config)
+	gotlsConfig, err := quichub.ServerTLSConfig(streamSettings, "Hysteria2")
+	if err != nil {
+		return nil, err
+	}
+	gotlsConfig.NextProtos = []string{"h3"}
+
+	l := &Listener{
+		ctx:           ctx,
+		config:        config,
+		addConn:       addConn,
+		authenticator: internet.AuthenticatorFromContext(ctx),
+		masquerade:    http.NotFoundHandler(),
+	}
+	if l.authenticator == nil {
+		errors.LogWarning(ctx, "Hysteria2 on ", address, ":", port, " authenticates no client, as the inbound has no users")
+	}
+	if config.Masquerade != nil {
+		if l.masquerade, err = fallback.NewHandler(config.Masquerade); err != nil {
+			return nil, errors.New("failed to set up masquerade for Hysteria2").Base(err)
+		}
+	}
+
+	var packetConn net.PacketConn
+	if config.Hop != nil {
+		packetConn, err = udp.ListenHop(ctx, address, port, config.Hop, streamSettings.SocketSettings)
+	} else {
+		packetConn, err = internet.ListenSystemPacket(ctx, &net.UDPAddr{
+			IP:   address.IP(),
+			Port: int(port),
+		}, streamSettings.SocketSettings)
+	}
+	if err != nil {
+		return nil, errors.New("failed to listen UDP for Hysteria2 on ", address, ":", port).Base(err)
+	}
+	if config.ObfsPassword != "" {
+		conn, err := newSalamanderConn(packetConn, config.ObfsPassword)
+		if err != nil {
+			packetConn.Close()
+			return nil, err
+		}
+		packetConn = conn
+	}
+
+	l.Hub, err = quichub.Listen[quic.EarlyConnection](ctx, "Hysteria2", address, port, packetConn, func(packetConn net.PacketConn) (io.Closer, quichub.Listener[quic.EarlyConnection], error) {
+		transport := &quic.Transport{Conn: packetConn}
+		listener, err := transport.ListenEarly(gotlsConfig, &quic.Config{
+			InitialStreamReceiveWindow:     streamReceiveWindow,
+			MaxStreamReceiveWindow:         streamReceiveWindow,
+			InitialConnectionReceiveWindow: connReceiveWindow,
+			MaxConnectionReceiveWindow:     connReceiveWindow,
+			MaxIncomingStreams:             maxIncomingStreams,
+			MaxIdleTimeout:                 config.idleTimeout(),
+			EnableDatagrams:                true,
+		})
+		return transport, listener, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l.Serve(func(conn quic.EarlyConnection) {
+		go l.serve(conn)
+	})
+	return l, nil
+}
+
+// serve answers the HTTP/3 requests of conn, until it is authenticated and its streams are TCP requests.
+func (l *Listener) serve(conn quic.EarlyConnection) {
+	c := &Conn{
+		conn:    conn,
+		streams: make(chan quic.Stream, 16),
+	}
+	var authenticate sync.Once
+	authenticated := make(chan struct{})
+
+	server := &http3.Server{
+		Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			credential := request.Header.Get(headerAuth)
+			if request.Method != http.MethodPost || request.Host != authHost || request.URL.Path != authPath ||
+				l.authenticator == nil || !l.authenticator.Authenticate(credential) {
+				l.masquerade.ServeHTTP(writer, request)
+				return
+			}
+			authenticate.Do(func() {
+				rx, _ := strconv.ParseUint(request.Header.Get(headerCCRX), 10, 64)
+				if l.config.IgnoreClientBandwidth {
+					rx = 0
+				}
+				conn.SetCongestionControl(l.config.newCongestionControl(rx, rx > 0))
+				c.auth = credential
+				close(authenticated)
+				l.addConn(c)
+			})
+			if l.config.IgnoreClientBandwidth {
+				writer.Header().Set(headerCCRX, "auto")
+			} else {
+				writer.Header().Set(headerCCRX, strconv.FormatUint(l.config.Down, 10))
+			}
+			writer.Header().Set(headerUDP, "true")
+			writer.Header().Set(headerPadding, padding(256, 2048))
+			writer.WriteHeader(authStatus)
+		}),
+		StreamHijacker: func(frameType http3.FrameType, _ quic.ConnectionTracingID, stream quic.Stream, err error) (bool, error) {
+			if err != nil || frameType != tcpRequestFrameType {
+				return false, nil
+			}
+			select {
+			case <-authenticated:
+			default:
+				return false, nil
+			}
+			select {
+			case c.streams <- stream:
+			case <-conn.Context().Done():
+				stream.CancelRead(0)
+				stream.Close()
+			}
+			return true, nil
+		},
+	}
+	if err := server.ServeQUICConn(conn); err != nil {
+		errors.LogDebugInner(l.ctx, err, "Hysteria2 connection from ", conn.RemoteAddr(), " ends")
+	}
+}
+
+func init() {
+	common.Must(internet.RegisterTransportListener(protocolName, Listen))
+}
@@ -0,0 +1,151 @@
+// Package hysteria2 is the transport of Hysteria2, QUIC connections authenticated with HTTP/3, whose streams carry
+// TCP and whose datagrams carry UDP. The connections of a server that aren't authenticated get the responses of a
+// web server, so that it looks like an HTTP/3 site.
+package hysteria2
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"time"
+
+	"github.com/apernet/quic-go"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+const protocolName = "hysteria2"
+
+const (
+	authHost      = "hysteria"
+	authPath      = "/auth"
+	authStatus    = 233
+	headerAuth    = "Hysteria-Auth"
+	headerCCRX    = "Hysteria-CC-RX"
+	headerUDP     = "Hysteria-UDP"
+	headerPadding = "Hysteria-Padding"
+
+	// tcpRequestFrameType starts every stream, as the frame of HTTP/3 that isn't a request.
+	tcpRequestFrameType = 0x401
+
+	streamReceiveWindow = 8 << 20
+	connReceiveWindow   = 20 << 20
+	maxIncomingStreams  = 1024
+	keepAlivePeriod     = 10 * time.Second
+)
+
+var errNotStream = errors.New("a Hysteria2 connection is read and written by its streams and datagrams")
+
+type authKey struct{}
+
+// ContextWithAuth returns a context for Dial, so that the connection is authenticated with auth.
+func ContextWithAuth(ctx context.Context, auth string) context.Context {
+	return context.WithValue(ctx, authKey{}, auth)
+}
+
+func authFromContext(ctx context.Context) string {
+	auth, _ := ctx.Value(authKey{}).(string)
+	return auth
+}
+
+// padding returns the random characters padding a header to hide its size.
+func padding(minSize, maxSize int64) string {
+	n, err := rand.Int(rand.Reader, big.NewInt(maxSize-minSize))
+	if err != nil {
+		return ""
+	}
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, minSize+n.Int64())
+	rand.Read(b)
+	for i := range b {
+		b[i] = letters[int(b[i])%len(letters)]
+	}
+	return string(b)
+}
+
+// Conn is a connection of a server, authenticated by a user. It is a stat.Connection for the inbound, which reads
+// the TCP streams by AcceptTCP and the UDP datagrams by ReceiveDatagram.
+type Conn struct {
+	conn    quic.Connection
+	auth    string
+	streams chan quic.Stream
+}
+
+// Auth returns the credential the client is authenticated with.
+func (c *Conn) Auth() string {
+	return c.auth
+}
+
+// AcceptTCP returns the next TCP stream, whose request is to be read.
+func (c *Conn) AcceptTCP(ctx context.Context) (stat.Connection, error) {
+	select {
+	case stream := <-c.streams:
+		return &streamConn{Stream: stream, conn: c.conn}, nil
+	case <-c.conn.Context().Done():
+		return nil, context.Cause(c.conn.Context())
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ReceiveDatagram returns the next UDP message.
+func (c *Conn) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	return c.conn.ReceiveDatagram(ctx)
+}
+
+// SendDatagram sends a UDP message, or returns a *quic.DatagramTooLargeError if it doesn't fit in a packet.
+func (c *Conn) SendDatagram(message []byte) error {
+	return c.conn.SendDatagram(message)
+}
+
+func (c *Conn) Read([]byte) (int, error) {
+	return 0, errNotStream
+}
+
+func (c *Conn) Write([]byte) (int, error) {
+	return 0, errNotStream
+}
+
+func (c *Conn) Close() error {
+	return c.conn.CloseWithError(0, "")
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (c *Conn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (c *Conn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// streamConn is a stream of a connection.
+type streamConn struct {
+	quic.Stream
+	conn quic.Connection
+}
+
+func (c *streamConn) Close() error {
+	c.CancelRead(0)
+	return c.Stream.Close()
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
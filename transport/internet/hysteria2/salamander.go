@@ -0,0 +1,83 @@
+package hysteria2
+
+import (
+	"crypto/rand"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	salamanderSaltSize = 8
+	// salamanderMinPasswordSize keeps the key from being guessed from the packets.
+	salamanderMinPasswordSize = 4
+)
+
+// salamanderConn obfuscates the packets of a PacketConn with the salamander of Hysteria2, which XORs every packet
+// with the BLAKE2b-256 of the password and a random salt prepended to it.
+type salamanderConn struct {
+	net.PacketConn
+	password []byte
+}
+
+func newSalamanderConn(conn net.PacketConn, password string) (*salamanderConn, error) {
+	if len(password) < salamanderMinPasswordSize {
+		return nil, errors.New("salamander password is shorter than ", salamanderMinPasswordSize)
+	}
+	return &salamanderConn{
+		PacketConn: conn,
+		password:   []byte(password),
+	}, nil
+}
+
+func (c *salamanderConn) key(salt []byte) [blake2b.Size256]byte {
+	return blake2b.Sum256(append(append(make([]byte, 0, len(c.password)+len(salt)), c.password...), salt...))
+}
+
+func (c *salamanderConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	buffer := buf.New()
+	defer buffer.Release()
+	b := buffer.Extend(min(int32(len(p))+salamanderSaltSize, buf.Size))
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(b)
+		if err != nil {
+			return 0, addr, err
+		}
+		if n <= salamanderSaltSize {
+			continue
+		}
+		key := c.key(b[:salamanderSaltSize])
+		payload := b[salamanderSaltSize:n]
+		for i := range payload {
+			p[i] = payload[i] ^ key[i%len(key)]
+		}
+		return len(payload), addr, nil
+	}
+}
+
+func (c *salamanderConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	buffer := buf.New()
+	defer buffer.Release()
+	if len(p)+salamanderSaltSize > buf.Size {
+		return 0, errors.New("packet is too large to obfuscate")
+	}
+	b := buffer.Extend(int32(salamanderSaltSize + len(p)))
+	if _, err := rand.Read(b[:salamanderSaltSize]); err != nil {
+		return 0, err
+	}
+	key := c.key(b[:salamanderSaltSize])
+	for i := range p {
+		b[salamanderSaltSize+i] = p[i] ^ key[i%len(key)]
+	}
+	if _, err := c.PacketConn.WriteTo(b, addr); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// SetReadBuffer keeps quic-go from warning about the buffer size of UDP, like FakePacketConn.
+func (c *salamanderConn) SetReadBuffer(int) error {
+	return nil
+}
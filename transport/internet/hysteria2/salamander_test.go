@@ -0,0 +1,51 @@
+package hysteria2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestSalamander(t *testing.T) {
+	serverConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer serverConn.Close()
+	clientConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer clientConn.Close()
+
+	server, err := newSalamanderConn(serverConn, "password")
+	common.Must(err)
+	client, err := newSalamanderConn(clientConn, "password")
+	common.Must(err)
+
+	payload := []byte("test string")
+	common.Must2(client.WriteTo(payload, serverConn.LocalAddr()))
+
+	b := make([]byte, 1500)
+	n, addr, err := serverConn.ReadFrom(b)
+	common.Must(err)
+	if n != salamanderSaltSize+len(payload) || bytes.Contains(b[:n], payload) {
+		t.Error("packet is not obfuscated")
+	}
+	common.Must2(serverConn.WriteTo(b[:n], addr))
+
+	n, _, err = client.ReadFrom(b)
+	common.Must(err)
+	if !bytes.Equal(b[:n], payload) {
+		t.Error("payload: ", b[:n])
+	}
+
+	common.Must2(client.WriteTo(payload, serverConn.LocalAddr()))
+	n, _, err = server.ReadFrom(b)
+	common.Must(err)
+	if !bytes.Equal(b[:n], payload) {
+		t.Error("payload: ", b[:n])
+	}
+
+	if _, err := newSalamanderConn(clientConn, "pwd"); err == nil {
+		t.Error("expected error for short password")
+	}
+}
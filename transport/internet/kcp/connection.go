@@ -197,7 +197,9 @@ type Connection struct {
 	receivingWorker *ReceivingWorker
 	sendingWorker   *SendingWorker
 
-	output SegmentWriter
+	output     SegmentWriter
+	fecWriter  *FECWriter
+	fecDecoder *fecDecoder
 
 	dataUpdater *Updater
 	pingUpdater *Updater
@@ -207,6 +209,18 @@ type Connection struct {
 func NewConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, config *Config) *Connection {
 	errors.LogInfo(context.Background(), "#", meta.Conversation, " creating connection to ", meta.RemoteAddr)
 
+	var fecWriter *FECWriter
+	if dataShards, parityShards := config.fecShards(); dataShards > 0 {
+		var err error
+		if fecWriter, err = NewFECWriter(writer, dataShards, parityShards); err != nil {
+			errors.LogWarningInner(context.Background(), err, "#", meta.Conversation, " sending without FEC")
+		} else {
+			// FEC is sent once the peer tells it decodes FEC.
+			fecWriter.off.Store(true)
+			writer = fecWriter
+		}
+	}
+
 	conn := &Connection{
 		meta:       meta,
 		closer:     closer,
@@ -215,6 +229,7 @@ func NewConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, con
 		dataOutput: signal.NewNotifier(),
 		Config:     config,
 		output:     NewRetryableWriter(NewSegmentWriter(writer)),
+		fecWriter:  fecWriter,
 		mss:        config.GetMTUValue() - uint32(writer.Overhead()) - DataSegmentOverhead,
 		roundTrip: &RoundTripInfo{
 			rto:    100,
@@ -244,6 +259,8 @@ func NewConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, con
 		isTerminated,
 		conn.updateTask)
 	conn.pingUpdater.WakeUp()
+	// The peer learns at once that FEC can be sent.
+	conn.Ping(conn.Elapsed(), CommandPing)
 
 	return conn
 }
@@ -531,6 +548,9 @@ func (c *Connection) Terminate() {
 		return
 	}
 	errors.LogInfo(context.Background(), "#", c.meta.Conversation, " terminating connection to ", c.RemoteAddr())
+	if d := c.fecDecoder; d != nil {
+		errors.LogDebug(context.Background(), "#", c.meta.Conversation, " FEC recovered ", d.recovered, " and lost ", d.unrecoverable, " data shards")
+	}
 
 	// v.SetState(StateTerminated)
 	c.dataInput.Signal()
@@ -541,6 +561,21 @@ func (c *Connection) Terminate() {
 	c.receivingWorker.Release()
 }
 
+// decodeFEC returns the segments in seg and the ones recovered with it. The decoder is created with the shards of the
+// peer, so FEC is decoded even if it isn't enabled locally.
+func (c *Connection) decodeFEC(seg *FECSegment) []Segment {
+	dataShards, parityShards := int(seg.DataShards), int(seg.ParityShards)
+	if d := c.fecDecoder; d == nil || d.dataShards != dataShards || d.parityShards != parityShards {
+		d, err := newFECDecoder(dataShards, parityShards)
+		if err != nil {
+			errors.LogInfoInner(context.Background(), err, "#", c.meta.Conversation, " discarding FEC segment")
+			return nil
+		}
+		c.fecDecoder = d
+	}
+	return c.fecDecoder.Decode(seg)
+}
+
 func (c *Connection) HandleOption(opt SegmentOption) {
 	if (opt & SegmentOptionClose) == SegmentOptionClose {
 		c.OnPeerClosed()
@@ -581,6 +616,10 @@ func (c *Connection) Input(segments []Segment) {
 			c.dataUpdater.WakeUp()
 		case *CmdOnlySegment:
 			c.HandleOption(seg.Option)
+			if seg.Option&SegmentOptionFEC == SegmentOptionFEC && c.fecWriter != nil && c.fecWriter.off.Load() {
+				errors.LogDebug(context.Background(), "#", c.meta.Conversation, " sending FEC to peer")
+				c.fecWriter.off.Store(false)
+			}
 			if seg.Command() == CommandTerminate {
 				switch c.State() {
 				case StateActive, StatePeerClosed:
@@ -591,7 +630,7 @@ func (c *Connection) Input(segments []Segment) {
 					c.SetState(StateTerminated)
 				}
 			}
-			if seg.Option == SegmentOptionClose || seg.Command() == CommandTerminate {
+			if seg.Option&SegmentOptionClose == SegmentOptionClose || seg.Command() == CommandTerminate {
 				c.dataInput.Signal()
 				c.dataOutput.Signal()
 			}
@@ -599,6 +638,10 @@ func (c *Connection) Input(segments []Segment) {
 			c.receivingWorker.ProcessSendingNext(seg.SendingNext)
 			c.roundTrip.UpdatePeerRTO(seg.PeerRTO, current)
 			seg.Release()
+		case *FECSegment:
+			segments := c.decodeFEC(seg)
+			seg.Release()
+			c.Input(segments)
 		default:
 		}
 	}
@@ -655,7 +698,10 @@ func (c *Connection) Ping(current uint32, cmd Command) {
 	seg.SendingNext = c.sendingWorker.FirstUnacknowledged()
 	seg.PeerRTO = c.roundTrip.Timeout()
 	if c.State() == StateReadyToClose {
+		// Older peers take no other option with it.
 		seg.Option = SegmentOptionClose
+	} else {
+		seg.Option = SegmentOptionFEC
 	}
 	c.output.Write(seg)
 	atomic.StoreUint32(&c.lastPingTime, current)
@@ -13,6 +13,7 @@ import (
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/udp"
 )
 
 var globalConv = uint32(dice.RollUint16())
@@ -49,13 +50,19 @@ func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet
 	dest.Network = net.Network_UDP
 	errors.LogInfo(ctx, "dialing mKCP to ", dest)
 
-	rawConn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	kcpSettings := streamSettings.ProtocolSettings.(*Config)
+
+	var rawConn net.Conn
+	var err error
+	if kcpSettings.Hop != nil {
+		rawConn, err = udp.DialHop(ctx, dest, kcpSettings.Hop, streamSettings.SocketSettings)
+	} else {
+		rawConn, err = internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	}
 	if err != nil {
 		return nil, errors.New("failed to dial to dest: ", err).AtWarning().Base(err)
 	}
 
-	kcpSettings := streamSettings.ProtocolSettings.(*Config)
-
 	header, err := kcpSettings.GetPackerHeader()
 	if err != nil {
 		return nil, errors.New("failed to create packet header").Base(err)
@@ -81,12 +88,18 @@ func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet
 		Conversation: conv,
 	}, writer, rawConn, kcpSettings)
 
+	registerFECCounters(ctx, kcpSettings)
 	go fetchInput(ctx, rawConn, reader, session)
 
 	var iConn stat.Connection = session
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
-		iConn = tls.Client(iConn, config.GetTLSConfig(tls.WithDestination(dest)))
+		tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
+		if err := config.ApplyECH(ctx, tlsConfig); err != nil {
+			iConn.Close()
+			return nil, err
+		}
+		iConn = tls.Client(iConn, tlsConfig)
 	}
 
 	return iConn, nil
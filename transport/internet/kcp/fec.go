@@ -0,0 +1,505 @@
+package kcp
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/reedsolomon"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const (
+	// FECSegmentOverhead is the size of the header of a FECSegment.
+	FECSegmentOverhead = 12
+
+	// fecMaxGroups is how many groups the receiver keeps for recovery.
+	fecMaxGroups = 16
+
+	// fecFlushDelay is how long a group waits for more data shards, before its parity is sent with the ones it has.
+	fecFlushDelay = 30 * time.Millisecond
+)
+
+// FECSegment is a packet of a FEC group, carrying either the segments of a data shard or a parity shard. As the
+// receiver decodes FEC regardless of its own config, peers with and without FEC can talk to each other, as long as
+// both have FEC support; see SegmentOptionFEC.
+type FECSegment struct {
+	Conv         uint16
+	Option       SegmentOption
+	Group        uint32
+	Index        byte
+	DataShards   byte
+	ParityShards byte
+	// Count is the number of data shards in the group of a parity shard, if the group is flushed before it is full.
+	// The missing data shards are empty.
+	Count byte
+
+	payload *buf.Buffer
+}
+
+const (
+	// SegmentOptionParity marks a FECSegment of a parity shard.
+	SegmentOptionParity SegmentOption = 2
+)
+
+func NewFECSegment() *FECSegment {
+	return new(FECSegment)
+}
+
+func (s *FECSegment) parse(conv uint16, cmd Command, opt SegmentOption, b []byte) (bool, []byte) {
+	s.Conv = conv
+	s.Option = opt
+	if len(b) < FECSegmentOverhead-4 {
+		return false, nil
+	}
+	s.Group = binary.BigEndian.Uint32(b)
+	s.Index = b[4]
+	s.DataShards = b[5]
+	s.ParityShards = b[6]
+	s.Count = b[7]
+	b = b[8:]
+
+	if len(b) > buf.Size {
+		return false, nil
+	}
+	s.Data().Clear()
+	s.Data().Write(b)
+	return true, nil
+}
+
+// IsParity returns whether s carries a parity shard.
+func (s *FECSegment) IsParity() bool {
+	return s.Option&SegmentOptionParity == SegmentOptionParity
+}
+
+func (s *FECSegment) Conversation() uint16 {
+	return s.Conv
+}
+
+func (*FECSegment) Command() Command {
+	return CommandFEC
+}
+
+func (s *FECSegment) Data() *buf.Buffer {
+	if s.payload == nil {
+		s.payload = buf.New()
+	}
+	return s.payload
+}
+
+func (s *FECSegment) ByteSize() int32 {
+	return FECSegmentOverhead + s.Data().Len()
+}
+
+func (s *FECSegment) Serialize(b []byte) {
+	binary.BigEndian.PutUint16(b, s.Conv)
+	b[2] = byte(CommandFEC)
+	b[3] = byte(s.Option)
+	binary.BigEndian.PutUint32(b[4:], s.Group)
+	b[8] = s.Index
+	b[9] = s.DataShards
+	b[10] = s.ParityShards
+	b[11] = s.Count
+	copy(b[FECSegmentOverhead:], s.Data().Bytes())
+}
+
+func (s *FECSegment) Release() {
+	if s.payload != nil {
+		s.payload.Release()
+		s.payload = nil
+	}
+}
+
+// fecShards returns the number of data and parity shards, or zeros if FEC is disabled.
+func (c *Config) fecShards() (int, int) {
+	if c == nil || c.Fec == nil || c.Fec.DataShards == 0 || c.Fec.ParityShards == 0 {
+		return 0, 0
+	}
+	return int(c.Fec.DataShards), int(c.Fec.ParityShards)
+}
+
+// FECWriter is a PacketWriter that wraps every packet into a data shard, and sends the parity shards after every
+// group of data shards, or once a group is left unfilled for fecFlushDelay.
+type FECWriter struct {
+	sync.Mutex
+	writer       PacketWriter
+	encoder      reedsolomon.Encoder
+	dataShards   int
+	parityShards int
+	// off is set while the peer isn't known to decode FEC, and the packets are passed through.
+	off atomic.Bool
+
+	group  uint32
+	conv   uint16
+	count  int
+	size   int
+	shards [][]byte
+	timer  *time.Timer
+}
+
+func NewFECWriter(writer PacketWriter, dataShards, parityShards int) (*FECWriter, error) {
+	if dataShards+parityShards > 255 {
+		return nil, errors.New("too many FEC shards: ", dataShards, "+", parityShards)
+	}
+	encoder, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, errors.New("failed to create FEC encoder").Base(err)
+	}
+	shards := make([][]byte, dataShards+parityShards)
+	for i := range shards {
+		shards[i] = make([]byte, 0, buf.Size)
+	}
+	return &FECWriter{
+		writer:       writer,
+		encoder:      encoder,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		shards:       shards,
+	}, nil
+}
+
+// Overhead implements PacketWriter. Parity shards are the largest packets, with the length of the data before it.
+func (w *FECWriter) Overhead() int {
+	return w.writer.Overhead() + FECSegmentOverhead + 2
+}
+
+// Write implements io.Writer. b is a serialized segment.
+func (w *FECWriter) Write(b []byte) (int, error) {
+	if len(b) < 2 || len(b)+2 > buf.Size || w.off.Load() {
+		return w.writer.Write(b)
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	conv := binary.BigEndian.Uint16(b)
+	if err := w.writeShard(conv, 0, w.count, 0, b); err != nil {
+		return 0, err
+	}
+
+	shard := w.shards[w.count][:2+len(b)]
+	binary.BigEndian.PutUint16(shard, uint16(len(b)))
+	copy(shard[2:], b)
+	w.shards[w.count] = shard
+	w.size = max(w.size, len(shard))
+	w.count++
+	w.conv = conv
+
+	if w.count == w.dataShards {
+		if err := w.writeParity(); err != nil {
+			return 0, err
+		}
+	} else if w.count == 1 {
+		group := w.group
+		w.timer = time.AfterFunc(fecFlushDelay, func() {
+			w.Lock()
+			defer w.Unlock()
+
+			if w.group == group && w.count > 0 {
+				w.writeParity()
+			}
+		})
+	}
+	return len(b), nil
+}
+
+func (w *FECWriter) writeShard(conv uint16, opt SegmentOption, index int, count int, b []byte) error {
+	bb := buf.StackNew()
+	defer bb.Release()
+
+	header := bb.Extend(FECSegmentOverhead)
+	(&FECSegment{
+		Conv:         conv,
+		Option:       opt,
+		Group:        w.group,
+		Index:        byte(index),
+		DataShards:   byte(w.dataShards),
+		ParityShards: byte(w.parityShards),
+		Count:        byte(count),
+	}).Serialize(header)
+	bb.Write(b)
+	_, err := w.writer.Write(bb.Bytes())
+	return err
+}
+
+// writeParity encodes and sends the parity shards of the current group, and starts the next group. The data shards
+// missing from a group flushed early are taken as empty.
+func (w *FECWriter) writeParity() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	count := w.count
+	defer func() {
+		w.group++
+		w.count = 0
+		w.size = 0
+	}()
+
+	for i, shard := range w.shards {
+		n := len(shard)
+		if i >= count {
+			n = 0
+		}
+		shard = shard[:w.size]
+		clear(shard[n:])
+		w.shards[i] = shard
+	}
+	if err := w.encoder.Encode(w.shards); err != nil {
+		return errors.New("failed to encode FEC parity").Base(err)
+	}
+	if count == w.dataShards {
+		count = 0
+	}
+	for i := w.dataShards; i < len(w.shards); i++ {
+		if err := w.writeShard(w.conv, SegmentOptionParity, i, count, w.shards[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fecCounters count the data shards recovered from parity shards, and the data shards lost with too few shards of
+// their group to recover them.
+type fecCounters struct {
+	recovered     stats.Counter
+	unrecoverable stats.Counter
+}
+
+const (
+	fecRecoveredCounter     = "kcp>>>fec>>>shards>>>recovered"
+	fecUnrecoverableCounter = "kcp>>>fec>>>shards>>>unrecoverable"
+)
+
+// fecStatsCounters are the counters in the stats manager of the latest instance.
+var fecStatsCounters atomic.Pointer[fecCounters]
+
+// registerFECCounters registers the FEC counters in the stats manager of the instance in ctx, if FEC is enabled in
+// config and stats are enabled.
+func registerFECCounters(ctx context.Context, config *Config) {
+	if dataShards, _ := config.fecShards(); dataShards == 0 {
+		return
+	}
+	v := core.FromContext(ctx)
+	if v == nil {
+		return
+	}
+	m, ok := v.GetFeature(stats.ManagerType()).(stats.Manager)
+	if !ok {
+		return
+	}
+	if current := fecStatsCounters.Load(); current != nil && m.GetCounter(fecRecoveredCounter) == current.recovered {
+		return
+	}
+	recovered, err := stats.GetOrRegisterCounter(m, fecRecoveredCounter)
+	if err != nil {
+		return
+	}
+	unrecoverable, err := stats.GetOrRegisterCounter(m, fecUnrecoverableCounter)
+	if err != nil {
+		return
+	}
+	fecStatsCounters.Store(&fecCounters{
+		recovered:     recovered,
+		unrecoverable: unrecoverable,
+	})
+}
+
+type fecGroup struct {
+	shards [][]byte
+	// count is the number of data shards of the group, which is only known to be fewer than all from a parity shard.
+	count    int
+	received int
+	done     bool
+}
+
+// fecDecoder recovers the lost data shards of a connection.
+type fecDecoder struct {
+	decoder      reedsolomon.Encoder
+	dataShards   int
+	parityShards int
+	groups       map[uint32]*fecGroup
+	latest       uint32
+
+	recovered     uint64
+	unrecoverable uint64
+}
+
+func newFECDecoder(dataShards, parityShards int) (*fecDecoder, error) {
+	decoder, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, errors.New("failed to create FEC decoder").Base(err)
+	}
+	return &fecDecoder{
+		decoder:      decoder,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		groups:       make(map[uint32]*fecGroup),
+	}, nil
+}
+
+// Decode returns the segments in seg, and the ones recovered with it.
+func (d *fecDecoder) Decode(seg *FECSegment) []Segment {
+	index := int(seg.Index)
+	if index >= d.dataShards+d.parityShards {
+		return nil
+	}
+
+	var result []Segment
+	if !seg.IsParity() {
+		result = readSegments(seg.Data().Bytes())
+	}
+
+	group := d.groups[seg.Group]
+	if group == nil {
+		if int32(seg.Group-d.latest) < -fecMaxGroups {
+			return result
+		}
+		group = &fecGroup{
+			shards: make([][]byte, d.dataShards+d.parityShards),
+			count:  d.dataShards,
+		}
+		d.groups[seg.Group] = group
+		if int32(seg.Group-d.latest) > 0 || len(d.groups) == 1 {
+			d.latest = seg.Group
+			d.evict()
+		}
+	}
+	if group.done || group.shards[index] != nil {
+		return result
+	}
+	if seg.IsParity() && seg.Count > 0 && int(seg.Count) < d.dataShards {
+		group.count = int(seg.Count)
+	}
+
+	payload := seg.Data().Bytes()
+	var shard []byte
+	if seg.IsParity() {
+		shard = append(make([]byte, 0, len(payload)), payload...)
+	} else {
+		shard = make([]byte, 2+len(payload))
+		binary.BigEndian.PutUint16(shard, uint16(len(payload)))
+		copy(shard[2:], payload)
+	}
+	group.shards[index] = shard
+	group.received++
+
+	if d.missing(group) == 0 {
+		group.done = true
+		return result
+	}
+	// The data shards missing from a group flushed early are known to be empty.
+	if group.received < group.count {
+		return result
+	}
+	return append(result, d.recover(group)...)
+}
+
+// recover reconstructs the missing data shards of group, which has enough shards.
+func (d *fecDecoder) recover(group *fecGroup) []Segment {
+	group.done = true
+
+	size := 0
+	for i := d.dataShards; i < len(group.shards); i++ {
+		if group.shards[i] != nil {
+			size = len(group.shards[i])
+			break
+		}
+	}
+	missing := make([]bool, d.dataShards)
+	for i, shard := range group.shards {
+		switch {
+		case i >= group.count && i < d.dataShards:
+			if shard != nil {
+				d.lose(d.missing(group))
+				return nil
+			}
+			group.shards[i] = make([]byte, size)
+		case shard == nil:
+			if i < d.dataShards {
+				missing[i] = true
+			}
+		case len(shard) > size || (i >= d.dataShards && len(shard) != size):
+			d.lose(d.missing(group))
+			return nil
+		case len(shard) < size:
+			group.shards[i] = append(shard, make([]byte, size-len(shard))...)
+		}
+	}
+	if err := d.decoder.ReconstructData(group.shards); err != nil {
+		d.lose(d.missing(group))
+		return nil
+	}
+
+	var result []Segment
+	for i, lost := range missing {
+		if !lost {
+			continue
+		}
+		shard := group.shards[i]
+		n := int(binary.BigEndian.Uint16(shard))
+		if n+2 > len(shard) {
+			d.lose(1)
+			continue
+		}
+		result = append(result, readSegments(shard[2:2+n])...)
+		d.recovered++
+		if counters := fecStatsCounters.Load(); counters != nil {
+			counters.recovered.Add(1)
+		}
+	}
+	return result
+}
+
+// evict drops the groups too old to be recovered.
+func (d *fecDecoder) evict() {
+	for id, group := range d.groups {
+		if int32(d.latest-id) < fecMaxGroups {
+			continue
+		}
+		if !group.done {
+			d.lose(d.missing(group))
+		}
+		delete(d.groups, id)
+	}
+}
+
+func (d *fecDecoder) lose(n int) {
+	d.unrecoverable += uint64(n)
+	if counters := fecStatsCounters.Load(); counters != nil {
+		counters.unrecoverable.Add(int64(n))
+	}
+}
+
+// missing returns the number of data shards of group not received.
+func (d *fecDecoder) missing(group *fecGroup) int {
+	n := 0
+	for _, shard := range group.shards[:group.count] {
+		if shard == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func readSegments(b []byte) []Segment {
+	var result []Segment
+	for len(b) > 0 {
+		seg, x := ReadSegment(b)
+		if seg == nil {
+			break
+		}
+		if _, ok := seg.(*FECSegment); ok {
+			seg.Release()
+			break
+		}
+		result = append(result, seg)
+		b = x
+	}
+	return result
+}
@@ -0,0 +1,170 @@
+package kcp
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/xtls/xray-core/common"
+)
+
+type packetRecorder struct {
+	packets [][]byte
+}
+
+func (r *packetRecorder) Overhead() int {
+	return 0
+}
+
+func (r *packetRecorder) Write(b []byte) (int, error) {
+	r.packets = append(r.packets, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func TestFECRecovery(t *testing.T) {
+	recorder := new(packetRecorder)
+	writer, err := NewFECWriter(recorder, 4, 2)
+	common.Must(err)
+
+	var sent []*DataSegment
+	for i := 0; i < 4; i++ {
+		seg := &DataSegment{
+			Conv:   1,
+			Number: uint32(i),
+		}
+		seg.Data().Write(make([]byte, 100*(i+1)))
+		b := make([]byte, seg.ByteSize())
+		seg.Serialize(b)
+		common.Must2(writer.Write(b))
+		sent = append(sent, seg)
+	}
+	if len(recorder.packets) != 6 {
+		t.Fatal("packets: ", len(recorder.packets))
+	}
+
+	decoder, err := newFECDecoder(4, 2)
+	common.Must(err)
+	var received []*DataSegment
+	for i, packet := range recorder.packets {
+		if i == 1 || i == 2 {
+			continue
+		}
+		seg, _ := ReadSegment(packet)
+		for _, s := range decoder.Decode(seg.(*FECSegment)) {
+			received = append(received, s.(*DataSegment))
+		}
+	}
+
+	if decoder.recovered != 2 || decoder.unrecoverable != 0 {
+		t.Error("recovered: ", decoder.recovered, ", unrecoverable: ", decoder.unrecoverable)
+	}
+	if len(received) != 4 {
+		t.Fatal("segments: ", len(received))
+	}
+	for _, seg := range sent {
+		var found *DataSegment
+		for _, s := range received {
+			if s.Number == seg.Number {
+				found = s
+			}
+		}
+		if found == nil {
+			t.Fatal("segment ", seg.Number, " is not recovered")
+		}
+		if r := cmp.Diff(found, seg, cmpopts.IgnoreUnexported(DataSegment{})); r != "" {
+			t.Error(r)
+		}
+		if r := cmp.Diff(found.Data().Bytes(), seg.Data().Bytes()); r != "" {
+			t.Error(r)
+		}
+	}
+}
+
+func TestFECUnrecoverable(t *testing.T) {
+	recorder := new(packetRecorder)
+	writer, err := NewFECWriter(recorder, 2, 1)
+	common.Must(err)
+	for i := 0; i < 2*(fecMaxGroups+1); i++ {
+		seg := &CmdOnlySegment{Conv: 1, Cmd: CommandPing}
+		b := make([]byte, seg.ByteSize())
+		seg.Serialize(b)
+		common.Must2(writer.Write(b))
+	}
+
+	decoder, err := newFECDecoder(2, 1)
+	common.Must(err)
+	for i, packet := range recorder.packets {
+		// Loses both data shards of the first group.
+		if i < 2 {
+			continue
+		}
+		seg, _ := ReadSegment(packet)
+		decoder.Decode(seg.(*FECSegment))
+	}
+	if decoder.unrecoverable != 2 {
+		t.Error("unrecoverable: ", decoder.unrecoverable)
+	}
+}
+
+func TestFECFlushPartialGroup(t *testing.T) {
+	recorder := new(packetRecorder)
+	writer, err := NewFECWriter(recorder, 4, 2)
+	common.Must(err)
+
+	var sent []*DataSegment
+	for i := 0; i < 2; i++ {
+		seg := &DataSegment{
+			Conv:   1,
+			Number: uint32(i),
+		}
+		seg.Data().Write(make([]byte, 100))
+		b := make([]byte, seg.ByteSize())
+		seg.Serialize(b)
+		common.Must2(writer.Write(b))
+		sent = append(sent, seg)
+	}
+	time.Sleep(3 * fecFlushDelay)
+
+	writer.Lock()
+	packets := recorder.packets
+	writer.Unlock()
+	if len(packets) != 4 {
+		t.Fatal("expected 2 data and 2 parity shards, but got packets: ", len(packets))
+	}
+
+	decoder, err := newFECDecoder(4, 2)
+	common.Must(err)
+	var received []Segment
+	for _, packet := range packets[1:] {
+		seg, _ := ReadSegment(packet)
+		received = append(received, decoder.Decode(seg.(*FECSegment))...)
+	}
+	if decoder.recovered != 1 || decoder.unrecoverable != 0 {
+		t.Error("recovered: ", decoder.recovered, ", unrecoverable: ", decoder.unrecoverable)
+	}
+	if len(received) != 2 || received[1].(*DataSegment).Number != sent[0].Number {
+		t.Error("unexpected segments: ", received)
+	}
+}
+
+func TestFECCapability(t *testing.T) {
+	recorder := new(packetRecorder)
+	conn := NewConnection(ConnMetadata{Conversation: 1}, recorder, io.NopCloser(nil), &Config{
+		Fec: &FEC{DataShards: 4, ParityShards: 2},
+	})
+	defer conn.Terminate()
+
+	if !conn.fecWriter.off.Load() {
+		t.Error("expected no FEC before the peer tells it decodes FEC")
+	}
+	conn.Input([]Segment{&CmdOnlySegment{Conv: 1, Cmd: CommandPing}})
+	if !conn.fecWriter.off.Load() {
+		t.Error("expected no FEC to a peer without FEC support")
+	}
+	conn.Input([]Segment{&CmdOnlySegment{Conv: 1, Cmd: CommandPing, Option: SegmentOptionFEC}})
+	if conn.fecWriter.off.Load() {
+		t.Error("expected FEC to a peer with FEC support")
+	}
+}
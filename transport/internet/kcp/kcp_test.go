@@ -11,9 +11,11 @@ import (
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	udpserver "github.com/xtls/xray-core/testing/servers/udp"
 	"github.com/xtls/xray-core/transport/internet"
 	. "github.com/xtls/xray-core/transport/internet/kcp"
 	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/udp"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -83,3 +85,146 @@ func TestDialAndListen(t *testing.T) {
 		t.Error("active connections: ", v)
 	}
 }
+
+func TestDialAndListenFEC(t *testing.T) {
+	// Only the client sends FEC shards, which the server decodes anyway.
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: &Config{},
+	}, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName: "mkcp",
+		ProtocolSettings: &Config{
+			Fec: &FEC{DataShards: 10, ParityShards: 3},
+		},
+	})
+	common.Must(err)
+	defer clientConn.Close()
+
+	clientSend := make([]byte, 256*1024)
+	rand.Read(clientSend)
+	go clientConn.Write(clientSend)
+
+	clientReceived := make([]byte, 256*1024)
+	common.Must2(io.ReadFull(clientConn, clientReceived))
+	if r := cmp.Diff(clientReceived, clientSend); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestDialAndListenHop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var serverPorts []net.Port
+	hopPorts := &net.PortList{}
+	for i := 0; i < 3; i++ {
+		p := udpserver.PickPort()
+		serverPorts = append(serverPorts, p)
+		hopPorts.Range = append(hopPorts.Range, &net.PortRange{From: uint32(p), To: uint32(p)})
+	}
+	// The client hops through a relay, so the server sees every hop from another address, as behind a NAT.
+	relay, err := udpserver.NewRelay(serverPorts)
+	common.Must(err)
+	defer relay.Close()
+
+	listerner, err := NewListener(ctx, net.LocalHostIP, udpserver.PickPort(), &internet.MemoryStreamConfig{
+		ProtocolName: "mkcp",
+		ProtocolSettings: &Config{
+			Hop: &udp.Hop{Ports: hopPorts},
+		},
+	}, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	clientConn, err := DialKCP(ctx, net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName: "mkcp",
+		ProtocolSettings: &Config{
+			Hop: &udp.Hop{
+				Ports:    relay.PortList(),
+				Interval: 1,
+			},
+		},
+	})
+	common.Must(err)
+	defer clientConn.Close()
+
+	// The conversation lasts for some hops, and past the grace period of the first ports.
+	for i := 0; i < 14; i++ {
+		clientSend := make([]byte, 16*1024)
+		rand.Read(clientSend)
+		common.Must2(clientConn.Write(clientSend))
+
+		clientReceived := make([]byte, 16*1024)
+		common.Must2(io.ReadFull(clientConn, clientReceived))
+		if r := cmp.Diff(clientReceived, clientSend); r != "" {
+			t.Fatal(r)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if v := relay.Clients(); v < 4 {
+		t.Error("client addresses: ", v)
+	}
+	if v := listerner.ActiveConnections(); v != 1 {
+		t.Error("active connections: ", v)
+	}
+}
+
+func TestListenerDrain(t *testing.T) {
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: &Config{},
+	}
+	listerner, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), streamSettings, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			io.Copy(c, c)
+			c.Close()
+		}(conn)
+	})
+	common.Must(err)
+	defer listerner.Close()
+
+	port := net.Port(listerner.Addr().(*net.UDPAddr).Port)
+	echo := func(conn stat.Connection) error {
+		send := []byte("hello")
+		if _, err := conn.Write(send); err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		received := make([]byte, len(send))
+		_, err := io.ReadFull(conn, received)
+		return err
+	}
+
+	clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), streamSettings)
+	common.Must(err)
+	defer clientConn.Close()
+	common.Must(echo(clientConn))
+
+	common.Must(listerner.Drain())
+	if err := echo(clientConn); err != nil {
+		t.Error("expected the session in progress to go on, but got ", err)
+	}
+
+	newConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), streamSettings)
+	common.Must(err)
+	defer newConn.Close()
+	if err := echo(newConn); err == nil {
+		t.Error("expected new session to be dropped")
+	}
+}
@@ -22,17 +22,25 @@ type ConnectionID struct {
 	Conv   uint16
 }
 
+type session struct {
+	conn   *Connection
+	writer *Writer
+}
+
 // Listener defines a server listening for connections
 type Listener struct {
 	sync.Mutex
-	sessions  map[ConnectionID]*Connection
+	sessions  map[ConnectionID]*session
 	hub       *udp.Hub
+	hop       *udp.HopListener
 	tlsConfig *gotls.Config
 	config    *Config
 	reader    PacketReader
 	header    internet.PacketHeader
 	security  cipher.AEAD
 	addConn   internet.ConnHandler
+	// draining is set once the listener stops taking new sessions.
+	draining bool
 }
 
 func NewListener(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (*Listener, error) {
@@ -52,7 +60,7 @@ func NewListener(ctx context.Context, address net.Address, port net.Port, stream
 			Header:   header,
 			Security: security,
 		},
-		sessions: make(map[ConnectionID]*Connection),
+		sessions: make(map[ConnectionID]*session),
 		config:   kcpSettings,
 		addConn:  addConn,
 	}
@@ -61,16 +69,28 @@ func NewListener(ctx context.Context, address net.Address, port net.Port, stream
 		l.tlsConfig = config.GetTLSConfig()
 	}
 
-	hub, err := udp.ListenUDP(ctx, address, port, streamSettings, udp.HubCapacity(1024))
-	if err != nil {
-		return nil, err
+	if kcpSettings.Hop != nil {
+		hop, err := udp.ListenHop(ctx, address, port, kcpSettings.Hop, streamSettings.SocketSettings)
+		if err != nil {
+			return nil, err
+		}
+		l.Lock()
+		l.hop = hop
+		l.Unlock()
+		go l.handleHopPackets()
+	} else {
+		hub, err := udp.ListenUDP(ctx, address, port, streamSettings, udp.HubCapacity(1024))
+		if err != nil {
+			return nil, err
+		}
+		l.Lock()
+		l.hub = hub
+		l.Unlock()
+		go l.handlePackets()
 	}
-	l.Lock()
-	l.hub = hub
-	l.Unlock()
 	errors.LogInfo(ctx, "listening on ", address, ":", port)
 
-	go l.handlePackets()
+	registerFECCounters(ctx, kcpSettings)
 
 	return l, nil
 }
@@ -82,6 +102,19 @@ func (l *Listener) handlePackets() {
 	}
 }
 
+func (l *Listener) handleHopPackets() {
+	for {
+		payload := buf.New()
+		n, addr, err := l.hop.ReadFrom(payload.Extend(buf.Size))
+		if err != nil {
+			payload.Release()
+			return
+		}
+		payload.Resize(0, int32(n))
+		l.OnReceive(payload, net.DestinationFromAddr(addr))
+	}
+}
+
 func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 	segments := l.reader.Read(payload.Bytes())
 	payload.Release()
@@ -99,19 +132,22 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 		Port:   src.Port,
 		Conv:   conv,
 	}
+	if l.hop != nil {
+		// The port of a client changes with every hop.
+		id.Port = 0
+	}
 
 	l.Lock()
 	defer l.Unlock()
 
-	conn, found := l.sessions[id]
+	s, found := l.sessions[id]
 
 	if !found {
-		if cmd == CommandTerminate {
+		if cmd == CommandTerminate || l.draining {
 			return
 		}
 		writer := &Writer{
 			id:       id,
-			hub:      l.hub,
 			dest:     src,
 			listener: l,
 		}
@@ -119,8 +155,8 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 			IP:   src.Address.IP(),
 			Port: int(src.Port),
 		}
-		localAddr := l.hub.Addr()
-		conn = NewConnection(ConnMetadata{
+		localAddr := l.Addr()
+		conn := NewConnection(ConnMetadata{
 			LocalAddr:    localAddr,
 			RemoteAddr:   remoteAddr,
 			Conversation: conv,
@@ -135,9 +171,15 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 		}
 
 		l.addConn(netConn)
-		l.sessions[id] = conn
+		s = &session{
+			conn:   conn,
+			writer: writer,
+		}
+		l.sessions[id] = s
+	} else if l.hop != nil {
+		s.writer.setDest(src)
 	}
-	conn.Input(segments)
+	s.conn.Input(segments)
 }
 
 func (l *Listener) Remove(id ConnectionID) {
@@ -146,15 +188,29 @@ func (l *Listener) Remove(id ConnectionID) {
 	l.Unlock()
 }
 
+// Drain implements internet.DrainableListener. The packets of new sessions are dropped, while the sessions in
+// progress go on until Close.
+func (l *Listener) Drain() error {
+	l.Lock()
+	defer l.Unlock()
+
+	l.draining = true
+	return nil
+}
+
 // Close stops listening on the UDP address. Already Accepted connections are not closed.
 func (l *Listener) Close() error {
-	l.hub.Close()
+	if l.hop != nil {
+		l.hop.Close()
+	} else {
+		l.hub.Close()
+	}
 
 	l.Lock()
 	defer l.Unlock()
 
-	for _, conn := range l.sessions {
-		go conn.Terminate()
+	for _, s := range l.sessions {
+		go s.conn.Terminate()
 	}
 
 	return nil
@@ -169,18 +225,42 @@ func (l *Listener) ActiveConnections() int {
 
 // Addr returns the listener's network address, The Addr returned is shared by all invocations of Addr, so do not modify it.
 func (l *Listener) Addr() net.Addr {
+	if l.hop != nil {
+		return l.hop.LocalAddr()
+	}
 	return l.hub.Addr()
 }
 
+func (l *Listener) writeTo(payload []byte, dest net.Destination) (int, error) {
+	if l.hop != nil {
+		return l.hop.WriteTo(payload, &net.UDPAddr{
+			IP:   dest.Address.IP(),
+			Port: int(dest.Port),
+		})
+	}
+	return l.hub.WriteTo(payload, dest)
+}
+
 type Writer struct {
 	id       ConnectionID
-	dest     net.Destination
-	hub      *udp.Hub
 	listener *Listener
+
+	access sync.Mutex
+	dest   net.Destination
 }
 
 func (w *Writer) Write(payload []byte) (int, error) {
-	return w.hub.WriteTo(payload, w.dest)
+	w.access.Lock()
+	dest := w.dest
+	w.access.Unlock()
+	return w.listener.writeTo(payload, dest)
+}
+
+// setDest sets the address of the client, which changes when it hops.
+func (w *Writer) setDest(dest net.Destination) {
+	w.access.Lock()
+	w.dest = dest
+	w.access.Unlock()
 }
 
 func (w *Writer) Close() error {
@@ -18,12 +18,17 @@ const (
 	CommandTerminate Command = 2
 	// CommandPing indicates a ping.
 	CommandPing Command = 3
+	// CommandFEC indicates a FECSegment.
+	CommandFEC Command = 4
 )
 
 type SegmentOption byte
 
 const (
 	SegmentOptionClose SegmentOption = 1
+	// SegmentOptionFEC is set in the pings of peers that decode FECSegment. Peers only send FEC to the ones known to
+	// decode it, as older builds take FECSegment for garbage.
+	SegmentOptionFEC SegmentOption = 4
 )
 
 type Segment interface {
@@ -291,6 +296,8 @@ func ReadSegment(buf []byte) (Segment, []byte) {
 		seg = NewDataSegment()
 	case CommandACK:
 		seg = NewAckSegment()
+	case CommandFEC:
+		seg = NewFECSegment()
 	default:
 		seg = NewCmdOnlySegment()
 	}
@@ -0,0 +1,23 @@
+package masque
+
+import (
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/transport/internet"
+)
+
+const defaultIdleTimeout = 30 * time.Second
+
+func (c *Config) idleTimeout() time.Duration {
+	if c.GetIdleTimeout() == 0 {
+		return defaultIdleTimeout
+	}
+	return time.Duration(c.IdleTimeout) * time.Second
+}
+
+func init() {
+	common.Must(internet.RegisterProtocolConfigCreator(protocolName, func() interface{} {
+		return new(Config)
+	}))
+}
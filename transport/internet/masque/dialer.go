@@ -0,0 +1,304 @@
+package masque
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quic-go/qpack"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/quicvarint"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+type dialerConf struct {
+	net.Destination
+	*internet.MemoryStreamConfig
+}
+
+type dialerEntry struct {
+	access  sync.Mutex
+	session *clientSession
+}
+
+var (
+	globalDialerMap    map[dialerConf]*dialerEntry
+	globalDialerAccess sync.Mutex
+)
+
+// Dial returns a Conn of the request in ctx, on the connection to dest, which is shared by every call for the same
+// dest and streamSettings.
+func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (stat.Connection, error) {
+	r := requestFromContext(ctx)
+	if r == nil {
+		return nil, errors.New("MASQUE is dialed by the MASQUE outbound only")
+	}
+	key := dialerConf{dest, streamSettings}
+
+	globalDialerAccess.Lock()
+	if globalDialerMap == nil {
+		globalDialerMap = make(map[dialerConf]*dialerEntry)
+	}
+	entry := globalDialerMap[key]
+	if entry == nil {
+		entry = new(dialerEntry)
+		globalDialerMap[key] = entry
+	}
+	globalDialerAccess.Unlock()
+
+	entry.access.Lock()
+	if entry.session == nil || entry.session.conn.Context().Err() != nil {
+		session, err := dialSession(context.WithoutCancel(ctx), dest, streamSettings)
+		if err != nil {
+			entry.access.Unlock()
+			return nil, err
+		}
+		entry.session = session
+	}
+	session := entry.session
+	session.acquire()
+	entry.access.Unlock()
+
+	conn, err := session.request(ctx, dest, r)
+	if err != nil {
+		session.release()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func dialSession(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (*clientSession, error) {
+	config := streamSettings.ProtocolSettings.(*Config)
+	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
+	if tlsConfig == nil {
+		return nil, errors.New("MASQUE requires TLS")
+	}
+	gotlsConfig := tlsConfig.GetTLSConfig(tls.WithDestination(dest))
+	if len(tlsConfig.NextProtocol) == 0 {
+		gotlsConfig.NextProtos = []string{"h3"}
+	}
+	if err := tlsConfig.ApplyECH(ctx, gotlsConfig); err != nil {
+		return nil, err
+	}
+
+	dest.Network = net.Network_UDP
+	rawConn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	if err != nil {
+		return nil, errors.New("failed to dial ", dest).Base(err)
+	}
+	packetConn := &internet.FakePacketConn{Conn: rawConn}
+	quicConn, err := quic.DialEarly(ctx, packetConn, rawConn.RemoteAddr(), gotlsConfig, &quic.Config{
+		InitialStreamReceiveWindow:     streamReceiveWindow,
+		MaxStreamReceiveWindow:         streamReceiveWindow,
+		InitialConnectionReceiveWindow: connReceiveWindow,
+		MaxConnectionReceiveWindow:     connReceiveWindow,
+		MaxIncomingStreams:             -1,
+		MaxIdleTimeout:                 config.idleTimeout(),
+		KeepAlivePeriod:                keepAlivePeriod,
+		EnableDatagrams:                true,
+	})
+	if err != nil {
+		packetConn.Close()
+		return nil, errors.New("failed to dial QUIC to ", dest).Base(err)
+	}
+	go func() {
+		<-quicConn.Context().Done()
+		packetConn.Close()
+	}()
+
+	control, err := quicConn.OpenUniStream()
+	if err != nil {
+		quicConn.CloseWithError(0, "")
+		return nil, errors.New("failed to open control stream").Base(err)
+	}
+	if _, err := control.Write(append(quicvarint.Append(nil, streamTypeControl), settingsFrame()...)); err != nil {
+		quicConn.CloseWithError(0, "")
+		return nil, errors.New("failed to write SETTINGS").Base(err)
+	}
+	errors.LogInfo(ctx, "connected to MASQUE server ", dest)
+
+	s := &clientSession{
+		conn:             quicConn,
+		mux:              newDatagramMux(quicConn),
+		idleTimeout:      config.idleTimeout(),
+		settingsReceived: make(chan struct{}),
+	}
+	go s.acceptUniStreams()
+	return s, nil
+}
+
+// clientSession is an HTTP/3 connection of a client, closed when no request uses it for the idle timeout.
+type clientSession struct {
+	conn        quic.EarlyConnection
+	mux         *datagramMux
+	idleTimeout time.Duration
+
+	settingsReceived chan struct{}
+	settings         map[uint64]uint64
+
+	access    sync.Mutex
+	refs      int
+	idleTimer *time.Timer
+}
+
+func (s *clientSession) acquire() {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.refs++
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+		s.idleTimer = nil
+	}
+}
+
+func (s *clientSession) release() {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.refs--
+	if s.refs == 0 {
+		s.idleTimer = time.AfterFunc(s.idleTimeout, func() {
+			s.access.Lock()
+			defer s.access.Unlock()
+			if s.refs == 0 {
+				s.conn.CloseWithError(errorCodeNoError, "")
+			}
+		})
+	}
+}
+
+// acceptUniStreams reads the SETTINGS of the server, and drains its QPACK streams.
+func (s *clientSession) acceptUniStreams() {
+	for {
+		stream, err := s.conn.AcceptUniStream(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			r := quicvarint.NewReader(stream)
+			streamType, err := quicvarint.Read(r)
+			if err != nil {
+				return
+			}
+			switch streamType {
+			case streamTypeControl:
+				settings, err := readSettings(r)
+				if err != nil {
+					s.conn.CloseWithError(errorCodeMissingSettings, "")
+					return
+				}
+				s.settings = settings
+				close(s.settingsReceived)
+				// The frames after SETTINGS, like GOAWAY, are of no use to requests already made.
+				io.Copy(io.Discard, stream)
+			case streamTypeQPACKEncoder, streamTypeQPACKDecoder:
+				// The dynamic table is never used, as its capacity is 0.
+				io.Copy(io.Discard, stream)
+			case streamTypePush:
+				s.conn.CloseWithError(errorCodeStreamCreation, "")
+			default:
+				stream.CancelRead(errorCodeStreamCreation)
+			}
+		}()
+	}
+}
+
+// request makes the request of r to the proxy at dest, returning its Conn once it's accepted.
+func (s *clientSession) request(ctx context.Context, dest net.Destination, r *request) (*Conn, error) {
+	fields := []qpack.HeaderField{{Name: ":method", Value: "CONNECT"}}
+	if r.target.Network == net.Network_UDP {
+		select {
+		case <-s.settingsReceived:
+		case <-s.conn.Context().Done():
+			return nil, context.Cause(s.conn.Context())
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if s.settings[settingDatagram] != 1 || s.settings[settingExtendedConnect] != 1 {
+			return nil, errors.New("MASQUE server ", dest, " doesn't support CONNECT-UDP")
+		}
+		fields = append(fields,
+			qpack.HeaderField{Name: ":protocol", Value: protocolUDP},
+			qpack.HeaderField{Name: ":scheme", Value: "https"},
+			qpack.HeaderField{Name: ":authority", Value: dest.NetAddr()},
+			qpack.HeaderField{Name: ":path", Value: udpPath(r.target)},
+			qpack.HeaderField{Name: "capsule-protocol", Value: "?1"},
+		)
+	} else {
+		fields = append(fields, qpack.HeaderField{Name: ":authority", Value: r.target.NetAddr()})
+	}
+	if r.authorization != "" {
+		fields = append(fields, qpack.HeaderField{Name: "proxy-authorization", Value: r.authorization})
+	}
+
+	quicStream, err := s.conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, errors.New("failed to open stream").Base(err)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		quicStream.CancelRead(errorCodeRequestCancel)
+		quicStream.CancelWrite(errorCodeRequestCancel)
+	})
+	defer stop()
+
+	stream := newRequestStream(quicStream)
+	conn := &Conn{
+		stream:     stream,
+		target:     r.target,
+		localAddr:  s.conn.LocalAddr(),
+		remoteAddr: s.conn.RemoteAddr(),
+		onClose:    s.release,
+	}
+	if r.target.Network == net.Network_UDP {
+		// The flow takes the datagrams the server may send right after the response.
+		conn.flow = s.mux.newFlow(stream)
+	}
+	fail := func(err error) (*Conn, error) {
+		if conn.flow != nil {
+			conn.flow.close()
+		}
+		quicStream.CancelRead(errorCodeRequestCancel)
+		quicStream.CancelWrite(errorCodeRequestCancel)
+		return nil, err
+	}
+
+	if _, err := quicStream.Write(headersFrame(fields)); err != nil {
+		return fail(errors.New("failed to write request").Base(err))
+	}
+	for {
+		response, err := readHeaders(stream.reader)
+		if err != nil {
+			return fail(errors.New("failed to read response").Base(err))
+		}
+		status := ""
+		for _, field := range response {
+			if field.Name == ":status" {
+				status = field.Value
+			}
+		}
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return fail(errors.New("invalid status ", status))
+		}
+		if code >= 100 && code < 200 {
+			continue
+		}
+		if code < 200 || code >= 300 {
+			return fail(errors.New("MASQUE server ", dest, " refused the request for ", r.target, ": ", status))
+		}
+		if conn.flow != nil {
+			go conn.flow.readCapsules()
+		}
+		return conn, nil
+	}
+}
+
+func init() {
+	common.Must(internet.RegisterTransportDialer(protocolName, Dial))
+}
@@ -0,0 +1,173 @@
+package masque
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/quic-go/qpack"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/quicvarint"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// The parts of HTTP/3 (RFC 9114) a client needs for the requests of CONNECT and CONNECT-UDP, as the response of
+// CONNECT in quic-go/http3 has a body of length 0.
+const (
+	frameTypeData     = 0x00
+	frameTypeHeaders  = 0x01
+	frameTypeSettings = 0x04
+
+	streamTypeControl      = 0x00
+	streamTypePush         = 0x01
+	streamTypeQPACKEncoder = 0x02
+	streamTypeQPACKDecoder = 0x03
+
+	settingExtendedConnect = 0x08
+	settingDatagram        = 0x33
+
+	errorCodeStreamCreation  = 0x103
+	errorCodeMissingSettings = 0x10a
+
+	maxHeadersLength = 64 << 10
+)
+
+func appendFrameHeader(b []byte, frameType uint64, length int) []byte {
+	b = quicvarint.Append(b, frameType)
+	return quicvarint.Append(b, uint64(length))
+}
+
+func readFrameHeader(r quicvarint.Reader) (uint64, uint64, error) {
+	frameType, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return frameType, length, nil
+}
+
+// settingsFrame returns the SETTINGS of a client, which takes HTTP datagrams.
+func settingsFrame() []byte {
+	settings := quicvarint.Append(nil, settingDatagram)
+	settings = quicvarint.Append(settings, 1)
+	return append(appendFrameHeader(nil, frameTypeSettings, len(settings)), settings...)
+}
+
+// readSettings reads the SETTINGS frame starting a control stream.
+func readSettings(r quicvarint.Reader) (map[uint64]uint64, error) {
+	frameType, length, err := readFrameHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if frameType != frameTypeSettings {
+		return nil, errors.New("control stream starts with frame ", frameType)
+	}
+	if length > maxHeadersLength {
+		return nil, errors.New("SETTINGS of ", length, " bytes is too large")
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	settings := make(map[uint64]uint64)
+	for len(b) > 0 {
+		id, n, err := quicvarint.Parse(b)
+		if err != nil {
+			return nil, err
+		}
+		value, m, err := quicvarint.Parse(b[n:])
+		if err != nil {
+			return nil, err
+		}
+		settings[id] = value
+		b = b[n+m:]
+	}
+	return settings, nil
+}
+
+// headersFrame returns the HEADERS frame of fields, encoded by QPACK without the dynamic table.
+func headersFrame(fields []qpack.HeaderField) []byte {
+	var block bytes.Buffer
+	encoder := qpack.NewEncoder(&block)
+	for _, field := range fields {
+		encoder.WriteField(field)
+	}
+	return append(appendFrameHeader(nil, frameTypeHeaders, block.Len()), block.Bytes()...)
+}
+
+// readHeaders reads a HEADERS frame, skipping the frames of unknown types.
+func readHeaders(r quicvarint.Reader) ([]qpack.HeaderField, error) {
+	for {
+		frameType, length, err := readFrameHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		switch frameType {
+		case frameTypeHeaders:
+			if length > maxHeadersLength {
+				return nil, errors.New("HEADERS of ", length, " bytes is too large")
+			}
+			block := make([]byte, length)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return nil, err
+			}
+			return qpack.NewDecoder(nil).DecodeFull(block)
+		case frameTypeData:
+			return nil, errors.New("unexpected DATA frame")
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// requestStream is a request stream of a client after its response, whose Read and Write are of DATA frames.
+type requestStream struct {
+	quic.Stream
+	reader    quicvarint.Reader
+	remaining uint64
+}
+
+func newRequestStream(s quic.Stream) *requestStream {
+	return &requestStream{
+		Stream: s,
+		reader: quicvarint.NewReader(s),
+	}
+}
+
+func (s *requestStream) Read(b []byte) (int, error) {
+	for s.remaining == 0 {
+		frameType, length, err := readFrameHeader(s.reader)
+		if err != nil {
+			return 0, err
+		}
+		switch frameType {
+		case frameTypeData:
+			s.remaining = length
+		default:
+			// Trailers, or a frame of an unknown type.
+			if _, err := io.CopyN(io.Discard, s.reader, int64(length)); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if uint64(len(b)) > s.remaining {
+		b = b[:s.remaining]
+	}
+	n, err := s.Stream.Read(b)
+	s.remaining -= uint64(n)
+	return n, err
+}
+
+func (s *requestStream) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if _, err := s.Stream.Write(appendFrameHeader(make([]byte, 0, 16), frameTypeData, len(b))); err != nil {
+		return 0, err
+	}
+	return s.Stream.Write(b)
+}
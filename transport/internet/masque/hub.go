@@ -0,0 +1,146 @@
+package masque
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/fallback"
+	"github.com/xtls/xray-core/transport/internet/quichub"
+)
+
+// Listener is a MASQUE server, passing the accepted requests as Conn.
+type Listener struct {
+	*quichub.Hub[quic.EarlyConnection, quic.ApplicationErrorCode]
+	ctx           context.Context
+	addConn       internet.ConnHandler
+	authenticator internet.Authenticator
+	masquerade    http.Handler
+}
+
+// Listen listens UDP on address:port for MASQUE.
+func Listen(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
+	config := streamSettings.ProtocolSettings.(*Config)
+	gotlsConfig, err := quichub.ServerTLSConfig(streamSettings, "MASQUE")
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Listener{
+		ctx:           ctx,
+		addConn:       addConn,
+		authenticator: internet.AuthenticatorFromContext(ctx),
+		masquerade:    http.NotFoundHandler(),
+	}
+	if config.Masquerade != nil {
+		if l.masquerade, err = fallback.NewHandler(config.Masquerade); err != nil {
+			return nil, errors.New("failed to set up masquerade for MASQUE").Base(err)
+		}
+	}
+
+	packetConn, err := internet.ListenSystemPacket(ctx, &net.UDPAddr{
+		IP:   address.IP(),
+		Port: int(port),
+	}, streamSettings.SocketSettings)
+	if err != nil {
+		return nil, errors.New("failed to listen UDP for MASQUE on ", address, ":", port).Base(err)
+	}
+	l.Hub, err = quichub.Listen[quic.EarlyConnection](ctx, "MASQUE", address, port, packetConn, func(packetConn net.PacketConn) (io.Closer, quichub.Listener[quic.EarlyConnection], error) {
+		transport := &quic.Transport{Conn: packetConn}
+		listener, err := transport.ListenEarly(gotlsConfig, &quic.Config{
+			InitialStreamReceiveWindow:     streamReceiveWindow,
+			MaxStreamReceiveWindow:         streamReceiveWindow,
+			InitialConnectionReceiveWindow: connReceiveWindow,
+			MaxConnectionReceiveWindow:     connReceiveWindow,
+			MaxIncomingStreams:             maxIncomingStreams,
+			MaxIdleTimeout:                 config.idleTimeout(),
+			EnableDatagrams:                true,
+		})
+		return transport, listener, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l.Serve(func(conn quic.EarlyConnection) {
+		go l.serve(conn)
+	})
+	return l, nil
+}
+
+// serverConn is a connection for http3.Server, which doesn't receive its datagrams, as they are passed by a
+// datagramMux, so that those of an unknown request are dropped instead of stopping the others.
+type serverConn struct {
+	quic.EarlyConnection
+}
+
+func (c *serverConn) ReceiveDatagram(context.Context) ([]byte, error) {
+	<-c.Context().Done()
+	return nil, context.Cause(c.Context())
+}
+
+// serve answers the HTTP/3 requests of conn, passing those of CONNECT and CONNECT-UDP as Conn.
+func (l *Listener) serve(conn quic.EarlyConnection) {
+	mux := newDatagramMux(conn)
+	server := &http3.Server{
+		EnableDatagrams: true,
+		Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			target, err := requestTarget(request)
+			if err != nil {
+				l.masquerade.ServeHTTP(writer, request)
+				return
+			}
+			credential := request.Header.Get(headerAuthorization)
+			if l.authenticator != nil && !l.authenticator.Authenticate(credential) {
+				writer.Header().Set(headerAuthenticate, "Basic realm=\"proxy\"")
+				writer.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+			if target.Network == net.Network_UDP {
+				writer.Header().Set(headerCapsuleProtocol, "?1")
+			}
+			writer.WriteHeader(http.StatusOK)
+			stream := writer.(http3.HTTPStreamer).HTTPStream()
+			c := &Conn{
+				stream:     stream,
+				target:     target,
+				credential: credential,
+				localAddr:  conn.LocalAddr(),
+				remoteAddr: conn.RemoteAddr(),
+			}
+			if target.Network == net.Network_UDP {
+				c.flow = mux.newFlow(stream)
+				go c.flow.readCapsules()
+			}
+			l.addConn(c)
+		}),
+	}
+	if err := server.ServeQUICConn(&serverConn{conn}); err != nil {
+		errors.LogDebugInner(l.ctx, err, "MASQUE connection from ", conn.RemoteAddr(), " ends")
+	}
+}
+
+// requestTarget returns the target of a request of CONNECT or CONNECT-UDP.
+func requestTarget(request *http.Request) (net.Destination, error) {
+	if request.Method != http.MethodConnect {
+		return net.Destination{}, errors.New("not CONNECT")
+	}
+	switch request.Proto {
+	case protocolUDP:
+		return parseUDPPath(request.URL.EscapedPath())
+	case "HTTP/3.0":
+		return net.ParseDestination("tcp:" + request.Host)
+	default:
+		return net.Destination{}, errors.New("unknown protocol ", request.Proto)
+	}
+}
+
+func init() {
+	common.Must(internet.RegisterTransportListener(protocolName, Listen))
+}
@@ -0,0 +1,347 @@
+// Package masque is the transport of MASQUE, requests over HTTP/3 that are the TCP streams of CONNECT, or the UDP
+// packets of CONNECT-UDP (RFC 9298) carried by HTTP datagrams (RFC 9297). A connection of a client is shared by its
+// requests, and the other requests to a server get the responses of a web server, so that it looks like an HTTP/3 site.
+package masque
+
+import (
+	"context"
+	goerrors "errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/quicvarint"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+const protocolName = "masque"
+
+const (
+	// udpPathPrefix starts the path of the default URI template of CONNECT-UDP,
+	// /.well-known/masque/udp/{target_host}/{target_port}/.
+	udpPathPrefix = "/.well-known/masque/udp/"
+	protocolUDP   = "connect-udp"
+
+	headerAuthorization    = "Proxy-Authorization"
+	headerAuthenticate     = "Proxy-Authenticate"
+	headerCapsuleProtocol  = "Capsule-Protocol"
+	capsuleTypeDatagram    = 0x00
+	contextIDUDP           = 0
+	maxUDPPayloadSize      = 65527
+	streamReceiveWindow    = 8 << 20
+	connReceiveWindow      = 20 << 20
+	maxIncomingStreams     = 1024
+	keepAlivePeriod        = 10 * time.Second
+	flowPacketQueueSize    = 256
+	errorCodeNoError       = 0x100
+	errorCodeRequestCancel = 0x10c
+)
+
+type requestKey struct{}
+
+type request struct {
+	target        net.Destination
+	authorization string
+}
+
+// ContextWithRequest returns a context for Dial, so that the connection is a request for target, of CONNECT for TCP
+// or CONNECT-UDP for UDP, with the Proxy-Authorization authorization if not empty.
+func ContextWithRequest(ctx context.Context, target net.Destination, authorization string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{
+		target:        target,
+		authorization: authorization,
+	})
+}
+
+func requestFromContext(ctx context.Context) *request {
+	r, _ := ctx.Value(requestKey{}).(*request)
+	return r
+}
+
+// udpPath returns the path of CONNECT-UDP to target.
+func udpPath(target net.Destination) string {
+	var host string
+	if target.Address.Family().IsDomain() {
+		host = url.PathEscape(target.Address.Domain())
+	} else {
+		host = strings.ReplaceAll(target.Address.IP().String(), ":", "%3A")
+	}
+	return udpPathPrefix + host + "/" + target.Port.String() + "/"
+}
+
+// parseUDPPath returns the target of the (escaped) path of CONNECT-UDP.
+func parseUDPPath(path string) (net.Destination, error) {
+	rest, ok := strings.CutPrefix(path, udpPathPrefix)
+	if !ok {
+		return net.Destination{}, errors.New("unknown path ", path)
+	}
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	if len(parts) != 2 {
+		return net.Destination{}, errors.New("invalid path ", path)
+	}
+	host, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return net.Destination{}, errors.New("invalid host ", parts[0]).Base(err)
+	}
+	port, err := net.PortFromString(parts[1])
+	if err != nil {
+		return net.Destination{}, errors.New("invalid port ", parts[1]).Base(err)
+	}
+	if host == "" || port == 0 {
+		return net.Destination{}, errors.New("invalid target ", host, ":", port)
+	}
+	return net.UDPDestination(net.ParseAddress(host), port), nil
+}
+
+// stream is a request stream whose Read and Write are of the data after the headers.
+type stream interface {
+	io.ReadWriteCloser
+	StreamID() quic.StreamID
+	CancelRead(quic.StreamErrorCode)
+	SetDeadline(time.Time) error
+	SetReadDeadline(time.Time) error
+	SetWriteDeadline(time.Time) error
+}
+
+// datagramMux passes the HTTP datagrams of a connection to the flows of their requests.
+type datagramMux struct {
+	conn   quic.Connection
+	access sync.Mutex
+	flows  map[uint64]*flow
+}
+
+func newDatagramMux(conn quic.Connection) *datagramMux {
+	m := &datagramMux{
+		conn:  conn,
+		flows: make(map[uint64]*flow),
+	}
+	go m.run()
+	return m
+}
+
+func (m *datagramMux) run() {
+	for {
+		datagram, err := m.conn.ReceiveDatagram(context.Background())
+		if err != nil {
+			return
+		}
+		quarterStreamID, n, err := quicvarint.Parse(datagram)
+		if err != nil {
+			continue
+		}
+		m.access.Lock()
+		f := m.flows[quarterStreamID]
+		m.access.Unlock()
+		if f != nil {
+			f.receive(datagram[n:])
+		}
+	}
+}
+
+// newFlow returns the flow of the UDP packets of s, which is removed when closed. Its capsules are read by
+// readCapsules once the headers of s are.
+func (m *datagramMux) newFlow(s stream) *flow {
+	f := &flow{
+		mux:     m,
+		stream:  s,
+		id:      uint64(s.StreamID()) / 4,
+		packets: make(chan []byte, flowPacketQueueSize),
+		done:    make(chan struct{}),
+	}
+	m.access.Lock()
+	m.flows[f.id] = f
+	m.access.Unlock()
+	return f
+}
+
+// flow is the UDP packets of CONNECT-UDP, in the HTTP datagrams of its request, or in the DATAGRAM capsules of its
+// stream if they don't fit.
+type flow struct {
+	mux     *datagramMux
+	stream  stream
+	id      uint64
+	packets chan []byte
+	done    chan struct{}
+	once    sync.Once
+
+	writeAccess sync.Mutex
+}
+
+// receive queues the UDP packet in the payload of an HTTP datagram.
+func (f *flow) receive(payload []byte) {
+	contextID, n, err := quicvarint.Parse(payload)
+	if err != nil || contextID != contextIDUDP {
+		return
+	}
+	select {
+	case f.packets <- payload[n:]:
+	case <-f.done:
+	default: // dropped like a UDP packet
+	}
+}
+
+// readCapsules receives the DATAGRAM capsules of the stream, until it ends, which ends f.
+func (f *flow) readCapsules() {
+	defer f.close()
+	r := quicvarint.NewReader(f.stream)
+	for {
+		capsuleType, length, err := readCapsuleHeader(r)
+		if err != nil {
+			return
+		}
+		if capsuleType != capsuleTypeDatagram || length > maxUDPPayloadSize+8 {
+			if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+				return
+			}
+			continue
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+		f.receive(payload)
+	}
+}
+
+func readCapsuleHeader(r quicvarint.Reader) (uint64, uint64, error) {
+	capsuleType, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return capsuleType, length, nil
+}
+
+// ReadPacket returns the next UDP packet.
+func (f *flow) ReadPacket() ([]byte, error) {
+	select {
+	case packet := <-f.packets:
+		return packet, nil
+	case <-f.done:
+		return nil, io.EOF
+	}
+}
+
+// WritePacket sends a UDP packet, in a DATAGRAM capsule if it doesn't fit in an HTTP datagram.
+func (f *flow) WritePacket(packet []byte) error {
+	datagram := quicvarint.Append(make([]byte, 0, 9+len(packet)), f.id)
+	datagram = quicvarint.Append(datagram, contextIDUDP)
+	datagram = append(datagram, packet...)
+	err := f.mux.conn.SendDatagram(datagram)
+	var tooLarge *quic.DatagramTooLargeError
+	if !goerrors.As(err, &tooLarge) {
+		return err
+	}
+	payload := datagram[quicvarint.Len(f.id):]
+	capsule := quicvarint.Append(make([]byte, 0, 16+len(payload)), capsuleTypeDatagram)
+	capsule = quicvarint.Append(capsule, uint64(len(payload)))
+	capsule = append(capsule, payload...)
+	f.writeAccess.Lock()
+	defer f.writeAccess.Unlock()
+	_, err = f.stream.Write(capsule)
+	return err
+}
+
+func (f *flow) close() {
+	f.once.Do(func() {
+		f.mux.access.Lock()
+		delete(f.mux.flows, f.id)
+		f.mux.access.Unlock()
+		close(f.done)
+	})
+}
+
+// Conn is a request of CONNECT, whose Read and Write are of the TCP stream, or of CONNECT-UDP, whose Read and Write
+// are of a UDP packet each.
+type Conn struct {
+	stream     stream
+	flow       *flow
+	target     net.Destination
+	credential string
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	onClose    func()
+	once       sync.Once
+}
+
+// Target returns the destination of the request.
+func (c *Conn) Target() net.Destination {
+	return c.target
+}
+
+// Credential returns the Proxy-Authorization of the request.
+func (c *Conn) Credential() string {
+	return c.credential
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	if c.flow == nil {
+		return c.stream.Read(b)
+	}
+	packet, err := c.flow.ReadPacket()
+	if err != nil {
+		return 0, err
+	}
+	// Truncated like a UDP socket if b is too small.
+	return copy(b, packet), nil
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	if c.flow == nil {
+		return c.stream.Write(b)
+	}
+	if err := c.flow.WritePacket(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *Conn) Close() error {
+	c.once.Do(func() {
+		if c.flow != nil {
+			c.flow.close()
+		}
+		c.stream.CancelRead(errorCodeRequestCancel)
+		c.stream.Close()
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return nil
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	if c.flow != nil {
+		return nil
+	}
+	return c.stream.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	if c.flow != nil {
+		return nil
+	}
+	return c.stream.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	if c.flow != nil {
+		return nil
+	}
+	return c.stream.SetWriteDeadline(t)
+}
@@ -0,0 +1,44 @@
+package masque
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestUDPPath(t *testing.T) {
+	cases := []struct {
+		target net.Destination
+		path   string
+	}{
+		{net.UDPDestination(net.DomainAddress("example.com"), 53), "/.well-known/masque/udp/example.com/53/"},
+		{net.UDPDestination(net.ParseAddress("192.0.2.6"), 443), "/.well-known/masque/udp/192.0.2.6/443/"},
+		{net.UDPDestination(net.ParseAddress("2001:db8::42"), 443), "/.well-known/masque/udp/2001%3Adb8%3A%3A42/443/"},
+	}
+	for _, c := range cases {
+		if path := udpPath(c.target); path != c.path {
+			t.Errorf("udpPath(%v) = %s, want %s", c.target, path, c.path)
+		}
+		target, err := parseUDPPath(c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != c.target {
+			t.Errorf("parseUDPPath(%s) = %v, want %v", c.path, target, c.target)
+		}
+	}
+}
+
+func TestParseInvalidUDPPath(t *testing.T) {
+	for _, path := range []string{
+		"/",
+		"/.well-known/masque/udp/example.com/",
+		"/.well-known/masque/udp/example.com/0/",
+		"/.well-known/masque/udp//53/",
+		"/.well-known/masque/udp/example.com/53/x/",
+	} {
+		if _, err := parseUDPPath(path); err == nil {
+			t.Errorf("parseUDPPath(%s) succeeded", path)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Package quichub is the listening side shared by the transports over QUIC, which are built on quic-go or on its fork
+// of Hysteria, whose types differ but have the same methods.
+package quichub
+
+import (
+	"context"
+	gotls "crypto/tls"
+	"io"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+// Conn is a QUIC connection, E being the type of its application error codes.
+type Conn[E ~uint64] interface {
+	RemoteAddr() net.Addr
+	CloseWithError(E, string) error
+}
+
+// Listener is a QUIC listener accepting connections of type C.
+type Listener[C any] interface {
+	Accept(context.Context) (C, error)
+	Addr() net.Addr
+	Close() error
+}
+
+// ServerTLSConfig returns the TLS config of a QUIC server of the transport name, which requires TLS. The ALPN is h3
+// if none is configured.
+func ServerTLSConfig(streamSettings *internet.MemoryStreamConfig, name string) (*gotls.Config, error) {
+	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
+	if tlsConfig == nil {
+		return nil, errors.New(name, " requires TLS")
+	}
+	gotlsConfig := tlsConfig.GetTLSConfig()
+	if len(tlsConfig.NextProtocol) == 0 {
+		gotlsConfig.NextProtos = []string{"h3"}
+	}
+	// quic-go sends a session ticket after every handshake, and crypto/tls doesn't tell it when tickets are disabled.
+	gotlsConfig.SessionTicketsDisabled = false
+	return gotlsConfig, nil
+}
+
+// Hub accepts the QUIC connections of a listener on a transport of its own, so that closing the listener stops
+// accepting only. It implements internet.DrainableListener.
+type Hub[C Conn[E], E ~uint64] struct {
+	ctx        context.Context
+	name       string
+	packetConn net.PacketConn
+	transport  io.Closer
+	listener   Listener[C]
+	filter     *internet.ConnectionFilter
+}
+
+// Listen makes the QUIC listener of the transport name on packetConn with listen, which returns the transport it
+// creates on packetConn and the listener. packetConn is closed if it fails.
+func Listen[C Conn[E], E ~uint64](ctx context.Context, name string, address net.Address, port net.Port, packetConn net.PacketConn, listen func(net.PacketConn) (io.Closer, Listener[C], error)) (*Hub[C, E], error) {
+	transport, listener, err := listen(packetConn)
+	if err != nil {
+		packetConn.Close()
+		return nil, errors.New("failed to listen QUIC for ", name, " on ", address, ":", port).Base(err)
+	}
+	errors.LogInfo(ctx, "listening QUIC for ", name, " on ", address, ":", port)
+	return &Hub[C, E]{
+		ctx:        ctx,
+		name:       name,
+		packetConn: packetConn,
+		transport:  transport,
+		listener:   listener,
+		filter:     internet.ConnectionFilterFromContext(ctx),
+	}, nil
+}
+
+// Serve passes the connections accepted from the addresses allowed by the connection filter to handle, until h is
+// drained or closed. handle is called in the goroutine accepting, so it must not block.
+func (h *Hub[C, E]) Serve(handle func(C)) {
+	go func() {
+		for {
+			conn, err := h.listener.Accept(context.Background())
+			if err != nil {
+				errors.LogInfoInner(h.ctx, err, "stop accepting ", h.name, " connections")
+				return
+			}
+			if h.filter != nil && !h.filter.Allow(conn.RemoteAddr()) {
+				conn.CloseWithError(0, "")
+				continue
+			}
+			handle(conn)
+		}
+	}()
+}
+
+// Addr implements net.Listener.Addr().
+func (h *Hub[C, E]) Addr() net.Addr {
+	return h.listener.Addr()
+}
+
+// Drain implements internet.DrainableListener. The transport goes on serving the connections accepted until Close.
+func (h *Hub[C, E]) Drain() error {
+	return h.listener.Close()
+}
+
+// Close implements net.Listener.Close().
+func (h *Hub[C, E]) Close() error {
+	err := h.listener.Close()
+	h.transport.Close()
+	h.packetConn.Close()
+	return err
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/xtls/reality"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/transport/internet/tls"
 	"golang.org/x/crypto/chacha20poly1305"
@@ -101,7 +102,11 @@ func (c *UConn) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x50
 	return nil
 }
 
-func UClient(c net.Conn, config *Config, ctx context.Context, dest net.Destination) (net.Conn, error) {
+func UClient(c net.Conn, config *Config, ctx context.Context, dest net.Destination) (_ net.Conn, err error) {
+	_, span := trace.Start(ctx, "reality.handshake")
+	defer func() {
+		span.End(err)
+	}()
 	localAddr := c.LocalAddr().String()
 	uConn := &UConn{}
 	utlsConfig := &utls.Config{
@@ -14,6 +14,7 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/signal/done"
+	"github.com/xtls/xray-core/common/trace"
 )
 
 // interface to abstract between use of browser dialer, vs net/http
@@ -47,18 +48,22 @@ func (c *DefaultDialerClient) OpenStream(ctx context.Context, url string, body i
 	// and we can unblock the Dial function and print correct net addresses in
 	// logs
 	gotConn := done.New()
+	method := "GET" // stream-down
+	if body != nil {
+		method = "POST" // stream-up/one
+	}
+	// The span covers getting a connection, which is dialed and handshaked unless a shared one is reused.
+	_, span := trace.Start(ctx, "splithttp.handshake")
+	span.SetAttribute("http.method", method)
 	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
 		GotConn: func(connInfo httptrace.GotConnInfo) {
 			remoteAddr = connInfo.Conn.RemoteAddr()
 			localAddr = connInfo.Conn.LocalAddr()
+			span.SetAttribute("http.reused", connInfo.Reused)
+			span.End(nil)
 			gotConn.Close()
 		},
 	})
-
-	method := "GET" // stream-down
-	if body != nil {
-		method = "POST" // stream-up/one
-	}
 	req, _ := http.NewRequestWithContext(context.WithoutCancel(ctx), method, url, body)
 	req.Header = c.transportConfig.GetRequestHeader(url)
 	if method == "POST" && !c.transportConfig.NoGRPCHeader {
@@ -73,6 +78,7 @@ func (c *DefaultDialerClient) OpenStream(ctx context.Context, url string, body i
 				c.closed = true
 				errors.LogInfoInner(ctx, err, "failed to "+method+" "+url)
 			}
+			span.End(err)
 			gotConn.Close()
 			wrc.Close()
 			return
@@ -26,6 +26,7 @@ import (
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/udp"
 	"github.com/xtls/xray-core/transport/pipe"
 	"golang.org/x/net/http2"
 )
@@ -122,6 +123,14 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 	transportConfig := streamSettings.ProtocolSettings.(*Config)
 
 	dialContext := func(ctxInner context.Context) (net.Conn, error) {
+		// The client lives for many dials, so ECH is set up for each of them, following the ECH configs as they change.
+		var config *gotls.Config
+		if gotlsConfig != nil {
+			config = gotlsConfig.Clone()
+			if err := tlsConfig.ApplyECH(ctxInner, config); err != nil {
+				return nil, err
+			}
+		}
 		conn, err := internet.DialSystem(ctxInner, dest, streamSettings.SocketSettings)
 		if err != nil {
 			return nil, err
@@ -131,14 +140,14 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 			return reality.UClient(conn, realityConfig, ctxInner, dest)
 		}
 
-		if gotlsConfig != nil {
-			if fingerprint := tls.GetFingerprint(tlsConfig.Fingerprint); fingerprint != nil {
-				conn = tls.UClient(conn, gotlsConfig, fingerprint)
+		if config != nil {
+			if fingerprint := tlsConfig.ClientFingerprint(config); fingerprint != nil {
+				conn = tls.UClient(conn, config, fingerprint)
 				if err := conn.(*tls.UConn).HandshakeContext(ctxInner); err != nil {
 					return nil, err
 				}
 			} else {
-				conn = tls.Client(conn, gotlsConfig)
+				conn = tls.Client(conn, config)
 			}
 		}
 
@@ -172,6 +181,26 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 			QUICConfig:      quicConfig,
 			TLSClientConfig: gotlsConfig,
 			Dial: func(ctx context.Context, addr string, tlsCfg *gotls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+				tlsCfg = tlsCfg.Clone()
+				if err := tlsConfig.ApplyECH(ctx, tlsCfg); err != nil {
+					return nil, err
+				}
+				if transportConfig.Hop != nil {
+					conn, err := udp.DialHop(ctx, dest, transportConfig.Hop, streamSettings.SocketSettings)
+					if err != nil {
+						return nil, err
+					}
+					quicConn, err := quic.DialEarly(ctx, conn, conn.RemoteAddr(), tlsCfg, cfg)
+					if err != nil {
+						conn.Close()
+						return nil, err
+					}
+					go func() {
+						<-quicConn.Context().Done()
+						conn.Close()
+					}()
+					return quicConn, nil
+				}
 				conn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
 				if err != nil {
 					return nil, err
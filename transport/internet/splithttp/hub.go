@@ -21,9 +21,11 @@ import (
 	http_proto "github.com/xtls/xray-core/common/protocol/http"
 	"github.com/xtls/xray-core/common/signal/done"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/fallback"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/udp"
 )
 
 type requestHandler struct {
@@ -34,8 +36,13 @@ type requestHandler struct {
 	sessionMu *sync.Mutex
 	sessions  sync.Map
 	localAddr net.Addr
+	fallback  http.Handler
 }
 
+// uploadWaitTimeout is how long a GET of packet-up waits for the first upload of its session, before it is taken for a
+// probe with a made up session ID.
+const uploadWaitTimeout = 5 * time.Second
+
 type httpSession struct {
 	uploadQueue *uploadQueue
 	// for as long as the GET request is not opened by the client, this will be
@@ -43,6 +50,22 @@ type httpSession struct {
 	// after the client connects, this becomes "done" and the session lives as
 	// long as the GET request.
 	isFullyConnected *done.Instance
+	// uploaded is done once the client uploads to the session.
+	uploaded *done.Instance
+}
+
+// waitUpload waits a while for the first upload of the session, and returns whether it arrived.
+func (s *httpSession) waitUpload(ctx context.Context) bool {
+	timer := time.NewTimer(uploadWaitTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.uploaded.Wait():
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return false
 }
 
 func (h *requestHandler) upsertSession(sessionId string) *httpSession {
@@ -64,6 +87,7 @@ func (h *requestHandler) upsertSession(sessionId string) *httpSession {
 	s := &httpSession{
 		uploadQueue:      NewUploadQueue(h.ln.config.GetNormalizedScMaxBufferedPosts()),
 		isFullyConnected: done.New(),
+		uploaded:         done.New(),
 	}
 
 	h.sessions.Store(sessionId, s)
@@ -85,16 +109,26 @@ func (h *requestHandler) upsertSession(sessionId string) *httpSession {
 	return s
 }
 
+// reject passes request to the fallback if there is one, or answers it with status.
+func (h *requestHandler) reject(writer http.ResponseWriter, request *http.Request, status int) {
+	if h.fallback != nil {
+		clear(writer.Header())
+		h.fallback.ServeHTTP(writer, request)
+		return
+	}
+	writer.WriteHeader(status)
+}
+
 func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	if len(h.host) > 0 && !internet.IsValidHTTPHost(request.Host, h.host) {
 		errors.LogInfo(context.Background(), "failed to validate host, request:", request.Host, ", config:", h.host)
-		writer.WriteHeader(http.StatusNotFound)
+		h.reject(writer, request, http.StatusNotFound)
 		return
 	}
 
 	if !strings.HasPrefix(request.URL.Path, h.path) {
 		errors.LogInfo(context.Background(), "failed to validate path, request:", request.URL.Path, ", config:", h.path)
-		writer.WriteHeader(http.StatusNotFound)
+		h.reject(writer, request, http.StatusNotFound)
 		return
 	}
 
@@ -123,7 +157,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 
 	if int32(paddingLength) < validRange.From || int32(paddingLength) > validRange.To {
 		errors.LogInfo(context.Background(), "invalid x_padding length:", int32(paddingLength))
-		writer.WriteHeader(http.StatusBadRequest)
+		h.reject(writer, request, http.StatusBadRequest)
 		return
 	}
 
@@ -135,7 +169,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 
 	if sessionId == "" && h.config.Mode != "" && h.config.Mode != "auto" && h.config.Mode != "stream-one" && h.config.Mode != "stream-up" {
 		errors.LogInfo(context.Background(), "stream-one mode is not allowed")
-		writer.WriteHeader(http.StatusBadRequest)
+		h.reject(writer, request, http.StatusBadRequest)
 		return
 	}
 
@@ -177,7 +211,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		if seq == "" {
 			if h.config.Mode != "" && h.config.Mode != "auto" && h.config.Mode != "stream-up" {
 				errors.LogInfo(context.Background(), "stream-up mode is not allowed")
-				writer.WriteHeader(http.StatusBadRequest)
+				h.reject(writer, request, http.StatusBadRequest)
 				return
 			}
 			httpSC := &httpServerConn{
@@ -192,6 +226,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 				errors.LogInfoInner(context.Background(), err, "failed to upload (PushReader)")
 				writer.WriteHeader(http.StatusConflict)
 			} else {
+				currentSession.uploaded.Close()
 				writer.Header().Set("X-Accel-Buffering", "no")
 				writer.Header().Set("Cache-Control", "no-store")
 				writer.WriteHeader(http.StatusOK)
@@ -218,7 +253,14 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 
 		if h.config.Mode != "" && h.config.Mode != "auto" && h.config.Mode != "packet-up" {
 			errors.LogInfo(context.Background(), "packet-up mode is not allowed")
-			writer.WriteHeader(http.StatusBadRequest)
+			h.reject(writer, request, http.StatusBadRequest)
+			return
+		}
+
+		seqInt, err := strconv.ParseUint(seq, 10, 64)
+		if err != nil {
+			errors.LogInfoInner(context.Background(), err, "failed to upload (ParseUint)")
+			h.reject(writer, request, http.StatusInternalServerError)
 			return
 		}
 
@@ -226,20 +268,14 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 
 		if len(payload) > scMaxEachPostBytes {
 			errors.LogInfo(context.Background(), "Too large upload. scMaxEachPostBytes is set to ", scMaxEachPostBytes, "but request size exceed it. Adjust scMaxEachPostBytes on the server to be at least as large as client.")
-			writer.WriteHeader(http.StatusRequestEntityTooLarge)
+			request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(payload), request.Body))
+			h.reject(writer, request, http.StatusRequestEntityTooLarge)
 			return
 		}
 
 		if err != nil {
 			errors.LogInfoInner(context.Background(), err, "failed to upload (ReadAll)")
-			writer.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		seqInt, err := strconv.ParseUint(seq, 10, 64)
-		if err != nil {
-			errors.LogInfoInner(context.Background(), err, "failed to upload (ParseUint)")
-			writer.WriteHeader(http.StatusInternalServerError)
+			h.reject(writer, request, http.StatusInternalServerError)
 			return
 		}
 
@@ -250,12 +286,23 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 
 		if err != nil {
 			errors.LogInfoInner(context.Background(), err, "failed to upload (PushPayload)")
-			writer.WriteHeader(http.StatusInternalServerError)
+			h.reject(writer, request, http.StatusInternalServerError)
 			return
 		}
+		currentSession.uploaded.Close()
 
 		writer.WriteHeader(http.StatusOK)
 	} else if request.Method == "GET" || sessionId == "" { // stream-down, stream-one
+		if sessionId != "" && (h.config.Mode == "" || h.config.Mode == "auto" || h.config.Mode == "packet-up") &&
+			!currentSession.waitUpload(request.Context()) {
+			// Clients of packet-up upload soon after the GET, and the session ID of a probe is unknown.
+			errors.LogInfo(context.Background(), "no upload to session ", sessionId)
+			if h.sessions.CompareAndDelete(sessionId, currentSession) {
+				currentSession.uploadQueue.Close()
+			}
+			h.reject(writer, request, http.StatusNotFound)
+			return
+		}
 		if sessionId != "" {
 			// after GET is done, the connection is finished. disable automatic
 			// session reaping, and handle it in defer
@@ -304,7 +351,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		conn.Close()
 	} else {
 		errors.LogInfo(context.Background(), "unsupported method: ", request.Method)
-		writer.WriteHeader(http.StatusMethodNotAllowed)
+		h.reject(writer, request, http.StatusMethodNotAllowed)
 	}
 }
 
@@ -336,13 +383,14 @@ func (c *httpServerConn) Close() error {
 
 type Listener struct {
 	sync.Mutex
-	server     http.Server
-	h3server   *http3.Server
-	listener   net.Listener
-	h3listener *quic.EarlyListener
-	config     *Config
-	addConn    internet.ConnHandler
-	isH3       bool
+	server      http.Server
+	h3server    *http3.Server
+	listener    net.Listener
+	h3transport *quic.Transport
+	h3listener  *quic.EarlyListener
+	config      *Config
+	addConn     internet.ConnHandler
+	isH3        bool
 }
 
 func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
@@ -363,6 +411,12 @@ func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSet
 		sessionMu: &sync.Mutex{},
 		sessions:  sync.Map{},
 	}
+	if l.config.Fallback != nil {
+		var err error
+		if handler.fallback, err = fallback.NewHandler(l.config.Fallback); err != nil {
+			return nil, errors.New("failed to set up fallback for XHTTP").Base(err)
+		}
+	}
 	tlsConfig := getTLSConfig(streamSettings)
 	l.isH3 = len(tlsConfig.NextProtos) == 1 && tlsConfig.NextProtos[0] == "h3"
 
@@ -377,15 +431,23 @@ func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSet
 		}
 		errors.LogInfo(ctx, "listening UNIX domain socket for XHTTP on ", address)
 	} else if l.isH3 { // quic
-		Conn, err := internet.ListenSystemPacket(context.Background(), &net.UDPAddr{
-			IP:   address.IP(),
-			Port: int(port),
-		}, streamSettings.SocketSettings)
+		var Conn net.PacketConn
+		if l.config.Hop != nil {
+			Conn, err = udp.ListenHop(ctx, address, port, l.config.Hop, streamSettings.SocketSettings)
+		} else {
+			Conn, err = internet.ListenSystemPacket(context.Background(), &net.UDPAddr{
+				IP:   address.IP(),
+				Port: int(port),
+			}, streamSettings.SocketSettings)
+		}
 		if err != nil {
 			return nil, errors.New("failed to listen UDP for XHTTP/3 on ", address, ":", port).Base(err)
 		}
-		l.h3listener, err = quic.ListenEarly(Conn, tlsConfig, nil)
+		// The listener is on a transport of its own, so that closing it stops accepting only.
+		l.h3transport = &quic.Transport{Conn: Conn}
+		l.h3listener, err = l.h3transport.ListenEarly(tlsConfig, nil)
 		if err != nil {
+			Conn.Close()
 			return nil, errors.New("failed to listen QUIC for XHTTP/3 on ", address, ":", port).Base(err)
 		}
 		errors.LogInfo(ctx, "listening QUIC for XHTTP/3 on ", address, ":", port)
@@ -435,7 +497,7 @@ func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSet
 			Protocols:         protocols,
 		}
 		go func() {
-			if err := l.server.Serve(l.listener); err != nil {
+			if err := l.server.Serve(l.listener); err != nil && err != http.ErrServerClosed {
 				errors.LogErrorInner(ctx, err, "failed to serve HTTP for XHTTP")
 			}
 		}()
@@ -455,14 +517,28 @@ func (ln *Listener) Addr() net.Addr {
 	return nil
 }
 
+// Drain implements internet.DrainableListener. The sessions in progress go on until Close.
+func (ln *Listener) Drain() error {
+	if ln.h3listener != nil {
+		return ln.h3listener.Close()
+	}
+	if ln.listener != nil {
+		// Shutdown closes the listener at once, and then waits for the connections, until Close.
+		go ln.server.Shutdown(context.Background())
+		return nil
+	}
+	return errors.New("listener does not have an HTTP/3 server or a net.listener")
+}
+
 // Close implements net.Listener.Close().
 func (ln *Listener) Close() error {
 	if ln.h3server != nil {
-		if err := ln.h3server.Close(); err != nil {
-			return err
-		}
+		err := ln.h3server.Close()
+		ln.h3transport.Close()
+		ln.h3transport.Conn.Close()
+		return err
 	} else if ln.listener != nil {
-		return ln.listener.Close()
+		return ln.server.Close()
 	}
 	return errors.New("listener does not have an HTTP/3 server or a net.listener")
 }
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,9 +21,11 @@ import (
 	"github.com/xtls/xray-core/testing/servers/tcp"
 	"github.com/xtls/xray-core/testing/servers/udp"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/fallback"
 	. "github.com/xtls/xray-core/transport/internet/splithttp"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
+	udptransport "github.com/xtls/xray-core/transport/internet/udp"
 )
 
 func Test_ListenXHAndDial(t *testing.T) {
@@ -300,6 +305,85 @@ func Test_ListenXHAndDial_QUIC(t *testing.T) {
 	}
 }
 
+func Test_ListenXHAndDial_QUIC_Hop(t *testing.T) {
+	if runtime.GOARCH == "arm64" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var serverPorts []net.Port
+	hopPorts := &net.PortList{}
+	for i := 0; i < 3; i++ {
+		p := udp.PickPort()
+		serverPorts = append(serverPorts, p)
+		hopPorts.Range = append(hopPorts.Range, &net.PortRange{From: uint32(p), To: uint32(p)})
+	}
+	// The client hops through a relay, so the server sees every hop from another address, as behind a NAT.
+	relay, err := udp.NewRelay(serverPorts)
+	common.Must(err)
+	defer relay.Close()
+
+	tlsSettings := &tls.Config{
+		AllowInsecure: true,
+		Certificate:   []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil, cert.CommonName("localhost")))},
+		NextProtocol:  []string{"h3"},
+	}
+	listenPort := udp.PickPort()
+	listen, err := ListenXH(ctx, net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path: "shs",
+			Hop:  &udptransport.Hop{Ports: hopPorts},
+		},
+		SecurityType:     "tls",
+		SecuritySettings: tlsSettings,
+	}, func(conn stat.Connection) {
+		go func() {
+			defer conn.Close()
+			io.Copy(conn, conn)
+		}()
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	conn, err := Dial(ctx, net.UDPDestination(net.DomainAddress("localhost"), listenPort), &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path: "shs",
+			Hop: &udptransport.Hop{
+				Ports:    relay.PortList(),
+				Interval: 1,
+			},
+		},
+		SecurityType:     "tls",
+		SecuritySettings: tlsSettings,
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	// The conversation lasts for some hops, and past the grace period of the first ports.
+	const N = 1024
+	b1 := make([]byte, N)
+	b2 := buf.New()
+	defer b2.Release()
+	for i := 0; i < 14; i++ {
+		common.Must2(rand.Read(b1))
+		common.Must2(conn.Write(b1))
+
+		b2.Clear()
+		common.Must2(b2.ReadFullFrom(conn, N))
+		if r := cmp.Diff(b2.Bytes(), b1); r != "" {
+			t.Fatal(r)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if v := relay.Clients(); v < 4 {
+		t.Error("client addresses: ", v)
+	}
+}
+
 func Test_ListenXHAndDial_Unix(t *testing.T) {
 	tempDir := t.TempDir()
 	tempSocket := tempDir + "/server.sock"
@@ -461,3 +545,36 @@ func Test_maxUpload(t *testing.T) {
 
 	common.Must(listen.Close())
 }
+
+func Test_listenXHFallbackUnknownSession(t *testing.T) {
+	dir := t.TempDir()
+	common.Must(os.MkdirAll(filepath.Join(dir, "sh"), 0o755))
+	common.Must(os.WriteFile(filepath.Join(dir, "sh", "probe"), []byte("hello"), 0o644))
+
+	listenPort := tcp.PickPort()
+	listen, err := ListenXH(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path:     "/sh",
+			Fallback: &fallback.Config{Dir: dir},
+		},
+	}, func(conn stat.Connection) {
+		conn.Close()
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	// A GET with a made up session ID, which no upload follows.
+	start := time.Now()
+	response, err := http.Get("http://" + net.LocalHostIP.String() + ":" + listenPort.String() + "/sh/probe?x_padding=" + strings.Repeat("X", 200))
+	common.Must(err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	common.Must(err)
+	if response.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Error("status: ", response.StatusCode, ", body: ", string(body))
+	}
+	if time.Since(start) < 4*time.Second {
+		t.Error("expected to wait for the upload of the session")
+	}
+}
@@ -151,6 +151,13 @@ func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *S
 	}
 
 	l, err = callback(lc.Listen(ctx, network, address))
+	// Behind the PROXY protocol, the source is only known after reading the header, which must not block Accept.
+	if filter := ConnectionFilterFromContext(ctx); err == nil && filter != nil && (sockopt == nil || !sockopt.AcceptProxyProtocol) {
+		if _, ok := addr.(*net.TCPAddr); ok {
+			l = &filteredListener{Listener: l, filter: filter}
+			filter.installed.Store(true)
+		}
+	}
 	if err == nil && sockopt != nil && sockopt.AcceptProxyProtocol {
 		policyFunc := func(upstream net.Addr) (proxyproto.Policy, error) { return proxyproto.REQUIRE, nil }
 		l = &proxyproto.Listener{Listener: l, Policy: policyFunc}
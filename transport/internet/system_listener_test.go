@@ -2,9 +2,12 @@ package internet_test
 
 import (
 	"context"
+	"errors"
 	"net"
+	"os"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/sagernet/sing/common/control"
 	"github.com/xtls/xray-core/common"
@@ -31,3 +34,28 @@ func TestRegisterListenerController(t *testing.T) {
 		t.Error("expected none-zero fd, but actually 0")
 	}
 }
+
+func TestConnectionFilter(t *testing.T) {
+	filter := &internet.ConnectionFilter{
+		Allow: func(source net.Addr) bool {
+			return false
+		},
+	}
+	l, err := internet.ListenSystem(internet.ContextWithConnectionFilter(context.Background(), filter), &net.TCPAddr{
+		IP: net.IPv4(127, 0, 0, 1),
+	}, nil)
+	common.Must(err)
+	defer l.Close()
+	if !filter.Installed() {
+		t.Fatal("expected filter to be installed")
+	}
+
+	go l.Accept()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	common.Must(err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Error("expected rejected connection to be closed, but got ", err)
+	}
+}
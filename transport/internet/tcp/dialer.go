@@ -9,6 +9,7 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -34,6 +35,10 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		if IsFromMitm(tlsConfig.ServerName) {
 			tlsConfig.ServerName = mitmServerName
 		}
+		if err := config.ApplyECH(ctx, tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
 		isFromMitmVerify := false
 		if r, ok := tlsConfig.Rand.(*tls.RandCarrier); ok && len(r.VerifyPeerCertInNames) > 0 {
 			for i, name := range r.VerifyPeerCertInNames {
@@ -64,7 +69,9 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 				tlsConfig.NextProtos = []string{"h2", "http/1.1"}
 			}
 		}
-		if fingerprint := tls.GetFingerprint(config.Fingerprint); fingerprint != nil {
+		_, span := trace.Start(ctx, "tls.handshake")
+		span.SetAttribute("tls.server_name", tlsConfig.ServerName)
+		if fingerprint := config.ClientFingerprint(tlsConfig); fingerprint != nil {
 			conn = tls.UClient(conn, tlsConfig, fingerprint)
 			if len(tlsConfig.NextProtos) == 1 && tlsConfig.NextProtos[0] == "http/1.1" { // allow manually specify
 				err = conn.(*tls.UConn).WebsocketHandshakeContext(ctx)
@@ -75,6 +82,7 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 			conn = tls.Client(conn, tlsConfig)
 			err = conn.(*tls.Conn).HandshakeContext(ctx)
 		}
+		span.End(err)
 		if err != nil {
 			if isFromMitmVerify {
 				return nil, errors.New("MITM freedom RAW TLS: failed to verify Domain Fronting certificate from " + mitmServerName).Base(err).AtWarning()
@@ -27,6 +27,13 @@ type Listener interface {
 	Addr() net.Addr
 }
 
+// DrainableListener is implemented by a Listener whose Close also ends the connections it accepted.
+type DrainableListener interface {
+	Listener
+	// Drain stops accepting connections. The ones accepted are served until Close.
+	Drain() error
+}
+
 // ListenUnix is the UDS version of ListenTCP
 func ListenUnix(ctx context.Context, address net.Address, settings *MemoryStreamConfig, handler ConnHandler) (Listener, error) {
 	if settings == nil {
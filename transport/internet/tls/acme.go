@@ -0,0 +1,161 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/protobuf/proto"
+)
+
+// acmeManagers holds the ACME managers by their configs, so that the inbounds with the same ACME config, including
+// the ones of a reloaded instance, share their certificates and challenge listener.
+var acmeManagers struct {
+	sync.Mutex
+	managers map[string]*acmeManager
+}
+
+type acmeManager struct {
+	*autocert.Manager
+	domains []string
+	// challengeServer answers HTTP-01 challenges, if not nil.
+	challengeServer *http.Server
+}
+
+// getACMEManager returns the ACME manager of config, creating it on the first call.
+func getACMEManager(config *ACME) (*acmeManager, error) {
+	key, err := proto.MarshalOptions{Deterministic: true}.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	acmeManagers.Lock()
+	defer acmeManagers.Unlock()
+
+	if m := acmeManagers.managers[string(key)]; m != nil {
+		return m, nil
+	}
+	if config.HttpChallengeListen != "" {
+		// The manager of an edited config is replaced, and its challenge listener is to be taken over.
+		for k, m := range acmeManagers.managers {
+			if s := m.challengeServer; s != nil && s.Addr == config.HttpChallengeListen {
+				s.Close()
+				delete(acmeManagers.managers, k)
+			}
+		}
+	}
+	m, err := newACMEManager(config)
+	if err != nil {
+		return nil, err
+	}
+	if acmeManagers.managers == nil {
+		acmeManagers.managers = make(map[string]*acmeManager)
+	}
+	acmeManagers.managers[string(key)] = m
+	return m, nil
+}
+
+func newACMEManager(config *ACME) (*acmeManager, error) {
+	if len(config.Domains) == 0 {
+		return nil, errors.New("no domains for ACME")
+	}
+	domains := make([]string, 0, len(config.Domains))
+	for _, domain := range config.Domains {
+		domains = append(domains, strings.ToLower(domain))
+	}
+
+	client := &acme.Client{
+		DirectoryURL: config.DirectoryUrl,
+	}
+	if len(config.DirectoryCertificate) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(config.DirectoryCertificate) {
+			return nil, errors.New("invalid ACME directory certificate")
+		}
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		}
+	}
+
+	m := &acmeManager{
+		Manager: &autocert.Manager{
+			Prompt:      autocert.AcceptTOS,
+			HostPolicy:  autocert.HostWhitelist(domains...),
+			Email:       config.Email,
+			RenewBefore: time.Duration(config.RenewBefore) * time.Second,
+			Client:      client,
+		},
+		domains: domains,
+	}
+	if config.StorageDir != "" {
+		m.Cache = autocert.DirCache(config.StorageDir)
+	} else {
+		errors.LogWarning(context.Background(), "ACME certificates of ", domains, " are not stored and will be issued again on restart")
+	}
+
+	if config.HttpChallengeListen != "" {
+		server := &http.Server{
+			Addr:              config.HttpChallengeListen,
+			Handler:           m.HTTPHandler(nil),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		m.challengeServer = server
+		go func() {
+			errors.LogInfo(context.Background(), "answering ACME HTTP-01 challenges on ", config.HttpChallengeListen)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errors.LogErrorInner(context.Background(), err, "failed to answer ACME HTTP-01 challenges")
+			}
+		}()
+	}
+
+	// Get the certificates in advance, so that the first clients don't wait for them.
+	for _, domain := range domains {
+		go func() {
+			if _, err := m.GetCertificate(acmeHello(domain)); err != nil {
+				errors.LogErrorInner(context.Background(), err, "failed to get ACME certificate of ", domain)
+				return
+			}
+			errors.LogInfo(context.Background(), "got ACME certificate of ", domain)
+		}()
+	}
+	return m, nil
+}
+
+// acmeHello returns a Client Hello for domain that accepts ECDSA certificates.
+func acmeHello(domain string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{
+		ServerName:        domain,
+		CipherSuites:      []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_AES_128_GCM_SHA256},
+		SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:   []tls.CurveID{tls.CurveP256},
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+	}
+}
+
+// getCertificateFunc returns a GetCertificate that answers TLS-ALPN-01 challenges and serves the ACME certificates,
+// using fallback for other server names. Without a certificate from fallback, the one of the first domain is used,
+// unless rejectUnknownSNI.
+func (m *acmeManager) getCertificateFunc(fallback func(*tls.ClientHelloInfo) (*tls.Certificate, error), rejectUnknownSNI bool) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if slices.Contains(hello.SupportedProtos, acme.ALPNProto) || slices.Contains(m.domains, strings.ToLower(hello.ServerName)) {
+			return m.GetCertificate(hello)
+		}
+		certificate, err := fallback(hello)
+		if err == errNoCertificates && !rejectUnknownSNI {
+			h := *hello
+			h.ServerName = m.domains[0]
+			return m.GetCertificate(&h)
+		}
+		return certificate, err
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/xtls/xray-core/common/platform/filesystem"
 	"github.com/xtls/xray-core/common/protocol/tls/cert"
 	"github.com/xtls/xray-core/transport/internet"
+	"golang.org/x/crypto/acme"
 )
 
 var globalSessionCache = tls.NewLRUClientSessionCache(128)
@@ -390,6 +391,19 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 		config.GetCertificate = getNewGetCertificateFunc(c.BuildCertificates(), c.RejectUnknownSni)
 	}
 
+	var acmeManager *acmeManager
+	if c.Acme != nil {
+		if acmeManager, err = getACMEManager(c.Acme); err != nil {
+			errors.LogErrorInner(context.Background(), err, "failed to set up ACME")
+		} else {
+			config.GetCertificate = acmeManager.getCertificateFunc(config.GetCertificate, c.RejectUnknownSni)
+		}
+	}
+
+	if len(c.EchServerKeys) > 0 || c.EchServerKeysPath != "" {
+		config.GetEncryptedClientHelloKeys = c.newECHServerKeys().get
+	}
+
 	if sn := c.parseServerName(); len(sn) > 0 {
 		config.ServerName = sn
 	}
@@ -402,6 +416,10 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 		config.NextProtos = []string{"h2", "http/1.1"}
 	}
 
+	if acmeManager != nil && !slices.Contains(config.NextProtos, acme.ALPNProto) {
+		config.NextProtos = append(config.NextProtos, acme.ALPNProto)
+	}
+
 	switch c.MinVersion {
 	case "1.0":
 		config.MinVersion = tls.VersionTLS10
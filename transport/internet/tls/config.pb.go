@@ -20,6 +20,58 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ECHForceQuery int32
+
+const (
+	// Connect without ECH if the ECH configs can't be found.
+	ECHForceQuery_None ECHForceQuery = 0
+	// Connect without ECH only if the HTTPS record has no ECH configs.
+	ECHForceQuery_Half ECHForceQuery = 1
+	// Never connect without ECH.
+	ECHForceQuery_Full ECHForceQuery = 2
+)
+
+// Enum value maps for ECHForceQuery.
+var (
+	ECHForceQuery_name = map[int32]string{
+		0: "None",
+		1: "Half",
+		2: "Full",
+	}
+	ECHForceQuery_value = map[string]int32{
+		"None": 0,
+		"Half": 1,
+		"Full": 2,
+	}
+)
+
+func (x ECHForceQuery) Enum() *ECHForceQuery {
+	p := new(ECHForceQuery)
+	*p = x
+	return p
+}
+
+func (x ECHForceQuery) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ECHForceQuery) Descriptor() protoreflect.EnumDescriptor {
+	return file_transport_internet_tls_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ECHForceQuery) Type() protoreflect.EnumType {
+	return &file_transport_internet_tls_config_proto_enumTypes[0]
+}
+
+func (x ECHForceQuery) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ECHForceQuery.Descriptor instead.
+func (ECHForceQuery) EnumDescriptor() ([]byte, []int) {
+	return file_transport_internet_tls_config_proto_rawDescGZIP(), []int{0}
+}
+
 type Certificate_Usage int32
 
 const (
@@ -53,11 +105,11 @@ func (x Certificate_Usage) String() string {
 }
 
 func (Certificate_Usage) Descriptor() protoreflect.EnumDescriptor {
-	return file_transport_internet_tls_config_proto_enumTypes[0].Descriptor()
+	return file_transport_internet_tls_config_proto_enumTypes[1].Descriptor()
 }
 
 func (Certificate_Usage) Type() protoreflect.EnumType {
-	return &file_transport_internet_tls_config_proto_enumTypes[0]
+	return &file_transport_internet_tls_config_proto_enumTypes[1]
 }
 
 func (x Certificate_Usage) Number() protoreflect.EnumNumber {
@@ -217,6 +269,21 @@ type Config struct {
 	// @Document After allow_insecure (automatically), if the server's cert can't be verified by any of these names, pinned_peer_certificate_chain_sha256 will be tried.
 	// @Critical
 	VerifyPeerCertInNames []string `protobuf:"bytes,17,rep,name=verify_peer_cert_in_names,json=verifyPeerCertInNames,proto3" json:"verify_peer_cert_in_names,omitempty"`
+	// ECHConfigList for the client to encrypt the Client Hello with.
+	EchConfigList []byte `protobuf:"bytes,18,opt,name=ech_config_list,json=echConfigList,proto3" json:"ech_config_list,omitempty"`
+	// Domain whose HTTPS record provides the ECHConfigList, if ech_config_list
+	// is empty.
+	EchConfigDomain string        `protobuf:"bytes,19,opt,name=ech_config_domain,json=echConfigDomain,proto3" json:"ech_config_domain,omitempty"`
+	EchForceQuery   ECHForceQuery `protobuf:"varint,20,opt,name=ech_force_query,json=echForceQuery,proto3,enum=xray.transport.internet.tls.ECHForceQuery" json:"ech_force_query,omitempty"`
+	// ECH keys of the server, in the format of "xray tls ech". The inner server
+	// name of a Client Hello decrypted with them picks the certificate, but
+	// isn't passed on to the session, so routing rules can't match it; they see
+	// the sniffed domain as without ECH.
+	EchServerKeys []byte `protobuf:"bytes,21,opt,name=ech_server_keys,json=echServerKeys,proto3" json:"ech_server_keys,omitempty"`
+	// File of the ECH keys of the server, reloaded when changed.
+	EchServerKeysPath string `protobuf:"bytes,22,opt,name=ech_server_keys_path,json=echServerKeysPath,proto3" json:"ech_server_keys_path,omitempty"`
+	// Certificates issued and renewed by an ACME CA.
+	Acme *ACME `protobuf:"bytes,23,opt,name=acme,proto3" json:"acme,omitempty"`
 }
 
 func (x *Config) Reset() {
@@ -361,6 +428,150 @@ func (x *Config) GetVerifyPeerCertInNames() []string {
 	return nil
 }
 
+func (x *Config) GetEchConfigList() []byte {
+	if x != nil {
+		return x.EchConfigList
+	}
+	return nil
+}
+
+func (x *Config) GetEchConfigDomain() string {
+	if x != nil {
+		return x.EchConfigDomain
+	}
+	return ""
+}
+
+func (x *Config) GetEchForceQuery() ECHForceQuery {
+	if x != nil {
+		return x.EchForceQuery
+	}
+	return ECHForceQuery_None
+}
+
+func (x *Config) GetEchServerKeys() []byte {
+	if x != nil {
+		return x.EchServerKeys
+	}
+	return nil
+}
+
+func (x *Config) GetEchServerKeysPath() string {
+	if x != nil {
+		return x.EchServerKeysPath
+	}
+	return ""
+}
+
+func (x *Config) GetAcme() *ACME {
+	if x != nil {
+		return x.Acme
+	}
+	return nil
+}
+
+type ACME struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Domains to get certificates for.
+	Domains []string `protobuf:"bytes,1,rep,name=domains,proto3" json:"domains,omitempty"`
+	// Contact email of the account.
+	Email string `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	// Directory URL of the CA, Let's Encrypt by default.
+	DirectoryUrl string `protobuf:"bytes,3,opt,name=directory_url,json=directoryUrl,proto3" json:"directory_url,omitempty"`
+	// Directory to store the certificates and the account key in.
+	StorageDir string `protobuf:"bytes,4,opt,name=storage_dir,json=storageDir,proto3" json:"storage_dir,omitempty"`
+	// Address to answer HTTP-01 challenges on, like ":80". TLS-ALPN-01
+	// challenges are always answered by the TLS listener itself.
+	HttpChallengeListen string `protobuf:"bytes,5,opt,name=http_challenge_listen,json=httpChallengeListen,proto3" json:"http_challenge_listen,omitempty"`
+	// PEM certificates to verify the directory URL with, like the ones of a
+	// test CA.
+	DirectoryCertificate []byte `protobuf:"bytes,6,opt,name=directory_certificate,json=directoryCertificate,proto3" json:"directory_certificate,omitempty"`
+	// Seconds before the expiry to renew certificates, 30 days by default.
+	RenewBefore uint32 `protobuf:"varint,7,opt,name=renew_before,json=renewBefore,proto3" json:"renew_before,omitempty"`
+}
+
+func (x *ACME) Reset() {
+	*x = ACME{}
+	mi := &file_transport_internet_tls_config_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ACME) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ACME) ProtoMessage() {}
+
+func (x *ACME) ProtoReflect() protoreflect.Message {
+	mi := &file_transport_internet_tls_config_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ACME.ProtoReflect.Descriptor instead.
+func (*ACME) Descriptor() ([]byte, []int) {
+	return file_transport_internet_tls_config_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ACME) GetDomains() []string {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+func (x *ACME) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ACME) GetDirectoryUrl() string {
+	if x != nil {
+		return x.DirectoryUrl
+	}
+	return ""
+}
+
+func (x *ACME) GetStorageDir() string {
+	if x != nil {
+		return x.StorageDir
+	}
+	return ""
+}
+
+func (x *ACME) GetHttpChallengeListen() string {
+	if x != nil {
+		return x.HttpChallengeListen
+	}
+	return ""
+}
+
+func (x *ACME) GetDirectoryCertificate() []byte {
+	if x != nil {
+		return x.DirectoryCertificate
+	}
+	return nil
+}
+
+func (x *ACME) GetRenewBefore() uint32 {
+	if x != nil {
+		return x.RenewBefore
+	}
+	return 0
+}
+
 var File_transport_internet_tls_config_proto protoreflect.FileDescriptor
 
 var file_transport_internet_tls_config_proto_rawDesc = []byte{
@@ -392,7 +603,7 @@ var file_transport_internet_tls_config_proto_rawDesc = []byte{
 	0x4e, 0x43, 0x49, 0x50, 0x48, 0x45, 0x52, 0x4d, 0x45, 0x4e, 0x54, 0x10, 0x00, 0x12, 0x14, 0x0a,
 	0x10, 0x41, 0x55, 0x54, 0x48, 0x4f, 0x52, 0x49, 0x54, 0x59, 0x5f, 0x56, 0x45, 0x52, 0x49, 0x46,
 	0x59, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f, 0x41, 0x55, 0x54, 0x48, 0x4f, 0x52, 0x49, 0x54, 0x59,
-	0x5f, 0x49, 0x53, 0x53, 0x55, 0x45, 0x10, 0x02, 0x22, 0x9a, 0x06, 0x0a, 0x06, 0x43, 0x6f, 0x6e,
+	0x5f, 0x49, 0x53, 0x53, 0x55, 0x45, 0x10, 0x02, 0x22, 0xd2, 0x08, 0x0a, 0x06, 0x43, 0x6f, 0x6e,
 	0x66, 0x69, 0x67, 0x12, 0x25, 0x0a, 0x0e, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x69, 0x6e, 0x73,
 	0x65, 0x63, 0x75, 0x72, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x61, 0x6c, 0x6c,
 	0x6f, 0x77, 0x49, 0x6e, 0x73, 0x65, 0x63, 0x75, 0x72, 0x65, 0x12, 0x4a, 0x0a, 0x0b, 0x63, 0x65,
@@ -442,15 +653,54 @@ var file_transport_internet_tls_config_proto_rawDesc = []byte{
 	0x65, 0x72, 0x69, 0x66, 0x79, 0x5f, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x5f,
 	0x69, 0x6e, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x11, 0x20, 0x03, 0x28, 0x09, 0x52, 0x15,
 	0x76, 0x65, 0x72, 0x69, 0x66, 0x79, 0x50, 0x65, 0x65, 0x72, 0x43, 0x65, 0x72, 0x74, 0x49, 0x6e,
-	0x4e, 0x61, 0x6d, 0x65, 0x73, 0x42, 0x73, 0x0a, 0x1f, 0x63, 0x6f, 0x6d, 0x2e, 0x78, 0x72, 0x61,
-	0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e, 0x74, 0x65,
-	0x72, 0x6e, 0x65, 0x74, 0x2e, 0x74, 0x6c, 0x73, 0x50, 0x01, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68,
-	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x78, 0x74, 0x6c, 0x73, 0x2f, 0x78, 0x72, 0x61, 0x79,
-	0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2f,
-	0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2f, 0x74, 0x6c, 0x73, 0xaa, 0x02, 0x1b, 0x58,
-	0x72, 0x61, 0x79, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x49, 0x6e,
-	0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x54, 0x6c, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x4e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x26, 0x0a, 0x0f, 0x65, 0x63, 0x68, 0x5f, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x5f, 0x6c, 0x69, 0x73, 0x74, 0x18, 0x12, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d,
+	0x65, 0x63, 0x68, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x2a, 0x0a,
+	0x11, 0x65, 0x63, 0x68, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x5f, 0x64, 0x6f, 0x6d, 0x61,
+	0x69, 0x6e, 0x18, 0x13, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x65, 0x63, 0x68, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x44, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x12, 0x52, 0x0a, 0x0f, 0x65, 0x63, 0x68,
+	0x5f, 0x66, 0x6f, 0x72, 0x63, 0x65, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x79, 0x18, 0x14, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x2a, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70,
+	0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x74, 0x6c, 0x73,
+	0x2e, 0x45, 0x43, 0x48, 0x46, 0x6f, 0x72, 0x63, 0x65, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x0d,
+	0x65, 0x63, 0x68, 0x46, 0x6f, 0x72, 0x63, 0x65, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x26, 0x0a,
+	0x0f, 0x65, 0x63, 0x68, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x6b, 0x65, 0x79, 0x73,
+	0x18, 0x15, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x65, 0x63, 0x68, 0x53, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x2f, 0x0a, 0x14, 0x65, 0x63, 0x68, 0x5f, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x16, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x11, 0x65, 0x63, 0x68, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x4b, 0x65,
+	0x79, 0x73, 0x50, 0x61, 0x74, 0x68, 0x12, 0x35, 0x0a, 0x04, 0x61, 0x63, 0x6d, 0x65, 0x18, 0x17,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e,
+	0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x74,
+	0x6c, 0x73, 0x2e, 0x41, 0x43, 0x4d, 0x45, 0x52, 0x04, 0x61, 0x63, 0x6d, 0x65, 0x22, 0x88, 0x02,
+	0x0a, 0x04, 0x41, 0x43, 0x4d, 0x45, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x73,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x12, 0x23, 0x0a, 0x0d, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74,
+	0x6f, 0x72, 0x79, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x64,
+	0x69, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x55, 0x72, 0x6c, 0x12, 0x1f, 0x0a, 0x0b, 0x73,
+	0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x44, 0x69, 0x72, 0x12, 0x32, 0x0a, 0x15,
+	0x68, 0x74, 0x74, 0x70, 0x5f, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x5f, 0x6c,
+	0x69, 0x73, 0x74, 0x65, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x13, 0x68, 0x74, 0x74,
+	0x70, 0x43, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x65, 0x6e,
+	0x12, 0x33, 0x0a, 0x15, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x5f, 0x63, 0x65,
+	0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x14, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66,
+	0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x6e, 0x65, 0x77, 0x5f, 0x62,
+	0x65, 0x66, 0x6f, 0x72, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x72, 0x65, 0x6e,
+	0x65, 0x77, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x2a, 0x2d, 0x0a, 0x0d, 0x45, 0x43, 0x48, 0x46,
+	0x6f, 0x72, 0x63, 0x65, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x6f, 0x6e,
+	0x65, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x48, 0x61, 0x6c, 0x66, 0x10, 0x01, 0x12, 0x08, 0x0a,
+	0x04, 0x46, 0x75, 0x6c, 0x6c, 0x10, 0x02, 0x42, 0x73, 0x0a, 0x1f, 0x63, 0x6f, 0x6d, 0x2e, 0x78,
+	0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x74, 0x6c, 0x73, 0x50, 0x01, 0x5a, 0x30, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x78, 0x74, 0x6c, 0x73, 0x2f, 0x78, 0x72,
+	0x61, 0x79, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72,
+	0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2f, 0x74, 0x6c, 0x73, 0xaa, 0x02,
+	0x1b, 0x58, 0x72, 0x61, 0x79, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e,
+	0x49, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x54, 0x6c, 0x73, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -465,21 +715,25 @@ func file_transport_internet_tls_config_proto_rawDescGZIP() []byte {
 	return file_transport_internet_tls_config_proto_rawDescData
 }
 
-var file_transport_internet_tls_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_transport_internet_tls_config_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_transport_internet_tls_config_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_transport_internet_tls_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_transport_internet_tls_config_proto_goTypes = []any{
-	(Certificate_Usage)(0), // 0: xray.transport.internet.tls.Certificate.Usage
-	(*Certificate)(nil),    // 1: xray.transport.internet.tls.Certificate
-	(*Config)(nil),         // 2: xray.transport.internet.tls.Config
+	(ECHForceQuery)(0),     // 0: xray.transport.internet.tls.ECHForceQuery
+	(Certificate_Usage)(0), // 1: xray.transport.internet.tls.Certificate.Usage
+	(*Certificate)(nil),    // 2: xray.transport.internet.tls.Certificate
+	(*Config)(nil),         // 3: xray.transport.internet.tls.Config
+	(*ACME)(nil),           // 4: xray.transport.internet.tls.ACME
 }
 var file_transport_internet_tls_config_proto_depIdxs = []int32{
-	0, // 0: xray.transport.internet.tls.Certificate.usage:type_name -> xray.transport.internet.tls.Certificate.Usage
-	1, // 1: xray.transport.internet.tls.Config.certificate:type_name -> xray.transport.internet.tls.Certificate
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	1, // 0: xray.transport.internet.tls.Certificate.usage:type_name -> xray.transport.internet.tls.Certificate.Usage
+	2, // 1: xray.transport.internet.tls.Config.certificate:type_name -> xray.transport.internet.tls.Certificate
+	0, // 2: xray.transport.internet.tls.Config.ech_force_query:type_name -> xray.transport.internet.tls.ECHForceQuery
+	4, // 3: xray.transport.internet.tls.Config.acme:type_name -> xray.transport.internet.tls.ACME
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_transport_internet_tls_config_proto_init() }
@@ -492,8 +746,8 @@ func file_transport_internet_tls_config_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_transport_internet_tls_config_proto_rawDesc,
-			NumEnums:      1,
-			NumMessages:   2,
+			NumEnums:      2,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -1,13 +1,30 @@
 package tls_test
 
 import (
+	"context"
 	gotls "crypto/tls"
 	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/OmarTariq612/goech"
+	"github.com/cloudflare/circl/hpke"
 	"github.com/xtls/xray-core/common"
+	xnet "github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+	"github.com/xtls/xray-core/transport/internet"
 	. "github.com/xtls/xray-core/transport/internet/tls"
 )
 
@@ -72,6 +89,254 @@ func TestInsecureCertificates(t *testing.T) {
 	}
 }
 
+func TestECH(t *testing.T) {
+	keySet, err := goech.GenerateECHKeySet(0, "public.example.com", hpke.KEM_X25519_HKDF_SHA256)
+	common.Must(err)
+	keys, err := keySet.MarshalBinary()
+	common.Must(err)
+	configList, err := keySet.ECHConfig.MarshalBinary()
+	common.Must(err)
+
+	if _, err := ParseECHKeys(keys); err != nil {
+		t.Fatal(err)
+	}
+	names, err := ParseECHConfigList(configList)
+	common.Must(err)
+	if len(names) != 1 || names[0] != "public.example.com" {
+		t.Fatal("unexpected public names: ", names)
+	}
+
+	serverConfig := (&Config{
+		Certificate: []*Certificate{
+			ParseCertificate(cert.MustGenerate(nil, cert.DNSNames("www.example.com", "public.example.com"))),
+		},
+		MinVersion:    "1.3",
+		EchServerKeys: keys,
+	}).GetTLSConfig()
+
+	c := &Config{
+		AllowInsecure: true,
+		ServerName:    "www.example.com",
+		EchConfigList: configList,
+	}
+	clientConfig := c.GetTLSConfig()
+	common.Must(c.ApplyECH(context.Background(), clientConfig))
+	if c.ClientFingerprint(clientConfig) != nil {
+		t.Error("uTLS fingerprint is used with ECH")
+	}
+
+	clientConn, serverConn := net.Pipe()
+	server := gotls.Server(serverConn, serverConfig)
+	client := gotls.Client(clientConn, clientConfig)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Handshake()
+	}()
+	common.Must(client.Handshake())
+	common.Must(<-errCh)
+
+	if !client.ConnectionState().ECHAccepted {
+		t.Error("ECH is not accepted")
+	}
+	if serverName := server.ConnectionState().ServerName; serverName != "www.example.com" {
+		t.Error("server name: ", serverName)
+	}
+}
+
+type echLookup struct {
+	configList []byte
+	release    chan struct{}
+	slow       atomic.Int32
+}
+
+func (*echLookup) Type() interface{} { return dns.ClientType() }
+func (*echLookup) Start() error      { return nil }
+func (*echLookup) Close() error      { return nil }
+
+func (*echLookup) LookupIP(string, dns.IPOption) ([]xnet.IP, error) { return nil, dns.ErrEmptyResponse }
+
+func (l *echLookup) LookupECHConfigList(domain string) ([]byte, uint32, error) {
+	if strings.HasPrefix(domain, "slow.") {
+		l.slow.Add(1)
+		<-l.release
+	}
+	return l.configList, 600, nil
+}
+
+func TestECHLookup(t *testing.T) {
+	keySet, err := goech.GenerateECHKeySet(0, "public.example.com", hpke.KEM_X25519_HKDF_SHA256)
+	common.Must(err)
+	configList, err := keySet.ECHConfig.MarshalBinary()
+	common.Must(err)
+	lookup := &echLookup{
+		configList: configList,
+		release:    make(chan struct{}),
+	}
+	internet.InitSystemDialer(lookup, nil)
+	defer internet.InitSystemDialer(nil, nil)
+
+	apply := func(domain string) {
+		c := &Config{
+			ServerName:      domain,
+			EchConfigDomain: domain,
+			EchForceQuery:   ECHForceQuery_Full,
+		}
+		config := c.GetTLSConfig()
+		if err := c.ApplyECH(context.Background(), config); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The results are cached by the process, so the domains are new to every run.
+	id := time.Now().UnixNano()
+	slow, other := fmt.Sprint("slow.", id, ".example.com"), fmt.Sprint("fast.", id, ".example.com")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			apply(slow)
+		}()
+	}
+	for lookup.slow.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The lookups of other domains don't wait for a slow one.
+	fast := make(chan struct{})
+	go func() {
+		apply(other)
+		close(fast)
+	}()
+	select {
+	case <-fast:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lookup of a domain waits for the one of another")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(lookup.release)
+	wg.Wait()
+	apply(slow)
+	if n := lookup.slow.Load(); n != 1 {
+		t.Error("expected the concurrent lookups of a domain to be merged, but got ", n, " queries")
+	}
+}
+
+func TestACME(t *testing.T) {
+	c := &Config{
+		Certificate: []*Certificate{
+			ParseCertificate(cert.MustGenerate(nil, cert.CommonName("static.example.com"), cert.DNSNames("static.example.com"))),
+		},
+		Acme: &ACME{
+			Domains:      []string{"acme.example.com"},
+			DirectoryUrl: "http://127.0.0.1:1/directory",
+		},
+	}
+
+	tlsConfig := c.GetTLSConfig()
+	if !slices.Contains(tlsConfig.NextProtos, "acme-tls/1") {
+		t.Error("NextProtos: ", tlsConfig.NextProtos)
+	}
+
+	xrayCert, err := tlsConfig.GetCertificate(&gotls.ClientHelloInfo{
+		ServerName: "static.example.com",
+	})
+	common.Must(err)
+	x509Cert, err := x509.ParseCertificate(xrayCert.Certificate[0])
+	common.Must(err)
+	if x509Cert.Subject.CommonName != "static.example.com" {
+		t.Error("CommonName: ", x509Cert.Subject.CommonName)
+	}
+
+	if _, err := tlsConfig.GetCertificate(&gotls.ClientHelloInfo{
+		ServerName:      "acme.example.com",
+		SupportedProtos: []string{"acme-tls/1"},
+	}); err == nil {
+		t.Error("expected no token certificate")
+	}
+}
+
+// TestACMEPebble gets a certificate from a local Pebble test CA, https://github.com/letsencrypt/pebble.
+func TestACMEPebble(t *testing.T) {
+	pebble, err := exec.LookPath("pebble")
+	if err != nil {
+		t.Skip("pebble is not installed")
+	}
+
+	dir := t.TempDir()
+	directoryCert := cert.MustGenerate(nil, cert.DNSNames("localhost"))
+	certPEM, keyPEM := directoryCert.ToPEM()
+	common.Must(os.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0o600))
+	common.Must(os.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0o600))
+	port := tcp.PickPort()
+	pebbleConfig, err := json.Marshal(map[string]any{
+		"pebble": map[string]any{
+			"listenAddress":           fmt.Sprint("127.0.0.1:", port),
+			"managementListenAddress": fmt.Sprint("127.0.0.1:", tcp.PickPort()),
+			"certificate":             filepath.Join(dir, "cert.pem"),
+			"privateKey":              filepath.Join(dir, "key.pem"),
+			"httpPort":                int(tcp.PickPort()),
+			"tlsPort":                 int(tcp.PickPort()),
+		},
+	})
+	common.Must(err)
+	common.Must(os.WriteFile(filepath.Join(dir, "pebble.json"), pebbleConfig, 0o600))
+
+	cmd := exec.Command(pebble, "-config", filepath.Join(dir, "pebble.json"))
+	// The challenges are not validated, as the test domain doesn't resolve to the listener.
+	cmd.Env = append(os.Environ(), "PEBBLE_VA_ALWAYS_VALID=1", "PEBBLE_VA_NOSLEEP=1")
+	common.Must(cmd.Start())
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", fmt.Sprint("127.0.0.1:", port))
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if i == 50 {
+			t.Fatal("pebble is not listening: ", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	c := &Config{
+		Acme: &ACME{
+			Domains:              []string{"acme.example.com"},
+			DirectoryUrl:         fmt.Sprint("https://localhost:", port, "/dir"),
+			DirectoryCertificate: certPEM,
+			StorageDir:           t.TempDir(),
+			HttpChallengeListen:  fmt.Sprint("127.0.0.1:", tcp.PickPort()),
+		},
+	}
+	tlsConfig := c.GetTLSConfig()
+
+	var xrayCert *gotls.Certificate
+	for i := 0; xrayCert == nil; i++ {
+		xrayCert, err = tlsConfig.GetCertificate(&gotls.ClientHelloInfo{
+			ServerName:        "acme.example.com",
+			CipherSuites:      []uint16{gotls.TLS_AES_128_GCM_SHA256},
+			SignatureSchemes:  []gotls.SignatureScheme{gotls.ECDSAWithP256AndSHA256},
+			SupportedCurves:   []gotls.CurveID{gotls.CurveP256},
+			SupportedVersions: []uint16{gotls.VersionTLS13},
+		})
+		if err != nil && i == 30 {
+			t.Fatal("failed to get certificate: ", err)
+		}
+		if err != nil {
+			time.Sleep(time.Second)
+		}
+	}
+	x509Cert, err := x509.ParseCertificate(xrayCert.Certificate[0])
+	common.Must(err)
+	if !slices.Contains(x509Cert.DNSNames, "acme.example.com") || !strings.Contains(x509Cert.Issuer.CommonName, "Pebble") {
+		t.Error("unexpected certificate: ", x509Cert.Subject, " issued by ", x509Cert.Issuer)
+	}
+}
+
 func BenchmarkCertificateIssuing(b *testing.B) {
 	certificate := ParseCertificate(cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign)))
 	certificate.Usage = Certificate_AUTHORITY_ISSUE
@@ -0,0 +1,251 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	goerrors "errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OmarTariq612/goech"
+	utls "github.com/refraction-networking/utls"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform/filesystem"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/transport/internet"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// echKeysCheckInterval is how often the ECH keys file is checked for changes.
+	echKeysCheckInterval = time.Minute
+	// echFailureTTL is how long a failed lookup of ECH configs is cached.
+	echFailureTTL = time.Minute
+)
+
+// ParseECHKeys parses the ECH keys generated by "xray tls ech", which are PEM "ECH KEYS" blocks, or their content:
+// the private key and the ECHConfig, each prefixed with its uint16 length, repeated for every key.
+func ParseECHKeys(data []byte) ([]tls.EncryptedClientHelloKey, error) {
+	var blocks [][]byte
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "ECH KEYS" {
+			blocks = append(blocks, block.Bytes)
+		}
+	}
+	if blocks == nil {
+		blocks = [][]byte{data}
+	}
+
+	var keys []tls.EncryptedClientHelloKey
+	for _, b := range blocks {
+		s := cryptobyte.String(b)
+		for !s.Empty() {
+			key := s
+			var sk, config cryptobyte.String
+			if !s.ReadUint16LengthPrefixed(&sk) || !s.ReadUint16LengthPrefixed(&config) {
+				return nil, errors.New("invalid ECH keys")
+			}
+			var keySet goech.ECHKeySet
+			if err := keySet.UnmarshalBinary(key[:len(key)-len(s)]); err != nil {
+				return nil, errors.New("invalid ECH key").Base(err)
+			}
+			keys = append(keys, tls.EncryptedClientHelloKey{
+				Config:      config,
+				PrivateKey:  sk,
+				SendAsRetry: true,
+			})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no ECH keys")
+	}
+	return keys, nil
+}
+
+// ParseECHConfigList validates an ECHConfigList and returns the public names of its configs.
+func ParseECHConfigList(data []byte) ([]string, error) {
+	configs, err := goech.UnmarshalECHConfigList(data)
+	if err != nil {
+		return nil, errors.New("invalid ECH config list").Base(err)
+	}
+	if len(configs) == 0 {
+		return nil, errors.New("empty ECH config list")
+	}
+	names := make([]string, 0, len(configs))
+	for _, config := range configs {
+		names = append(names, string(config.RawPublicName))
+	}
+	return names, nil
+}
+
+// echServerKeys provides the ECH keys of a server, reloading them when the keys file changes.
+type echServerKeys struct {
+	path string
+
+	access  sync.Mutex
+	keys    []tls.EncryptedClientHelloKey
+	modTime time.Time
+	checked time.Time
+}
+
+func (c *Config) newECHServerKeys() *echServerKeys {
+	s := &echServerKeys{path: c.EchServerKeysPath}
+	if len(c.EchServerKeys) > 0 {
+		keys, err := ParseECHKeys(c.EchServerKeys)
+		if err != nil {
+			errors.LogErrorInner(context.Background(), err, "ignoring invalid ECH keys")
+		}
+		s.keys = keys
+	}
+	if s.path != "" {
+		s.reload()
+	}
+	return s
+}
+
+// reload loads the keys file if it has changed. It must be called with access held.
+func (s *echServerKeys) reload() {
+	s.checked = time.Now()
+	info, err := os.Stat(s.path)
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to check ECH keys file ", s.path)
+		return
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return
+	}
+	data, err := filesystem.ReadFile(s.path)
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to read ECH keys file ", s.path)
+		return
+	}
+	keys, err := ParseECHKeys(data)
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "ignoring invalid ECH keys file ", s.path)
+		return
+	}
+	if !s.modTime.IsZero() {
+		errors.LogInfo(context.Background(), "reloaded ", len(keys), " ECH keys from ", s.path)
+	}
+	s.keys = keys
+	s.modTime = info.ModTime()
+}
+
+// get is the GetEncryptedClientHelloKeys of tls.Config. hello is the outer Client Hello, the inner one is only
+// seen by GetCertificate and the like.
+func (s *echServerKeys) get(hello *tls.ClientHelloInfo) ([]tls.EncryptedClientHelloKey, error) {
+	s.access.Lock()
+	if s.path != "" && time.Since(s.checked) >= echKeysCheckInterval {
+		s.reload()
+	}
+	keys := s.keys
+	s.access.Unlock()
+
+	if hello.Conn != nil {
+		errors.LogDebug(context.Background(), "Client Hello from ", hello.Conn.RemoteAddr(), " with outer server name ", hello.ServerName)
+	}
+	return keys, nil
+}
+
+type echConfigEntry struct {
+	configList []byte
+	err        error
+	expire     time.Time
+}
+
+var echConfigCache struct {
+	sync.Mutex
+	entries map[string]*echConfigEntry
+	// group merges the concurrent lookups of a domain, which are done without the lock.
+	group singleflight.Group
+}
+
+// lookupECHConfigList looks up the ECHConfigList of domain, caching the result for its TTL.
+func lookupECHConfigList(domain string) ([]byte, error) {
+	echConfigCache.Lock()
+	entry := echConfigCache.entries[domain]
+	echConfigCache.Unlock()
+	if entry != nil && time.Now().Before(entry.expire) {
+		return entry.configList, entry.err
+	}
+
+	v, _, _ := echConfigCache.group.Do(domain, func() (interface{}, error) {
+		return queryECHConfigList(domain), nil
+	})
+	entry = v.(*echConfigEntry)
+	return entry.configList, entry.err
+}
+
+// queryECHConfigList queries the ECHConfigList of domain, and caches the result.
+func queryECHConfigList(domain string) *echConfigEntry {
+	configList, ttl, err := internet.LookupECHConfigList(domain)
+	if err == nil {
+		_, err = ParseECHConfigList(configList)
+	}
+	entry := &echConfigEntry{
+		configList: configList,
+		err:        err,
+		expire:     time.Now().Add(time.Duration(ttl) * time.Second),
+	}
+	if err != nil {
+		entry.configList = nil
+		entry.expire = time.Now().Add(echFailureTTL)
+	}
+	echConfigCache.Lock()
+	if echConfigCache.entries == nil {
+		echConfigCache.entries = make(map[string]*echConfigEntry)
+	}
+	echConfigCache.entries[domain] = entry
+	echConfigCache.Unlock()
+	return entry
+}
+
+// ApplyECH sets up the ECH of a client config made by GetTLSConfig, looking up the ECH configs if needed. It returns
+// an error if ECH is required by EchForceQuery but not available.
+func (c *Config) ApplyECH(ctx context.Context, config *tls.Config) error {
+	if c == nil {
+		return nil
+	}
+	configList := c.EchConfigList
+	if len(configList) == 0 && c.EchConfigDomain != "" {
+		var err error
+		configList, err = lookupECHConfigList(c.EchConfigDomain)
+		if err != nil {
+			if c.EchForceQuery == ECHForceQuery_Full || (c.EchForceQuery == ECHForceQuery_Half && !goerrors.Is(err, dns.ErrEmptyResponse)) {
+				return errors.New("failed to get ECH configs of ", c.EchConfigDomain).Base(err)
+			}
+			errors.LogInfoInner(ctx, err, "connecting without ECH to ", config.ServerName)
+			return nil
+		}
+	}
+	if len(configList) == 0 {
+		return nil
+	}
+
+	names, err := ParseECHConfigList(configList)
+	if err != nil {
+		return err
+	}
+	config.EncryptedClientHelloConfigList = configList
+	config.MinVersion = tls.VersionTLS13
+	errors.LogInfo(ctx, "using ECH with outer server name ", strings.Join(names, ","), " and inner server name ", config.ServerName)
+	return nil
+}
+
+// ClientFingerprint returns the uTLS fingerprint of a client config made by GetTLSConfig, or nil if the handshake
+// must be done by crypto/tls, which is the case with ECH.
+func (c *Config) ClientFingerprint(config *tls.Config) *utls.ClientHelloID {
+	if len(config.EncryptedClientHelloConfigList) > 0 {
+		return nil
+	}
+	return GetFingerprint(c.Fingerprint)
+}
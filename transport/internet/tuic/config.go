@@ -0,0 +1,46 @@
+package tuic
+
+import (
+	"time"
+
+	"github.com/apernet/quic-go"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/hysteria2/congestion"
+)
+
+const defaultIdleTimeout = 30 * time.Second
+
+func (c *Config) idleTimeout() time.Duration {
+	if c.GetIdleTimeout() == 0 {
+		return defaultIdleTimeout
+	}
+	return time.Duration(c.IdleTimeout) * time.Second
+}
+
+func (c *Config) quicConfig() *quic.Config {
+	return &quic.Config{
+		InitialStreamReceiveWindow:     streamReceiveWindow,
+		MaxStreamReceiveWindow:         streamReceiveWindow,
+		InitialConnectionReceiveWindow: connReceiveWindow,
+		MaxConnectionReceiveWindow:     connReceiveWindow,
+		MaxIncomingStreams:             maxIncomingStreams,
+		MaxIncomingUniStreams:          maxIncomingUniStreams,
+		MaxIdleTimeout:                 c.idleTimeout(),
+		Allow0RTT:                      c.GetZeroRttHandshake(),
+		EnableDatagrams:                true,
+	}
+}
+
+// setCongestionControl replaces the CUBIC of quic-go, if another congestion control is configured.
+func (c *Config) setCongestionControl(conn quic.Connection) {
+	if c.GetCongestion() == Congestion_BBR {
+		conn.SetCongestionControl(congestion.NewBBR())
+	}
+}
+
+func init() {
+	common.Must(internet.RegisterProtocolConfigCreator(protocolName, func() interface{} {
+		return new(Config)
+	}))
+}
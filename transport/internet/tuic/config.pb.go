@@ -0,0 +1,214 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.1
+// 	protoc        v5.28.2
+// source: transport/internet/tuic/config.proto
+
+package tuic
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Congestion int32
+
+const (
+	Congestion_CUBIC Congestion = 0
+	Congestion_BBR   Congestion = 1
+)
+
+// Enum value maps for Congestion.
+var (
+	Congestion_name = map[int32]string{
+		0: "CUBIC",
+		1: "BBR",
+	}
+	Congestion_value = map[string]int32{
+		"CUBIC": 0,
+		"BBR":   1,
+	}
+)
+
+func (x Congestion) Enum() *Congestion {
+	p := new(Congestion)
+	*p = x
+	return p
+}
+
+func (x Congestion) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Congestion) Descriptor() protoreflect.EnumDescriptor {
+	return file_transport_internet_tuic_config_proto_enumTypes[0].Descriptor()
+}
+
+func (Congestion) Type() protoreflect.EnumType {
+	return &file_transport_internet_tuic_config_proto_enumTypes[0]
+}
+
+func (x Congestion) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Congestion.Descriptor instead.
+func (Congestion) EnumDescriptor() ([]byte, []int) {
+	return file_transport_internet_tuic_config_proto_rawDescGZIP(), []int{0}
+}
+
+type Config struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Congestion Congestion `protobuf:"varint,1,opt,name=congestion,proto3,enum=xray.transport.internet.tuic.Congestion" json:"congestion,omitempty"`
+	// Whether a client sends its requests in 0-RTT, before the handshake of a
+	// resumed session completes, and a server accepts them.
+	ZeroRttHandshake bool `protobuf:"varint,2,opt,name=zero_rtt_handshake,json=zeroRttHandshake,proto3" json:"zero_rtt_handshake,omitempty"`
+	// Seconds a connection survives without any packet, 0 for 30.
+	IdleTimeout uint32 `protobuf:"varint,3,opt,name=idle_timeout,json=idleTimeout,proto3" json:"idle_timeout,omitempty"`
+}
+
+func (x *Config) Reset() {
+	*x = Config{}
+	mi := &file_transport_internet_tuic_config_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Config) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Config) ProtoMessage() {}
+
+func (x *Config) ProtoReflect() protoreflect.Message {
+	mi := &file_transport_internet_tuic_config_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Config.ProtoReflect.Descriptor instead.
+func (*Config) Descriptor() ([]byte, []int) {
+	return file_transport_internet_tuic_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Config) GetCongestion() Congestion {
+	if x != nil {
+		return x.Congestion
+	}
+	return Congestion_CUBIC
+}
+
+func (x *Config) GetZeroRttHandshake() bool {
+	if x != nil {
+		return x.ZeroRttHandshake
+	}
+	return false
+}
+
+func (x *Config) GetIdleTimeout() uint32 {
+	if x != nil {
+		return x.IdleTimeout
+	}
+	return 0
+}
+
+var File_transport_internet_tuic_config_proto protoreflect.FileDescriptor
+
+var file_transport_internet_tuic_config_proto_rawDesc = []byte{
+	0x0a, 0x24, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x6e, 0x65, 0x74, 0x2f, 0x74, 0x75, 0x69, 0x63, 0x2f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1c, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61,
+	0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e,
+	0x74, 0x75, 0x69, 0x63, 0x22, 0xa3, 0x01, 0x0a, 0x06, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12,
+	0x48, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x28, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73,
+	0x70, 0x6f, 0x72, 0x74, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x74, 0x75,
+	0x69, 0x63, 0x2e, 0x43, 0x6f, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x63,
+	0x6f, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2c, 0x0a, 0x12, 0x7a, 0x65, 0x72,
+	0x6f, 0x5f, 0x72, 0x74, 0x74, 0x5f, 0x68, 0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x7a, 0x65, 0x72, 0x6f, 0x52, 0x74, 0x74, 0x48, 0x61,
+	0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x69, 0x64, 0x6c, 0x65, 0x5f,
+	0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x69,
+	0x64, 0x6c, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x2a, 0x20, 0x0a, 0x0a, 0x43, 0x6f,
+	0x6e, 0x67, 0x65, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x09, 0x0a, 0x05, 0x43, 0x55, 0x42, 0x49,
+	0x43, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x42, 0x42, 0x52, 0x10, 0x01, 0x42, 0x76, 0x0a, 0x20,
+	0x63, 0x6f, 0x6d, 0x2e, 0x78, 0x72, 0x61, 0x79, 0x2e, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f,
+	0x72, 0x74, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e, 0x74, 0x75, 0x69, 0x63,
+	0x50, 0x01, 0x5a, 0x31, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x78,
+	0x74, 0x6c, 0x73, 0x2f, 0x78, 0x72, 0x61, 0x79, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x74, 0x72,
+	0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74,
+	0x2f, 0x74, 0x75, 0x69, 0x63, 0xaa, 0x02, 0x1c, 0x58, 0x72, 0x61, 0x79, 0x2e, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x2e,
+	0x54, 0x75, 0x69, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_transport_internet_tuic_config_proto_rawDescOnce sync.Once
+	file_transport_internet_tuic_config_proto_rawDescData = file_transport_internet_tuic_config_proto_rawDesc
+)
+
+func file_transport_internet_tuic_config_proto_rawDescGZIP() []byte {
+	file_transport_internet_tuic_config_proto_rawDescOnce.Do(func() {
+		file_transport_internet_tuic_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_transport_internet_tuic_config_proto_rawDescData)
+	})
+	return file_transport_internet_tuic_config_proto_rawDescData
+}
+
+var file_transport_internet_tuic_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_transport_internet_tuic_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_transport_internet_tuic_config_proto_goTypes = []any{
+	(Congestion)(0), // 0: xray.transport.internet.tuic.Congestion
+	(*Config)(nil),  // 1: xray.transport.internet.tuic.Config
+}
+var file_transport_internet_tuic_config_proto_depIdxs = []int32{
+	0, // 0: xray.transport.internet.tuic.Config.congestion:type_name -> xray.transport.internet.tuic.Congestion
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_transport_internet_tuic_config_proto_init() }
+func file_transport_internet_tuic_config_proto_init() {
+	if File_transport_internet_tuic_config_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_transport_internet_tuic_config_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_transport_internet_tuic_config_proto_goTypes,
+		DependencyIndexes: file_transport_internet_tuic_config_proto_depIdxs,
+		EnumInfos:         file_transport_internet_tuic_config_proto_enumTypes,
+		MessageInfos:      file_transport_internet_tuic_config_proto_msgTypes,
+	}.Build()
+	File_transport_internet_tuic_config_proto = out.File
+	file_transport_internet_tuic_config_proto_rawDesc = nil
+	file_transport_internet_tuic_config_proto_goTypes = nil
+	file_transport_internet_tuic_config_proto_depIdxs = nil
+}
@@ -0,0 +1,65 @@
+package tuic
+
+import (
+	"context"
+
+	"github.com/apernet/quic-go"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+// Dial returns a Conn of a new connection to dest. With 0-RTT, it returns before the handshake completes if the
+// session is resumed.
+func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (stat.Connection, error) {
+	config := streamSettings.ProtocolSettings.(*Config)
+	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
+	if tlsConfig == nil {
+		return nil, errors.New("TUIC requires TLS")
+	}
+	gotlsConfig := tlsConfig.GetTLSConfig(tls.WithDestination(dest))
+	if len(tlsConfig.NextProtocol) == 0 {
+		gotlsConfig.NextProtos = []string{"h3"}
+	}
+	if config.ZeroRttHandshake {
+		// 0-RTT is only possible in a resumed session.
+		gotlsConfig.SessionTicketsDisabled = false
+	}
+	if err := tlsConfig.ApplyECH(ctx, gotlsConfig); err != nil {
+		return nil, err
+	}
+
+	// The connection outlives the request it is dialed for.
+	ctx = context.WithoutCancel(ctx)
+	dest.Network = net.Network_UDP
+	rawConn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+	if err != nil {
+		return nil, errors.New("failed to dial ", dest).Base(err)
+	}
+	packetConn := &internet.FakePacketConn{Conn: rawConn}
+	quicConn, err := quic.DialEarly(ctx, packetConn, rawConn.RemoteAddr(), gotlsConfig, config.quicConfig())
+	if err != nil {
+		rawConn.Close()
+		return nil, errors.New("failed to dial QUIC to ", dest).Base(err)
+	}
+	go func() {
+		<-quicConn.Context().Done()
+		rawConn.Close()
+	}()
+	if !config.ZeroRttHandshake {
+		select {
+		case <-quicConn.HandshakeComplete():
+		case <-quicConn.Context().Done():
+			return nil, errors.New("failed to handshake with ", dest).Base(context.Cause(quicConn.Context()))
+		}
+	}
+	config.setCongestionControl(quicConn)
+	return &Conn{conn: quicConn}, nil
+}
+
+func init() {
+	common.Must(internet.RegisterTransportDialer(protocolName, Dial))
+}
@@ -0,0 +1,60 @@
+package tuic
+
+import (
+	"context"
+	"io"
+
+	"github.com/apernet/quic-go"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/quichub"
+)
+
+// Listener is a TUIC server, passing every connection as a Conn, before it is authenticated by the proxy.
+type Listener struct {
+	*quichub.Hub[quic.EarlyConnection, quic.ApplicationErrorCode]
+	config  *Config
+	addConn internet.ConnHandler
+}
+
+// Listen listens UDP on address:port for TUIC.
+func Listen(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (internet.Listener, error) {
+	config := streamSettings.ProtocolSettings.(*Config)
+	gotlsConfig, err := quichub.ServerTLSConfig(streamSettings, "TUIC")
+	if err != nil {
+		return nil, err
+	}
+
+	packetConn, err := internet.ListenSystemPacket(ctx, &net.UDPAddr{
+		IP:   address.IP(),
+		Port: int(port),
+	}, streamSettings.SocketSettings)
+	if err != nil {
+		return nil, errors.New("failed to listen UDP for TUIC on ", address, ":", port).Base(err)
+	}
+	hub, err := quichub.Listen[quic.EarlyConnection](ctx, "TUIC", address, port, packetConn, func(packetConn net.PacketConn) (io.Closer, quichub.Listener[quic.EarlyConnection], error) {
+		transport := &quic.Transport{Conn: packetConn}
+		listener, err := transport.ListenEarly(gotlsConfig, config.quicConfig())
+		return transport, listener, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Listener{
+		Hub:     hub,
+		config:  config,
+		addConn: addConn,
+	}
+	hub.Serve(func(conn quic.EarlyConnection) {
+		l.config.setCongestionControl(conn)
+		l.addConn(&Conn{conn: conn})
+	})
+	return l, nil
+}
+
+func init() {
+	common.Must(internet.RegisterTransportListener(protocolName, Listen))
+}
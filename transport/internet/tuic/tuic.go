@@ -0,0 +1,144 @@
+// Package tuic is the transport of TUIC, QUIC connections whose streams and datagrams carry the commands of the TUIC
+// proxy. A Conn is a whole connection, on which the proxy authenticates and relays.
+//
+// It is built on the quic-go fork of Hysteria, as hysteria2 is, rather than on quic-go itself, since the BBR
+// congestion control that TUIC offers besides CUBIC needs the SetCongestionControl of the fork, which quic-go has no
+// equivalent of. The BBR of hysteria2 is shared rather than copied.
+package tuic
+
+import (
+	"context"
+	"time"
+
+	"github.com/apernet/quic-go"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+const protocolName = "tuic"
+
+const (
+	streamReceiveWindow   = 8 << 20
+	connReceiveWindow     = 20 << 20
+	maxIncomingStreams    = 1024
+	maxIncomingUniStreams = 1024
+)
+
+var errNotStream = errors.New("a TUIC connection is read and written by its streams and datagrams")
+
+// Conn is a connection of a client or a server. It is a stat.Connection for the proxy, which reads and writes it by
+// its streams and datagrams.
+type Conn struct {
+	conn quic.EarlyConnection
+}
+
+// AcceptStream returns the next bidirectional stream opened by the peer.
+func (c *Conn) AcceptStream(ctx context.Context) (stat.Connection, error) {
+	stream, err := c.conn.AcceptStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &streamConn{Stream: stream, conn: c.conn}, nil
+}
+
+// OpenStream opens a bidirectional stream, waiting for the peer to allow it.
+func (c *Conn) OpenStream(ctx context.Context) (stat.Connection, error) {
+	stream, err := c.conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &streamConn{Stream: stream, conn: c.conn}, nil
+}
+
+// AcceptUniStream returns the next unidirectional stream opened by the peer.
+func (c *Conn) AcceptUniStream(ctx context.Context) (quic.ReceiveStream, error) {
+	return c.conn.AcceptUniStream(ctx)
+}
+
+// OpenUniStream opens a unidirectional stream, waiting for the peer to allow it.
+func (c *Conn) OpenUniStream(ctx context.Context) (quic.SendStream, error) {
+	return c.conn.OpenUniStreamSync(ctx)
+}
+
+// ReceiveDatagram returns the next datagram.
+func (c *Conn) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	return c.conn.ReceiveDatagram(ctx)
+}
+
+// SendDatagram sends a datagram, or returns a *quic.DatagramTooLargeError if it doesn't fit in a packet.
+func (c *Conn) SendDatagram(datagram []byte) error {
+	return c.conn.SendDatagram(datagram)
+}
+
+// ExportKeyingMaterial returns the keying material of the TLS session, waiting for the handshake to complete.
+func (c *Conn) ExportKeyingMaterial(label string, keyContext []byte, length int) ([]byte, error) {
+	select {
+	case <-c.conn.HandshakeComplete():
+	case <-c.conn.Context().Done():
+		return nil, context.Cause(c.conn.Context())
+	}
+	state := c.conn.ConnectionState().TLS
+	return state.ExportKeyingMaterial(label, keyContext, length)
+}
+
+// Done returns a channel closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.conn.Context().Done()
+}
+
+// CloseWithError closes the connection with an error code and its message for the peer.
+func (c *Conn) CloseWithError(code uint64, message string) error {
+	return c.conn.CloseWithError(quic.ApplicationErrorCode(code), message)
+}
+
+func (c *Conn) Read([]byte) (int, error) {
+	return 0, errNotStream
+}
+
+func (c *Conn) Write([]byte) (int, error) {
+	return 0, errNotStream
+}
+
+func (c *Conn) Close() error {
+	return c.conn.CloseWithError(0, "")
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (c *Conn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (c *Conn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// streamConn is a bidirectional stream of a connection.
+type streamConn struct {
+	quic.Stream
+	conn quic.Connection
+}
+
+func (c *streamConn) Close() error {
+	c.CancelRead(0)
+	return c.Stream.Close()
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
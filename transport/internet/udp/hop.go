@@ -0,0 +1,437 @@
+package udp
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+)
+
+const (
+	defaultHopInterval = 30 * time.Second
+	// hopGracePeriod is how long the previous connection of a HopConn is still read after a hop.
+	hopGracePeriod = 5 * time.Second
+	// hopRouteTimeout is how long a server remembers the port a client sent to.
+	hopRouteTimeout = 2 * time.Minute
+	// MaxHopPorts is the most ports a hop may have, as a server listens on every one of them with its own socket.
+	MaxHopPorts = 1024
+)
+
+// AllPorts returns every port in the ranges of h.
+func (h *Hop) AllPorts() []net.Port {
+	var ports []net.Port
+	for _, r := range h.GetPorts().GetRange() {
+		for port := r.From; port <= r.To && port <= 65535; port++ {
+			ports = append(ports, net.Port(port))
+		}
+	}
+	return ports
+}
+
+// IntervalDuration returns the time between hops.
+func (h *Hop) IntervalDuration() time.Duration {
+	if h.GetInterval() == 0 {
+		return defaultHopInterval
+	}
+	return time.Duration(h.Interval) * time.Second
+}
+
+type hopPacket struct {
+	payload *buf.Buffer
+	conn    net.PacketConn
+	addr    net.Addr
+}
+
+// packetQueue is the packets read from some connections, with the deadline for reading them.
+type packetQueue struct {
+	packets chan hopPacket
+	done    chan struct{}
+
+	access   sync.Mutex
+	deadline time.Time
+	notify   chan struct{}
+}
+
+func newPacketQueue() *packetQueue {
+	return &packetQueue{
+		packets: make(chan hopPacket, 256),
+		done:    make(chan struct{}),
+		notify:  make(chan struct{}),
+	}
+}
+
+// readFrom reads the packets of conn into the queue, until conn fails.
+func (q *packetQueue) readFrom(conn net.PacketConn) {
+	for {
+		payload := buf.New()
+		n, addr, err := conn.ReadFrom(payload.Extend(buf.Size))
+		if err != nil {
+			payload.Release()
+			return
+		}
+		payload.Resize(0, int32(n))
+		select {
+		case q.packets <- hopPacket{payload: payload, conn: conn, addr: addr}:
+		case <-q.done:
+			payload.Release()
+			return
+		}
+	}
+}
+
+func (q *packetQueue) read() (hopPacket, error) {
+	for {
+		q.access.Lock()
+		deadline, notify := q.deadline, q.notify
+		q.access.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return hopPacket{}, os.ErrDeadlineExceeded
+			}
+			timer = time.NewTimer(d)
+			timeout = timer.C
+		}
+
+		select {
+		case p := <-q.packets:
+			return p, nil
+		case <-q.done:
+			return hopPacket{}, io.ErrClosedPipe
+		case <-notify:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (q *packetQueue) setDeadline(t time.Time) {
+	q.access.Lock()
+	q.deadline = t
+	close(q.notify)
+	q.notify = make(chan struct{})
+	q.access.Unlock()
+}
+
+func (q *packetQueue) close() {
+	close(q.done)
+}
+
+// HopConn is a UDP connection that sends to a random port of a Hop, moving to another port and another local port
+// every interval. The packets of all the ports are read as from the first one, so that QUIC or mKCP sees a single
+// peer.
+type HopConn struct {
+	ctx        context.Context
+	dest       net.Destination
+	sockopt    *internet.SocketConfig
+	ports      []net.Port
+	remoteAddr *net.UDPAddr
+	queue      *packetQueue
+
+	access sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// DialHop dials dest from a new local port every interval of hop, to a random one of its ports.
+func DialHop(ctx context.Context, dest net.Destination, hop *Hop, sockopt *internet.SocketConfig) (*HopConn, error) {
+	ports := hop.AllPorts()
+	if len(ports) == 0 {
+		return nil, errors.New("no ports to hop")
+	}
+	dest.Network = net.Network_UDP
+	c := &HopConn{
+		ctx:     ctx,
+		dest:    dest,
+		sockopt: sockopt,
+		ports:   ports,
+		queue:   newPacketQueue(),
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	// The later hops go to the same IP as the first one.
+	if addr, ok := conn.RemoteAddr().(*net.UDPAddr); ok {
+		c.dest.Address = net.IPAddress(addr.IP)
+	}
+	c.remoteAddr, err = net.ResolveUDPAddr("udp", c.dest.NetAddr())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.conn = conn
+	go c.queue.readFrom(&internet.FakePacketConn{Conn: conn})
+	go c.hop(hop.IntervalDuration())
+	return c, nil
+}
+
+func (c *HopConn) dial() (net.Conn, error) {
+	dest := c.dest
+	dest.Port = c.ports[dice.Roll(len(c.ports))]
+	conn, err := internet.DialSystem(c.ctx, dest, c.sockopt)
+	if err != nil {
+		return nil, errors.New("failed to dial ", dest).Base(err)
+	}
+	errors.LogDebug(c.ctx, "hopping to ", dest, " from ", conn.LocalAddr())
+	return conn, nil
+}
+
+func (c *HopConn) hop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.queue.done:
+			return
+		}
+
+		conn, err := c.dial()
+		if err != nil {
+			errors.LogInfoInner(c.ctx, err, "failed to hop")
+			continue
+		}
+		c.access.Lock()
+		if c.closed {
+			c.access.Unlock()
+			conn.Close()
+			return
+		}
+		previous := c.conn
+		c.conn = conn
+		c.access.Unlock()
+
+		go c.queue.readFrom(&internet.FakePacketConn{Conn: conn})
+		time.AfterFunc(hopGracePeriod, func() {
+			previous.Close()
+		})
+	}
+}
+
+// ReadFrom implements net.PacketConn. addr is always the first destination.
+func (c *HopConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	p, err := c.queue.read()
+	if err != nil {
+		return 0, nil, err
+	}
+	n := copy(b, p.payload.Bytes())
+	p.payload.Release()
+	return n, c.remoteAddr, nil
+}
+
+// WriteTo implements net.PacketConn. addr is ignored, as packets go to the current port.
+func (c *HopConn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	return c.Write(b)
+}
+
+// Read implements net.Conn.
+func (c *HopConn) Read(b []byte) (int, error) {
+	n, _, err := c.ReadFrom(b)
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *HopConn) Write(b []byte) (int, error) {
+	c.access.Lock()
+	conn := c.conn
+	c.access.Unlock()
+	return conn.Write(b)
+}
+
+// Close implements net.Conn.
+func (c *HopConn) Close() error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.queue.close()
+	return c.conn.Close()
+}
+
+// LocalAddr implements net.Conn. It is the address of the current connection.
+func (c *HopConn) LocalAddr() net.Addr {
+	c.access.Lock()
+	defer c.access.Unlock()
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr implements net.Conn. It is the first destination.
+func (c *HopConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// SetDeadline implements net.Conn.
+func (c *HopConn) SetDeadline(t time.Time) error {
+	c.queue.setDeadline(t)
+	return nil
+}
+
+// SetReadDeadline implements net.Conn.
+func (c *HopConn) SetReadDeadline(t time.Time) error {
+	c.queue.setDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline implements net.Conn. Writes of UDP don't block.
+func (c *HopConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadBuffer keeps quic-go from warning about the buffer size of UDP, like FakePacketConn.
+func (c *HopConn) SetReadBuffer(int) error {
+	return nil
+}
+
+type hopRoute struct {
+	conn     net.PacketConn
+	lastSeen time.Time
+}
+
+// HopListener is a UDP PacketConn listening on the port of an inbound and the ports of a Hop, which replies to a
+// client from the port it sent to last.
+type HopListener struct {
+	conns []net.PacketConn
+	queue *packetQueue
+
+	access sync.Mutex
+	routes map[string]*hopRoute
+}
+
+// ListenHop listens UDP on port and the ports of hop, with a socket and a goroutine for each port. The config
+// builder keeps the ports of hop under MaxHopPorts.
+func ListenHop(ctx context.Context, address net.Address, port net.Port, hop *Hop, sockopt *internet.SocketConfig) (*HopListener, error) {
+	l := &HopListener{
+		queue:  newPacketQueue(),
+		routes: make(map[string]*hopRoute),
+	}
+	ports := append([]net.Port{port}, hop.AllPorts()...)
+	listened := make(map[net.Port]bool)
+	for _, p := range ports {
+		if listened[p] {
+			continue
+		}
+		listened[p] = true
+		conn, err := internet.ListenSystemPacket(ctx, &net.UDPAddr{
+			IP:   address.IP(),
+			Port: int(p),
+		}, sockopt)
+		if err != nil {
+			l.Close()
+			return nil, errors.New("failed to listen UDP on ", address, ":", p).Base(err)
+		}
+		l.conns = append(l.conns, conn)
+	}
+	for _, conn := range l.conns {
+		go l.queue.readFrom(conn)
+	}
+	errors.LogInfo(ctx, "listening UDP on ", address, ":", port, " and ", len(l.conns)-1, " hopping ports")
+	go l.cleanup()
+	return l, nil
+}
+
+func (l *HopListener) cleanup() {
+	ticker := time.NewTicker(hopRouteTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-l.queue.done:
+			return
+		}
+		l.access.Lock()
+		for addr, route := range l.routes {
+			if time.Since(route.lastSeen) > hopRouteTimeout {
+				delete(l.routes, addr)
+			}
+		}
+		l.access.Unlock()
+	}
+}
+
+// ReadFrom implements net.PacketConn.
+func (l *HopListener) ReadFrom(b []byte) (int, net.Addr, error) {
+	p, err := l.queue.read()
+	if err != nil {
+		return 0, nil, err
+	}
+	n := copy(b, p.payload.Bytes())
+	p.payload.Release()
+
+	l.access.Lock()
+	route := l.routes[p.addr.String()]
+	if route == nil {
+		route = new(hopRoute)
+		l.routes[p.addr.String()] = route
+	}
+	route.conn = p.conn
+	route.lastSeen = time.Now()
+	l.access.Unlock()
+	return n, p.addr, nil
+}
+
+// WriteTo implements net.PacketConn.
+func (l *HopListener) WriteTo(b []byte, addr net.Addr) (int, error) {
+	conn := l.conns[0]
+	l.access.Lock()
+	if route := l.routes[addr.String()]; route != nil {
+		conn = route.conn
+	}
+	l.access.Unlock()
+	return conn.WriteTo(b, addr)
+}
+
+// Close implements net.PacketConn.
+func (l *HopListener) Close() error {
+	select {
+	case <-l.queue.done:
+		return nil
+	default:
+	}
+	l.queue.close()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+// LocalAddr implements net.PacketConn. It is the address of the port of the inbound.
+func (l *HopListener) LocalAddr() net.Addr {
+	return l.conns[0].LocalAddr()
+}
+
+// SetDeadline implements net.PacketConn.
+func (l *HopListener) SetDeadline(t time.Time) error {
+	l.queue.setDeadline(t)
+	return nil
+}
+
+// SetReadDeadline implements net.PacketConn.
+func (l *HopListener) SetReadDeadline(t time.Time) error {
+	l.queue.setDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline implements net.PacketConn.
+func (l *HopListener) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadBuffer keeps quic-go from warning about the buffer size of UDP, like FakePacketConn.
+func (l *HopListener) SetReadBuffer(int) error {
+	return nil
+}
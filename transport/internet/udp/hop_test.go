@@ -0,0 +1,68 @@
+package udp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	udpserver "github.com/xtls/xray-core/testing/servers/udp"
+	. "github.com/xtls/xray-core/transport/internet/udp"
+)
+
+func TestHop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	port := udpserver.PickPort()
+	hopPorts := &net.PortList{}
+	for i := 0; i < 3; i++ {
+		p := uint32(udpserver.PickPort())
+		hopPorts.Range = append(hopPorts.Range, &net.PortRange{From: p, To: p})
+	}
+	hop := &Hop{
+		Ports:    hopPorts,
+		Interval: 1,
+	}
+
+	listener, err := ListenHop(ctx, net.LocalHostIP, port, hop, nil)
+	common.Must(err)
+	defer listener.Close()
+
+	localPorts := make(chan net.Port, 16)
+	go func() {
+		b := make([]byte, 1500)
+		for {
+			n, addr, err := listener.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			localPorts <- net.Port(addr.(*net.UDPAddr).Port)
+			listener.WriteTo(b[:n], addr)
+		}
+	}()
+
+	conn, err := DialHop(ctx, net.UDPDestination(net.LocalHostIP, port), hop, nil)
+	common.Must(err)
+	defer conn.Close()
+
+	seen := make(map[net.Port]bool)
+	b := make([]byte, 1500)
+	for i := 0; i < 5; i++ {
+		common.Must2(conn.Write([]byte("hop")))
+		common.Must(conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := conn.Read(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b[:n]) != "hop" {
+			t.Error("payload: ", string(b[:n]))
+		}
+		seen[<-localPorts] = true
+		time.Sleep(600 * time.Millisecond)
+	}
+	if len(seen) < 2 {
+		t.Error("local ports: ", seen)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/trace"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/browser_dialer"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -62,8 +63,17 @@ func dialWebSocket(ctx context.Context, dest net.Destination, streamSettings *in
 	if tConfig != nil {
 		protocol = "wss"
 		tlsConfig := tConfig.GetTLSConfig(tls.WithDestination(dest), tls.WithNextProto("http/1.1"))
+		if err := tConfig.ApplyECH(ctx, tlsConfig); err != nil {
+			return nil, err
+		}
 		dialer.TLSClientConfig = tlsConfig
-		if fingerprint := tls.GetFingerprint(tConfig.Fingerprint); fingerprint != nil {
+		if useH2(tlsConfig) {
+			// A stream of an HTTP/2 connection shared by the calls for the same dest and streamSettings
+			transport := getH2Transport(dest, streamSettings, tConfig, tlsConfig)
+			dialer.NetDialTLSContext = func(handshakeCtx context.Context, _, _ string) (gonet.Conn, error) {
+				return newH2ClientConn(ctx, handshakeCtx, transport), nil
+			}
+		} else if fingerprint := tConfig.ClientFingerprint(tlsConfig); fingerprint != nil {
 			dialer.NetDialTLSContext = func(_ context.Context, _, addr string) (gonet.Conn, error) {
 				// Like the NetDial in the dialer
 				pconn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
@@ -117,7 +127,11 @@ func dialWebSocket(ctx context.Context, dest net.Destination, streamSettings *in
 		header.Set("Sec-WebSocket-Protocol", base64.RawURLEncoding.EncodeToString(ed))
 	}
 
-	conn, resp, err := dialer.DialContext(ctx, uri, header)
+	// The span covers the TCP dial too, which gorilla/websocket does with the handshake.
+	spanCtx, span := trace.Start(ctx, "websocket.handshake")
+	span.SetAttribute("http.path", wsSettings.GetNormalizedPath())
+	conn, resp, err := dialer.DialContext(spanCtx, uri, header)
+	span.End(err)
 	if err != nil {
 		var reason string
 		if resp != nil {
@@ -0,0 +1,49 @@
+// Package extconnect enables extended CONNECT (RFC 8441) in the HTTP/2 servers of net/http and x/net/http2, which
+// only advertise it if GODEBUG has http2xconnect=1 when they are initialized.
+//
+// It imports no package of HTTP, so it's initialized before them: the packages of a program are initialized in the
+// order of their import paths, each once its imports are. A //go:debug directive can't take its place, as the go
+// command refuses settings it doesn't know, and both servers read the environment rather than the settings of the
+// binary. TestExtendedConnect of package main checks the order in Xray.
+//
+// Once the servers are initialized, Restore puts GODEBUG back, so that the processes started by Xray don't inherit
+// the setting. Extended CONNECT stays enabled in all the HTTP/2 servers of the process.
+package extconnect
+
+import (
+	"os"
+	"strings"
+)
+
+// changed is whether init changed GODEBUG, from original, which is unset unless originalSet.
+var (
+	changed     bool
+	original    string
+	originalSet bool
+)
+
+func init() {
+	godebug, set := os.LookupEnv("GODEBUG")
+	if strings.Contains(godebug, "http2xconnect=") {
+		return
+	}
+	changed, original, originalSet = true, godebug, set
+	if godebug != "" {
+		godebug += ","
+	}
+	os.Setenv("GODEBUG", godebug+"http2xconnect=1")
+}
+
+// Restore puts GODEBUG back as it was before init. It must be called once net/http and x/net/http2 are initialized,
+// which is the case in the init of a package importing them.
+func Restore() {
+	if !changed {
+		return
+	}
+	changed = false
+	if originalSet {
+		os.Setenv("GODEBUG", original)
+	} else {
+		os.Unsetenv("GODEBUG")
+	}
+}
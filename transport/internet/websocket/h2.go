@@ -0,0 +1,439 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	gotls "crypto/tls"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/websocket/extconnect"
+	"golang.org/x/net/http2"
+)
+
+// WebSocket over HTTP/2 (RFC 8441) is a stream of an extended CONNECT request, with the frames of a connection after
+// an Upgrade of HTTP/1.1. gorilla/websocket only does the latter, so h2ClientConn and h2ServerConn are streams that
+// translate the handshake of HTTP/1.1 it writes, and pass its frames as is.
+
+func init() {
+	// net/http and x/net/http2 have read GODEBUG by now, as this package imports them.
+	extconnect.Restore()
+}
+
+const (
+	h2Protocol        = "websocket"
+	h2IdleTimeout     = 5 * time.Minute
+	h2KeepAlivePeriod = 45 * time.Second
+	acceptGUID        = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+var headerEnd = []byte("\r\n\r\n")
+
+// useH2 returns whether WebSocket is over HTTP/2, which is when h2 is the only ALPN.
+func useH2(tlsConfig *gotls.Config) bool {
+	return len(tlsConfig.NextProtos) == 1 && tlsConfig.NextProtos[0] == "h2"
+}
+
+// isExtendedConnect returns whether request is a WebSocket over HTTP/2.
+func isExtendedConnect(request *http.Request) bool {
+	return request.ProtoMajor == 2 && request.Method == http.MethodConnect && request.Header.Get(":protocol") == h2Protocol
+}
+
+func computeAcceptKey(challengeKey string) string {
+	h := sha1.New()
+	h.Write([]byte(challengeKey))
+	h.Write([]byte(acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+type dialerConf struct {
+	net.Destination
+	*internet.MemoryStreamConfig
+}
+
+var (
+	globalH2TransportMap    map[dialerConf]*http2.Transport
+	globalH2TransportAccess sync.Mutex
+)
+
+// getH2Transport returns the HTTP/2 transport to dest, whose connections are shared by the calls for the same dest and
+// streamSettings.
+func getH2Transport(dest net.Destination, streamSettings *internet.MemoryStreamConfig, tConfig *tls.Config, tlsConfig *gotls.Config) *http2.Transport {
+	globalH2TransportAccess.Lock()
+	defer globalH2TransportAccess.Unlock()
+
+	if globalH2TransportMap == nil {
+		globalH2TransportMap = make(map[dialerConf]*http2.Transport)
+	}
+	key := dialerConf{dest, streamSettings}
+	if transport, found := globalH2TransportMap[key]; found {
+		return transport
+	}
+
+	transport := &http2.Transport{
+		DialTLSContext: func(ctx context.Context, _, addr string, _ *gotls.Config) (net.Conn, error) {
+			pconn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+			if err != nil {
+				return nil, err
+			}
+			var cn tls.Interface
+			if fingerprint := tConfig.ClientFingerprint(tlsConfig); fingerprint != nil {
+				cn = tls.UClient(pconn, tlsConfig, fingerprint).(*tls.UConn)
+			} else {
+				cn = tls.Client(pconn, tlsConfig).(*tls.Conn)
+			}
+			if err := cn.HandshakeContext(ctx); err != nil {
+				pconn.Close()
+				return nil, err
+			}
+			if _, ok := cn.(*tls.UConn); ok && !tlsConfig.InsecureSkipVerify {
+				if err := cn.VerifyHostname(tlsConfig.ServerName); err != nil {
+					cn.Close()
+					return nil, err
+				}
+			}
+			if protocol := cn.NegotiatedProtocol(); protocol != "h2" {
+				cn.Close()
+				return nil, errors.New("failed to negotiate h2 with ", addr, ", got: ", protocol)
+			}
+			return cn, nil
+		},
+		IdleConnTimeout: h2IdleTimeout,
+		ReadIdleTimeout: h2KeepAlivePeriod,
+	}
+	globalH2TransportMap[key] = transport
+	return transport
+}
+
+// h2ClientConn is a stream of an extended CONNECT request, made when the request of HTTP/1.1 is written, and whose
+// response is read as one of HTTP/1.1 before the frames.
+type h2ClientConn struct {
+	ctx          context.Context
+	cancel       context.CancelFunc
+	handshakeCtx context.Context
+	transport    *http2.Transport
+
+	handshake  bytes.Buffer
+	response   *bytes.Reader
+	body       io.ReadCloser
+	writer     *io.PipeWriter
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+// newH2ClientConn returns an h2ClientConn of transport, whose stream lasts for ctx, and whose request is canceled
+// if handshakeCtx ends before it's answered.
+func newH2ClientConn(ctx context.Context, handshakeCtx context.Context, transport *http2.Transport) *h2ClientConn {
+	ctx, cancel := context.WithCancel(ctx)
+	return &h2ClientConn{
+		ctx:          ctx,
+		cancel:       cancel,
+		handshakeCtx: handshakeCtx,
+		transport:    transport,
+		localAddr:    &net.TCPAddr{},
+		remoteAddr:   &net.TCPAddr{},
+	}
+}
+
+func (c *h2ClientConn) Write(b []byte) (int, error) {
+	if c.writer != nil {
+		n, err := c.writer.Write(b)
+		if err == io.ErrClosedPipe && c.ctx.Err() == nil {
+			// The request is closed once the server ends the stream, whose frames are still to be read, like the
+			// writes to a TCP connection closed by the server, which don't fail either.
+			return len(b), nil
+		}
+		return n, err
+	}
+	c.handshake.Write(b)
+	if !bytes.Contains(c.handshake.Bytes(), headerEnd) {
+		return len(b), nil
+	}
+	if err := c.request(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// request sends the extended CONNECT of the request of HTTP/1.1 in handshake.
+func (c *h2ClientConn) request() error {
+	h1Request, err := http.ReadRequest(bufio.NewReader(&c.handshake))
+	if err != nil {
+		return errors.New("failed to read WebSocket request").Base(err)
+	}
+	u, err := url.Parse("https://" + h1Request.Host + h1Request.RequestURI)
+	if err != nil {
+		return errors.New("failed to parse WebSocket request URI").Base(err)
+	}
+	challengeKey := h1Request.Header.Get("Sec-WebSocket-Key")
+	header := h1Request.Header.Clone()
+	header.Del("Connection")
+	header.Del("Upgrade")
+	header.Del("Sec-WebSocket-Key")
+	header.Set(":protocol", h2Protocol)
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.localAddr = info.Conn.LocalAddr()
+			c.remoteAddr = info.Conn.RemoteAddr()
+		},
+	}
+	reader, writer := io.Pipe()
+	request := (&http.Request{
+		Method:        http.MethodConnect,
+		URL:           u,
+		Host:          h1Request.Host,
+		Header:        header,
+		Body:          reader,
+		ContentLength: -1,
+	}).WithContext(httptrace.WithClientTrace(c.ctx, trace))
+
+	stop := context.AfterFunc(c.handshakeCtx, c.cancel)
+	response, err := c.transport.RoundTrip(request)
+	if !stop() && err == nil {
+		response.Body.Close()
+		err = c.handshakeCtx.Err()
+	}
+	if err != nil {
+		writer.Close()
+		return errors.New("failed to send extended CONNECT").Base(err)
+	}
+	c.writer = writer
+	c.body = response.Body
+
+	h1Response := &http.Response{
+		StatusCode: response.StatusCode,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     response.Header.Clone(),
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		h1Response.StatusCode = http.StatusSwitchingProtocols
+		h1Response.Header.Set("Upgrade", "websocket")
+		h1Response.Header.Set("Connection", "Upgrade")
+		h1Response.Header.Set("Sec-WebSocket-Accept", computeAcceptKey(challengeKey))
+	}
+	var b bytes.Buffer
+	if err := h1Response.Write(&b); err != nil {
+		return err
+	}
+	c.response = bytes.NewReader(b.Bytes())
+	return nil
+}
+
+func (c *h2ClientConn) Read(b []byte) (int, error) {
+	if c.response == nil {
+		return 0, errors.New("WebSocket request is not sent")
+	}
+	if c.response.Len() > 0 {
+		return c.response.Read(b)
+	}
+	return c.body.Read(b)
+}
+
+func (c *h2ClientConn) Close() error {
+	c.cancel()
+	if c.writer != nil {
+		c.writer.Close()
+		c.body.Close()
+	}
+	return nil
+}
+
+func (c *h2ClientConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *h2ClientConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// The stream has no deadline, but the request does, which is of handshakeCtx.
+
+func (c *h2ClientConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (c *h2ClientConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (c *h2ClientConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// h2ServerConn is a stream of an extended CONNECT request, whose response is written when the one of HTTP/1.1 is.
+// It must be ended by serve before the handler returns.
+type h2ServerConn struct {
+	writer     http.ResponseWriter
+	controller *http.ResponseController
+	body       io.ReadCloser
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	handshake  bytes.Buffer
+	responded  bool
+	done       chan struct{}
+	once       sync.Once
+
+	access      sync.Mutex
+	closed      bool
+	writeAccess sync.Mutex
+}
+
+func newH2ServerConn(writer http.ResponseWriter, request *http.Request) *h2ServerConn {
+	c := &h2ServerConn{
+		writer:     writer,
+		controller: http.NewResponseController(writer),
+		body:       request.Body,
+		done:       make(chan struct{}),
+	}
+	c.localAddr, _ = request.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if c.localAddr == nil {
+		c.localAddr = &net.TCPAddr{}
+	}
+	var err error
+	if c.remoteAddr, err = net.ResolveTCPAddr("tcp", request.RemoteAddr); err != nil {
+		c.remoteAddr = &net.TCPAddr{}
+	}
+	return c
+}
+
+// upgradeRequest returns request as the Upgrade of HTTP/1.1 that gorilla/websocket takes.
+func upgradeRequest(request *http.Request) (*http.Request, error) {
+	challengeKey := make([]byte, 16)
+	if _, err := rand.Read(challengeKey); err != nil {
+		return nil, err
+	}
+	h1Request := request.Clone(request.Context())
+	h1Request.Method = http.MethodGet
+	h1Request.Header.Del(":protocol")
+	h1Request.Header.Set("Connection", "Upgrade")
+	h1Request.Header.Set("Upgrade", "websocket")
+	h1Request.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(challengeKey))
+	return h1Request, nil
+}
+
+// Hijack implements http.Hijacker for the Upgrade of gorilla/websocket.
+func (c *h2ServerConn) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return c, bufio.NewReadWriter(bufio.NewReader(c), bufio.NewWriter(c)), nil
+}
+
+// Header, Write and WriteHeader are of http.ResponseWriter, for the errors of the Upgrade.
+
+func (c *h2ServerConn) Header() http.Header {
+	return c.writer.Header()
+}
+
+func (c *h2ServerConn) WriteHeader(statusCode int) {
+	c.writer.WriteHeader(statusCode)
+	c.responded = true
+}
+
+func (c *h2ServerConn) Read(b []byte) (int, error) {
+	return c.body.Read(b)
+}
+
+func (c *h2ServerConn) Write(b []byte) (int, error) {
+	c.writeAccess.Lock()
+	defer c.writeAccess.Unlock()
+	c.access.Lock()
+	closed := c.closed
+	c.access.Unlock()
+	if closed {
+		return 0, io.ErrClosedPipe
+	}
+	if !c.responded {
+		c.handshake.Write(b)
+		if !bytes.Contains(c.handshake.Bytes(), headerEnd) {
+			return len(b), nil
+		}
+		h1Response, err := http.ReadResponse(bufio.NewReader(&c.handshake), nil)
+		if err != nil {
+			return 0, errors.New("failed to read WebSocket response").Base(err)
+		}
+		for k, v := range h1Response.Header {
+			switch k {
+			case "Connection", "Upgrade", "Sec-Websocket-Accept":
+			default:
+				c.writer.Header()[k] = v
+			}
+		}
+		c.writer.WriteHeader(http.StatusOK)
+		c.responded = true
+		return len(b), c.controller.Flush()
+	}
+	n, err := c.writer.Write(b)
+	if err != nil {
+		return n, err
+	}
+	return n, c.controller.Flush()
+}
+
+func (c *h2ServerConn) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
+
+// serve waits for c to be closed, or the request to end, and then for its writing to end.
+func (c *h2ServerConn) serve(ctx context.Context) {
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+	}
+	c.access.Lock()
+	c.closed = true
+	c.access.Unlock()
+	if !c.writeAccess.TryLock() {
+		// A write blocked by the flow control of the client.
+		c.controller.SetWriteDeadline(time.Now())
+		c.writeAccess.Lock()
+	}
+	c.writeAccess.Unlock()
+}
+
+func (c *h2ServerConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *h2ServerConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *h2ServerConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *h2ServerConn) SetReadDeadline(t time.Time) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.closed {
+		return nil
+	}
+	return c.controller.SetReadDeadline(t)
+}
+
+func (c *h2ServerConn) SetWriteDeadline(t time.Time) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.closed {
+		return nil
+	}
+	return c.controller.SetWriteDeadline(t)
+}
@@ -17,13 +17,15 @@ import (
 	"github.com/xtls/xray-core/common/net"
 	http_proto "github.com/xtls/xray-core/common/protocol/http"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/fallback"
 	v2tls "github.com/xtls/xray-core/transport/internet/tls"
 )
 
 type requestHandler struct {
-	host string
-	path string
-	ln   *Listener
+	host     string
+	path     string
+	ln       *Listener
+	fallback http.Handler
 }
 
 var replacer = strings.NewReplacer("+", "-", "/", "_", "=", "")
@@ -40,15 +42,39 @@ var upgrader = &websocket.Upgrader{
 func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	if len(h.host) > 0 && !internet.IsValidHTTPHost(request.Host, h.host) {
 		errors.LogInfo(context.Background(), "failed to validate host, request:", request.Host, ", config:", h.host)
-		writer.WriteHeader(http.StatusNotFound)
+		h.reject(writer, request, http.StatusNotFound)
 		return
 	}
 	if request.URL.Path != h.path {
 		errors.LogInfo(context.Background(), "failed to validate path, request:", request.URL.Path, ", config:", h.path)
-		writer.WriteHeader(http.StatusNotFound)
+		h.reject(writer, request, http.StatusNotFound)
 		return
 	}
+	if h.fallback != nil && !websocket.IsWebSocketUpgrade(request) && !isExtendedConnect(request) {
+		errors.LogInfo(context.Background(), "not a WebSocket upgrade request")
+		h.fallback.ServeHTTP(writer, request)
+		return
+	}
+
+	if isExtendedConnect(request) {
+		h2Conn := newH2ServerConn(writer, request)
+		h1Request, err := upgradeRequest(request)
+		if err != nil {
+			errors.LogInfoInner(context.Background(), err, "failed to convert extended CONNECT")
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// The stream ends with the handler, so it waits for the connection to be closed.
+		if h.upgrade(h2Conn, h1Request) {
+			h2Conn.serve(request.Context())
+		}
+		return
+	}
+	h.upgrade(writer, request)
+}
 
+// upgrade passes the WebSocket connection of the Upgrade of request to the listener, returning whether it's done.
+func (h *requestHandler) upgrade(writer http.ResponseWriter, request *http.Request) bool {
 	var extraReader io.Reader
 	responseHeader := http.Header{}
 	if str := request.Header.Get("Sec-WebSocket-Protocol"); str != "" {
@@ -61,7 +87,7 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 	conn, err := upgrader.Upgrade(writer, request, responseHeader)
 	if err != nil {
 		errors.LogInfoInner(context.Background(), err, "failed to convert to WebSocket connection")
-		return
+		return false
 	}
 
 	forwardedAddrs := http_proto.ParseXForwardedFor(request.Header)
@@ -74,6 +100,16 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 	}
 
 	h.ln.addConn(NewConnection(conn, remoteAddr, extraReader, h.ln.config.HeartbeatPeriod))
+	return true
+}
+
+// reject passes request to the fallback if there is one, or answers it with status.
+func (h *requestHandler) reject(writer http.ResponseWriter, request *http.Request, status int) {
+	if h.fallback != nil {
+		h.fallback.ServeHTTP(writer, request)
+		return
+	}
+	writer.WriteHeader(status)
 }
 
 type Listener struct {
@@ -130,12 +166,20 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 
 	l.listener = listener
 
+	handler := &requestHandler{
+		host: wsSettings.Host,
+		path: wsSettings.GetNormalizedPath(),
+		ln:   l,
+	}
+	if wsSettings.Fallback != nil {
+		if handler.fallback, err = fallback.NewHandler(wsSettings.Fallback); err != nil {
+			listener.Close()
+			return nil, errors.New("failed to set up fallback for WS").Base(err)
+		}
+	}
+
 	l.server = http.Server{
-		Handler: &requestHandler{
-			host: wsSettings.Host,
-			path: wsSettings.GetNormalizedPath(),
-			ln:   l,
-		},
+		Handler:           handler,
 		ReadHeaderTimeout: time.Second * 4,
 		MaxHeaderBytes:    8192,
 	}
@@ -2,7 +2,12 @@ package websocket_test
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +16,7 @@ import (
 	"github.com/xtls/xray-core/common/protocol/tls/cert"
 	"github.com/xtls/xray-core/testing/servers/tcp"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/fallback"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
 	. "github.com/xtls/xray-core/transport/internet/websocket"
@@ -151,3 +157,84 @@ func Test_listenWSAndDial_TLS(t *testing.T) {
 		t.Error("end: ", end, " start: ", start)
 	}
 }
+
+func Test_listenWSFallback(t *testing.T) {
+	dir := t.TempDir()
+	common.Must(os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello"), 0o644))
+
+	listenPort := tcp.PickPort()
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:     "ws",
+			Fallback: &fallback.Config{Dir: dir},
+		},
+	}, func(conn stat.Connection) {
+		conn.Close()
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	response, err := http.Get("http://" + net.LocalHostIP.String() + ":" + listenPort.String() + "/hello.txt")
+	common.Must(err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	common.Must(err)
+	if response.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Error("status: ", response.StatusCode, ", body: ", string(body))
+	}
+}
+
+func Test_listenWSAndDial_H2(t *testing.T) {
+	listenPort := tcp.PickPort()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:            "ws",
+			Ed:              2048,
+			HeartbeatPeriod: 1,
+		},
+		SecurityType: "tls",
+		SecuritySettings: &tls.Config{
+			AllowInsecure: true,
+			NextProtocol:  []string{"h2"},
+			Certificate:   []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil, cert.CommonName("localhost")))},
+		},
+	}
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, listenPort, streamSettings, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			defer c.Close()
+
+			var b [1024]byte
+			n, err := c.Read(b[:])
+			if err != nil {
+				return
+			}
+			time.Sleep(2 * time.Second) // for the heartbeats
+			common.Must2(c.Write([]byte(string(b[:n]) + " from " + c.RemoteAddr().String())))
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	var responses []string
+	for i := 0; i < 2; i++ {
+		conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), listenPort), streamSettings)
+		common.Must(err)
+		_, err = conn.Write([]byte("Test connection"))
+		common.Must(err)
+
+		var b [1024]byte
+		n, err := conn.Read(b[:])
+		common.Must(err)
+		responses = append(responses, string(b[:n]))
+		_ = conn.Close()
+	}
+	if !strings.HasPrefix(responses[0], "Test connection from ") {
+		t.Error("response: ", responses[0])
+	}
+	// The streams share an HTTP/2 connection.
+	if responses[0] != responses[1] {
+		t.Error("responses: ", responses)
+	}
+}